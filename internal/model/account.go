@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+type ExportStatus = string
+const (
+	ExportPending ExportStatus = "pending"
+	ExportReady ExportStatus = "ready"
+	ExportFailed ExportStatus = "failed"
+)
+
+// Export is a takeout archive of all the user's data, a user has at most one
+type Export struct {
+	UserId Id
+	Status ExportStatus
+	Archive []byte
+	CreatedAt time.Time
+	CompletedAt time.Time
+}
+
+// AccountDeletion is a scheduled removal of the user which can be cancelled until DeleteAfter
+type AccountDeletion struct {
+	UserId Id
+	DeleteAfter time.Time
+}
@@ -0,0 +1,10 @@
+package model
+
+import "time"
+
+// CalendarFeed lets calendar clients read the user's notes by a secret token, only its hash is stored
+type CalendarFeed struct {
+	UserId Id
+	TokenHash []byte
+	CreatedAt time.Time
+}
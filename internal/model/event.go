@@ -0,0 +1,42 @@
+package model
+
+import "time"
+
+type EventType = string
+const (
+	EventNoteCreated EventType = "note.created"
+	EventNoteUpdated EventType = "note.updated"
+	// EventNoteCompleted follows the note.updated event of the update which finished the note
+	EventNoteCompleted EventType = "note.completed"
+	EventNoteDeleted EventType = "note.deleted"
+	EventUserCreated EventType = "user.created"
+	EventUserUpdated EventType = "user.updated"
+	EventUserDeleted EventType = "user.deleted"
+)
+
+// Event is a domain event kept in the outbox with the change it is about until it is published
+type Event struct {
+	Id Id
+	Type EventType
+	UserId Id
+	// SubjectId is the note or the user the event is about
+	SubjectId Id
+	// Payload is the json document published to sinks
+	Payload []byte
+	CreatedAt time.Time
+	// Attempts is the number of failed publishes, NextAttemptAt is when the event is due
+	Attempts int
+	NextAttemptAt time.Time
+	LastError string
+}
+
+func NewEvent(eventType EventType, userId, subjectId Id, payload []byte, createdAt time.Time) *Event {
+	return &Event{
+		Type: eventType,
+		UserId: userId,
+		SubjectId: subjectId,
+		Payload: payload,
+		CreatedAt: createdAt,
+		NextAttemptAt: createdAt,
+	}
+}
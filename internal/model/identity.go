@@ -0,0 +1,16 @@
+package model
+
+// UserIdentity links a local user to an account of an external identity provider
+type UserIdentity struct {
+	UserId   Id
+	Provider string
+	Subject  string
+}
+
+func NewUserIdentity(userId Id, provider, subject string) *UserIdentity {
+	return &UserIdentity{
+		UserId:   userId,
+		Provider: provider,
+		Subject:  subject,
+	}
+}
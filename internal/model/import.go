@@ -0,0 +1,39 @@
+package model
+
+type ImportStatus = string
+const (
+	ImportCreated ImportStatus = "created"
+	ImportUpdated ImportStatus = "updated"
+	ImportSkipped ImportStatus = "skipped"
+)
+
+// ImportItem is the outcome of importing one calendar item
+type ImportItem struct {
+	// Line of the item in csv and ndjson files, its number in json arrays
+	Line int
+	Uid string
+	// NoteId is 0 for skipped items which did not match a note
+	NoteId Id
+	Status ImportStatus
+	Reason string
+}
+
+type ImportReport struct {
+	Created int
+	Updated int
+	Skipped int
+	Items []ImportItem
+}
+
+func(r *ImportReport) Add(item ImportItem) {
+	switch item.Status {
+	case ImportCreated:
+		r.Created++
+	case ImportUpdated:
+		r.Updated++
+	case ImportSkipped:
+		r.Skipped++
+	}
+
+	r.Items = append(r.Items, item)
+}
@@ -0,0 +1,11 @@
+package model
+
+import "time"
+
+// LoginAttempts counts consecutive failed logins for a user name or a client address
+type LoginAttempts struct {
+	Key         string
+	Failures    int
+	LastFailure time.Time
+	LockedUntil time.Time
+}
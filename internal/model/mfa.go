@@ -0,0 +1,22 @@
+package model
+
+type Mfa struct {
+	UserId        Id
+	Secret        string
+	IsEnabled     bool
+	RecoveryCodes [][]byte
+	// LastStep is the time step of the last accepted totp code
+	LastStep      int64
+}
+
+func NewMfa(userId Id, secret string) *Mfa {
+	return &Mfa{
+		UserId: userId,
+		Secret: secret,
+	}
+}
+
+type MfaEnrollment struct {
+	Secret string
+	Uri    string
+}
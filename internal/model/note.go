@@ -7,10 +7,29 @@ type Id = int64
 type Note struct {
 	Id     Id
 	UserId Id
+	// OrganizationId is the organization of the user, it is set by the repository from the tenant of the context
+	OrganizationId Id
 	Title  string
 	Text string
 	Date time.Time
 	IsFinished bool
+	// TimeZone the note was created in, empty when unknown
+	TimeZone TimeZone
+	// IsFloating notes keep wall-clock Date and are shown at the same local time in any zone
+	IsFloating bool
+	// ReminderOffset is how long before Date to remind about the note
+	ReminderOffset time.Duration
+	// Uid of the calendar item the note was imported from, empty for notes created here
+	Uid string
+	// Priority is a todo.txt priority from A to Z, empty when the note has none
+	Priority string
+	// Projects and Contexts are todo.txt +project and @context tags without the sign
+	Projects []string
+	Contexts []string
+	// FinishedAt is when the note was finished, nil when it is not or the time is unknown
+	FinishedAt *time.Time
+	// Version grows with every change of the user's notes, it is set by the repository
+	Version int64
 }
 
 func NewNote(id Id, usedId Id, title, text string, date time.Time, isFinished bool) *Note {
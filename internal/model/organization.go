@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// DefaultOrganizationId is the organization of users and notes created before organizations
+// and of every request that does not name one
+const DefaultOrganizationId Id = 1
+
+const DefaultOrganizationName = "default"
+
+// Organization is a tenant, it owns users and their notes which are never visible to other organizations
+type Organization struct {
+	Id Id
+	Name string
+	CreatedAt time.Time
+}
+
+func NewOrganization(name string, createdAt time.Time) *Organization {
+	return &Organization{
+		Name: name,
+		CreatedAt: createdAt,
+	}
+}
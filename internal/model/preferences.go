@@ -0,0 +1,36 @@
+package model
+
+import "time"
+
+type DateFormat = string
+const (
+	DateFormatIso DateFormat = "YYYY-MM-DD"
+	DateFormatEu DateFormat = "DD.MM.YYYY"
+	DateFormatUs DateFormat = "MM/DD/YYYY"
+)
+
+type Preferences struct {
+	DisplayName string
+	Locale string
+	WeekStart time.Weekday
+	// ReminderOffset is applied to new notes which do not set their own
+	ReminderOffset time.Duration
+	DateFormat DateFormat
+}
+
+func DefaultPreferences() Preferences {
+	return Preferences{
+		Locale: "en",
+		WeekStart: time.Monday,
+		DateFormat: DateFormatIso,
+	}
+}
+
+// PreferencesUpdate changes only the fields which are set
+type PreferencesUpdate struct {
+	DisplayName *string
+	Locale *string
+	WeekStart *time.Weekday
+	ReminderOffset *time.Duration
+	DateFormat *DateFormat
+}
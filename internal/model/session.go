@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+type Session struct {
+	Id         Id
+	UserId     Id
+	UserAgent  string
+	Ip         string
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	ExpiresAt  time.Time
+}
+
+func NewSession(userId Id, userAgent, ip string, createdAt, expiresAt time.Time) *Session {
+	return &Session{
+		UserId:     userId,
+		UserAgent:  userAgent,
+		Ip:         ip,
+		CreatedAt:  createdAt,
+		LastSeenAt: createdAt,
+		ExpiresAt:  expiresAt,
+	}
+}
@@ -0,0 +1,10 @@
+package model
+
+// NoteChange is the last change of a note, a deleted note is a tombstone without Note
+type NoteChange struct {
+	// Version is the position of the change in the user's change sequence
+	Version int64
+	NoteId Id
+	Deleted bool
+	Note *Note
+}
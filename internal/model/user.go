@@ -2,9 +2,12 @@ package model
 
 type User struct {
 	Id           Id
+	// OrganizationId is set by the repository from the tenant of the context
+	OrganizationId Id
 	Name         string
 	PasswordHash []byte
 	TimeZone     string
+	Preferences  Preferences
 }
 
 func NewUser(id Id, name string, hash []byte, timeZone string) *User {
@@ -13,11 +16,14 @@ func NewUser(id Id, name string, hash []byte, timeZone string) *User {
 		Name: name,
 		PasswordHash: hash,
 		TimeZone: timeZone,
+		Preferences: DefaultPreferences(),
 	}
 }
 
 type UserInReq struct {
 	Id int64
+	SessionId Id
+	OrganizationId Id
 	//TimeZone TimeZone
 }
 
@@ -30,4 +36,5 @@ type UserNew struct {
 type UserUpdate struct {
 	Id Id
 	TimeZone string
+	Preferences PreferencesUpdate
 }
\ No newline at end of file
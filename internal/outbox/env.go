@@ -0,0 +1,49 @@
+package outbox
+
+import (
+	"fmt"
+	"os"
+)
+
+const (
+	SinkEnv = "OUTBOX_SINK"
+	WebhookUrlEnv = "OUTBOX_WEBHOOK_URL"
+	WebhookSecretEnv = "OUTBOX_WEBHOOK_SECRET"
+	NatsUrlEnv = "OUTBOX_NATS_URL"
+	NatsSubjectEnv = "OUTBOX_NATS_SUBJECT"
+
+	SinkLog = "log"
+	SinkWebhook = "webhook"
+	SinkNats = "nats"
+
+	defaultNatsSubject = "todoNote"
+)
+
+// SinkFromEnv is the sink of OUTBOX_SINK, events are logged when it is not set
+func SinkFromEnv(logger Logger) (Sink, error) {
+	switch kind := os.Getenv(SinkEnv); kind {
+	case "", SinkLog:
+		return NewLogSink(logger), nil
+	case SinkWebhook:
+		u := os.Getenv(WebhookUrlEnv)
+		if u == "" {
+			return nil, fmt.Errorf("%v is required for the %v sink", WebhookUrlEnv, kind)
+		}
+
+		return NewWebhookSink(u, os.Getenv(WebhookSecretEnv)), nil
+	case SinkNats:
+		u := os.Getenv(NatsUrlEnv)
+		if u == "" {
+			return nil, fmt.Errorf("%v is required for the %v sink", NatsUrlEnv, kind)
+		}
+
+		subject := os.Getenv(NatsSubjectEnv)
+		if subject == "" {
+			subject = defaultNatsSubject
+		}
+
+		return NewNatsSink(u, subject)
+	default:
+		return nil, fmt.Errorf("unknown %v %q, use %v, %v or %v", SinkEnv, kind, SinkLog, SinkWebhook, SinkNats)
+	}
+}
@@ -0,0 +1,115 @@
+package outbox
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+	"todoNote/internal/model"
+)
+
+type natsMsg struct {
+	subject string
+	body string
+}
+
+// natsServer speaks enough of the protocol for NatsSink, subjects ending with .rejected get -ERR
+func natsServer(t *testing.T) (string, chan string, chan natsMsg) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	connects := make(chan string, 10)
+	msgs := make(chan natsMsg, 10)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go serveNats(conn, connects, msgs)
+		}
+	}()
+
+	return l.Addr().String(), connects, msgs
+}
+
+func serveNats(conn net.Conn, connects chan string, msgs chan natsMsg) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	fmt.Fprint(conn, "INFO {\"server_id\":\"test\"}\r\n")
+
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		switch {
+		case strings.HasPrefix(line, "CONNECT "):
+			connects <- strings.TrimPrefix(line, "CONNECT ")
+		case strings.HasPrefix(line, "PUB "):
+			var subject string
+			var n int
+			fmt.Sscanf(line, "PUB %s %d", &subject, &n)
+			body := make([]byte, n + 2)
+			if _, err := io.ReadFull(r, body); err != nil {
+				return
+			}
+			if strings.HasSuffix(subject, ".rejected") {
+				fmt.Fprint(conn, "-ERR 'Permissions Violation'\r\n")
+				continue
+			}
+			msgs <- natsMsg{subject: subject, body: string(body[:n])}
+		case line == "PING":
+			fmt.Fprint(conn, "PONG\r\n")
+		}
+	}
+}
+
+func TestNatsSink(t *testing.T) {
+	addr, connects, msgs := natsServer(t)
+	s, err := NewNatsSink("nats://user:pass@" + addr, "todoNote")
+	assert.Nil(t, err)
+	defer s.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5 * time.Second)
+	defer cancel()
+
+	e := model.NewEvent(model.EventNoteCreated, 1, 2, []byte(`{"id":2}`), time.Date(2021, 10, 2, 10, 0, 0, 0, time.UTC))
+	e.Id = 1
+	assert.Nil(t, s.Publish(ctx, *e))
+	assert.JSONEq(t, `{"verbose":false,"pedantic":false,"name":"todoNote","user":"user","pass":"pass"}`, <-connects)
+
+	msg := <-msgs
+	assert.Equal(t, "todoNote.note.created", msg.subject)
+	assert.Equal(t, model.Id(1), envelopeOf(t, []byte(msg.body)).Id)
+
+	t.Run("errors of the server", func(t *testing.T) {
+		rejected := *e
+		rejected.Type = "rejected"
+		assert.NotNil(t, s.Publish(ctx, rejected))
+
+		// the connection is opened again
+		assert.Nil(t, s.Publish(ctx, *e))
+		<-connects
+		assert.Equal(t, "todoNote.note.created", (<-msgs).subject)
+	})
+}
+
+func TestNewNatsSink(t *testing.T) {
+	s, err := NewNatsSink("nats://localhost", "todoNote")
+	assert.Nil(t, err)
+	assert.Equal(t, "localhost:4222", s.addr)
+
+	_, err = NewNatsSink("http://localhost:4222", "todoNote")
+	assert.NotNil(t, err)
+}
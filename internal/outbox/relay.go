@@ -0,0 +1,158 @@
+// Package outbox publishes the domain events usecases write to the outbox of the storage.
+// Delivery is at least once: an event is removed only after its sink took it,
+// a relay which stops in between publishes it again once the claim of the event ends
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+	"todoNote/internal/model"
+	"todoNote/internal/repo"
+)
+
+const (
+	defaultBatch = 50
+	// defaultLease is how long claimed events are hidden from other relays, publishing a batch takes less
+	defaultLease = time.Minute
+	minBackoff = time.Second
+	maxBackoff = 10 * time.Minute
+)
+
+// Sink takes published events, an error means the event was not taken and is retried
+type Sink interface {
+	Publish(ctx context.Context, e model.Event) error
+}
+
+type Logger interface {
+	Info(v ...interface{})
+	Error(v ...interface{})
+}
+
+type Relay struct {
+	repo repo.IRepoOutbox
+	sink Sink
+	logger Logger
+	batch uint64
+	lease time.Duration
+	now func() time.Time
+}
+
+func NewRelay(r repo.IRepoOutbox, sink Sink, logger Logger) *Relay {
+	return &Relay{
+		repo: r,
+		sink: sink,
+		logger: logger,
+		batch: defaultBatch,
+		lease: defaultLease,
+		now: time.Now,
+	}
+}
+
+// Run publishes due events every interval until ctx is done, full batches are followed at once
+func(r *Relay) Run(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		n, err := r.Publish(ctx)
+		if err != nil {
+			r.logger.Error(fmt.Sprintf("outbox relay: %v", err))
+		}
+		if err == nil && uint64(n) == r.batch {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// Publish claims one batch of due events and hands them to the sink one by one,
+// it returns the number of claimed events
+func(r *Relay) Publish(ctx context.Context) (int, error) {
+	events, err := r.repo.Claim(ctx, r.now(), r.lease, r.batch)
+	if err != nil {
+		return 0, fmt.Errorf("publish: %w", err)
+	}
+
+	for _, e := range events {
+		if err := r.sink.Publish(ctx, e); err != nil {
+			next := r.now().Add(backoff(e.Attempts))
+			r.logger.Error(fmt.Sprintf("outbox relay: event %v %v, attempt %v, retry at %v: %v", e.Id, e.Type, e.Attempts + 1, next, err))
+
+			if err := r.repo.Retry(ctx, e.Id, next, err.Error()); err != nil {
+				return len(events), fmt.Errorf("publish: %w", err)
+			}
+			continue
+		}
+
+		if err := r.repo.Delete(ctx, e.Id); err != nil {
+			return len(events), fmt.Errorf("publish: %w", err)
+		}
+	}
+
+	return len(events), nil
+}
+
+// backoff doubles the wait after every failed attempt up to maxBackoff
+func backoff(attempts int) time.Duration {
+	d := minBackoff
+	for i := 0; i < attempts && d < maxBackoff; i++ {
+		d *= 2
+	}
+	if d > maxBackoff {
+		d = maxBackoff
+	}
+
+	return d
+}
+
+// Envelope is the document sinks publish, Data is the payload of the event
+type Envelope struct {
+	Id model.Id `json:"id"`
+	Type model.EventType `json:"type"`
+	UserId model.Id `json:"user_id"`
+	SubjectId model.Id `json:"subject_id"`
+	CreatedAt time.Time `json:"created_at"`
+	Data json.RawMessage `json:"data"`
+}
+
+func envelope(e model.Event) ([]byte, error) {
+	data := json.RawMessage(e.Payload)
+	if len(data) == 0 {
+		data = json.RawMessage("null")
+	}
+
+	return json.Marshal(Envelope{
+		Id: e.Id,
+		Type: e.Type,
+		UserId: e.UserId,
+		SubjectId: e.SubjectId,
+		CreatedAt: e.CreatedAt,
+		Data: data,
+	})
+}
+
+// LogSink writes events to the log, it is the sink when no other is configured
+type LogSink struct {
+	logger Logger
+}
+
+func NewLogSink(logger Logger) *LogSink {
+	return &LogSink{logger: logger}
+}
+
+func(s *LogSink) Publish(_ context.Context, e model.Event) error {
+	b, err := envelope(e)
+	if err != nil {
+		return err
+	}
+
+	s.logger.Info(fmt.Sprintf("event %s", b))
+	return nil
+}
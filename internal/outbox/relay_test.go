@@ -0,0 +1,106 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+	"todoNote/internal/model"
+	in_memory "todoNote/internal/repo/in-memory"
+)
+
+type logger struct {
+	infos, errors []string
+}
+
+func (l *logger) Info(v ...interface{}) { l.infos = append(l.infos, fmt.Sprint(v...)) }
+func (l *logger) Error(v ...interface{}) { l.errors = append(l.errors, fmt.Sprint(v...)) }
+
+// sink fails the first fails events it gets
+type sink struct {
+	fails int
+	published []model.Event
+}
+
+func (s *sink) Publish(_ context.Context, e model.Event) error {
+	if s.fails > 0 {
+		s.fails--
+		return fmt.Errorf("unavailable")
+	}
+
+	s.published = append(s.published, e)
+	return nil
+}
+
+func TestRelay_Publish(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2021, 10, 2, 10, 0, 0, 0, time.UTC)
+	repo := in_memory.NewRepoOutbox()
+	for _, eventType := range []model.EventType{model.EventNoteCreated, model.EventNoteUpdated} {
+		_, err := repo.Insert(ctx, model.NewEvent(eventType, 1, 2, []byte(`{}`), now))
+		assert.Nil(t, err)
+	}
+
+	s := &sink{fails: 1}
+	l := &logger{}
+	r := NewRelay(repo, s, l)
+	r.now = func() time.Time { return now }
+
+	n, err := r.Publish(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, n)
+	assert.Len(t, l.errors, 1)
+	if assert.Len(t, s.published, 1) {
+		assert.Equal(t, model.EventNoteUpdated, s.published[0].Type)
+	}
+
+	// the failed event waits for its backoff
+	n, err = r.Publish(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, n)
+
+	now = now.Add(minBackoff)
+	n, err = r.Publish(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, n)
+	if assert.Len(t, s.published, 2) {
+		assert.Equal(t, model.EventNoteCreated, s.published[1].Type)
+		assert.Equal(t, 1, s.published[1].Attempts)
+	}
+
+	// published events are removed
+	now = now.Add(time.Hour)
+	n, err = r.Publish(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, n)
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, time.Second, backoff(0))
+	assert.Equal(t, 2 * time.Second, backoff(1))
+	assert.Equal(t, 8 * time.Second, backoff(3))
+	assert.Equal(t, maxBackoff, backoff(20))
+	assert.Equal(t, maxBackoff, backoff(1000))
+}
+
+func TestLogSink(t *testing.T) {
+	l := &logger{}
+	e := model.NewEvent(model.EventUserCreated, 1, 1, []byte(`{"id":1}`), time.Date(2021, 10, 2, 10, 0, 0, 0, time.UTC))
+	e.Id = 7
+
+	assert.Nil(t, NewLogSink(l).Publish(context.Background(), *e))
+	if assert.Len(t, l.infos, 1) {
+		assert.Equal(t, `event {"id":7,"type":"user.created","user_id":1,"subject_id":1,"created_at":"2021-10-02T10:00:00Z","data":{"id":1}}`, l.infos[0])
+	}
+}
+
+func envelopeOf(t *testing.T, b []byte) Envelope {
+	var e Envelope
+	if err := json.Unmarshal(b, &e); err != nil {
+		t.Fatal(err)
+	}
+
+	return e
+}
@@ -0,0 +1,80 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+	"todoNote/internal/model"
+)
+
+const (
+	webhookTimeout = 10 * time.Second
+
+	EventIdHeader = "X-Event-Id"
+	EventTypeHeader = "X-Event-Type"
+	// SignatureHeader is sha256= and the hex HMAC-SHA256 of the body with the secret of the webhook
+	SignatureHeader = "X-Signature"
+)
+
+// WebhookSink posts the envelope of every event to url, any status but 2xx is a failure.
+// Receivers tell redeliveries apart by the X-Event-Id header
+type WebhookSink struct {
+	url string
+	secret []byte
+	client *http.Client
+}
+
+// NewWebhookSink signs the requests when secret is not empty
+func NewWebhookSink(url, secret string) *WebhookSink {
+	return &WebhookSink{
+		url: url,
+		secret: []byte(secret),
+		client: &http.Client{Timeout: webhookTimeout},
+	}
+}
+
+func(s *WebhookSink) Publish(ctx context.Context, e model.Event) error {
+	body, err := envelope(e)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("webhook: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventIdHeader, strconv.FormatInt(e.Id, 10))
+	req.Header.Set(EventTypeHeader, e.Type)
+	if len(s.secret) > 0 {
+		req.Header.Set(SignatureHeader, Sign(s.secret, body))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1 << 16))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook: status %v", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// Sign is the X-Signature of body
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
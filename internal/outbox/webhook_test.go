@@ -0,0 +1,51 @@
+package outbox
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"todoNote/internal/model"
+)
+
+func TestWebhookSink(t *testing.T) {
+	e := model.NewEvent(model.EventNoteDeleted, 1, 5, []byte(`{"id":5}`), time.Date(2021, 10, 2, 10, 0, 0, 0, time.UTC))
+	e.Id = 3
+
+	var got *http.Request
+	var body []byte
+	status := http.StatusNoContent
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	t.Run("signed", func(t *testing.T) {
+		s := NewWebhookSink(srv.URL, "secret")
+		assert.Nil(t, s.Publish(context.Background(), *e))
+
+		assert.Equal(t, http.MethodPost, got.Method)
+		assert.Equal(t, "3", got.Header.Get(EventIdHeader))
+		assert.Equal(t, model.EventNoteDeleted, got.Header.Get(EventTypeHeader))
+		assert.Equal(t, Sign([]byte("secret"), body), got.Header.Get(SignatureHeader))
+
+		env := envelopeOf(t, body)
+		assert.Equal(t, model.Id(5), env.SubjectId)
+		assert.JSONEq(t, `{"id":5}`, string(env.Data))
+	})
+
+	t.Run("without secret", func(t *testing.T) {
+		assert.Nil(t, NewWebhookSink(srv.URL, "").Publish(context.Background(), *e))
+		assert.Equal(t, "", got.Header.Get(SignatureHeader))
+	})
+
+	t.Run("failed status", func(t *testing.T) {
+		status = http.StatusServiceUnavailable
+		assert.NotNil(t, NewWebhookSink(srv.URL, "").Publish(context.Background(), *e))
+	})
+}
@@ -0,0 +1,21 @@
+package repo
+
+import (
+	"context"
+	"time"
+	"todoNote/internal/model"
+)
+
+type IRepoExport interface {
+	Upsert(ctx context.Context, e *model.Export) error
+	GetByUserId(ctx context.Context, uId model.Id) (model.Export, error)
+	Delete(ctx context.Context, uId model.Id) error
+}
+
+type IRepoAccountDeletion interface {
+	Upsert(ctx context.Context, d *model.AccountDeletion) error
+	GetByUserId(ctx context.Context, uId model.Id) (model.AccountDeletion, error)
+	Delete(ctx context.Context, uId model.Id) error
+	// GetDue returns deletions with DeleteAfter before now
+	GetDue(ctx context.Context, now time.Time) ([]model.AccountDeletion, error)
+}
@@ -0,0 +1,12 @@
+package repo
+
+import (
+	"context"
+	"todoNote/internal/model"
+)
+
+type IRepoCalendarFeed interface {
+	Upsert(ctx context.Context, f *model.CalendarFeed) error
+	GetByTokenHash(ctx context.Context, hash []byte) (model.CalendarFeed, error)
+	Delete(ctx context.Context, uId model.Id) error
+}
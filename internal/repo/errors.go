@@ -0,0 +1,20 @@
+package repo
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors of every repository implementation, check them with errors.Is
+var (
+	ErrNotFound = errors.New("not found")
+	// ErrConflict is a unique value taken already or a transaction that lost to a concurrent one
+	ErrConflict = errors.New("conflict")
+	ErrForeignKey = errors.New("referenced element does not exist")
+	ErrTimeout = errors.New("timeout")
+)
+
+// NewNotFoundError is ErrNotFound for the element with key, an id or a name
+func NewNotFoundError(key interface{}) error {
+	return fmt.Errorf("no such element %v: %w", key, ErrNotFound)
+}
@@ -0,0 +1,11 @@
+package repo
+
+import (
+	"context"
+	"todoNote/internal/model"
+)
+
+type IRepoIdentity interface {
+	Insert(ctx context.Context, i *model.UserIdentity) error
+	GetByProviderSubject(ctx context.Context, provider, subject string) (*model.UserIdentity, error)
+}
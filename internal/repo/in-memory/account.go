@@ -0,0 +1,164 @@
+package in_memory
+
+import (
+	"context"
+	"sync"
+	"time"
+	"todoNote/internal/model"
+	"todoNote/internal/repo"
+)
+
+var _ repo.IRepoExport = &RepoExport{}
+
+type RepoExport struct {
+	sync.RWMutex
+	storage map[model.Id]model.Export
+	journaled
+}
+
+func NewRepoExport() repo.IRepoExport {
+	return newRepoExport()
+}
+
+func newRepoExport() *RepoExport {
+	return &RepoExport{
+		storage: make(map[model.Id]model.Export),
+	}
+}
+
+func(r *RepoExport) Upsert(ctx context.Context, e *model.Export) error {
+	r.Lock()
+	old, ok := r.storage[e.UserId]
+	if err := r.log(record{Kind: kindExport, Export: e}); err != nil {
+		r.Unlock()
+		return err
+	}
+	r.storage[e.UserId] = *e
+	r.Unlock()
+
+	onRollback(ctx, func() { r.restore(e.UserId, old, ok) })
+	return nil
+}
+
+func(r *RepoExport) GetByUserId(_ context.Context, uId model.Id) (model.Export, error) {
+	r.RLock()
+	e, ok := r.storage[uId]
+	r.RUnlock()
+	if !ok {
+		return model.Export{}, repo.NewNotFoundError(uId)
+	}
+
+	return e, nil
+}
+
+func(r *RepoExport) Delete(ctx context.Context, uId model.Id) error {
+	r.Lock()
+	old, ok := r.storage[uId]
+	if err := r.log(record{Kind: kindExportRemoved, UserId: uId}); err != nil {
+		r.Unlock()
+		return err
+	}
+	delete(r.storage, uId)
+	r.Unlock()
+
+	onRollback(ctx, func() { r.restore(uId, old, ok) })
+	return nil
+}
+
+// restore puts back the export of the user as it was before a rolled back change, ok is false when there was none
+func(r *RepoExport) restore(uId model.Id, e model.Export, ok bool) {
+	r.Lock()
+	defer r.Unlock()
+
+	if ok {
+		r.log(record{Kind: kindExport, Export: &e})
+		r.storage[uId] = e
+	} else {
+		r.log(record{Kind: kindExportRemoved, UserId: uId})
+		delete(r.storage, uId)
+	}
+}
+
+var _ repo.IRepoAccountDeletion = &RepoAccountDeletion{}
+
+type RepoAccountDeletion struct {
+	sync.RWMutex
+	storage map[model.Id]model.AccountDeletion
+	journaled
+}
+
+func NewRepoAccountDeletion() repo.IRepoAccountDeletion {
+	return newRepoAccountDeletion()
+}
+
+func newRepoAccountDeletion() *RepoAccountDeletion {
+	return &RepoAccountDeletion{
+		storage: make(map[model.Id]model.AccountDeletion),
+	}
+}
+
+func(r *RepoAccountDeletion) Upsert(ctx context.Context, d *model.AccountDeletion) error {
+	r.Lock()
+	old, ok := r.storage[d.UserId]
+	if err := r.log(record{Kind: kindAccountDeletion, AccountDeletion: d}); err != nil {
+		r.Unlock()
+		return err
+	}
+	r.storage[d.UserId] = *d
+	r.Unlock()
+
+	onRollback(ctx, func() { r.restore(d.UserId, old, ok) })
+	return nil
+}
+
+func(r *RepoAccountDeletion) GetByUserId(_ context.Context, uId model.Id) (model.AccountDeletion, error) {
+	r.RLock()
+	d, ok := r.storage[uId]
+	r.RUnlock()
+	if !ok {
+		return model.AccountDeletion{}, repo.NewNotFoundError(uId)
+	}
+
+	return d, nil
+}
+
+func(r *RepoAccountDeletion) Delete(ctx context.Context, uId model.Id) error {
+	r.Lock()
+	old, ok := r.storage[uId]
+	if err := r.log(record{Kind: kindAccountDeletionRemoved, UserId: uId}); err != nil {
+		r.Unlock()
+		return err
+	}
+	delete(r.storage, uId)
+	r.Unlock()
+
+	onRollback(ctx, func() { r.restore(uId, old, ok) })
+	return nil
+}
+
+func(r *RepoAccountDeletion) restore(uId model.Id, d model.AccountDeletion, ok bool) {
+	r.Lock()
+	defer r.Unlock()
+
+	if ok {
+		r.log(record{Kind: kindAccountDeletion, AccountDeletion: &d})
+		r.storage[uId] = d
+	} else {
+		r.log(record{Kind: kindAccountDeletionRemoved, UserId: uId})
+		delete(r.storage, uId)
+	}
+}
+
+func(r *RepoAccountDeletion) GetDue(_ context.Context, now time.Time) ([]model.AccountDeletion, error) {
+	due := make([]model.AccountDeletion, 0)
+
+	r.RLock()
+	for _, d := range r.storage {
+		if d.DeleteAfter.Before(now) {
+			due = append(due, d)
+		}
+	}
+	r.RUnlock()
+
+	return due, nil
+}
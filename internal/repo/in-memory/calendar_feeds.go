@@ -0,0 +1,64 @@
+package in_memory
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"todoNote/internal/model"
+	"todoNote/internal/repo"
+)
+
+var _ repo.IRepoCalendarFeed = &RepoCalendarFeed{}
+
+type RepoCalendarFeed struct {
+	sync.RWMutex
+	storage map[model.Id]model.CalendarFeed
+	journaled
+}
+
+func NewRepoCalendarFeed() repo.IRepoCalendarFeed {
+	return newRepoCalendarFeed()
+}
+
+func newRepoCalendarFeed() *RepoCalendarFeed {
+	return &RepoCalendarFeed{
+		storage: make(map[model.Id]model.CalendarFeed),
+	}
+}
+
+func(r *RepoCalendarFeed) Upsert(_ context.Context, f *model.CalendarFeed) error {
+	r.Lock()
+	defer r.Unlock()
+
+	if err := r.log(record{Kind: kindCalendarFeed, CalendarFeed: f}); err != nil {
+		return err
+	}
+	r.storage[f.UserId] = *f
+
+	return nil
+}
+
+func(r *RepoCalendarFeed) GetByTokenHash(_ context.Context, hash []byte) (model.CalendarFeed, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	for _, f := range r.storage {
+		if bytes.Equal(f.TokenHash, hash) {
+			return f, nil
+		}
+	}
+
+	return model.CalendarFeed{}, repo.NewNotFoundError(0)
+}
+
+func(r *RepoCalendarFeed) Delete(_ context.Context, uId model.Id) error {
+	r.Lock()
+	defer r.Unlock()
+
+	if err := r.log(record{Kind: kindCalendarFeedRemoved, UserId: uId}); err != nil {
+		return err
+	}
+	delete(r.storage, uId)
+
+	return nil
+}
@@ -0,0 +1,12 @@
+package in_memory
+
+import (
+	"testing"
+	"todoNote/internal/repo/repotest"
+)
+
+func TestConformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repotest.Repositories {
+		return repotest.Repositories{Note: NewRepoNote(), User: NewRepoUser(), Transactor: NewTransactor(), Outbox: NewRepoOutbox(), Organization: NewRepoOrganization()}
+	})
+}
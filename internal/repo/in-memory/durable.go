@@ -0,0 +1,666 @@
+package in_memory
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+	"todoNote/internal/model"
+	"todoNote/internal/repo"
+)
+
+const (
+	snapshotFile = "snapshot.json"
+	walFile = "wal.log"
+)
+
+// Store keeps the state of its repositories in dir, only login attempts are left to NewRepoLoginAttempts.
+// Every change is appended to the write-ahead log before it is applied, the snapshot holds the whole state
+// and empties the log. Open replays the snapshot and then the log
+type Store struct {
+	sync.Mutex
+	dir string
+	wal *os.File
+	notes *RepoNote
+	users *RepoUser
+	organizations *RepoOrganization
+	outbox *RepoOutbox
+	mfa *RepoMfa
+	sessions *RepoSession
+	identities *RepoIdentity
+	exports *RepoExport
+	deletions *RepoAccountDeletion
+	feeds *RepoCalendarFeed
+	// err is the first failed write, changes fail until a snapshot writes the whole state again
+	err error
+}
+
+// record is one line of the log, it sets the state of one element so replaying it twice changes nothing
+type record struct {
+	Kind string `json:"kind"`
+	Note *model.Note `json:"note,omitempty"`
+	User *model.User `json:"user,omitempty"`
+	Organization *model.Organization `json:"organization,omitempty"`
+	Event *model.Event `json:"event,omitempty"`
+	Mfa *model.Mfa `json:"mfa,omitempty"`
+	Session *model.Session `json:"session,omitempty"`
+	Identity *model.UserIdentity `json:"identity,omitempty"`
+	Export *model.Export `json:"export,omitempty"`
+	AccountDeletion *model.AccountDeletion `json:"account_deletion,omitempty"`
+	CalendarFeed *model.CalendarFeed `json:"calendar_feed,omitempty"`
+	Id model.Id `json:"id,omitempty"`
+	UserId model.Id `json:"user_id,omitempty"`
+	OrganizationId model.Id `json:"organization_id,omitempty"`
+	Version int64 `json:"version,omitempty"`
+}
+
+const (
+	// kindNote stores Note and drops its tombstone
+	kindNote = "note"
+	// kindNoteRemoved drops the note of Id, rolled back inserts leave no tombstone
+	kindNoteRemoved = "note_removed"
+	// kindNoteDeleted drops the note of Id and leaves the tombstone of UserId, OrganizationId and Version
+	kindNoteDeleted = "note_deleted"
+	kindUser = "user"
+	kindUserRemoved = "user_removed"
+	kindOrganization = "organization"
+	kindOrganizationRemoved = "organization_removed"
+	kindEvent = "event"
+	kindEventRemoved = "event_removed"
+	// kindMfa and the other elements kept by their user are removed by UserId
+	kindMfa = "mfa"
+	kindMfaRemoved = "mfa_removed"
+	kindSession = "session"
+	kindSessionRemoved = "session_removed"
+	// kindIdentityRemoved drops the provider and subject of Identity
+	kindIdentity = "identity"
+	kindIdentityRemoved = "identity_removed"
+	kindExport = "export"
+	kindExportRemoved = "export_removed"
+	kindAccountDeletion = "account_deletion"
+	kindAccountDeletionRemoved = "account_deletion_removed"
+	kindCalendarFeed = "calendar_feed"
+	kindCalendarFeedRemoved = "calendar_feed_removed"
+)
+
+type snapshot struct {
+	NoteCounter int64 `json:"note_counter"`
+	UserCounter int64 `json:"user_counter"`
+	OrganizationCounter int64 `json:"organization_counter,omitempty"`
+	Notes []model.Note `json:"notes"`
+	Users []model.User `json:"users"`
+	Organizations []model.Organization `json:"organizations,omitempty"`
+	Tombstones []snapshotTombstone `json:"tombstones"`
+	Versions map[model.Id]int64 `json:"versions"`
+	// snapshots written before the account state was kept have none of the fields below
+	EventCounter int64 `json:"event_counter,omitempty"`
+	Events []model.Event `json:"events,omitempty"`
+	Mfa []model.Mfa `json:"mfa,omitempty"`
+	SessionCounter int64 `json:"session_counter,omitempty"`
+	Sessions []model.Session `json:"sessions,omitempty"`
+	Identities []model.UserIdentity `json:"identities,omitempty"`
+	Exports []model.Export `json:"exports,omitempty"`
+	AccountDeletions []model.AccountDeletion `json:"account_deletions,omitempty"`
+	CalendarFeeds []model.CalendarFeed `json:"calendar_feeds,omitempty"`
+}
+
+type snapshotTombstone struct {
+	UserId model.Id `json:"user_id"`
+	OrganizationId model.Id `json:"organization_id,omitempty"`
+	NoteId model.Id `json:"note_id"`
+	Version int64 `json:"version"`
+}
+
+// journal takes the changes of a repository before they are applied
+type journal interface {
+	append(rec record) error
+}
+
+// journaled is embedded by the repositories, journal keeps their changes when they are durable
+// and is nil when the changes are lost on restart
+type journaled struct {
+	journal journal
+}
+
+// log must be called with the lock held so records follow the order of the changes,
+// failures of rollbacks stay with the journal
+func(j *journaled) log(rec record) error {
+	if j.journal == nil {
+		return nil
+	}
+
+	return j.journal.append(rec)
+}
+
+// Open reads the state kept in dir, it is created when missing.
+// An unfinished last line of the log is a write the process did not live to finish, it is dropped
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("open %v: %w", dir, err)
+	}
+
+	s := &Store{
+		dir: dir,
+		notes: newRepoNote(),
+		users: newRepoUser(),
+		organizations: newRepoOrganization(),
+		outbox: newRepoOutbox(),
+		mfa: newRepoMfa(),
+		sessions: newRepoSession(),
+		identities: newRepoIdentity(),
+		exports: newRepoExport(),
+		deletions: newRepoAccountDeletion(),
+		feeds: newRepoCalendarFeed(),
+	}
+	if err := s.readSnapshot(); err != nil {
+		return nil, fmt.Errorf("open %v: %w", dir, err)
+	}
+
+	wal, err := os.OpenFile(filepath.Join(dir, walFile), os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("open %v: %w", dir, err)
+	}
+
+	if err := s.replay(wal); err != nil {
+		wal.Close()
+		return nil, fmt.Errorf("open %v: %w", dir, err)
+	}
+
+	s.wal = wal
+	s.notes.journal = s
+	s.users.journal = s
+	s.organizations.journal = s
+	s.outbox.journal = s
+	s.mfa.journal = s
+	s.sessions.journal = s
+	s.identities.journal = s
+	s.exports.journal = s
+	s.deletions.journal = s
+	s.feeds.journal = s
+
+	return s, nil
+}
+
+func(s *Store) Notes() repo.IRepoNote {
+	return s.notes
+}
+
+func(s *Store) Users() repo.IRepoUser {
+	return s.users
+}
+
+func(s *Store) Organizations() repo.IRepoOrganization {
+	return s.organizations
+}
+
+func(s *Store) Outbox() repo.IRepoOutbox {
+	return s.outbox
+}
+
+func(s *Store) Mfa() repo.IRepoMfa {
+	return s.mfa
+}
+
+func(s *Store) Sessions() repo.IRepoSession {
+	return s.sessions
+}
+
+func(s *Store) Identities() repo.IRepoIdentity {
+	return s.identities
+}
+
+func(s *Store) Exports() repo.IRepoExport {
+	return s.exports
+}
+
+func(s *Store) AccountDeletions() repo.IRepoAccountDeletion {
+	return s.deletions
+}
+
+func(s *Store) CalendarFeeds() repo.IRepoCalendarFeed {
+	return s.feeds
+}
+
+// Run takes a snapshot every interval until ctx is done
+func(s *Store) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Snapshot(); err != nil {
+				log.Printf("in-memory store: %v", err)
+			}
+		}
+	}
+}
+
+// Snapshot writes the whole state and empties the log. The repositories are not changed meanwhile,
+// a crash before the log is emptied replays it over the state it already holds
+func(s *Store) Snapshot() error {
+	s.notes.RLock()
+	defer s.notes.RUnlock()
+	s.users.RLock()
+	defer s.users.RUnlock()
+	s.organizations.RLock()
+	defer s.organizations.RUnlock()
+	s.outbox.RLock()
+	defer s.outbox.RUnlock()
+	s.mfa.RLock()
+	defer s.mfa.RUnlock()
+	s.sessions.RLock()
+	defer s.sessions.RUnlock()
+	s.identities.RLock()
+	defer s.identities.RUnlock()
+	s.exports.RLock()
+	defer s.exports.RUnlock()
+	s.deletions.RLock()
+	defer s.deletions.RUnlock()
+	s.feeds.RLock()
+	defer s.feeds.RUnlock()
+	s.Lock()
+	defer s.Unlock()
+
+	if s.wal == nil {
+		return fmt.Errorf("snapshot: store is closed")
+	}
+
+	if err := s.writeSnapshot(); err != nil {
+		return fmt.Errorf("snapshot: %w", err)
+	}
+
+	if err := s.wal.Truncate(0); err != nil {
+		return fmt.Errorf("snapshot: %w", err)
+	}
+	if _, err := s.wal.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("snapshot: %w", err)
+	}
+
+	s.err = nil
+	return nil
+}
+
+// Close takes the last snapshot, the repositories must not be used after it
+func(s *Store) Close() error {
+	err := s.Snapshot()
+
+	s.Lock()
+	defer s.Unlock()
+	if s.wal == nil {
+		return err
+	}
+
+	if closeErr := s.wal.Close(); err == nil {
+		err = closeErr
+	}
+	s.wal = nil
+
+	return err
+}
+
+func(s *Store) append(rec record) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.wal == nil {
+		return fmt.Errorf("write ahead log: store is closed")
+	}
+	if s.err != nil {
+		return fmt.Errorf("write ahead log: %w", s.err)
+	}
+
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("write ahead log: %w", err)
+	}
+
+	if _, err := s.wal.Write(append(line, '\n')); err != nil {
+		s.err = err
+		return fmt.Errorf("write ahead log: %w", err)
+	}
+	if err := s.wal.Sync(); err != nil {
+		s.err = err
+		return fmt.Errorf("write ahead log: %w", err)
+	}
+
+	return nil
+}
+
+func(s *Store) readSnapshot() error {
+	b, err := os.ReadFile(filepath.Join(s.dir, snapshotFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read snapshot: %w", err)
+	}
+
+	var snap snapshot
+	if err := json.Unmarshal(b, &snap); err != nil {
+		return fmt.Errorf("read snapshot: %w", err)
+	}
+
+	s.notes.counter = snap.NoteCounter
+	for _, n := range snap.Notes {
+		n.OrganizationId = organization(n.OrganizationId)
+		s.notes.storage[n.Id] = n
+	}
+	for _, t := range snap.Tombstones {
+		s.notes.tombstones[t.NoteId] = tombstone{
+			userId: t.UserId,
+			organizationId: organization(t.OrganizationId),
+			change: model.NoteChange{Version: t.Version, NoteId: t.NoteId, Deleted: true},
+		}
+	}
+	for userId, v := range snap.Versions {
+		s.notes.versions[userId] = v
+	}
+
+	s.users.counter = snap.UserCounter
+	for _, u := range snap.Users {
+		u.OrganizationId = organization(u.OrganizationId)
+		s.users.storage[u.Id] = u
+	}
+
+	// snapshots written before organizations have only the default one
+	if s.organizations.counter < snap.OrganizationCounter {
+		s.organizations.counter = snap.OrganizationCounter
+	}
+	for _, o := range snap.Organizations {
+		s.organizations.storage[o.Id] = o
+	}
+
+	if s.outbox.counter < snap.EventCounter {
+		s.outbox.counter = snap.EventCounter
+	}
+	for _, e := range snap.Events {
+		s.outbox.storage[e.Id] = e
+	}
+	for _, m := range snap.Mfa {
+		s.mfa.storage[m.UserId] = m
+	}
+	if s.sessions.counter < snap.SessionCounter {
+		s.sessions.counter = snap.SessionCounter
+	}
+	for _, v := range snap.Sessions {
+		s.sessions.storage[v.Id] = v
+	}
+	for _, i := range snap.Identities {
+		s.identities.storage[identityKey(i.Provider, i.Subject)] = i
+	}
+	for _, e := range snap.Exports {
+		s.exports.storage[e.UserId] = e
+	}
+	for _, d := range snap.AccountDeletions {
+		s.deletions.storage[d.UserId] = d
+	}
+	for _, f := range snap.CalendarFeeds {
+		s.feeds.storage[f.UserId] = f
+	}
+
+	return nil
+}
+
+// writeSnapshot must be called with the locks of the store and all repositories held
+func(s *Store) writeSnapshot() error {
+	snap := snapshot{
+		NoteCounter: s.notes.counter,
+		UserCounter: s.users.counter,
+		OrganizationCounter: s.organizations.counter,
+		Notes: make([]model.Note, 0, len(s.notes.storage)),
+		Users: make([]model.User, 0, len(s.users.storage)),
+		Organizations: make([]model.Organization, 0, len(s.organizations.storage)),
+		Tombstones: make([]snapshotTombstone, 0, len(s.notes.tombstones)),
+		Versions: s.notes.versions,
+		EventCounter: s.outbox.counter,
+		Events: make([]model.Event, 0, len(s.outbox.storage)),
+		Mfa: make([]model.Mfa, 0, len(s.mfa.storage)),
+		SessionCounter: s.sessions.counter,
+		Sessions: make([]model.Session, 0, len(s.sessions.storage)),
+		Identities: make([]model.UserIdentity, 0, len(s.identities.storage)),
+		Exports: make([]model.Export, 0, len(s.exports.storage)),
+		AccountDeletions: make([]model.AccountDeletion, 0, len(s.deletions.storage)),
+		CalendarFeeds: make([]model.CalendarFeed, 0, len(s.feeds.storage)),
+	}
+	for _, n := range s.notes.storage {
+		snap.Notes = append(snap.Notes, n)
+	}
+	for id, t := range s.notes.tombstones {
+		snap.Tombstones = append(snap.Tombstones, snapshotTombstone{
+			UserId: t.userId,
+			OrganizationId: t.organizationId,
+			NoteId: id,
+			Version: t.change.Version,
+		})
+	}
+	for _, u := range s.users.storage {
+		snap.Users = append(snap.Users, u)
+	}
+	for _, o := range s.organizations.storage {
+		snap.Organizations = append(snap.Organizations, o)
+	}
+	for _, e := range s.outbox.storage {
+		snap.Events = append(snap.Events, e)
+	}
+	for _, m := range s.mfa.storage {
+		snap.Mfa = append(snap.Mfa, m)
+	}
+	for _, v := range s.sessions.storage {
+		snap.Sessions = append(snap.Sessions, v)
+	}
+	for _, i := range s.identities.storage {
+		snap.Identities = append(snap.Identities, i)
+	}
+	for _, e := range s.exports.storage {
+		snap.Exports = append(snap.Exports, e)
+	}
+	for _, d := range s.deletions.storage {
+		snap.AccountDeletions = append(snap.AccountDeletions, d)
+	}
+	for _, f := range s.feeds.storage {
+		snap.CalendarFeeds = append(snap.CalendarFeeds, f)
+	}
+
+	b, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+
+	// the old snapshot is replaced at once, a crash leaves one of them whole
+	tmp := filepath.Join(s.dir, snapshotFile + ".tmp")
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp, filepath.Join(s.dir, snapshotFile)); err != nil {
+		return err
+	}
+
+	return syncDir(s.dir)
+}
+
+// replay applies the records of wal and leaves it positioned after the last whole one
+func(s *Store) replay(wal *os.File) error {
+	reader := bufio.NewReader(wal)
+	var offset int64
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("replay: %w", err)
+		}
+
+		var rec record
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return fmt.Errorf("replay record at %d: %w", offset, err)
+		}
+		if err := s.apply(rec); err != nil {
+			return fmt.Errorf("replay record at %d: %w", offset, err)
+		}
+
+		offset += int64(len(line))
+	}
+
+	if err := wal.Truncate(offset); err != nil {
+		return fmt.Errorf("replay: %w", err)
+	}
+	if _, err := wal.Seek(offset, io.SeekStart); err != nil {
+		return fmt.Errorf("replay: %w", err)
+	}
+
+	return nil
+}
+
+// apply replays rec, counters and versions are raised to what it holds
+func(s *Store) apply(rec record) error {
+	notes, users, organizations := s.notes, s.users, s.organizations
+
+	switch rec.Kind {
+	case kindNote:
+		if rec.Note == nil {
+			return fmt.Errorf("%v without note", rec.Kind)
+		}
+		n := *rec.Note
+		n.OrganizationId = organization(n.OrganizationId)
+		notes.storage[n.Id] = n
+		delete(notes.tombstones, n.Id)
+		notes.seen(n.Id, n.UserId, n.Version)
+	case kindNoteRemoved:
+		delete(notes.storage, rec.Id)
+	case kindNoteDeleted:
+		delete(notes.storage, rec.Id)
+		notes.tombstones[rec.Id] = tombstone{
+			userId: rec.UserId,
+			organizationId: organization(rec.OrganizationId),
+			change: model.NoteChange{Version: rec.Version, NoteId: rec.Id, Deleted: true},
+		}
+		notes.seen(rec.Id, rec.UserId, rec.Version)
+	case kindUser:
+		if rec.User == nil {
+			return fmt.Errorf("%v without user", rec.Kind)
+		}
+		u := *rec.User
+		u.OrganizationId = organization(u.OrganizationId)
+		users.storage[u.Id] = u
+		if users.counter <= rec.User.Id {
+			users.counter = rec.User.Id + 1
+		}
+	case kindUserRemoved:
+		delete(users.storage, rec.Id)
+	case kindOrganization:
+		if rec.Organization == nil {
+			return fmt.Errorf("%v without organization", rec.Kind)
+		}
+		organizations.storage[rec.Organization.Id] = *rec.Organization
+		if organizations.counter <= rec.Organization.Id {
+			organizations.counter = rec.Organization.Id + 1
+		}
+	case kindOrganizationRemoved:
+		delete(organizations.storage, rec.Id)
+	case kindEvent:
+		if rec.Event == nil {
+			return fmt.Errorf("%v without event", rec.Kind)
+		}
+		s.outbox.storage[rec.Event.Id] = *rec.Event
+		if s.outbox.counter <= rec.Event.Id {
+			s.outbox.counter = rec.Event.Id + 1
+		}
+	case kindEventRemoved:
+		delete(s.outbox.storage, rec.Id)
+	case kindMfa:
+		if rec.Mfa == nil {
+			return fmt.Errorf("%v without mfa", rec.Kind)
+		}
+		s.mfa.storage[rec.Mfa.UserId] = *rec.Mfa
+	case kindMfaRemoved:
+		delete(s.mfa.storage, rec.UserId)
+	case kindSession:
+		if rec.Session == nil {
+			return fmt.Errorf("%v without session", rec.Kind)
+		}
+		s.sessions.storage[rec.Session.Id] = *rec.Session
+		if s.sessions.counter <= rec.Session.Id {
+			s.sessions.counter = rec.Session.Id + 1
+		}
+	case kindSessionRemoved:
+		delete(s.sessions.storage, rec.Id)
+	case kindIdentity, kindIdentityRemoved:
+		if rec.Identity == nil {
+			return fmt.Errorf("%v without identity", rec.Kind)
+		}
+		key := identityKey(rec.Identity.Provider, rec.Identity.Subject)
+		if rec.Kind == kindIdentity {
+			s.identities.storage[key] = *rec.Identity
+		} else {
+			delete(s.identities.storage, key)
+		}
+	case kindExport:
+		if rec.Export == nil {
+			return fmt.Errorf("%v without export", rec.Kind)
+		}
+		s.exports.storage[rec.Export.UserId] = *rec.Export
+	case kindExportRemoved:
+		delete(s.exports.storage, rec.UserId)
+	case kindAccountDeletion:
+		if rec.AccountDeletion == nil {
+			return fmt.Errorf("%v without account deletion", rec.Kind)
+		}
+		s.deletions.storage[rec.AccountDeletion.UserId] = *rec.AccountDeletion
+	case kindAccountDeletionRemoved:
+		delete(s.deletions.storage, rec.UserId)
+	case kindCalendarFeed:
+		if rec.CalendarFeed == nil {
+			return fmt.Errorf("%v without calendar feed", rec.Kind)
+		}
+		s.feeds.storage[rec.CalendarFeed.UserId] = *rec.CalendarFeed
+	case kindCalendarFeedRemoved:
+		delete(s.feeds.storage, rec.UserId)
+	default:
+		return fmt.Errorf("unknown kind %q", rec.Kind)
+	}
+
+	return nil
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	return d.Sync()
+}
+
+// organization is the organization of a replayed element, those written before organizations have none
+func organization(id model.Id) model.Id {
+	if id == 0 {
+		return model.DefaultOrganizationId
+	}
+
+	return id
+}
@@ -0,0 +1,284 @@
+package in_memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"todoNote/internal/model"
+	"todoNote/internal/repo"
+	"todoNote/internal/repo/repotest"
+)
+
+func open(t *testing.T, dir string) *Store {
+	s, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s
+}
+
+func TestStore_Conformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repotest.Repositories {
+		s := open(t, t.TempDir())
+		t.Cleanup(func() { s.Close() })
+
+		return repotest.Repositories{Note: s.Notes(), User: s.Users(), Transactor: NewTransactor(), Outbox: s.Outbox(), Organization: s.Organizations()}
+	})
+}
+
+func TestStore_Replay(t *testing.T) {
+	ctx := context.Background()
+	date := time.Date(2021, 10, 2, 10, 0, 0, 0, time.UTC)
+
+	for _, snapshot := range []bool{false, true} {
+		t.Run(fmt.Sprintf("snapshot %v", snapshot), func(t *testing.T) {
+			dir := t.TempDir()
+			s := open(t, dir)
+
+			uId, err := s.Users().Insert(ctx, model.NewUser(0, "user", []byte("hash"), model.UTC))
+			assert.Nil(t, err)
+			kept := model.NewNote(0, uId, "kept", "", date, false)
+			kept.Projects, kept.Contexts = []string{}, []string{}
+			_, err = s.Notes().Insert(ctx, kept)
+			assert.Nil(t, err)
+			deleted := model.NewNote(0, uId, "deleted", "", date, false)
+			_, err = s.Notes().Insert(ctx, deleted)
+			assert.Nil(t, err)
+
+			if snapshot {
+				assert.Nil(t, s.Snapshot())
+			}
+
+			kept.Title = "changed"
+			assert.Nil(t, s.Notes().Update(ctx, kept))
+			assert.Nil(t, s.Notes().Delete(ctx, deleted.Id))
+
+			// the store is not closed, like after a crash
+			replayed := open(t, dir)
+
+			u, err := replayed.Users().GetByUserName(ctx, "user")
+			assert.Nil(t, err)
+			assert.Equal(t, uId, u.Id)
+
+			notes, err := replayed.Notes().GetAllOffset(ctx, repo.NoteFilter{UserId: uId})
+			assert.Nil(t, err)
+			assert.Equal(t, []model.Note{*kept}, notes)
+
+			changes, err := replayed.Notes().Changes(ctx, uId, kept.Version, 10)
+			assert.Nil(t, err)
+			assert.Equal(t, []model.NoteChange{{Version: kept.Version + 1, NoteId: deleted.Id, Deleted: true}}, changes)
+
+			// counters go on from the replayed ones
+			n := model.NewNote(0, uId, "new", "", date, false)
+			id, err := replayed.Notes().Insert(ctx, n)
+			assert.Nil(t, err)
+			assert.Equal(t, deleted.Id + 1, id)
+			assert.Equal(t, kept.Version + 2, n.Version)
+		})
+	}
+}
+
+func TestStore_Rollback(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	s := open(t, dir)
+	tx := NewTransactor()
+
+	broken := fmt.Errorf("broken")
+	err := tx.InTransaction(ctx, func(ctx context.Context) error {
+		if _, err := s.Users().Insert(ctx, model.NewUser(0, "user", []byte("hash"), model.UTC)); err != nil {
+			return err
+		}
+
+		return broken
+	})
+	assert.Equal(t, broken, err)
+
+	_, err = open(t, dir).Users().GetByUserName(ctx, "user")
+	assert.True(t, errors.Is(err, repo.ErrNotFound))
+}
+
+func TestStore_UnfinishedRecord(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	s := open(t, dir)
+	_, err := s.Users().Insert(ctx, model.NewUser(0, "user", []byte("hash"), model.UTC))
+	assert.Nil(t, err)
+
+	f, err := os.OpenFile(filepath.Join(dir, walFile), os.O_WRONLY|os.O_APPEND, 0)
+	assert.Nil(t, err)
+	_, err = f.WriteString(`{"kind":"user","user":{"Id":2,`)
+	assert.Nil(t, err)
+	f.Close()
+
+	replayed := open(t, dir)
+	_, err = replayed.Users().GetByUserName(ctx, "user")
+	assert.Nil(t, err)
+
+	// the unfinished record is dropped, new ones follow the last whole one
+	_, err = replayed.Users().Insert(ctx, model.NewUser(0, "other", []byte("hash"), model.UTC))
+	assert.Nil(t, err)
+	_, err = open(t, dir).Users().GetByUserName(ctx, "other")
+	assert.Nil(t, err)
+}
+
+func TestStore_BeforeOrganizations(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	wal := `{"kind":"user","user":{"Id":1,"Name":"user","TimeZone":"UTC"}}
+{"kind":"note","note":{"Id":1,"UserId":1,"Title":"kept","Version":1}}
+{"kind":"note","note":{"Id":2,"UserId":1,"Title":"deleted","Version":2}}
+{"kind":"note_deleted","id":2,"user_id":1,"version":3}
+`
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, walFile), []byte(wal), 0o600))
+
+	// elements written before organizations belong to the default one
+	s := open(t, dir)
+	u, err := s.Users().GetByUserName(ctx, "user")
+	assert.Nil(t, err)
+	assert.Equal(t, model.DefaultOrganizationId, u.OrganizationId)
+
+	n, err := s.Notes().GetById(ctx, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, model.DefaultOrganizationId, n.OrganizationId)
+
+	changes, err := s.Notes().Changes(ctx, 1, 0, 10)
+	assert.Nil(t, err)
+	assert.Len(t, changes, 2)
+
+	other := repo.WithTenant(ctx, model.DefaultOrganizationId + 1)
+	_, err = s.Notes().GetById(other, 1)
+	assert.True(t, errors.Is(err, repo.ErrNotFound))
+}
+
+func TestStore_Organizations(t *testing.T) {
+	ctx := context.Background()
+
+	for _, snapshot := range []bool{false, true} {
+		t.Run(fmt.Sprintf("snapshot %v", snapshot), func(t *testing.T) {
+			dir := t.TempDir()
+			s := open(t, dir)
+
+			oId, err := s.Organizations().Insert(ctx, model.NewOrganization("sales", time.Now().UTC()))
+			assert.Nil(t, err)
+			_, err = s.Users().Insert(repo.WithTenant(ctx, oId), model.NewUser(0, "user", []byte("hash"), model.UTC))
+			assert.Nil(t, err)
+
+			if snapshot {
+				assert.Nil(t, s.Snapshot())
+			}
+
+			replayed := open(t, dir)
+			o, err := replayed.Organizations().GetByName(ctx, "sales")
+			assert.Nil(t, err)
+			assert.Equal(t, oId, o.Id)
+
+			u, err := replayed.Users().GetByUserName(repo.WithTenant(ctx, oId), "user")
+			assert.Nil(t, err)
+			assert.Equal(t, oId, u.OrganizationId)
+
+			id, err := replayed.Organizations().Insert(ctx, model.NewOrganization("support", time.Now().UTC()))
+			assert.Nil(t, err)
+			assert.Equal(t, oId + 1, id)
+		})
+	}
+}
+
+func TestStore_AccountState(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2021, 10, 2, 10, 0, 0, 0, time.UTC)
+
+	for _, snapshot := range []bool{false, true} {
+		t.Run(fmt.Sprintf("snapshot %v", snapshot), func(t *testing.T) {
+			dir := t.TempDir()
+			s := open(t, dir)
+
+			uId, err := s.Users().Insert(ctx, model.NewUser(0, "user", []byte("hash"), model.UTC))
+			assert.Nil(t, err)
+
+			event := model.Event{Type: model.EventUserCreated, UserId: uId, SubjectId: uId, Payload: []byte(`{}`), CreatedAt: now, NextAttemptAt: now}
+			_, err = s.Outbox().Insert(ctx, &event)
+			assert.Nil(t, err)
+			published := model.Event{Type: model.EventUserUpdated, UserId: uId, SubjectId: uId, Payload: []byte(`{}`), CreatedAt: now, NextAttemptAt: now}
+			_, err = s.Outbox().Insert(ctx, &published)
+			assert.Nil(t, err)
+			assert.Nil(t, s.Outbox().Delete(ctx, published.Id))
+
+			mfa := model.NewMfa(uId, "secret")
+			mfa.RecoveryCodes = [][]byte{[]byte("code")}
+			assert.Nil(t, s.Mfa().Upsert(ctx, mfa))
+
+			session := model.Session{UserId: uId, CreatedAt: now, LastSeenAt: now, ExpiresAt: now.Add(time.Hour)}
+			sId, err := s.Sessions().Insert(ctx, &session)
+			assert.Nil(t, err)
+
+			identity := model.NewUserIdentity(uId, "google", "subject")
+			assert.Nil(t, s.Identities().Insert(ctx, identity))
+
+			export := model.Export{UserId: uId, Status: model.ExportReady, Archive: []byte("archive"), CreatedAt: now, CompletedAt: now}
+			assert.Nil(t, s.Exports().Upsert(ctx, &export))
+			deletion := model.AccountDeletion{UserId: uId, DeleteAfter: now.Add(time.Hour)}
+			assert.Nil(t, s.AccountDeletions().Upsert(ctx, &deletion))
+			feed := model.CalendarFeed{UserId: uId, TokenHash: []byte("hash"), CreatedAt: now}
+			assert.Nil(t, s.CalendarFeeds().Upsert(ctx, &feed))
+
+			if snapshot {
+				assert.Nil(t, s.Snapshot())
+			}
+
+			replayed := open(t, dir)
+
+			events, err := replayed.Outbox().Claim(ctx, now, time.Minute, 10)
+			assert.Nil(t, err)
+			assert.Len(t, events, 1)
+			assert.Equal(t, event.Id, events[0].Id)
+
+			gotMfa, err := replayed.Mfa().GetByUserId(ctx, uId)
+			assert.Nil(t, err)
+			assert.Equal(t, mfa, gotMfa)
+
+			gotSession, err := replayed.Sessions().GetById(ctx, sId)
+			assert.Nil(t, err)
+			assert.Equal(t, session, gotSession)
+
+			gotIdentity, err := replayed.Identities().GetByProviderSubject(ctx, "google", "subject")
+			assert.Nil(t, err)
+			assert.Equal(t, identity, gotIdentity)
+
+			gotExport, err := replayed.Exports().GetByUserId(ctx, uId)
+			assert.Nil(t, err)
+			assert.Equal(t, export, gotExport)
+			gotDeletion, err := replayed.AccountDeletions().GetByUserId(ctx, uId)
+			assert.Nil(t, err)
+			assert.Equal(t, deletion, gotDeletion)
+			gotFeed, err := replayed.CalendarFeeds().GetByTokenHash(ctx, []byte("hash"))
+			assert.Nil(t, err)
+			assert.Equal(t, feed, gotFeed)
+
+			// counters go on from the replayed ones
+			other := model.Event{Type: model.EventUserUpdated, UserId: uId, SubjectId: uId, Payload: []byte(`{}`), CreatedAt: now, NextAttemptAt: now}
+			id, err := replayed.Outbox().Insert(ctx, &other)
+			assert.Nil(t, err)
+			assert.Equal(t, published.Id + 1, id)
+			id, err = replayed.Sessions().Insert(ctx, &model.Session{UserId: uId, ExpiresAt: now})
+			assert.Nil(t, err)
+			assert.Equal(t, sId + 1, id)
+		})
+	}
+}
+
+func TestStore_Closed(t *testing.T) {
+	s := open(t, t.TempDir())
+	assert.Nil(t, s.Close())
+
+	_, err := s.Users().Insert(context.Background(), model.NewUser(0, "user", []byte("hash"), model.UTC))
+	assert.NotNil(t, err)
+	_, err = s.Users().GetByUserName(context.Background(), "user")
+	assert.True(t, errors.Is(err, repo.ErrNotFound))
+}
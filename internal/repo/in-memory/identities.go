@@ -0,0 +1,61 @@
+package in_memory
+
+import (
+	"context"
+	"sync"
+	"todoNote/internal/model"
+	"todoNote/internal/repo"
+)
+
+var _ repo.IRepoIdentity = &RepoIdentity{}
+
+type RepoIdentity struct {
+	sync.RWMutex
+	storage map[string]model.UserIdentity
+	journaled
+}
+
+func NewRepoIdentity() repo.IRepoIdentity {
+	return newRepoIdentity()
+}
+
+func newRepoIdentity() *RepoIdentity {
+	return &RepoIdentity{
+		storage: make(map[string]model.UserIdentity),
+	}
+}
+
+func identityKey(provider, subject string) string {
+	return provider + "\x00" + subject
+}
+
+func(r *RepoIdentity) Insert(ctx context.Context, i *model.UserIdentity) error {
+	key := identityKey(i.Provider, i.Subject)
+	r.Lock()
+	if err := r.log(record{Kind: kindIdentity, Identity: i}); err != nil {
+		r.Unlock()
+		return err
+	}
+	r.storage[key] = *i
+	r.Unlock()
+
+	removed := *i
+	onRollback(ctx, func() {
+		r.Lock()
+		r.log(record{Kind: kindIdentityRemoved, Identity: &removed})
+		delete(r.storage, key)
+		r.Unlock()
+	})
+	return nil
+}
+
+func(r *RepoIdentity) GetByProviderSubject(_ context.Context, provider, subject string) (*model.UserIdentity, error) {
+	r.RLock()
+	i, ok := r.storage[identityKey(provider, subject)]
+	r.RUnlock()
+	if !ok {
+		return nil, repo.NewNotFoundError(provider + ":" + subject)
+	}
+
+	return &i, nil
+}
@@ -0,0 +1,91 @@
+package in_memory
+
+import (
+	"context"
+	"sync"
+	"time"
+	"todoNote/internal/model"
+	"todoNote/internal/repo"
+)
+
+var _ repo.IRepoLoginAttempts = &RepoLoginAttempts{}
+
+// RepoLoginAttempts is not kept by Store, failed logins are forgotten on restart
+type RepoLoginAttempts struct {
+	sync.RWMutex
+	storage map[string]model.LoginAttempts
+	// evicted is when forgotten attempts were last dropped
+	evicted time.Time
+}
+
+func NewRepoLoginAttempts() repo.IRepoLoginAttempts {
+	return &RepoLoginAttempts{
+		storage: make(map[string]model.LoginAttempts),
+	}
+}
+
+func(r *RepoLoginAttempts) Get(_ context.Context, key string) (model.LoginAttempts, error) {
+	r.RLock()
+	a, ok := r.storage[key]
+	r.RUnlock()
+	if !ok {
+		return model.LoginAttempts{Key: key}, nil
+	}
+
+	return a, nil
+}
+
+func(r *RepoLoginAttempts) AddFailure(_ context.Context, key string, now time.Time, window time.Duration) (int, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.evict(now, window)
+
+	a := r.storage[key]
+	if now.Sub(a.LastFailure) > window {
+		a.Failures = 0
+	}
+
+	a.Key = key
+	a.Failures++
+	a.LastFailure = now
+	r.storage[key] = a
+
+	return a.Failures, nil
+}
+
+func(r *RepoLoginAttempts) LockUntil(_ context.Context, key string, until time.Time) error {
+	r.Lock()
+	defer r.Unlock()
+
+	a, ok := r.storage[key]
+	if ok && until.After(a.LockedUntil) {
+		a.LockedUntil = until
+		r.storage[key] = a
+	}
+
+	return nil
+}
+
+func(r *RepoLoginAttempts) Delete(_ context.Context, key string) error {
+	r.Lock()
+	delete(r.storage, key)
+	r.Unlock()
+
+	return nil
+}
+
+// evict drops the attempts which are forgotten and lock nothing any more, it goes over them once a window.
+// It must be called with the lock held
+func(r *RepoLoginAttempts) evict(now time.Time, window time.Duration) {
+	if now.Sub(r.evicted) < window {
+		return
+	}
+
+	for k, a := range r.storage {
+		if now.Sub(a.LastFailure) > window && !a.LockedUntil.After(now) {
+			delete(r.storage, k)
+		}
+	}
+	r.evicted = now
+}
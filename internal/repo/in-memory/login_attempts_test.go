@@ -0,0 +1,29 @@
+package in_memory
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestRepoLoginAttempts_Evict(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2021, 10, 2, 10, 0, 0, 0, time.UTC)
+	r := NewRepoLoginAttempts().(*RepoLoginAttempts)
+
+	_, err := r.AddFailure(ctx, "forgotten", now, time.Hour)
+	assert.Nil(t, err)
+	_, err = r.AddFailure(ctx, "locked", now, time.Hour)
+	assert.Nil(t, err)
+	assert.Nil(t, r.LockUntil(ctx, "locked", now.Add(3 * time.Hour)))
+
+	failures, err := r.AddFailure(ctx, "recent", now.Add(2 * time.Hour), time.Hour)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, failures)
+
+	// the forgotten attempts are dropped, a lock which is still held is kept
+	assert.Len(t, r.storage, 2)
+	assert.Contains(t, r.storage, "locked")
+	assert.Contains(t, r.storage, "recent")
+}
@@ -0,0 +1,107 @@
+package in_memory
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"todoNote/internal/model"
+	"todoNote/internal/repo"
+)
+
+var _ repo.IRepoMfa = &RepoMfa{}
+
+type RepoMfa struct {
+	sync.RWMutex
+	storage map[model.Id]model.Mfa
+	journaled
+}
+
+func NewRepoMfa() repo.IRepoMfa {
+	return newRepoMfa()
+}
+
+func newRepoMfa() *RepoMfa {
+	return &RepoMfa{
+		storage: make(map[model.Id]model.Mfa),
+	}
+}
+
+func(r *RepoMfa) Upsert(_ context.Context, m *model.Mfa) error {
+	r.Lock()
+	defer r.Unlock()
+
+	if err := r.log(record{Kind: kindMfa, Mfa: m}); err != nil {
+		return err
+	}
+	r.storage[m.UserId] = *m
+
+	return nil
+}
+
+func(r *RepoMfa) GetByUserId(_ context.Context, uId model.Id) (*model.Mfa, error) {
+	r.RLock()
+	m, ok := r.storage[uId]
+	r.RUnlock()
+	if !ok {
+		return nil, repo.NewNotFoundError(uId)
+	}
+
+	return &m, nil
+}
+
+func(r *RepoMfa) Delete(_ context.Context, uId model.Id) error {
+	r.Lock()
+	defer r.Unlock()
+
+	if err := r.log(record{Kind: kindMfaRemoved, UserId: uId}); err != nil {
+		return err
+	}
+	delete(r.storage, uId)
+
+	return nil
+}
+
+func(r *RepoMfa) UseStep(_ context.Context, uId model.Id, step int64) (bool, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	m, ok := r.storage[uId]
+	if !ok || m.LastStep >= step {
+		return false, nil
+	}
+
+	m.LastStep = step
+	if err := r.log(record{Kind: kindMfa, Mfa: &m}); err != nil {
+		return false, err
+	}
+	r.storage[uId] = m
+
+	return true, nil
+}
+
+func(r *RepoMfa) UseRecoveryCode(_ context.Context, uId model.Id, hash []byte) (bool, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	m, ok := r.storage[uId]
+	if !ok {
+		return false, nil
+	}
+
+	for i, h := range m.RecoveryCodes {
+		if !bytes.Equal(h, hash) {
+			continue
+		}
+
+		codes := make([][]byte, 0, len(m.RecoveryCodes) - 1)
+		m.RecoveryCodes = append(append(codes, m.RecoveryCodes[:i]...), m.RecoveryCodes[i+1:]...)
+		if err := r.log(record{Kind: kindMfa, Mfa: &m}); err != nil {
+			return false, err
+		}
+		r.storage[uId] = m
+
+		return true, nil
+	}
+
+	return false, nil
+}
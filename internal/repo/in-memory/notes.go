@@ -15,43 +15,96 @@ type RepoNote struct {
 	sync.RWMutex
 	storage map[model.Id]model.Note
 	counter int64 //can make int64 and incr
+	// versions is the last change version of every user
+	versions map[model.Id]int64
+	tombstones map[model.Id]tombstone
+	journaled
 }
 
+type tombstone struct {
+	userId model.Id
+	organizationId model.Id
+	change model.NoteChange
+}
+
+// NewRepoNote is a repository with demo notes of the demo user, they are lost on restart
 func NewRepoNote() repo.IRepoNote {
-	r := RepoNote{
-		storage: make(map[model.Id]model.Note),
-		counter: 1,
-	}
+	r := newRepoNote()
 
 	r.Insert(context.Background(), model.NewNote(1, 1, "title", "text", time.Now(), false))
 	r.Insert(context.Background(), model.NewNote(2, 1, "title", "text", time.Now(), false))
 	r.Insert(context.Background(), model.NewNote(3, 1, "title", "text", time.Now(), false))
 
-	return &r
+	return r
 }
 
-func(r *RepoNote) Insert(_ context.Context, n *model.Note) (model.Id, error) {
-	n.Id = r.counter
+func newRepoNote() *RepoNote {
+	return &RepoNote{
+		storage: make(map[model.Id]model.Note),
+		counter: 1,
+		versions: make(map[model.Id]int64),
+		tombstones: make(map[model.Id]tombstone),
+	}
+}
 
+func(r *RepoNote) Insert(ctx context.Context, n *model.Note) (model.Id, error) {
 	r.Lock()
-	r.storage[n.Id] = *n
+	n.Id = r.counter
+	n.OrganizationId = repo.InsertTenant(ctx)
+	n.Version = r.nextVersion(n.UserId)
+	note := stored(*n)
+	if err := r.log(record{Kind: kindNote, Note: &note}); err != nil {
+		r.Unlock()
+		return 0, err
+	}
+	r.storage[n.Id] = note
 	r.counter++
 	r.Unlock()
 
+	id := n.Id
+	onRollback(ctx, func() {
+		r.Lock()
+		r.log(record{Kind: kindNoteRemoved, Id: id})
+		delete(r.storage, id)
+		r.Unlock()
+	})
+
 	return n.Id, nil
 }
 
-func(r *RepoNote) GetById(_ context.Context, id model.Id) (model.Note, error) {
+func(r *RepoNote) GetById(ctx context.Context, id model.Id) (model.Note, error) {
 	r.RLock()
 	elem, ok := r.storage[id]
 	r.RUnlock()
-	if !ok {
-		return model.Note{}, NewNoSuchElementError(id)
+	if !ok || !inTenant(ctx, elem.OrganizationId) {
+		return model.Note{}, repo.NewNotFoundError(id)
 	}
 
 	return elem, nil
 }
 
+// GetByIdForUpdate is GetById, in-memory transactions do not run concurrently
+func(r *RepoNote) GetByIdForUpdate(ctx context.Context, id model.Id) (model.Note, error) {
+	return r.GetById(ctx, id)
+}
+
+// GetByUidForUpdate is GetByUid, in-memory transactions do not run concurrently
+func(r *RepoNote) GetByUidForUpdate(ctx context.Context, userId model.Id, uid string) (model.Note, error) {
+	return r.GetByUid(ctx, userId, uid)
+}
+
+func(r *RepoNote) GetByUid(ctx context.Context, userId model.Id, uid string) (model.Note, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	for _, n := range r.storage {
+		if n.UserId == userId && n.Uid == uid && inTenant(ctx, n.OrganizationId) {
+			return n, nil
+		}
+	}
+
+	return model.Note{}, repo.NewNotFoundError(0)
+}
 
 type FindParams struct {
 	Limit int
@@ -60,29 +113,31 @@ type FindParams struct {
 	IsFinished bool
 }
 
-func(r *RepoNote) GetAllOffset(_ context.Context, filter repo.NoteFilter) ([]model.Note, error){
+func(r *RepoNote) GetAllOffset(ctx context.Context, filter repo.NoteFilter) ([]model.Note, error){
 	filtered := make([]model.Note, 0)
-	keys := make([]int, 0)
+	err := r.Each(ctx, filter, func(n model.Note) error {
+		filtered = append(filtered, n)
+		return nil
+	})
+
+	return filtered, err
+}
+
+func(r *RepoNote) Each(ctx context.Context, filter repo.NoteFilter, fn func(n model.Note) error) error {
+	notes := make([]model.Note, 0)
 
 	r.RLock()
-	for k, v := range r.storage {
-		if v.UserId == filter.UserId {
-			keys = append(keys, int(k))
+	for _, elem := range r.storage {
+		if elem.UserId != filter.UserId || !inTenant(ctx, elem.OrganizationId) {
+			continue
 		}
-	}
-	r.RUnlock()
 
-	sort.Ints(keys)
-	for i, k := range keys {
-		if filter.Page.Offset != nil && uint64(i) < *filter.Page.Offset {
+		from, to := filter.Bounds(elem.IsFloating)
+		if from != nil && elem.Date.Before(*from) {
 			continue
 		}
 
-		r.RLock()
-		elem := r.storage[model.Id(k)]
-		r.RUnlock()
-
-		if filter.TakeFrom != nil && elem.Date.Before(*filter.TakeFrom) {
+		if to != nil && !elem.Date.Before(*to) {
 			continue
 		}
 
@@ -90,38 +145,141 @@ func(r *RepoNote) GetAllOffset(_ context.Context, filter repo.NoteFilter) ([]mod
 			continue
 		}
 
-		filtered = append(filtered, elem)
+		notes = append(notes, elem)
+	}
+	r.RUnlock()
 
-		if filter.Page.Limit != nil && uint64(len(filtered)) >= *filter.Page.Limit {
-			return filtered, nil
+	sort.Slice(notes, func(i, j int) bool {
+		if notes[i].Date.Equal(notes[j].Date) {
+			return notes[i].Id < notes[j].Id
 		}
+		return notes[i].Date.Before(notes[j].Date)
+	})
+
+	if filter.Page.Offset != nil {
+		if *filter.Page.Offset >= uint64(len(notes)) {
+			return nil
+		}
+		notes = notes[*filter.Page.Offset:]
 	}
 
+	if filter.Page.Limit != nil && *filter.Page.Limit < uint64(len(notes)) {
+		notes = notes[:*filter.Page.Limit]
+	}
 
-	return filtered, nil
+	for _, n := range notes {
+		if err := fn(n); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
-func(r *RepoNote) Update(_ context.Context, n *model.Note) error {
-	r.RLock()
-	_, ok := r.storage[n.Id]
-	r.RUnlock()
+func(r *RepoNote) Update(ctx context.Context, n *model.Note) error {
+	r.Lock()
+	defer r.Unlock()
+
+	old, ok := r.storage[n.Id]
 	//process
-	if !ok  {
-		return NewNoSuchElementError(n.Id)
+	if !ok || !inTenant(ctx, old.OrganizationId) {
+		return repo.NewNotFoundError(n.Id)
 	}
 
-	r.Lock()
-	r.storage[n.Id] = *n
-	r.Unlock()
+	n.OrganizationId = old.OrganizationId
+	n.Version = r.nextVersion(old.UserId)
+	note := stored(*n)
+	if err := r.log(record{Kind: kindNote, Note: &note}); err != nil {
+		return err
+	}
+	r.storage[n.Id] = note
+
+	onRollback(ctx, func() {
+		r.Lock()
+		r.log(record{Kind: kindNote, Note: &old})
+		r.storage[old.Id] = old
+		r.Unlock()
+	})
 	return nil
 }
 
-func(r *RepoNote) Delete(_ context.Context, id model.Id) error {
+func(r *RepoNote) Delete(ctx context.Context, id model.Id) error {
 	r.Lock()
-	_, ok := r.storage[id]
-	if ok {
-		delete(r.storage, id)
+	n, ok := r.storage[id]
+	if !ok || !inTenant(ctx, n.OrganizationId) {
+		r.Unlock()
+		return repo.NewNotFoundError(id)
+	}
+
+	change := model.NoteChange{Version: r.nextVersion(n.UserId), NoteId: id, Deleted: true}
+	rec := record{Kind: kindNoteDeleted, Id: id, UserId: n.UserId, OrganizationId: n.OrganizationId, Version: change.Version}
+	if err := r.log(rec); err != nil {
+		r.Unlock()
+		return err
 	}
+	delete(r.storage, id)
+	r.tombstones[id] = tombstone{userId: n.UserId, organizationId: n.OrganizationId, change: change}
 	r.Unlock()
+
+	onRollback(ctx, func() {
+		r.Lock()
+		r.log(record{Kind: kindNote, Note: &n})
+		delete(r.tombstones, id)
+		r.storage[id] = n
+		r.Unlock()
+	})
 	return nil
 }
+
+func(r *RepoNote) Changes(ctx context.Context, userId model.Id, since int64, limit uint64) ([]model.NoteChange, error) {
+	changes := make([]model.NoteChange, 0)
+
+	r.RLock()
+	for _, n := range r.storage {
+		if n.UserId == userId && n.Version > since && inTenant(ctx, n.OrganizationId) {
+			n := n
+			changes = append(changes, model.NoteChange{Version: n.Version, NoteId: n.Id, Note: &n})
+		}
+	}
+	for _, t := range r.tombstones {
+		if t.userId == userId && t.change.Version > since && inTenant(ctx, t.organizationId) {
+			changes = append(changes, t.change)
+		}
+	}
+	r.RUnlock()
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Version < changes[j].Version })
+	if uint64(len(changes)) > limit {
+		changes = changes[:limit]
+	}
+
+	return changes, nil
+}
+
+// nextVersion must be called with the lock held
+func(r *RepoNote) nextVersion(userId model.Id) int64 {
+	r.versions[userId]++
+	return r.versions[userId]
+}
+
+// seen raises the counter and the version of the user to a replayed note
+func(r *RepoNote) seen(id, userId model.Id, version int64) {
+	if r.counter <= id {
+		r.counter = id + 1
+	}
+	if r.versions[userId] < version {
+		r.versions[userId] = version
+	}
+}
+
+// stored is n as the SQL repositories return it, tags are never nil
+func stored(n model.Note) model.Note {
+	if n.Projects == nil {
+		n.Projects = []string{}
+	}
+	if n.Contexts == nil {
+		n.Contexts = []string{}
+	}
+
+	return n
+}
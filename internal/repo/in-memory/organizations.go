@@ -0,0 +1,96 @@
+package in_memory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+	"todoNote/internal/model"
+	"todoNote/internal/repo"
+)
+
+var _ repo.IRepoOrganization = &RepoOrganization{}
+
+type RepoOrganization struct {
+	sync.RWMutex
+	storage map[model.Id]model.Organization
+	counter int64
+	journaled
+}
+
+// NewRepoOrganization is a repository with the default organization, the others are lost on restart
+func NewRepoOrganization() repo.IRepoOrganization {
+	return newRepoOrganization()
+}
+
+func newRepoOrganization() *RepoOrganization {
+	return &RepoOrganization{
+		storage: map[model.Id]model.Organization{
+			model.DefaultOrganizationId: {
+				Id: model.DefaultOrganizationId,
+				Name: model.DefaultOrganizationName,
+				CreatedAt: time.Now().UTC(),
+			},
+		},
+		counter: model.DefaultOrganizationId + 1,
+	}
+}
+
+func(r *RepoOrganization) Insert(ctx context.Context, o *model.Organization) (model.Id, error) {
+	r.Lock()
+	for _, v := range r.storage {
+		if v.Name == o.Name {
+			r.Unlock()
+			return 0, fmt.Errorf("organization (%v) already exists: %w", o.Name, repo.ErrConflict)
+		}
+	}
+
+	o.Id = r.counter
+	if err := r.log(record{Kind: kindOrganization, Organization: o}); err != nil {
+		r.Unlock()
+		return 0, err
+	}
+	r.storage[o.Id] = *o
+	r.counter++
+	r.Unlock()
+
+	id := o.Id
+	onRollback(ctx, func() {
+		r.Lock()
+		r.log(record{Kind: kindOrganizationRemoved, Id: id})
+		delete(r.storage, id)
+		r.Unlock()
+	})
+
+	return o.Id, nil
+}
+
+func(r *RepoOrganization) GetById(_ context.Context, id model.Id) (model.Organization, error) {
+	r.RLock()
+	o, ok := r.storage[id]
+	r.RUnlock()
+	if !ok {
+		return model.Organization{}, repo.NewNotFoundError(id)
+	}
+
+	return o, nil
+}
+
+func(r *RepoOrganization) GetByName(_ context.Context, name string) (model.Organization, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	for _, o := range r.storage {
+		if o.Name == name {
+			return o, nil
+		}
+	}
+
+	return model.Organization{}, repo.NewNotFoundError(name)
+}
+
+// inTenant tells if an element of the organization is visible to the repositories called with ctx
+func inTenant(ctx context.Context, organizationId model.Id) bool {
+	id, ok := repo.Tenant(ctx)
+	return !ok || id == organizationId
+}
@@ -0,0 +1,114 @@
+package in_memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+	"todoNote/internal/model"
+	"todoNote/internal/repo"
+)
+
+var _ repo.IRepoOutbox = &RepoOutbox{}
+
+type RepoOutbox struct {
+	sync.RWMutex
+	storage map[model.Id]model.Event
+	counter int64
+	journaled
+}
+
+func NewRepoOutbox() repo.IRepoOutbox {
+	return newRepoOutbox()
+}
+
+func newRepoOutbox() *RepoOutbox {
+	return &RepoOutbox{
+		storage: make(map[model.Id]model.Event),
+		counter: 1,
+	}
+}
+
+func(r *RepoOutbox) Insert(ctx context.Context, e *model.Event) (model.Id, error) {
+	r.Lock()
+	e.Id = r.counter
+	if err := r.log(record{Kind: kindEvent, Event: e}); err != nil {
+		r.Unlock()
+		return 0, err
+	}
+	r.storage[e.Id] = *e
+	r.counter++
+	r.Unlock()
+
+	id := e.Id
+	onRollback(ctx, func() {
+		r.Lock()
+		r.log(record{Kind: kindEventRemoved, Id: id})
+		delete(r.storage, id)
+		r.Unlock()
+	})
+
+	return e.Id, nil
+}
+
+func(r *RepoOutbox) Claim(_ context.Context, now time.Time, lease time.Duration, limit uint64) ([]model.Event, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	due := make([]model.Event, 0)
+	for _, e := range r.storage {
+		if !e.NextAttemptAt.After(now) {
+			due = append(due, e)
+		}
+	}
+
+	sort.Slice(due, func(i, j int) bool { return due[i].Id < due[j].Id })
+	if uint64(len(due)) > limit {
+		due = due[:limit]
+	}
+
+	for i, e := range due {
+		e.NextAttemptAt = now.Add(lease)
+		if err := r.log(record{Kind: kindEvent, Event: &e}); err != nil {
+			return nil, err
+		}
+		r.storage[e.Id] = e
+		due[i] = e
+	}
+
+	return due, nil
+}
+
+func(r *RepoOutbox) Delete(_ context.Context, id model.Id) error {
+	r.Lock()
+	defer r.Unlock()
+
+	if _, ok := r.storage[id]; !ok {
+		return repo.NewNotFoundError(id)
+	}
+
+	if err := r.log(record{Kind: kindEventRemoved, Id: id}); err != nil {
+		return err
+	}
+	delete(r.storage, id)
+	return nil
+}
+
+func(r *RepoOutbox) Retry(_ context.Context, id model.Id, next time.Time, lastError string) error {
+	r.Lock()
+	defer r.Unlock()
+
+	e, ok := r.storage[id]
+	if !ok {
+		return repo.NewNotFoundError(id)
+	}
+
+	e.Attempts++
+	e.NextAttemptAt = next
+	e.LastError = lastError
+	if err := r.log(record{Kind: kindEvent, Event: &e}); err != nil {
+		return err
+	}
+	r.storage[id] = e
+	return nil
+}
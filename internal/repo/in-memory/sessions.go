@@ -0,0 +1,118 @@
+package in_memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+	"todoNote/internal/model"
+	"todoNote/internal/repo"
+)
+
+var _ repo.IRepoSession = &RepoSession{}
+
+type RepoSession struct {
+	sync.RWMutex
+	storage map[model.Id]model.Session
+	counter int64
+	journaled
+}
+
+func NewRepoSession() repo.IRepoSession {
+	return newRepoSession()
+}
+
+func newRepoSession() *RepoSession {
+	return &RepoSession{
+		storage: make(map[model.Id]model.Session),
+		counter: 1,
+	}
+}
+
+func(r *RepoSession) Insert(_ context.Context, s *model.Session) (model.Id, error) {
+	r.Lock()
+	s.Id = r.counter
+	if err := r.log(record{Kind: kindSession, Session: s}); err != nil {
+		r.Unlock()
+		return 0, err
+	}
+	r.storage[s.Id] = *s
+	r.counter++
+	r.Unlock()
+
+	return s.Id, nil
+}
+
+func(r *RepoSession) GetById(_ context.Context, id model.Id) (model.Session, error) {
+	r.RLock()
+	s, ok := r.storage[id]
+	r.RUnlock()
+	if !ok {
+		return model.Session{}, repo.NewNotFoundError(id)
+	}
+
+	return s, nil
+}
+
+func(r *RepoSession) GetAllByUser(_ context.Context, uId model.Id) ([]model.Session, error) {
+	res := make([]model.Session, 0)
+
+	r.RLock()
+	for _, s := range r.storage {
+		if s.UserId == uId {
+			res = append(res, s)
+		}
+	}
+	r.RUnlock()
+
+	sort.Slice(res, func(i, j int) bool { return res[i].Id < res[j].Id })
+	return res, nil
+}
+
+func(r *RepoSession) Touch(_ context.Context, id model.Id, at time.Time) error {
+	r.Lock()
+	defer r.Unlock()
+
+	s, ok := r.storage[id]
+	if !ok {
+		return repo.NewNotFoundError(id)
+	}
+
+	s.LastSeenAt = at
+	if err := r.log(record{Kind: kindSession, Session: &s}); err != nil {
+		return err
+	}
+	r.storage[id] = s
+	return nil
+}
+
+func(r *RepoSession) Delete(_ context.Context, id model.Id) error {
+	r.Lock()
+	defer r.Unlock()
+
+	if _, ok := r.storage[id]; !ok {
+		return repo.NewNotFoundError(id)
+	}
+
+	if err := r.log(record{Kind: kindSessionRemoved, Id: id}); err != nil {
+		return err
+	}
+	delete(r.storage, id)
+	return nil
+}
+
+func(r *RepoSession) DeleteExpired(_ context.Context, before time.Time) error {
+	r.Lock()
+	defer r.Unlock()
+
+	for id, s := range r.storage {
+		if s.ExpiresAt.Before(before) {
+			if err := r.log(record{Kind: kindSessionRemoved, Id: id}); err != nil {
+				return err
+			}
+			delete(r.storage, id)
+		}
+	}
+
+	return nil
+}
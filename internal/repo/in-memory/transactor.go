@@ -0,0 +1,51 @@
+package in_memory
+
+import (
+	"context"
+	"sync"
+	"todoNote/internal/repo"
+)
+
+var _ repo.ITransactor = &Transactor{}
+
+// Transactor runs one transaction at a time, so reads inside it are as good as locked.
+// Changes are applied immediately and undone in reverse order when fn fails
+type Transactor struct {
+	sync.Mutex
+}
+
+type txKey struct{}
+
+type tx struct {
+	undo []func()
+}
+
+func NewTransactor() repo.ITransactor {
+	return &Transactor{}
+}
+
+func(t *Transactor) InTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*tx); ok {
+		return fn(ctx)
+	}
+
+	t.Lock()
+	defer t.Unlock()
+
+	current := &tx{}
+	if err := fn(context.WithValue(ctx, txKey{}, current)); err != nil {
+		for i := len(current.undo) - 1; i >= 0; i-- {
+			current.undo[i]()
+		}
+		return err
+	}
+
+	return nil
+}
+
+// onRollback registers undo of a change made with ctx, changes made outside of a transaction are kept
+func onRollback(ctx context.Context, undo func()) {
+	if t, ok := ctx.Value(txKey{}).(*tx); ok {
+		t.undo = append(t.undo, undo)
+	}
+}
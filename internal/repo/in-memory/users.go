@@ -2,6 +2,7 @@ package in_memory
 
 import (
 	"context"
+	"fmt"
 	"golang.org/x/crypto/bcrypt"
 	"sync"
 	"todoNote/internal/model"
@@ -14,76 +15,136 @@ type RepoUser struct {
 	sync.RWMutex
 	storage map[model.Id]model.User
 	counter int64
+	journaled
 }
 
+// NewRepoUser is a repository with the demo user, it is lost on restart
 func NewRepoUser() repo.IRepoUser {
-	r := RepoUser{
-		storage: make(map[model.Id]model.User),
-		counter: 1,
-	}
+	r := newRepoUser()
 
 	h, _ := bcrypt.GenerateFromPassword([]byte("user"), 14)
 	r.Insert(context.Background(), model.NewUser(1, "user", h, model.UTCm4))
 
-	return &r
+	return r
 }
 
-func(r *RepoUser) Insert(_ context.Context, u *model.User) (model.Id, error) {
-	u.Id = r.counter
+func newRepoUser() *RepoUser {
+	return &RepoUser{
+		storage: make(map[model.Id]model.User),
+		counter: 1,
+	}
+}
+
+func(r *RepoUser) Insert(ctx context.Context, u *model.User) (model.Id, error) {
+	organizationId := repo.InsertTenant(ctx)
 
 	r.Lock()
+	for _, v := range r.storage {
+		if v.OrganizationId == organizationId && v.Name == u.Name {
+			r.Unlock()
+			return 0, fmt.Errorf("user (%v) already exists: %w", u.Name, repo.ErrConflict)
+		}
+	}
+
+	u.Id = r.counter
+	u.OrganizationId = organizationId
+	if err := r.log(record{Kind: kindUser, User: u}); err != nil {
+		r.Unlock()
+		return 0, err
+	}
 	r.storage[u.Id] = *u
 	r.counter++
 	r.Unlock()
 
+	id := u.Id
+	onRollback(ctx, func() {
+		r.Lock()
+		r.log(record{Kind: kindUserRemoved, Id: id})
+		delete(r.storage, id)
+		r.Unlock()
+	})
+
 	return u.Id, nil
 }
 
-func(r *RepoUser) GetByUserName(_ context.Context, uName string) (*model.User, error) {
+func(r *RepoUser) GetByUserName(ctx context.Context, uName string) (*model.User, error) {
 	r.RLock()
 	defer r.RUnlock()
 
 	for _, v := range r.storage {
-		if v.Name == uName {
+		if v.Name == uName && inTenant(ctx, v.OrganizationId) {
 			return &v, nil
 		}
 	}
 
-	return nil, NewNoSuchNameError(uName)
+	return nil, repo.NewNotFoundError(uName)
 }
 
 func(r *RepoUser) GetById(ctx context.Context, uId model.Id) (*model.User, error) {
 	r.RLock()
 	u, ok := r.storage[uId]
 	r.RUnlock()
-	if !ok {
-		return &u, NewNoSuchElementError(uId)
+	if !ok || !inTenant(ctx, u.OrganizationId) {
+		return &model.User{}, repo.NewNotFoundError(uId)
 	}
 
 	return &u, nil
 }
 
+// GetByIdForUpdate is GetById, in-memory transactions do not run concurrently
+func(r *RepoUser) GetByIdForUpdate(ctx context.Context, uId model.Id) (*model.User, error) {
+	return r.GetById(ctx, uId)
+}
 
-func(r *RepoUser) Update(_ context.Context, u *model.User) error {
-		r.RLock()
-		_, ok := r.storage[u.Id]
-		r.RUnlock()
+func(r *RepoUser) Update(ctx context.Context, u *model.User) error {
+	r.Lock()
+	old, ok := r.storage[u.Id]
+	if !ok || !inTenant(ctx, old.OrganizationId) {
+		r.Unlock()
+		return repo.NewNotFoundError(u.Id)
+	}
 
-		if !ok {
-			return NoSuchElementError{id: u.Id}
-		}
+	// users do not move between organizations
+	u.OrganizationId = old.OrganizationId
+
+	if err := r.log(record{Kind: kindUser, User: u}); err != nil {
+		r.Unlock()
+		return err
+	}
+	r.storage[u.Id] = *u
+	r.Unlock()
 
+	onRollback(ctx, func() {
 		r.Lock()
-		r.storage[u.Id] = *u
+		r.log(record{Kind: kindUser, User: &old})
+		r.storage[old.Id] = old
 		r.Unlock()
+	})
 
-		return nil
+	return nil
 }
 
-func(r *RepoUser) Delete(_ context.Context, userId model.Id) error {
+func(r *RepoUser) Delete(ctx context.Context, userId model.Id) error {
 	r.Lock()
+	old, ok := r.storage[userId]
+	if !ok || !inTenant(ctx, old.OrganizationId) {
+		r.Unlock()
+		return repo.NewNotFoundError(userId)
+	}
+
+	if err := r.log(record{Kind: kindUserRemoved, Id: userId}); err != nil {
+		r.Unlock()
+		return err
+	}
 	delete(r.storage, userId)
 	r.Unlock()
 
+	onRollback(ctx, func() {
+		r.Lock()
+		r.log(record{Kind: kindUser, User: &old})
+		r.storage[userId] = old
+		r.Unlock()
+	})
+
 	return nil
 }
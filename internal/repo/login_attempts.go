@@ -0,0 +1,18 @@
+package repo
+
+import (
+	"context"
+	"time"
+	"todoNote/internal/model"
+)
+
+// IRepoLoginAttempts returns empty attempts for unknown keys
+type IRepoLoginAttempts interface {
+	Get(ctx context.Context, key string) (model.LoginAttempts, error)
+	// AddFailure counts a failure of key at now in one step and returns the failures counted,
+	// those which were last counted more than window before now are forgotten first
+	AddFailure(ctx context.Context, key string, now time.Time, window time.Duration) (int, error)
+	// LockUntil locks key until the time, a later lock already held is kept
+	LockUntil(ctx context.Context, key string, until time.Time) error
+	Delete(ctx context.Context, key string) error
+}
@@ -0,0 +1,16 @@
+package repo
+
+import (
+	"context"
+	"todoNote/internal/model"
+)
+
+type IRepoMfa interface {
+	Upsert(ctx context.Context, m *model.Mfa) error
+	GetByUserId(ctx context.Context, uId model.Id) (*model.Mfa, error)
+	Delete(ctx context.Context, uId model.Id) error
+	// UseStep sets LastStep to the totp step, false when it is not later than LastStep
+	UseStep(ctx context.Context, uId model.Id, step int64) (bool, error)
+	// UseRecoveryCode removes the hash of a recovery code, false when the user has no such code
+	UseRecoveryCode(ctx context.Context, uId model.Id, hash []byte) (bool, error)
+}
@@ -7,23 +7,75 @@ import (
 	"todoNote/internal/model"
 )
 
+// IRepoNote is scoped to the tenant of the context, see WithTenant
 type IRepoNote interface {
+	// Insert and Update set the new Version of n, Insert sets its OrganizationId too
 	Insert(ctx context.Context, n *model.Note) (model.Id, error)
 	GetById(ctx context.Context, noteId model.Id) (model.Note, error)
+	// GetByIdForUpdate is GetById that keeps others from changing the note until the transaction of ctx ends
+	GetByIdForUpdate(ctx context.Context, noteId model.Id) (model.Note, error)
+	GetByUid(ctx context.Context, userId model.Id, uid string) (model.Note, error)
+	// GetByUidForUpdate is GetByUid that keeps others from changing the note until the transaction of ctx ends
+	GetByUidForUpdate(ctx context.Context, userId model.Id, uid string) (model.Note, error)
+	// GetAllOffset returns the notes of filter.UserId ordered by date and then by id
 	GetAllOffset(ctx context.Context, filter NoteFilter) ([]model.Note, error)
+	// Each calls fn for every note GetAllOffset would return without collecting them, no limit means all notes
+	Each(ctx context.Context, filter NoteFilter, fn func(n model.Note) error) error
+	// Update and Delete of an unknown note fail with ErrNotFound
 	Update(ctx context.Context, n *model.Note) error
 	Delete(ctx context.Context, noteId model.Id) error
+	// Changes are notes and tombstones of deleted notes changed after the since version, ordered by version
+	Changes(ctx context.Context, userId model.Id, since int64, limit uint64) ([]model.NoteChange, error)
 }
 
 type NoteFilter struct {
 	Page PageFilter
 	UserId model.Id
+	// TakeFrom is inclusive
 	TakeFrom *time.Time
+	// TakeTo is exclusive
+	TakeTo *time.Time
+	// FloatingFrom and FloatingTo bound the wall clock of floating notes in place of TakeFrom and TakeTo,
+	// without them floating notes are bounded like the others
+	FloatingFrom *time.Time
+	FloatingTo *time.Time
 	IsFinished *bool
 }
 
+// Bounds are the bounds of the dates of floating notes or of the others
+func(f NoteFilter) Bounds(floating bool) (from, to *time.Time) {
+	from, to = f.TakeFrom, f.TakeTo
+	if floating && f.FloatingFrom != nil {
+		from = f.FloatingFrom
+	}
+	if floating && f.FloatingTo != nil {
+		to = f.FloatingTo
+	}
+
+	return from, to
+}
+
+// Span are the loosest bounds of the dates of all notes, nil when either kind of notes is not bounded
+func(f NoteFilter) Span() (from, to *time.Time) {
+	from, to = f.Bounds(false)
+	floatingFrom, floatingTo := f.Bounds(true)
+	if from == nil || floatingFrom == nil {
+		from = nil
+	} else if floatingFrom.Before(*from) {
+		from = floatingFrom
+	}
+	if to == nil || floatingTo == nil {
+		to = nil
+	} else if floatingTo.After(*to) {
+		to = floatingTo
+	}
+
+	return from, to
+}
+
 type PageFilter struct {
 	Limit *uint64
+	// Offset is the number of matching notes skipped
 	Offset *uint64
 }
 
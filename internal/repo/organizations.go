@@ -0,0 +1,14 @@
+package repo
+
+import (
+	"context"
+	"todoNote/internal/model"
+)
+
+// IRepoOrganization keeps the tenants, it is not scoped by the tenant of the context
+type IRepoOrganization interface {
+	// Insert of a taken name fails with ErrConflict
+	Insert(ctx context.Context, o *model.Organization) (model.Id, error)
+	GetById(ctx context.Context, id model.Id) (model.Organization, error)
+	GetByName(ctx context.Context, name string) (model.Organization, error)
+}
@@ -0,0 +1,20 @@
+package repo
+
+import (
+	"context"
+	"time"
+	"todoNote/internal/model"
+)
+
+// IRepoOutbox keeps domain events in the storage of the changes they are about,
+// so an event is kept if and only if the transaction of its change commits
+type IRepoOutbox interface {
+	Insert(ctx context.Context, e *model.Event) (model.Id, error)
+	// Claim returns up to limit events due at now, oldest first, and postpones them until now + lease.
+	// An event which is neither deleted nor retried by then is claimed again
+	Claim(ctx context.Context, now time.Time, lease time.Duration, limit uint64) ([]model.Event, error)
+	// Delete removes a published event
+	Delete(ctx context.Context, id model.Id) error
+	// Retry counts a failed attempt to publish the event and makes it due at next
+	Retry(ctx context.Context, id model.Id, next time.Time, lastError string) error
+}
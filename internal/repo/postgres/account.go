@@ -0,0 +1,161 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v4"
+	"time"
+	"todoNote/internal/model"
+	"todoNote/internal/repo"
+)
+
+var _ repo.IRepoExport = &RepoExport{}
+
+type RepoExport struct {
+	conn DB
+}
+
+func NewRepoExport(conn DB) repo.IRepoExport {
+	return &RepoExport{
+		conn: conn,
+	}
+}
+
+func (r *RepoExport) Upsert(ctx context.Context, e *model.Export) error {
+	query := `
+INSERT INTO data_exports (user_id, status, archive, created_at, completed_at)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (user_id) DO UPDATE
+SET status = EXCLUDED.status, archive = EXCLUDED.archive,
+created_at = EXCLUDED.created_at, completed_at = EXCLUDED.completed_at;`
+
+	var completedAt *time.Time
+	if !e.CompletedAt.IsZero() {
+		completedAt = &e.CompletedAt
+	}
+
+	_, err := db(ctx, r.conn).Exec(ctx,
+		query,
+		e.UserId,
+		e.Status,
+		e.Archive,
+		e.CreatedAt,
+		completedAt)
+
+	if err != nil {
+		return NewExportsError(insert, err)
+	}
+
+	return nil
+}
+
+func (r *RepoExport) GetByUserId(ctx context.Context, uId model.Id) (model.Export, error) {
+	query := `SELECT user_id, status, archive, created_at, completed_at FROM data_exports WHERE user_id = $1;`
+	var e model.Export
+	var completedAt *time.Time
+	err := db(ctx, r.conn).QueryRow(ctx, query, uId).Scan(
+		&e.UserId,
+		&e.Status,
+		&e.Archive,
+		&e.CreatedAt,
+		&completedAt)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Export{}, repo.NewNotFoundError(uId)
+		}
+
+		return model.Export{}, NewExportsError(select_sql, err)
+	}
+
+	if completedAt != nil {
+		e.CompletedAt = *completedAt
+	}
+
+	return e, nil
+}
+
+func (r *RepoExport) Delete(ctx context.Context, uId model.Id) error {
+	query := `DELETE FROM data_exports WHERE user_id = $1;`
+	if _, err := db(ctx, r.conn).Exec(ctx, query, uId); err != nil {
+		return NewExportsError(delete_sql, err)
+	}
+
+	return nil
+}
+
+var _ repo.IRepoAccountDeletion = &RepoAccountDeletion{}
+
+type RepoAccountDeletion struct {
+	conn DB
+}
+
+func NewRepoAccountDeletion(conn DB) repo.IRepoAccountDeletion {
+	return &RepoAccountDeletion{
+		conn: conn,
+	}
+}
+
+func (r *RepoAccountDeletion) Upsert(ctx context.Context, d *model.AccountDeletion) error {
+	query := `
+INSERT INTO account_deletions (user_id, delete_after)
+VALUES ($1, $2)
+ON CONFLICT (user_id) DO UPDATE
+SET delete_after = EXCLUDED.delete_after;`
+
+	if _, err := db(ctx, r.conn).Exec(ctx, query, d.UserId, d.DeleteAfter); err != nil {
+		return NewAccountDeletionsError(insert, err)
+	}
+
+	return nil
+}
+
+func (r *RepoAccountDeletion) GetByUserId(ctx context.Context, uId model.Id) (model.AccountDeletion, error) {
+	query := `SELECT user_id, delete_after FROM account_deletions WHERE user_id = $1;`
+	var d model.AccountDeletion
+	err := db(ctx, r.conn).QueryRow(ctx, query, uId).Scan(&d.UserId, &d.DeleteAfter)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.AccountDeletion{}, repo.NewNotFoundError(uId)
+		}
+
+		return model.AccountDeletion{}, NewAccountDeletionsError(select_sql, err)
+	}
+
+	return d, nil
+}
+
+func (r *RepoAccountDeletion) Delete(ctx context.Context, uId model.Id) error {
+	query := `DELETE FROM account_deletions WHERE user_id = $1;`
+	if _, err := db(ctx, r.conn).Exec(ctx, query, uId); err != nil {
+		return NewAccountDeletionsError(delete_sql, err)
+	}
+
+	return nil
+}
+
+func (r *RepoAccountDeletion) GetDue(ctx context.Context, now time.Time) ([]model.AccountDeletion, error) {
+	query := `SELECT user_id, delete_after FROM account_deletions WHERE delete_after < $1;`
+	rows, err := db(ctx, r.conn).Query(ctx, query, now)
+	if err != nil {
+		return nil, NewAccountDeletionsError(select_sql, err)
+	}
+
+	res := make([]model.AccountDeletion, 0)
+	defer rows.Close()
+	for rows.Next() {
+		var d model.AccountDeletion
+		if err := rows.Scan(&d.UserId, &d.DeleteAfter); err != nil {
+			return nil, NewAccountDeletionsError(select_sql, err)
+		}
+
+		res = append(res, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, NewAccountDeletionsError(select_sql, err)
+	}
+
+	return res, nil
+}
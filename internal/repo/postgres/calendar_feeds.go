@@ -0,0 +1,60 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v4"
+	"todoNote/internal/model"
+	"todoNote/internal/repo"
+)
+
+var _ repo.IRepoCalendarFeed = &RepoCalendarFeed{}
+
+type RepoCalendarFeed struct {
+	conn DB
+}
+
+func NewRepoCalendarFeed(conn DB) repo.IRepoCalendarFeed {
+	return &RepoCalendarFeed{
+		conn: conn,
+	}
+}
+
+func (r *RepoCalendarFeed) Upsert(ctx context.Context, f *model.CalendarFeed) error {
+	query := `
+INSERT INTO calendar_feeds (user_id, token_hash, created_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id) DO UPDATE
+SET token_hash = EXCLUDED.token_hash, created_at = EXCLUDED.created_at;`
+
+	if _, err := r.conn.Exec(ctx, query, f.UserId, f.TokenHash, f.CreatedAt); err != nil {
+		return NewCalendarFeedsError(insert, err)
+	}
+
+	return nil
+}
+
+func (r *RepoCalendarFeed) GetByTokenHash(ctx context.Context, hash []byte) (model.CalendarFeed, error) {
+	query := `SELECT user_id, token_hash, created_at FROM calendar_feeds WHERE token_hash = $1;`
+	var f model.CalendarFeed
+	err := r.conn.QueryRow(ctx, query, hash).Scan(&f.UserId, &f.TokenHash, &f.CreatedAt)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.CalendarFeed{}, repo.NewNotFoundError(0)
+		}
+
+		return model.CalendarFeed{}, NewCalendarFeedsError(select_sql, err)
+	}
+
+	return f, nil
+}
+
+func (r *RepoCalendarFeed) Delete(ctx context.Context, uId model.Id) error {
+	query := `DELETE FROM calendar_feeds WHERE user_id = $1;`
+	if _, err := r.conn.Exec(ctx, query, uId); err != nil {
+		return NewCalendarFeedsError(delete_sql, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,17 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"todoNote/internal/repo/repotest"
+)
+
+// TestConformance needs the database of dbUrl like the other tests of the package
+func TestConformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repotest.Repositories {
+		conn := connect(t)
+		t.Cleanup(func() { conn.Close(context.Background()) })
+
+		return repotest.Repositories{Note: NewRepoNote(conn), User: NewRepoUser(conn), Transactor: NewTransactor(conn), Outbox: NewRepoOutbox(conn), Organization: NewRepoOrganization(conn)}
+	})
+}
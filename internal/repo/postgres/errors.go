@@ -1,10 +1,26 @@
 package postgres
 
-import "fmt"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"todoNote/internal/repo"
+)
 
 const (
 	users      = "users:"
 	notes = "notes:"
+	mfa = "mfa:"
+	identities = "identities:"
+	loginAttempts = "login_attempts:"
+	sessions = "sessions:"
+	exports = "data_exports:"
+	accountDeletions = "account_deletions:"
+	calendarFeeds = "calendar_feeds:"
+	outbox = "outbox:"
+	organizations = "organizations:"
 	select_sql = "select:"
 	insert = "insert:"
 	delete_sql = "delete_sql:"
@@ -12,20 +28,101 @@ const (
 
 )
 
-type UserExistsError struct {
-	name string
+// SQLSTATE codes mapped to repo errors
+const (
+	uniqueViolation = "23505"
+	foreignKeyViolation = "23503"
+	serializationFailure = "40001"
+	deadlockDetected = "40P01"
+	lockNotAvailable = "55P03"
+	queryCanceled = "57014"
+)
+
+var rowsAffectedNotOne = fmt.Errorf("rows affected != 1: %w", repo.ErrNotFound)
+
+// pgError keeps the error of the driver for errors.As and matches the repo error of its kind for errors.Is
+type pgError struct {
+	kind error
+	err error
+}
+
+func (e pgError) Error() string {
+	return fmt.Sprintf("%v: %v", e.kind, e.err)
+}
+
+func (e pgError) Unwrap() error {
+	return e.err
 }
 
-func (e UserExistsError) Error() string {
-	return fmt.Sprintf("user (%v) already exists", e.name)
+func (e pgError) Is(target error) bool {
+	return target == e.kind
 }
 
-var rowsAffectedNotOne = fmt.Errorf("rows affected != 1")
+// mapError wraps errors of the driver with the repo error they stand for, others are returned as is
+func mapError(err error) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return pgError{kind: repo.ErrNotFound, err: err}
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case uniqueViolation, serializationFailure, deadlockDetected:
+			return pgError{kind: repo.ErrConflict, err: err}
+		case foreignKeyViolation:
+			return pgError{kind: repo.ErrForeignKey, err: err}
+		case lockNotAvailable, queryCanceled:
+			return pgError{kind: repo.ErrTimeout, err: err}
+		}
+	}
+
+	if pgconn.Timeout(err) || errors.Is(err, context.DeadlineExceeded) {
+		return pgError{kind: repo.ErrTimeout, err: err}
+	}
+
+	return err
+}
 
 func NewUsersError(method string, err error) error {
-	return fmt.Errorf("%v %v %w", users, method, err)
+	return fmt.Errorf("%v %v %w", users, method, mapError(err))
 }
 
 func NewNotesError(method string, err error) error {
-	return fmt.Errorf("%v %v %w", notes, method, err)
+	return fmt.Errorf("%v %v %w", notes, method, mapError(err))
+}
+
+func NewMfaError(method string, err error) error {
+	return fmt.Errorf("%v %v %w", mfa, method, mapError(err))
+}
+
+func NewIdentitiesError(method string, err error) error {
+	return fmt.Errorf("%v %v %w", identities, method, mapError(err))
+}
+
+func NewLoginAttemptsError(method string, err error) error {
+	return fmt.Errorf("%v %v %w", loginAttempts, method, mapError(err))
+}
+
+func NewSessionsError(method string, err error) error {
+	return fmt.Errorf("%v %v %w", sessions, method, mapError(err))
+}
+
+func NewExportsError(method string, err error) error {
+	return fmt.Errorf("%v %v %w", exports, method, mapError(err))
+}
+
+func NewAccountDeletionsError(method string, err error) error {
+	return fmt.Errorf("%v %v %w", accountDeletions, method, mapError(err))
+}
+
+func NewCalendarFeedsError(method string, err error) error {
+	return fmt.Errorf("%v %v %w", calendarFeeds, method, mapError(err))
+}
+
+func NewOutboxError(method string, err error) error {
+	return fmt.Errorf("%v %v %w", outbox, method, mapError(err))
+}
+
+func NewOrganizationsError(method string, err error) error {
+	return fmt.Errorf("%v %v %w", organizations, method, mapError(err))
 }
@@ -0,0 +1,43 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"todoNote/internal/repo"
+)
+
+func TestMapError(t *testing.T) {
+	tts := []struct{
+		name string
+		err error
+		want error
+	}{
+		{"no rows", pgx.ErrNoRows, repo.ErrNotFound},
+		{"unique violation", &pgconn.PgError{Code: uniqueViolation}, repo.ErrConflict},
+		{"serialization failure", &pgconn.PgError{Code: serializationFailure}, repo.ErrConflict},
+		{"foreign key violation", &pgconn.PgError{Code: foreignKeyViolation}, repo.ErrForeignKey},
+		{"statement timeout", &pgconn.PgError{Code: queryCanceled}, repo.ErrTimeout},
+		{"context deadline", fmt.Errorf("query: %w", context.DeadlineExceeded), repo.ErrTimeout},
+	}
+
+	for _, tt := range tts {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewNotesError(select_sql, tt.err)
+			assert.True(t, errors.Is(err, tt.want))
+			assert.True(t, errors.Is(err, tt.err))
+		})
+	}
+
+	t.Run("other errors are kept", func(t *testing.T) {
+		err := &pgconn.PgError{Code: "42P01"}
+		got := mapError(err)
+		assert.Equal(t, err, got)
+		var pgErr *pgconn.PgError
+		assert.True(t, errors.As(NewUsersError(insert, err), &pgErr))
+	})
+}
@@ -0,0 +1,49 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v4"
+	"todoNote/internal/model"
+	"todoNote/internal/repo"
+)
+
+var _ repo.IRepoIdentity = &RepoIdentity{}
+
+type RepoIdentity struct {
+	conn DB
+}
+
+func NewRepoIdentity(conn DB) repo.IRepoIdentity {
+	return &RepoIdentity{
+		conn: conn,
+	}
+}
+
+func (r *RepoIdentity) Insert(ctx context.Context, i *model.UserIdentity) error {
+	query := `INSERT INTO user_identities (provider, subject, user_id) VALUES ($1, $2, $3);`
+	if _, err := db(ctx, r.conn).Exec(ctx, query, i.Provider, i.Subject, i.UserId); err != nil {
+		return NewIdentitiesError(insert, err)
+	}
+
+	return nil
+}
+
+func (r *RepoIdentity) GetByProviderSubject(ctx context.Context, provider, subject string) (*model.UserIdentity, error) {
+	query := `SELECT provider, subject, user_id FROM user_identities WHERE provider = $1 AND subject = $2;`
+	var i model.UserIdentity
+	err := db(ctx, r.conn).QueryRow(ctx, query, provider, subject).Scan(
+		&i.Provider,
+		&i.Subject,
+		&i.UserId)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, repo.NewNotFoundError(provider + ":" + subject)
+		}
+
+		return nil, NewIdentitiesError(select_sql, err)
+	}
+
+	return &i, nil
+}
@@ -0,0 +1,85 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v4"
+	"time"
+	"todoNote/internal/model"
+	"todoNote/internal/repo"
+)
+
+var _ repo.IRepoLoginAttempts = &RepoLoginAttempts{}
+
+type RepoLoginAttempts struct {
+	conn DB
+}
+
+func NewRepoLoginAttempts(conn DB) repo.IRepoLoginAttempts {
+	return &RepoLoginAttempts{
+		conn: conn,
+	}
+}
+
+func (r *RepoLoginAttempts) Get(ctx context.Context, key string) (model.LoginAttempts, error) {
+	query := `SELECT key, failures, last_failure, locked_until FROM login_attempts WHERE key = $1;`
+	var a model.LoginAttempts
+	err := r.conn.QueryRow(ctx, query, key).Scan(
+		&a.Key,
+		&a.Failures,
+		&a.LastFailure,
+		&a.LockedUntil)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.LoginAttempts{Key: key}, nil
+		}
+
+		return model.LoginAttempts{}, NewLoginAttemptsError(select_sql, err)
+	}
+
+	return a, nil
+}
+
+func (r *RepoLoginAttempts) AddFailure(ctx context.Context, key string, now time.Time, window time.Duration) (int, error) {
+	query := `
+INSERT INTO login_attempts (key, failures, last_failure, locked_until)
+VALUES ($1, 1, $2, $3)
+ON CONFLICT (key) DO UPDATE
+SET failures = CASE WHEN login_attempts.last_failure < $4 THEN 1 ELSE login_attempts.failures + 1 END,
+last_failure = EXCLUDED.last_failure
+RETURNING failures;`
+
+	var failures int
+	err := r.conn.QueryRow(ctx,
+		query,
+		key,
+		now,
+		time.Time{},
+		now.Add(-window)).
+		Scan(&failures)
+
+	if err != nil {
+		return 0, NewLoginAttemptsError(insert, err)
+	}
+
+	return failures, nil
+}
+
+func (r *RepoLoginAttempts) LockUntil(ctx context.Context, key string, until time.Time) error {
+	query := `UPDATE login_attempts SET locked_until = GREATEST(locked_until, $2) WHERE key = $1;`
+	if _, err := r.conn.Exec(ctx, query, key, until); err != nil {
+		return NewLoginAttemptsError(update, err)
+	}
+
+	return nil
+}
+
+func (r *RepoLoginAttempts) Delete(ctx context.Context, key string) error {
+	query := `DELETE FROM login_attempts WHERE key = $1;`
+	if _, err := r.conn.Exec(ctx, query, key); err != nil {
+		return NewLoginAttemptsError(delete_sql, err)
+	}
+
+	return nil
+}
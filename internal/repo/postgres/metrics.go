@@ -0,0 +1,70 @@
+package postgres
+
+import (
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const poolMetricsNamespace = "todonote_db_pool"
+
+var _ prometheus.Collector = &PoolCollector{}
+
+// PoolCollector exposes connection pool statistics, they are read from the pool on every scrape
+type PoolCollector struct {
+	stat func() *pgxpool.Stat
+
+	acquiredConns *prometheus.Desc
+	idleConns *prometheus.Desc
+	constructingConns *prometheus.Desc
+	totalConns *prometheus.Desc
+	maxConns *prometheus.Desc
+	acquireCount *prometheus.Desc
+	canceledAcquireCount *prometheus.Desc
+	emptyAcquireCount *prometheus.Desc
+	acquireDuration *prometheus.Desc
+}
+
+func NewPoolCollector(pool *pgxpool.Pool) *PoolCollector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(poolMetricsNamespace, "", name), help, nil, nil)
+	}
+
+	return &PoolCollector{
+		stat: pool.Stat,
+		acquiredConns: desc("acquired_connections", "Connections in use."),
+		idleConns: desc("idle_connections", "Idle connections."),
+		constructingConns: desc("constructing_connections", "Connections being opened."),
+		totalConns: desc("total_connections", "All connections of the pool."),
+		maxConns: desc("max_connections", "Maximum size of the pool."),
+		acquireCount: desc("acquires_total", "Successful acquires of a connection."),
+		canceledAcquireCount: desc("canceled_acquires_total", "Acquires canceled by their context."),
+		emptyAcquireCount: desc("empty_acquires_total", "Acquires which waited because no connection was idle."),
+		acquireDuration: desc("acquire_seconds_total", "Time spent waiting for a connection."),
+	}
+}
+
+func(c *PoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.acquiredConns
+	ch <- c.idleConns
+	ch <- c.constructingConns
+	ch <- c.totalConns
+	ch <- c.maxConns
+	ch <- c.acquireCount
+	ch <- c.canceledAcquireCount
+	ch <- c.emptyAcquireCount
+	ch <- c.acquireDuration
+}
+
+func(c *PoolCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.stat()
+
+	ch <- prometheus.MustNewConstMetric(c.acquiredConns, prometheus.GaugeValue, float64(s.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(c.idleConns, prometheus.GaugeValue, float64(s.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(c.constructingConns, prometheus.GaugeValue, float64(s.ConstructingConns()))
+	ch <- prometheus.MustNewConstMetric(c.totalConns, prometheus.GaugeValue, float64(s.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(c.maxConns, prometheus.GaugeValue, float64(s.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(c.acquireCount, prometheus.CounterValue, float64(s.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.canceledAcquireCount, prometheus.CounterValue, float64(s.CanceledAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.emptyAcquireCount, prometheus.CounterValue, float64(s.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.acquireDuration, prometheus.CounterValue, s.AcquireDuration().Seconds())
+}
@@ -0,0 +1,98 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v4"
+	"todoNote/internal/model"
+	"todoNote/internal/repo"
+)
+
+var _ repo.IRepoMfa = &RepoMfa{}
+
+type RepoMfa struct {
+	conn DB
+}
+
+func NewRepoMfa(conn DB) repo.IRepoMfa {
+	return &RepoMfa{
+		conn: conn,
+	}
+}
+
+func (r *RepoMfa) Upsert(ctx context.Context, m *model.Mfa) error {
+	query := `
+INSERT INTO user_mfa (user_id, secret, is_enabled, recovery_codes, last_step)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (user_id) DO UPDATE
+SET secret = EXCLUDED.secret, is_enabled = EXCLUDED.is_enabled, recovery_codes = EXCLUDED.recovery_codes, last_step = EXCLUDED.last_step;`
+
+	codes := m.RecoveryCodes
+	if codes == nil {
+		codes = [][]byte{}
+	}
+
+	_, err := r.conn.Exec(ctx,
+		query,
+		m.UserId,
+		m.Secret,
+		m.IsEnabled,
+		codes,
+		m.LastStep)
+
+	if err != nil {
+		return NewMfaError(insert, err)
+	}
+
+	return nil
+}
+
+func (r *RepoMfa) GetByUserId(ctx context.Context, uId model.Id) (*model.Mfa, error) {
+	query := `SELECT user_id, secret, is_enabled, recovery_codes, last_step FROM user_mfa WHERE user_id = $1;`
+	var m model.Mfa
+	err := r.conn.QueryRow(ctx, query, uId).Scan(
+		&m.UserId,
+		&m.Secret,
+		&m.IsEnabled,
+		&m.RecoveryCodes,
+		&m.LastStep)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, repo.NewNotFoundError(uId)
+		}
+
+		return nil, NewMfaError(select_sql, err)
+	}
+
+	return &m, nil
+}
+
+func (r *RepoMfa) Delete(ctx context.Context, uId model.Id) error {
+	query := `DELETE FROM user_mfa WHERE user_id = $1;`
+	if _, err := r.conn.Exec(ctx, query, uId); err != nil {
+		return NewMfaError(delete_sql, err)
+	}
+
+	return nil
+}
+
+func (r *RepoMfa) UseStep(ctx context.Context, uId model.Id, step int64) (bool, error) {
+	query := `UPDATE user_mfa SET last_step = $2 WHERE user_id = $1 AND last_step < $2;`
+	res, err := r.conn.Exec(ctx, query, uId, step)
+	if err != nil {
+		return false, NewMfaError(update, err)
+	}
+
+	return res.RowsAffected() == 1, nil
+}
+
+func (r *RepoMfa) UseRecoveryCode(ctx context.Context, uId model.Id, hash []byte) (bool, error) {
+	query := `UPDATE user_mfa SET recovery_codes = array_remove(recovery_codes, $2) WHERE user_id = $1 AND $2 = ANY(recovery_codes);`
+	res, err := r.conn.Exec(ctx, query, uId, hash)
+	if err != nil {
+		return false, NewMfaError(update, err)
+	}
+
+	return res.RowsAffected() == 1, nil
+}
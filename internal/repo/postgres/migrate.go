@@ -0,0 +1,272 @@
+package postgres
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"github.com/jackc/pgx/v4"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+const (
+	migrationSeparator = "---- create above / drop below ----"
+	// ternVersionTable is where the tern tool kept the version of a database migrated before
+	ternVersionTable = "schema_version"
+	// migrationLockKey is the advisory lock replicas take while migrating
+	migrationLockKey = 0x746f646f4e6f7465
+)
+
+type Migration struct {
+	Version int
+	Name string
+	Up string
+	Down string
+}
+
+type MigrationStatus struct {
+	Migration
+	// AppliedAt is nil for a pending migration
+	AppliedAt *time.Time
+}
+
+// Migrations are the migrations embedded from the migrations directory, ordered by version
+func Migrations() ([]Migration, error) {
+	return parseMigrations(migrationFiles, "migrations")
+}
+
+// parseMigrations reads <version>_<name>.sql files, versions have to go one after another starting with 1
+func parseMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("read migrations: %w", err)
+	}
+
+	migrations := make([]Migration, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() || path.Ext(e.Name()) != ".sql" {
+			continue
+		}
+
+		base := strings.TrimSuffix(e.Name(), ".sql")
+		i := strings.Index(base, "_")
+		if i < 0 {
+			return nil, fmt.Errorf("migration %v: name is not <version>_<name>.sql", e.Name())
+		}
+		version, err := strconv.Atoi(base[:i])
+		if err != nil {
+			return nil, fmt.Errorf("migration %v: %w", e.Name(), err)
+		}
+		if version != len(migrations) + 1 {
+			return nil, fmt.Errorf("migration %v: expected version %v", e.Name(), len(migrations) + 1)
+		}
+
+		b, err := fs.ReadFile(fsys, path.Join(dir, e.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("migration %v: %w", e.Name(), err)
+		}
+
+		up, down := string(b), ""
+		if j := strings.Index(up, migrationSeparator); j >= 0 {
+			up, down = up[:j], up[j + len(migrationSeparator):]
+		}
+
+		migrations = append(migrations, Migration{
+			Version: version,
+			Name: base[i+1:],
+			Up: strings.TrimSpace(up),
+			Down: strings.TrimSpace(down),
+		})
+	}
+
+	return migrations, nil
+}
+
+// Migrator applies migrations under an advisory lock, every command runs in one transaction,
+// so replicas starting together wait for each other and a failed migration leaves nothing behind
+type Migrator struct {
+	conn DB
+	migrations []Migration
+}
+
+func NewMigrator(conn DB) (*Migrator, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		conn: conn,
+		migrations: migrations,
+	}, nil
+}
+
+// Up applies pending migrations and returns them
+func(m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	applied := make([]Migration, 0)
+	err := m.locked(ctx, func(tx pgx.Tx, done map[int]time.Time) error {
+		for _, mg := range m.migrations {
+			if _, ok := done[mg.Version]; ok {
+				continue
+			}
+
+			if _, err := tx.Exec(ctx, mg.Up); err != nil {
+				return fmt.Errorf("migration %03d_%v: %w", mg.Version, mg.Name, err)
+			}
+			if _, err := tx.Exec(ctx, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2);`, mg.Version, mg.Name); err != nil {
+				return err
+			}
+			applied = append(applied, mg)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("migrate up: %w", err)
+	}
+
+	return applied, nil
+}
+
+// Down reverts the latest applied migration, nil means nothing was applied
+func(m *Migrator) Down(ctx context.Context) (*Migration, error) {
+	var reverted *Migration
+	err := m.locked(ctx, func(tx pgx.Tx, done map[int]time.Time) error {
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			mg := m.migrations[i]
+			if _, ok := done[mg.Version]; !ok {
+				continue
+			}
+
+			if mg.Down == "" {
+				return fmt.Errorf("migration %03d_%v can not be reverted", mg.Version, mg.Name)
+			}
+			if _, err := tx.Exec(ctx, mg.Down); err != nil {
+				return fmt.Errorf("migration %03d_%v: %w", mg.Version, mg.Name, err)
+			}
+			if _, err := tx.Exec(ctx, `DELETE FROM schema_migrations WHERE version = $1;`, mg.Version); err != nil {
+				return err
+			}
+
+			reverted = &mg
+			return nil
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("migrate down: %w", err)
+	}
+
+	return reverted, nil
+}
+
+func(m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	status := make([]MigrationStatus, 0, len(m.migrations))
+	err := m.locked(ctx, func(_ pgx.Tx, done map[int]time.Time) error {
+		for _, mg := range m.migrations {
+			s := MigrationStatus{Migration: mg}
+			if at, ok := done[mg.Version]; ok {
+				s.AppliedAt = &at
+			}
+			status = append(status, s)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("migrate status: %w", err)
+	}
+
+	return status, nil
+}
+
+// locked runs fn with applied versions in a transaction holding the migration lock
+func(m *Migrator) locked(ctx context.Context, fn func(tx pgx.Tx, applied map[int]time.Time) error) error {
+	tx, err := m.conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1);`, int64(migrationLockKey)); err != nil {
+		return fmt.Errorf("lock: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    version INT PRIMARY KEY,
+    name TEXT NOT NULL,
+    applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);`); err != nil {
+		return err
+	}
+
+	if err := m.adoptTernVersion(ctx, tx); err != nil {
+		return err
+	}
+
+	applied, err := appliedMigrations(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx, applied); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// adoptTernVersion marks migrations tern has applied as applied once schema_migrations is created
+func(m *Migrator) adoptTernVersion(ctx context.Context, tx pgx.Tx) error {
+	var tern, empty bool
+	err := tx.QueryRow(ctx, `
+SELECT to_regclass($1) IS NOT NULL, NOT EXISTS (SELECT 1 FROM schema_migrations);`, ternVersionTable).
+		Scan(&tern, &empty)
+	if err != nil || !tern || !empty {
+		return err
+	}
+
+	var version int
+	if err := tx.QueryRow(ctx, `SELECT coalesce(max(version), 0) FROM ` + ternVersionTable + `;`).Scan(&version); err != nil {
+		return fmt.Errorf("read %v: %w", ternVersionTable, err)
+	}
+
+	for _, mg := range m.migrations {
+		if mg.Version > version {
+			break
+		}
+		if _, err := tx.Exec(ctx, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2);`, mg.Version, mg.Name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func appliedMigrations(ctx context.Context, tx pgx.Tx) (map[int]time.Time, error) {
+	rows, err := tx.Query(ctx, `SELECT version, applied_at FROM schema_migrations;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		applied[version] = at
+	}
+
+	return applied, rows.Err()
+}
@@ -0,0 +1,52 @@
+package postgres
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"testing/fstest"
+)
+
+func TestMigrations(t *testing.T) {
+	migrations, err := Migrations()
+	assert.Nil(t, err)
+	assert.NotEmpty(t, migrations)
+
+	for i, m := range migrations {
+		assert.Equal(t, i + 1, m.Version)
+		assert.NotEmpty(t, m.Up, m.Name)
+		assert.NotEmpty(t, m.Down, m.Name)
+	}
+	assert.Equal(t, "initial", migrations[0].Name)
+}
+
+func TestParseMigrations(t *testing.T) {
+	t.Run("up and down", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"m/001_first.sql": {Data: []byte("CREATE TABLE a ();\n\n" + migrationSeparator + "\n\nDROP TABLE a;\n")},
+			"m/002_second.sql": {Data: []byte("CREATE TABLE b ();\n")},
+			"m/tern.conf": {Data: []byte("[database]")},
+		}
+
+		migrations, err := parseMigrations(fsys, "m")
+		assert.Nil(t, err)
+		assert.Equal(t, []Migration{
+			{Version: 1, Name: "first", Up: "CREATE TABLE a ();", Down: "DROP TABLE a;"},
+			{Version: 2, Name: "second", Up: "CREATE TABLE b ();"},
+		}, migrations)
+	})
+
+	t.Run("gap in versions", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"m/001_first.sql": {Data: []byte("SELECT 1;")},
+			"m/003_third.sql": {Data: []byte("SELECT 3;")},
+		}
+
+		_, err := parseMigrations(fsys, "m")
+		assert.NotNil(t, err)
+	})
+
+	t.Run("name without version", func(t *testing.T) {
+		_, err := parseMigrations(fstest.MapFS{"m/first.sql": {Data: []byte("SELECT 1;")}}, "m")
+		assert.NotNil(t, err)
+	})
+}
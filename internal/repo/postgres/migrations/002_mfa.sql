@@ -0,0 +1,10 @@
+CREATE TABLE user_mfa (
+    user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret VARCHAR(64) NOT NULL,
+    is_enabled BOOLEAN DEFAULT false,
+    recovery_codes bytea[] DEFAULT '{}'
+);
+
+---- create above / drop below ----
+
+DROP TABLE user_mfa;
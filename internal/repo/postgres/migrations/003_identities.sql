@@ -0,0 +1,14 @@
+ALTER TABLE users ALTER COLUMN name TYPE VARCHAR(64);
+
+CREATE TABLE user_identities (
+    provider VARCHAR(64) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    PRIMARY KEY (provider, subject)
+);
+
+---- create above / drop below ----
+
+DROP TABLE user_identities;
+
+ALTER TABLE users ALTER COLUMN name TYPE VARCHAR(10);
@@ -0,0 +1,10 @@
+CREATE TABLE login_attempts (
+    key VARCHAR(128) PRIMARY KEY,
+    failures INT NOT NULL DEFAULT 0,
+    last_failure TIMESTAMP NOT NULL,
+    locked_until TIMESTAMP NOT NULL
+);
+
+---- create above / drop below ----
+
+DROP TABLE login_attempts;
@@ -0,0 +1,17 @@
+ALTER TABLE users ALTER COLUMN time_zone TYPE VARCHAR(64);
+
+-- fixed offsets become Etc/GMT zones, their sign is inverted by the tz database convention
+UPDATE users SET time_zone = 'Etc/GMT-' || substring(time_zone from 5)
+    WHERE time_zone ~ '^UTC\+([1-9]|1[0-2])$';
+UPDATE users SET time_zone = 'Etc/GMT+' || substring(time_zone from 5)
+    WHERE time_zone ~ '^UTC-([1-9]|1[0-2])$';
+
+---- create above / drop below ----
+
+UPDATE users SET time_zone = 'UTC+' || substring(time_zone from 9)
+    WHERE time_zone ~ '^Etc/GMT-([1-9]|1[0-2])$';
+UPDATE users SET time_zone = 'UTC-' || substring(time_zone from 9)
+    WHERE time_zone ~ '^Etc/GMT\+([1-9]|1[0-2])$';
+UPDATE users SET time_zone = 'UTC' WHERE length(time_zone) > 6;
+
+ALTER TABLE users ALTER COLUMN time_zone TYPE VARCHAR(6);
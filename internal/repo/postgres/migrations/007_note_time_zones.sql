@@ -0,0 +1,8 @@
+ALTER TABLE notes ADD COLUMN time_zone VARCHAR(64) NOT NULL DEFAULT '';
+-- date of a floating note is wall-clock time and not an utc instant
+ALTER TABLE notes ADD COLUMN is_floating BOOLEAN NOT NULL DEFAULT false;
+
+---- create above / drop below ----
+
+ALTER TABLE notes DROP COLUMN is_floating;
+ALTER TABLE notes DROP COLUMN time_zone;
@@ -0,0 +1,18 @@
+ALTER TABLE users ADD COLUMN display_name VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN locale VARCHAR(35) NOT NULL DEFAULT 'en';
+-- 0 is sunday
+ALTER TABLE users ADD COLUMN week_start SMALLINT NOT NULL DEFAULT 1;
+ALTER TABLE users ADD COLUMN reminder_offset_minutes INT NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN date_format VARCHAR(16) NOT NULL DEFAULT 'YYYY-MM-DD';
+
+ALTER TABLE notes ADD COLUMN reminder_offset_minutes INT NOT NULL DEFAULT 0;
+
+---- create above / drop below ----
+
+ALTER TABLE notes DROP COLUMN reminder_offset_minutes;
+
+ALTER TABLE users DROP COLUMN date_format;
+ALTER TABLE users DROP COLUMN reminder_offset_minutes;
+ALTER TABLE users DROP COLUMN week_start;
+ALTER TABLE users DROP COLUMN locale;
+ALTER TABLE users DROP COLUMN display_name;
@@ -0,0 +1,20 @@
+CREATE TABLE data_exports (
+    user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    status VARCHAR(16) NOT NULL,
+    archive bytea,
+    created_at TIMESTAMP NOT NULL,
+    completed_at TIMESTAMP
+);
+
+CREATE TABLE account_deletions (
+    user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    delete_after TIMESTAMP NOT NULL
+);
+
+CREATE INDEX account_deletions_delete_after_idx ON account_deletions (delete_after);
+
+---- create above / drop below ----
+
+DROP TABLE account_deletions;
+
+DROP TABLE data_exports;
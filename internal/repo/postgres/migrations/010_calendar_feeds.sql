@@ -0,0 +1,9 @@
+CREATE TABLE calendar_feeds (
+    user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    token_hash bytea NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL
+);
+
+---- create above / drop below ----
+
+DROP TABLE calendar_feeds;
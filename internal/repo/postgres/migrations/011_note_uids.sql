@@ -0,0 +1,9 @@
+ALTER TABLE notes ADD COLUMN uid VARCHAR(255) NOT NULL DEFAULT '';
+
+CREATE UNIQUE INDEX notes_user_id_uid_idx ON notes (user_id, uid) WHERE uid <> '';
+
+---- create above / drop below ----
+
+DROP INDEX notes_user_id_uid_idx;
+
+ALTER TABLE notes DROP COLUMN uid;
@@ -0,0 +1,13 @@
+ALTER TABLE notes
+    ADD COLUMN priority VARCHAR(1) NOT NULL DEFAULT '',
+    ADD COLUMN projects TEXT[] NOT NULL DEFAULT '{}',
+    ADD COLUMN contexts TEXT[] NOT NULL DEFAULT '{}',
+    ADD COLUMN finished_at TIMESTAMP;
+
+---- create above / drop below ----
+
+ALTER TABLE notes
+    DROP COLUMN priority,
+    DROP COLUMN projects,
+    DROP COLUMN contexts,
+    DROP COLUMN finished_at;
@@ -0,0 +1,36 @@
+-- every change of a user's notes takes the next version from the user's row,
+-- the row stays locked until the change commits so versions become visible in order
+CREATE TABLE note_versions (
+    user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    version BIGINT NOT NULL
+);
+
+CREATE TABLE note_tombstones (
+    note_id BIGINT PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    version BIGINT NOT NULL,
+    deleted_at TIMESTAMP NOT NULL DEFAULT (now() at time zone 'utc')
+);
+
+CREATE INDEX note_tombstones_user_id_version_idx ON note_tombstones (user_id, version);
+
+ALTER TABLE notes ADD COLUMN version BIGINT NOT NULL DEFAULT 0;
+
+UPDATE notes SET version = v.version
+FROM (SELECT id, row_number() OVER (PARTITION BY user_id ORDER BY id) AS version FROM notes) v
+WHERE notes.id = v.id;
+
+INSERT INTO note_versions (user_id, version)
+SELECT user_id, max(version) FROM notes WHERE user_id IS NOT NULL GROUP BY user_id;
+
+CREATE INDEX notes_user_id_version_idx ON notes (user_id, version);
+
+---- create above / drop below ----
+
+DROP INDEX notes_user_id_version_idx;
+
+ALTER TABLE notes DROP COLUMN version;
+
+DROP TABLE note_tombstones;
+
+DROP TABLE note_versions;
@@ -0,0 +1,6 @@
+-- listing notes filters by user and date and orders by date and id
+CREATE INDEX notes_user_id_date_id_idx ON notes (user_id, date, id);
+
+---- create above / drop below ----
+
+DROP INDEX notes_user_id_date_id_idx;
@@ -0,0 +1,18 @@
+-- domain events are written with the change they are about and removed once they are published
+CREATE TABLE outbox (
+    id BIGSERIAL PRIMARY KEY,
+    type TEXT NOT NULL,
+    user_id BIGINT NOT NULL,
+    subject_id BIGINT NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL,
+    last_error TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX outbox_next_attempt_at_id_idx ON outbox (next_attempt_at, id);
+
+---- create above / drop below ----
+
+DROP TABLE outbox;
@@ -0,0 +1,53 @@
+-- every user and note belongs to an organization, those created before belong to the default one
+CREATE TABLE organizations (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(64) NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL DEFAULT (now() at time zone 'utc')
+);
+
+INSERT INTO organizations (id, name) VALUES (1, 'default');
+SELECT setval('organizations_id_seq', 1);
+
+ALTER TABLE users ADD COLUMN organization_id BIGINT NOT NULL DEFAULT 1 REFERENCES organizations(id);
+ALTER TABLE users ALTER COLUMN organization_id DROP DEFAULT;
+ALTER TABLE users DROP CONSTRAINT users_name_key;
+ALTER TABLE users ADD CONSTRAINT users_organization_id_name_key UNIQUE (organization_id, name);
+ALTER TABLE users ADD CONSTRAINT users_id_organization_id_key UNIQUE (id, organization_id);
+
+-- a note references the organization through its user, so it can not be in another organization than the user
+ALTER TABLE notes ADD COLUMN organization_id BIGINT NOT NULL DEFAULT 1;
+ALTER TABLE notes ALTER COLUMN organization_id DROP DEFAULT;
+ALTER TABLE notes ADD CONSTRAINT notes_user_id_organization_id_fkey
+    FOREIGN KEY (user_id, organization_id) REFERENCES users (id, organization_id) ON DELETE CASCADE;
+
+-- the policies only restrict sessions which set app.organization_id, the service sets it on every
+-- connection it takes from the pool when DB_ROW_LEVEL_SECURITY is true. FORCE applies them to the owner of the tables
+ALTER TABLE users ENABLE ROW LEVEL SECURITY;
+ALTER TABLE users FORCE ROW LEVEL SECURITY;
+CREATE POLICY users_organization ON users
+    USING (organization_id = coalesce(nullif(current_setting('app.organization_id', true), '')::bigint, organization_id));
+
+ALTER TABLE notes ENABLE ROW LEVEL SECURITY;
+ALTER TABLE notes FORCE ROW LEVEL SECURITY;
+CREATE POLICY notes_organization ON notes
+    USING (organization_id = coalesce(nullif(current_setting('app.organization_id', true), '')::bigint, organization_id));
+
+---- create above / drop below ----
+
+DROP POLICY notes_organization ON notes;
+ALTER TABLE notes NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE notes DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY users_organization ON users;
+ALTER TABLE users NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE users DISABLE ROW LEVEL SECURITY;
+
+ALTER TABLE notes DROP CONSTRAINT notes_user_id_organization_id_fkey;
+ALTER TABLE notes DROP COLUMN organization_id;
+
+ALTER TABLE users DROP CONSTRAINT users_id_organization_id_key;
+ALTER TABLE users DROP CONSTRAINT users_organization_id_name_key;
+ALTER TABLE users ADD CONSTRAINT users_name_key UNIQUE (name);
+ALTER TABLE users DROP COLUMN organization_id;
+
+DROP TABLE organizations;
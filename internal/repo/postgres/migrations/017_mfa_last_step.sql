@@ -0,0 +1,6 @@
+-- the time step of the last accepted totp code, codes of it and of earlier steps are refused
+ALTER TABLE user_mfa ADD COLUMN last_step BIGINT NOT NULL DEFAULT 0;
+
+---- create above / drop below ----
+
+ALTER TABLE user_mfa DROP COLUMN last_step;
@@ -2,60 +2,73 @@ package postgres
 
 import (
 	"context"
+	"errors"
 	"github.com/jackc/pgx/v4"
-	"strings"
+	"sort"
 	"time"
 	"todoNote/internal/model"
 	"todoNote/internal/repo"
-	in_memory "todoNote/internal/repo/in-memory"
 )
 
 var _ repo.IRepoNote = RepoNote{}
 type RepoNote struct {
-	conn *pgx.Conn
+	conn DB
 }
 
-func NewRepoNote(c *pgx.Conn) *RepoNote {
+func NewRepoNote(c DB) *RepoNote {
 	return &RepoNote{conn: c}
 }
 
 func (r RepoNote) Insert(ctx context.Context, n *model.Note) (model.Id, error) {
 	query := `
-INSERT INTO notes (user_id, title, text, date, is_finished)
-VALUES ($1, $2, $3, $4, $5) RETURNING id;`
+WITH v AS (` + nextVersion + `VALUES ($1, 1)` + bumpVersion + `)
+INSERT INTO notes (user_id, title, text, date, is_finished, time_zone, is_floating, reminder_offset_minutes, uid,
+priority, projects, contexts, finished_at, organization_id, version)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, (SELECT version FROM v)) RETURNING id, version;`
 
 	var id model.Id
-	err := r.conn.QueryRow(ctx,
+	organizationId := repo.InsertTenant(ctx)
+	err := db(ctx, r.conn).QueryRow(ctx,
 		query,
 		n.UserId,
 		n.Title,
 		n.Text,
 		n.Date,
-		n.IsFinished).
-		Scan(&id)
+		n.IsFinished,
+		n.TimeZone,
+		n.IsFloating,
+		minutes(n.ReminderOffset),
+		n.Uid,
+		n.Priority,
+		tags(n.Projects),
+		tags(n.Contexts),
+		n.FinishedAt,
+		organizationId).
+		Scan(&id, &n.Version)
 
 	if err != nil {
 		return 0, NewNotesError(insert, err)
 	}
 
+	n.OrganizationId = organizationId
 	return id, nil
 }
 
 func (r RepoNote) GetById(ctx context.Context, noteId model.Id) (model.Note, error) {
-	query := `SELECT id, user_id, title, text, date, is_finished FROM notes WHERE id = $1;`
-	var note model.Note
-	err := r.conn.QueryRow(ctx, query, noteId).Scan(
-		&note.Id,
-		&note.UserId,
-		&note.Title,
-		&note.Text,
-		&note.Date,
-		&note.IsFinished)
+	return r.getById(ctx, `SELECT ` + noteColumns + ` FROM notes WHERE id = $1 AND ` + inTenant(2) + `;`, noteId)
+}
+
+// GetByIdForUpdate locks the note until the end of the transaction of ctx
+func (r RepoNote) GetByIdForUpdate(ctx context.Context, noteId model.Id) (model.Note, error) {
+	return r.getById(ctx, `SELECT ` + noteColumns + ` FROM notes WHERE id = $1 AND ` + inTenant(2) + ` FOR UPDATE;`, noteId)
+}
+
+func (r RepoNote) getById(ctx context.Context, query string, noteId model.Id) (model.Note, error) {
+	note, err := scanNote(db(ctx, r.conn).QueryRow(ctx, query, noteId, tenantArg(ctx)))
 
 	if err != nil {
-		isEmpty := strings.Contains(err.Error(), "no rows in result set")
-		if isEmpty {
-			return model.Note{}, in_memory.NewNoSuchElementError(noteId)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Note{}, repo.NewNotFoundError(noteId)
 		}
 
 		return model.Note{}, NewNotesError(select_sql, err)
@@ -64,124 +77,122 @@ func (r RepoNote) GetById(ctx context.Context, noteId model.Id) (model.Note, err
 	return note, err
 }
 
-func (r RepoNote) GetAllOffset(ctx context.Context, filter repo.NoteFilter) ([]model.Note, error) {
-	q := `SELECT id, user_id, title, text, date, is_finished FROM notes
-WHERE user_id = $1
-AND id > $2 --0
-AND (is_finished = $3 OR is_finished = $4) --true and false
-AND date > $5 --1970
-ORDER BY date 
-LIMIT $6;`
-	//query := `SELECT id, user_id, title, text, date, is_finished FROM notes WHERE user_id = $1`
-	//if filter.IsFinished != nil {
-	//	query += fmt.Sprintf(` AND is_finished = %v`, *filter.IsFinished)
-	//}
-	//if filter.TakeFrom != nil {
-	//	dt := filter.TakeFrom.Format(time.RFC3339)
-	//	dtT := strings.ReplaceAll(dt, "T", " ")
-	//	dtTZ := strings.ReplaceAll(dtT, "Z", "")
- 	//	query += fmt.Sprintf(` AND date > '%v'`, dtTZ)
-	//}
-	//if filter.Page.Offset != nil {
-	//	query += fmt.Sprintf(` AND id > %v`, *filter.Page.Offset)
-	//}
-	//
-	//query += ` ORDER BY date`
-	//if filter.Page.Limit != nil {
-	//	query += fmt.Sprintf(` LIMIT %v`, *filter.Page.Limit)
-	//}
-	//query += `;`
-	//
-	//rows, err := r.conn.Query(ctx,
-	//	query,
-	//	filter.UserId)
-
-	var offset uint64
-	is_finished1 := true
-	is_finished2 := false
-	date := time.Date(1970, 1, 1, 0, 0 ,0 ,0, time.UTC)
-	limit := uint64(1000)
-
-	if filter.IsFinished != nil {
-		is_finished1 = *filter.IsFinished
-		is_finished2 = *filter.IsFinished
-	}
-	if filter.TakeFrom != nil {
-		date = *filter.TakeFrom
-	}
-	if filter.Page.Offset != nil {
-		offset = *filter.Page.Offset
-	}
-
-	if filter.Page.Limit != nil {
-		limit = *filter.Page.Limit
-	}
-
-	rows, err := r.conn.Query(ctx,
-		q,
-		filter.UserId,
-		offset,
-		is_finished1,
-		is_finished2,
-		date,
-		limit)
+func (r RepoNote) GetByUid(ctx context.Context, userId model.Id, uid string) (model.Note, error) {
+	return r.getByUid(ctx, `SELECT ` + noteColumns + ` FROM notes WHERE user_id = $1 AND uid = $2 AND ` + inTenant(3) + `;`, userId, uid)
+}
+
+// GetByUidForUpdate locks the note until the end of the transaction of ctx
+func (r RepoNote) GetByUidForUpdate(ctx context.Context, userId model.Id, uid string) (model.Note, error) {
+	return r.getByUid(ctx, `SELECT ` + noteColumns + ` FROM notes WHERE user_id = $1 AND uid = $2 AND ` + inTenant(3) + ` FOR UPDATE;`, userId, uid)
+}
+
+func (r RepoNote) getByUid(ctx context.Context, query string, userId model.Id, uid string) (model.Note, error) {
+	note, err := scanNote(db(ctx, r.conn).QueryRow(ctx, query, userId, uid, tenantArg(ctx)))
 
 	if err != nil {
-		return nil, NewNotesError(select_sql, err)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Note{}, repo.NewNotFoundError(0)
+		}
+
+		return model.Note{}, NewNotesError(select_sql, err)
+	}
+
+	return note, nil
+}
+
+func (r RepoNote) GetAllOffset(ctx context.Context, filter repo.NoteFilter) ([]model.Note, error) {
+	if filter.Page.Limit == nil {
+		limit := uint64(1000)
+		filter.Page.Limit = &limit
 	}
 
 	res := make([]model.Note, 0)
+	err := r.Each(ctx, filter, func(n model.Note) error {
+		res = append(res, n)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (r RepoNote) Each(ctx context.Context, filter repo.NoteFilter, fn func(n model.Note) error) error {
+	q, args := noteFilterQuery(ctx, filter).Build()
+	rows, err := db(ctx, r.conn).Query(ctx, q, args...)
+
+	if err != nil {
+		return NewNotesError(select_sql, err)
+	}
+
 	defer rows.Close()
 	for rows.Next() {
-		var row model.Note
-		err := rows.Scan(
-			&row.Id,
-			&row.UserId,
-			&row.Title,
-			&row.Text,
-			&row.Date,
-			&row.IsFinished)
-
+		row, err := scanNote(rows)
 		if err != nil {
-			return nil, NewNotesError(select_sql, err)
+			return NewNotesError(select_sql, err)
 		}
 
-		res = append(res, row)
+		if err := fn(row); err != nil {
+			return err
+		}
 	}
 
-	if rows.Err() != nil {
-		return nil, NewNotesError(select_sql, err)
+	if err := rows.Err(); err != nil {
+		return NewNotesError(select_sql, err)
 	}
 
-	return res, err
+	return nil
 }
 
 func (r RepoNote) Update(ctx context.Context, n *model.Note) error {
-	query := `UPDATE notes SET title = $1, text = $2, date = $3, is_finished = $4 WHERE id = $5;`
-	res, err := r.conn.Exec(ctx,
+	query := `
+WITH v AS (` + nextVersion + `SELECT user_id, 1 FROM notes WHERE id = $13 AND ` + inTenant(14) + bumpVersion + `)
+UPDATE notes SET title = $1, text = $2, date = $3, is_finished = $4, time_zone = $5, is_floating = $6,
+reminder_offset_minutes = $7, uid = $8, priority = $9, projects = $10, contexts = $11, finished_at = $12,
+version = (SELECT version FROM v)
+WHERE id = $13 AND ` + inTenant(14) + ` RETURNING organization_id, version;`
+	err := db(ctx, r.conn).QueryRow(ctx,
 		query,
 		n.Title,
 		n.Text,
 		n.Date,
 		n.IsFinished,
-		n.Id)
+		n.TimeZone,
+		n.IsFloating,
+		minutes(n.ReminderOffset),
+		n.Uid,
+		n.Priority,
+		tags(n.Projects),
+		tags(n.Contexts),
+		n.FinishedAt,
+		n.Id,
+		tenantArg(ctx)).
+		Scan(&n.OrganizationId, &n.Version)
 
 	if err != nil {
-		return NewNotesError(update, err)
-	}
+		if errors.Is(err, pgx.ErrNoRows) {
+			return NewNotesError(update, rowsAffectedNotOne)
+		}
 
-	if res.RowsAffected() != 1 {
-		return NewNotesError(update, rowsAffectedNotOne)
+		return NewNotesError(update, err)
 	}
 
 	return nil
 }
 
 func (r RepoNote) Delete(ctx context.Context, noteId model.Id) error {
-	query := `DELETE FROM notes WHERE id = $1;`
-	res, err := r.conn.Exec(ctx,
+	// the tombstone tells syncing clients about the deletion
+	query := `
+WITH d AS (DELETE FROM notes WHERE id = $1 AND ` + inTenant(2) + ` RETURNING id, user_id),
+v AS (` + nextVersion + `SELECT user_id, 1 FROM d` + bumpVersion + `)
+INSERT INTO note_tombstones (note_id, user_id, version)
+SELECT d.id, d.user_id, v.version FROM d, v
+ON CONFLICT (note_id) DO UPDATE SET version = excluded.version;`
+	res, err := db(ctx, r.conn).Exec(ctx,
 		query,
-		noteId)
+		noteId,
+		tenantArg(ctx))
 
 	if err != nil {
 		return NewNotesError(delete_sql, err)
@@ -194,3 +205,96 @@ func (r RepoNote) Delete(ctx context.Context, noteId model.Id) error {
 	return nil
 }
 
+func (r RepoNote) Changes(ctx context.Context, userId model.Id, since int64, limit uint64) ([]model.NoteChange, error) {
+	changes := make([]model.NoteChange, 0)
+
+	q := `SELECT ` + noteColumns + ` FROM notes WHERE user_id = $1 AND version > $2 AND ` + inTenant(4) + `
+ORDER BY version LIMIT $3;`
+	rows, err := db(ctx, r.conn).Query(ctx, q, userId, since, limit, tenantArg(ctx))
+	if err != nil {
+		return nil, NewNotesError(select_sql, err)
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		n, err := scanNote(rows)
+		if err != nil {
+			return nil, NewNotesError(select_sql, err)
+		}
+		changes = append(changes, model.NoteChange{Version: n.Version, NoteId: n.Id, Note: &n})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, NewNotesError(select_sql, err)
+	}
+
+	// tombstones are in the organization of their user
+	q = `SELECT note_id, version FROM note_tombstones WHERE user_id = $1 AND version > $2
+AND user_id IN (SELECT id FROM users WHERE ` + inTenant(4) + `) ORDER BY version LIMIT $3;`
+	tombstones, err := db(ctx, r.conn).Query(ctx, q, userId, since, limit, tenantArg(ctx))
+	if err != nil {
+		return nil, NewNotesError(select_sql, err)
+	}
+
+	defer tombstones.Close()
+	for tombstones.Next() {
+		c := model.NoteChange{Deleted: true}
+		if err := tombstones.Scan(&c.NoteId, &c.Version); err != nil {
+			return nil, NewNotesError(select_sql, err)
+		}
+		changes = append(changes, c)
+	}
+	if err := tombstones.Err(); err != nil {
+		return nil, NewNotesError(select_sql, err)
+	}
+
+	// both lists are ordered, the first limit changes of their union are all taken
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Version < changes[j].Version })
+	if uint64(len(changes)) > limit {
+		changes = changes[:limit]
+	}
+
+	return changes, nil
+}
+
+const (
+	// nextVersion and bumpVersion wrap the select of the user whose version is taken
+	nextVersion = `INSERT INTO note_versions (user_id, version) `
+	bumpVersion = ` ON CONFLICT (user_id) DO UPDATE SET version = note_versions.version + 1 RETURNING version`
+)
+
+const noteColumns = `id, user_id, title, text, date, is_finished, time_zone, is_floating, reminder_offset_minutes, uid,
+priority, projects, contexts, finished_at, version, organization_id`
+
+func scanNote(row pgx.Row) (model.Note, error) {
+	var note model.Note
+	var reminderOffset int
+	err := row.Scan(
+		&note.Id,
+		&note.UserId,
+		&note.Title,
+		&note.Text,
+		&note.Date,
+		&note.IsFinished,
+		&note.TimeZone,
+		&note.IsFloating,
+		&reminderOffset,
+		&note.Uid,
+		&note.Priority,
+		&note.Projects,
+		&note.Contexts,
+		&note.FinishedAt,
+		&note.Version,
+		&note.OrganizationId)
+	note.ReminderOffset = time.Duration(reminderOffset) * time.Minute
+
+	return note, err
+}
+
+// tags are never NULL in the table
+func tags(t []string) []string {
+	if t == nil {
+		return []string{}
+	}
+
+	return t
+}
@@ -12,7 +12,6 @@ import (
 	"time"
 	"todoNote/internal/model"
 	"todoNote/internal/repo"
-	in_memory "todoNote/internal/repo/in-memory"
 )
 
 
@@ -283,7 +282,7 @@ func TestRepoNote_Insert_GetById_Delete(t *testing.T) {
 		_, err = rn.GetById(context.Background(), id)
 		assert.NotNil(t, err)
 
-		assert.Equal(t, true, errors.As(err, &in_memory.NoSuchElementError{}))
+		assert.Equal(t, true, errors.Is(err, repo.ErrNotFound))
 	})
 }
 
@@ -0,0 +1,54 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v4"
+	"todoNote/internal/model"
+	"todoNote/internal/repo"
+)
+
+var _ repo.IRepoOrganization = &RepoOrganization{}
+
+type RepoOrganization struct {
+	conn DB
+}
+
+func NewRepoOrganization(conn DB) repo.IRepoOrganization {
+	return &RepoOrganization{
+		conn: conn,
+	}
+}
+
+func (r *RepoOrganization) Insert(ctx context.Context, o *model.Organization) (model.Id, error) {
+	query := `INSERT INTO organizations (name, created_at) VALUES ($1, $2) RETURNING id;`
+	var id model.Id
+	if err := db(ctx, r.conn).QueryRow(ctx, query, o.Name, o.CreatedAt).Scan(&id); err != nil {
+		return 0, NewOrganizationsError(insert, err)
+	}
+
+	return id, nil
+}
+
+func (r *RepoOrganization) GetById(ctx context.Context, id model.Id) (model.Organization, error) {
+	return r.get(ctx, `SELECT id, name, created_at FROM organizations WHERE id = $1;`, id)
+}
+
+func (r *RepoOrganization) GetByName(ctx context.Context, name string) (model.Organization, error) {
+	return r.get(ctx, `SELECT id, name, created_at FROM organizations WHERE name = $1;`, name)
+}
+
+func (r *RepoOrganization) get(ctx context.Context, query string, key interface{}) (model.Organization, error) {
+	var o model.Organization
+	err := db(ctx, r.conn).QueryRow(ctx, query, key).Scan(&o.Id, &o.Name, &o.CreatedAt)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Organization{}, repo.NewNotFoundError(key)
+		}
+
+		return model.Organization{}, NewOrganizationsError(select_sql, err)
+	}
+
+	return o, nil
+}
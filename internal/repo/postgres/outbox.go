@@ -0,0 +1,117 @@
+package postgres
+
+import (
+	"context"
+	"sort"
+	"time"
+	"todoNote/internal/model"
+	"todoNote/internal/repo"
+)
+
+var _ repo.IRepoOutbox = &RepoOutbox{}
+
+type RepoOutbox struct {
+	conn DB
+}
+
+func NewRepoOutbox(conn DB) repo.IRepoOutbox {
+	return &RepoOutbox{
+		conn: conn,
+	}
+}
+
+func (r *RepoOutbox) Insert(ctx context.Context, e *model.Event) (model.Id, error) {
+	query := `
+INSERT INTO outbox (type, user_id, subject_id, payload, created_at, attempts, next_attempt_at, last_error)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id;`
+
+	var id model.Id
+	err := db(ctx, r.conn).QueryRow(ctx,
+		query,
+		e.Type,
+		e.UserId,
+		e.SubjectId,
+		string(e.Payload),
+		e.CreatedAt.UTC(),
+		e.Attempts,
+		e.NextAttemptAt.UTC(),
+		e.LastError).
+		Scan(&id)
+
+	if err != nil {
+		return 0, NewOutboxError(insert, err)
+	}
+
+	return id, nil
+}
+
+// Claim skips events other relays have locked and are claiming at the moment
+func (r *RepoOutbox) Claim(ctx context.Context, now time.Time, lease time.Duration, limit uint64) ([]model.Event, error) {
+	query := `
+UPDATE outbox SET next_attempt_at = $2
+WHERE id IN (
+	SELECT id FROM outbox WHERE next_attempt_at <= $1 ORDER BY next_attempt_at, id LIMIT $3 FOR UPDATE SKIP LOCKED
+)
+RETURNING id, type, user_id, subject_id, payload::text, created_at, attempts, next_attempt_at, last_error;`
+
+	rows, err := db(ctx, r.conn).Query(ctx, query, now.UTC(), now.Add(lease).UTC(), limit)
+	if err != nil {
+		return nil, NewOutboxError(update, err)
+	}
+	defer rows.Close()
+
+	res := make([]model.Event, 0)
+	for rows.Next() {
+		var e model.Event
+		var payload string
+		err := rows.Scan(
+			&e.Id,
+			&e.Type,
+			&e.UserId,
+			&e.SubjectId,
+			&payload,
+			&e.CreatedAt,
+			&e.Attempts,
+			&e.NextAttemptAt,
+			&e.LastError)
+		if err != nil {
+			return nil, NewOutboxError(update, err)
+		}
+
+		e.Payload = []byte(payload)
+		res = append(res, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, NewOutboxError(update, err)
+	}
+
+	// RETURNING does not keep the order of the subquery
+	sort.Slice(res, func(i, j int) bool { return res[i].Id < res[j].Id })
+	return res, nil
+}
+
+func (r *RepoOutbox) Delete(ctx context.Context, id model.Id) error {
+	tag, err := db(ctx, r.conn).Exec(ctx, `DELETE FROM outbox WHERE id = $1;`, id)
+	if err != nil {
+		return NewOutboxError(delete_sql, err)
+	}
+	if tag.RowsAffected() != 1 {
+		return NewOutboxError(delete_sql, rowsAffectedNotOne)
+	}
+
+	return nil
+}
+
+func (r *RepoOutbox) Retry(ctx context.Context, id model.Id, next time.Time, lastError string) error {
+	query := `UPDATE outbox SET attempts = attempts + 1, next_attempt_at = $2, last_error = $3 WHERE id = $1;`
+
+	tag, err := db(ctx, r.conn).Exec(ctx, query, id, next.UTC(), lastError)
+	if err != nil {
+		return NewOutboxError(update, err)
+	}
+	if tag.RowsAffected() != 1 {
+		return NewOutboxError(update, rowsAffectedNotOne)
+	}
+
+	return nil
+}
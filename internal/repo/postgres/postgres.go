@@ -3,16 +3,99 @@ package postgres
 import (
 	"context"
 	"fmt"
-	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
 	"os"
+	"strconv"
 	"time"
 )
 
-func Connect(ctx context.Context) (*pgx.Conn, error) {
+const (
+	dbUrlEnv = "DB_URL"
+	maxConnsEnv = "DB_MAX_CONNS"
+	minConnsEnv = "DB_MIN_CONNS"
+	maxConnLifetimeEnv = "DB_MAX_CONN_LIFETIME_SECONDS"
+	maxConnIdleEnv = "DB_MAX_CONN_IDLE_SECONDS"
+	healthCheckEnv = "DB_HEALTH_CHECK_SECONDS"
+	// rowLevelSecurityEnv makes the database enforce the tenant of every query with the policies of users and notes,
+	// it costs a round trip whenever a connection is taken from the pool
+	rowLevelSecurityEnv = "DB_ROW_LEVEL_SECURITY"
+)
+
+// PoolConfig reads DB_URL, pool settings of the environment override pool_* parameters of the url
+func PoolConfig() (*pgxpool.Config, error) {
+	config, err := pgxpool.ParseConfig(os.Getenv(dbUrlEnv))
+	if err != nil {
+		return nil, fmt.Errorf("parse %v: %w", dbUrlEnv, err)
+	}
+
+	if n, ok, err := envInt(maxConnsEnv); err != nil {
+		return nil, err
+	} else if ok {
+		if n < 1 {
+			return nil, fmt.Errorf("%v must be a positive number", maxConnsEnv)
+		}
+		config.MaxConns = int32(n)
+	}
+
+	if n, ok, err := envInt(minConnsEnv); err != nil {
+		return nil, err
+	} else if ok {
+		config.MinConns = int32(n)
+	}
+
+	if config.MinConns > config.MaxConns {
+		return nil, fmt.Errorf("%v must not be greater than %v", minConnsEnv, maxConnsEnv)
+	}
+
+	for env, d := range map[string]*time.Duration{
+		maxConnLifetimeEnv: &config.MaxConnLifetime,
+		maxConnIdleEnv: &config.MaxConnIdleTime,
+		healthCheckEnv: &config.HealthCheckPeriod,
+	} {
+		n, ok, err := envInt(env)
+		if err != nil {
+			return nil, err
+		}
+		if ok && n < 1 {
+			return nil, fmt.Errorf("%v must be a positive number of seconds", env)
+		}
+		if ok {
+			*d = time.Duration(n) * time.Second
+		}
+	}
+
+	if os.Getenv(rowLevelSecurityEnv) == "true" {
+		config.BeforeAcquire = setTenant
+	}
+
+	return config, nil
+}
+
+// envInt is a non-negative number of the environment, false when it is not set
+func envInt(env string) (int, bool, error) {
+	s := os.Getenv(env)
+	if s == "" {
+		return 0, false, nil
+	}
+
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, false, fmt.Errorf("%v must be a non-negative number", env)
+	}
+
+	return n, true, nil
+}
+
+func Connect(ctx context.Context) (*pgxpool.Pool, error) {
+	config, err := PoolConfig()
+	if err != nil {
+		return nil, err
+	}
+
 	for i := 0; i < 2; i++ {
-		conn, err := pgx.Connect(ctx, os.Getenv("DB_URL"))
+		pool, err := pgxpool.ConnectConfig(ctx, config)
 		if err == nil {
-			return conn, nil
+			return pool, nil
 		}
 
 		select {
@@ -26,9 +109,9 @@ func Connect(ctx context.Context) (*pgx.Conn, error) {
 	return nil, fmt.Errorf("could not connect to database")
 }
 
-func Ping(ctx context.Context, conn *pgx.Conn) error {
+func Ping(ctx context.Context, pool *pgxpool.Pool) error {
 	for i := 0; i < 2; i++ {
-		if err := conn.Ping(ctx); err == nil {
+		if err := pool.Ping(ctx); err == nil {
 			return nil
 		}
 
@@ -0,0 +1,129 @@
+package postgres
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"todoNote/internal/repo"
+)
+
+// selectQuery builds a SELECT of only the conditions that are set, so the planner sees
+// the filter as it is and picks the index for it. Values are never part of the SQL,
+// every ? of a condition becomes the next $n argument
+type selectQuery struct {
+	columns string
+	from string
+	where []string
+	args []interface{}
+	orderBy string
+	limit *uint64
+	offset *uint64
+}
+
+func newSelect(columns, from string) *selectQuery {
+	return &selectQuery{columns: columns, from: from}
+}
+
+// Where adds the condition joined with AND, cond has a ? for every one of args.
+// Conditions with OR have to be in parentheses
+func(q *selectQuery) Where(cond string, args ...interface{}) *selectQuery {
+	var b strings.Builder
+	arg := 0
+	for _, c := range cond {
+		if c == '?' && arg < len(args) {
+			q.args = append(q.args, args[arg])
+			b.WriteString("$" + strconv.Itoa(len(q.args)))
+			arg++
+			continue
+		}
+		b.WriteRune(c)
+	}
+
+	q.where = append(q.where, b.String())
+	return q
+}
+
+func(q *selectQuery) OrderBy(columns string) *selectQuery {
+	q.orderBy = columns
+	return q
+}
+
+// Limit of nil is no limit
+func(q *selectQuery) Limit(limit *uint64) *selectQuery {
+	q.limit = limit
+	return q
+}
+
+// Offset of nil or 0 skips nothing
+func(q *selectQuery) Offset(offset *uint64) *selectQuery {
+	q.offset = offset
+	return q
+}
+
+// Build returns the statement and its arguments
+func(q *selectQuery) Build() (string, []interface{}) {
+	args := append([]interface{}{}, q.args...)
+
+	var b strings.Builder
+	b.WriteString("SELECT " + q.columns + " FROM " + q.from)
+	if len(q.where) > 0 {
+		b.WriteString(" WHERE " + strings.Join(q.where, " AND "))
+	}
+	if q.orderBy != "" {
+		b.WriteString(" ORDER BY " + q.orderBy)
+	}
+	if q.limit != nil {
+		args = append(args, *q.limit)
+		b.WriteString(" LIMIT $" + strconv.Itoa(len(args)))
+	}
+	if q.offset != nil && *q.offset > 0 {
+		args = append(args, *q.offset)
+		b.WriteString(" OFFSET $" + strconv.Itoa(len(args)))
+	}
+	b.WriteString(";")
+
+	return b.String(), args
+}
+
+// noteFilterQuery selects the notes of filter in the tenant of ctx in the order of repo.IRepoNote,
+// notes_user_id_date_id_idx serves the conditions and the order
+func noteFilterQuery(ctx context.Context, filter repo.NoteFilter) *selectQuery {
+	q := newSelect(noteColumns, "notes").Where("user_id = ?", filter.UserId)
+
+	if id, ok := repo.Tenant(ctx); ok {
+		q.Where("organization_id = ?", id)
+	}
+
+	if filter.IsFinished != nil {
+		q.Where("is_finished = ?", *filter.IsFinished)
+	}
+	if filter.FloatingFrom == nil && filter.FloatingTo == nil {
+		if filter.TakeFrom != nil {
+			q.Where("date >= ?", *filter.TakeFrom)
+		}
+		if filter.TakeTo != nil {
+			q.Where("date < ?", *filter.TakeTo)
+		}
+	} else {
+		// the span lets the index serve the range, each kind of notes is bounded after it
+		from, to := filter.Span()
+		if from != nil {
+			q.Where("date >= ?", *from)
+		}
+		if to != nil {
+			q.Where("date < ?", *to)
+		}
+
+		for _, floating := range []bool{false, true} {
+			from, to := filter.Bounds(floating)
+			if from != nil {
+				q.Where("(is_floating <> ? OR date >= ?)", floating, *from)
+			}
+			if to != nil {
+				q.Where("(is_floating <> ? OR date < ?)", floating, *to)
+			}
+		}
+	}
+
+	return q.OrderBy("date, id").Limit(filter.Page.Limit).Offset(filter.Page.Offset)
+}
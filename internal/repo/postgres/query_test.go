@@ -0,0 +1,95 @@
+package postgres
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"todoNote/internal/repo"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files of testdata")
+
+// golden compares got with testdata/name.golden, go test -run TestNoteFilterQuery -update rewrites it
+func golden(t *testing.T, name, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", name + ".golden")
+
+	if *updateGolden {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, string(want), got)
+}
+
+func TestNoteFilterQuery(t *testing.T) {
+	from := time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2021, 11, 1, 0, 0, 0, 0, time.UTC)
+	// the wall clock of the bounds in a zone east of UTC
+	floatingFrom, floatingTo := from.Add(3 * time.Hour), to.Add(3 * time.Hour)
+	finished := false
+	limit, offset, zero := uint64(20), uint64(40), uint64(0)
+
+	tests := []struct {
+		name string
+		filter repo.NoteFilter
+		// ctx is context.Background when nil
+		ctx context.Context
+	}{
+		{"user", repo.NoteFilter{UserId: 1}, nil},
+		{"finished", repo.NoteFilter{UserId: 1, IsFinished: &finished}, nil},
+		{"dates", repo.NoteFilter{UserId: 1, TakeFrom: &from, TakeTo: &to}, nil},
+		{"from", repo.NoteFilter{UserId: 1, TakeFrom: &from}, nil},
+		{"floating dates", repo.NoteFilter{UserId: 1, TakeFrom: &from, TakeTo: &to, FloatingFrom: &floatingFrom, FloatingTo: &floatingTo}, nil},
+		{"page", repo.NoteFilter{UserId: 1, Page: repo.PageFilter{Limit: &limit, Offset: &offset}}, nil},
+		{"zero offset", repo.NoteFilter{UserId: 1, Page: repo.PageFilter{Limit: &limit, Offset: &zero}}, nil},
+		{"tenant", repo.NoteFilter{UserId: 1}, repo.WithTenant(context.Background(), 2)},
+		{"all tenants", repo.NoteFilter{UserId: 1}, repo.AllTenants(context.Background())},
+		{"all", repo.NoteFilter{
+			UserId: 1,
+			TakeFrom: &from,
+			TakeTo: &to,
+			IsFinished: &finished,
+			Page: repo.PageFilter{Limit: &limit, Offset: &offset},
+		}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := tt.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			q, args := noteFilterQuery(ctx, tt.filter).Build()
+
+			var b strings.Builder
+			b.WriteString(q + "\n")
+			for i, a := range args {
+				b.WriteString(fmt.Sprintf("-- $%d = %v\n", i + 1, a))
+			}
+
+			golden(t, filepath.Join("note_filter_query", strings.ReplaceAll(tt.name, " ", "_")), b.String())
+		})
+	}
+}
+
+func TestSelectQuery(t *testing.T) {
+	q, args := newSelect("id", "t").Where("(a = ? OR b = ?)", 1, 2).Where("c IS NULL").Where("d < ?", 3).Build()
+
+	assert.Equal(t, "SELECT id FROM t WHERE (a = $1 OR b = $2) AND c IS NULL AND d < $3;", q)
+	assert.Equal(t, []interface{}{1, 2, 3}, args)
+}
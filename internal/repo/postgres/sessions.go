@@ -0,0 +1,141 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v4"
+	"time"
+	"todoNote/internal/model"
+	"todoNote/internal/repo"
+)
+
+var _ repo.IRepoSession = &RepoSession{}
+
+type RepoSession struct {
+	conn DB
+}
+
+func NewRepoSession(conn DB) repo.IRepoSession {
+	return &RepoSession{
+		conn: conn,
+	}
+}
+
+func (r *RepoSession) Insert(ctx context.Context, s *model.Session) (model.Id, error) {
+	query := `
+INSERT INTO sessions (user_id, user_agent, ip, created_at, last_seen_at, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6) RETURNING id;`
+
+	var id model.Id
+	err := r.conn.QueryRow(ctx,
+		query,
+		s.UserId,
+		s.UserAgent,
+		s.Ip,
+		s.CreatedAt,
+		s.LastSeenAt,
+		s.ExpiresAt).
+		Scan(&id)
+
+	if err != nil {
+		return 0, NewSessionsError(insert, err)
+	}
+
+	return id, nil
+}
+
+func (r *RepoSession) GetById(ctx context.Context, id model.Id) (model.Session, error) {
+	query := `SELECT id, user_id, user_agent, ip, created_at, last_seen_at, expires_at FROM sessions WHERE id = $1;`
+	var s model.Session
+	err := r.conn.QueryRow(ctx, query, id).Scan(
+		&s.Id,
+		&s.UserId,
+		&s.UserAgent,
+		&s.Ip,
+		&s.CreatedAt,
+		&s.LastSeenAt,
+		&s.ExpiresAt)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Session{}, repo.NewNotFoundError(id)
+		}
+
+		return model.Session{}, NewSessionsError(select_sql, err)
+	}
+
+	return s, nil
+}
+
+func (r *RepoSession) GetAllByUser(ctx context.Context, uId model.Id) ([]model.Session, error) {
+	query := `SELECT id, user_id, user_agent, ip, created_at, last_seen_at, expires_at FROM sessions
+WHERE user_id = $1 ORDER BY id;`
+
+	rows, err := r.conn.Query(ctx, query, uId)
+	if err != nil {
+		return nil, NewSessionsError(select_sql, err)
+	}
+	defer rows.Close()
+
+	res := make([]model.Session, 0)
+	for rows.Next() {
+		var s model.Session
+		err := rows.Scan(
+			&s.Id,
+			&s.UserId,
+			&s.UserAgent,
+			&s.Ip,
+			&s.CreatedAt,
+			&s.LastSeenAt,
+			&s.ExpiresAt)
+
+		if err != nil {
+			return nil, NewSessionsError(select_sql, err)
+		}
+
+		res = append(res, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, NewSessionsError(select_sql, err)
+	}
+
+	return res, nil
+}
+
+func (r *RepoSession) Touch(ctx context.Context, id model.Id, at time.Time) error {
+	query := `UPDATE sessions SET last_seen_at = $1 WHERE id = $2;`
+	res, err := r.conn.Exec(ctx, query, at, id)
+	if err != nil {
+		return NewSessionsError(update, err)
+	}
+
+	if res.RowsAffected() != 1 {
+		return repo.NewNotFoundError(id)
+	}
+
+	return nil
+}
+
+func (r *RepoSession) Delete(ctx context.Context, id model.Id) error {
+	query := `DELETE FROM sessions WHERE id = $1;`
+	res, err := r.conn.Exec(ctx, query, id)
+	if err != nil {
+		return NewSessionsError(delete_sql, err)
+	}
+
+	if res.RowsAffected() != 1 {
+		return repo.NewNotFoundError(id)
+	}
+
+	return nil
+}
+
+func (r *RepoSession) DeleteExpired(ctx context.Context, before time.Time) error {
+	query := `DELETE FROM sessions WHERE expires_at < $1;`
+	if _, err := r.conn.Exec(ctx, query, before); err != nil {
+		return NewSessionsError(delete_sql, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,39 @@
+package postgres
+
+import (
+	"context"
+	"github.com/jackc/pgx/v4"
+	"strconv"
+	"todoNote/internal/repo"
+)
+
+// tenantSetting is the session setting the row level security policies of users and notes compare to organization_id
+const tenantSetting = "app.organization_id"
+
+// inTenant is the condition of the tenant passed as the argument $n, see tenantArg
+func inTenant(n int) string {
+	return "organization_id = coalesce($" + strconv.Itoa(n) + "::bigint, organization_id)"
+}
+
+// tenantArg is the organization queries of ctx are scoped to, nil for repo.AllTenants which matches every row
+func tenantArg(ctx context.Context) interface{} {
+	id, ok := repo.Tenant(ctx)
+	if !ok {
+		return nil
+	}
+
+	return id
+}
+
+// setTenant is a BeforeAcquire of the pool, it sets the tenant of ctx on every connection taken from the pool
+// so the policies hold even for a query that misses its condition. An empty setting is not restricted,
+// that is repo.AllTenants. A connection which can not be set is not used
+func setTenant(ctx context.Context, conn *pgx.Conn) bool {
+	setting := ""
+	if id, ok := repo.Tenant(ctx); ok {
+		setting = strconv.FormatInt(id, 10)
+	}
+
+	_, err := conn.Exec(ctx, `SELECT set_config('` + tenantSetting + `', $1, false);`, setting)
+	return err == nil
+}
@@ -0,0 +1,72 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"todoNote/internal/repo"
+)
+
+type txKey struct{}
+
+var _ repo.ITransactor = &Transactor{}
+
+type Transactor struct {
+	conn DB
+}
+
+func NewTransactor(conn DB) repo.ITransactor {
+	return &Transactor{
+		conn: conn,
+	}
+}
+
+func (t *Transactor) InTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := t.conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+
+	// a panic of fn must not keep the connection, Rollback after Commit does nothing
+	defer tx.Rollback(ctx)
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		if rbErr := tx.Rollback(ctx); rbErr != nil {
+			return fmt.Errorf("%w, rollback transaction: %v", err, rbErr)
+		}
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// DB runs queries on a pool of connections, *pgxpool.Pool implements it and so does a single *pgx.Conn
+type DB interface {
+	querier
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// querier is implemented by DB and pgx.Tx
+type querier interface {
+	Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// db is the transaction of ctx if there is one
+func db(ctx context.Context, conn DB) querier {
+	if tx, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
+		return tx
+	}
+
+	return conn
+}
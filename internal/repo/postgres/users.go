@@ -2,53 +2,59 @@ package postgres
 
 import (
 	"context"
+	"errors"
 	"github.com/jackc/pgx/v4"
-	"strings"
+	"time"
 	"todoNote/internal/model"
 	"todoNote/internal/repo"
-	in_memory "todoNote/internal/repo/in-memory"
 )
 var _ repo.IRepoUser = &RepoUser{}
 
 type RepoUser struct {
-	conn *pgx.Conn
+	conn DB
 }
 
-func NewRepoUser(conn *pgx.Conn) repo.IRepoUser {
+func NewRepoUser(conn DB) repo.IRepoUser {
 	return &RepoUser{
 		conn: conn,
 	}
 }
 
 func (r *RepoUser) Insert(ctx context.Context, u *model.User) (model.Id, error) {
-	query := `INSERT INTO users (name, password_hash, time_zone) VALUES ($1, $2, $3) RETURNING id;`
+	query := `
+INSERT INTO users (name, password_hash, time_zone, display_name, locale, week_start, reminder_offset_minutes, date_format,
+organization_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id;`
 	var id model.Id
-	err := r.conn.QueryRow(ctx,
+	organizationId := repo.InsertTenant(ctx)
+	err := db(ctx, r.conn).QueryRow(ctx,
 		query,
 		u.Name,
 		u.PasswordHash,
-		u.TimeZone).
+		u.TimeZone,
+		u.Preferences.DisplayName,
+		u.Preferences.Locale,
+		int(u.Preferences.WeekStart),
+		minutes(u.Preferences.ReminderOffset),
+		u.Preferences.DateFormat,
+		organizationId).
 		Scan(&id)
 
 	if err != nil {
-		if strings.Contains(err.Error(), "duplicate key value violates unique constraint") {
-			return id, UserExistsError{name: u.Name}
-		}
-
 		return 0, NewUsersError(insert, err)
 	}
 
+	u.OrganizationId = organizationId
 	return id, nil
 }
 
 func (r *RepoUser) GetByUserName(ctx context.Context, name string) (*model.User, error) {
-	query := `SELECT id, name, time_zone, password_hash FROM users WHERE name = $1;`
-	u, err := r.get(ctx, query, name)
+	query := `SELECT ` + userColumns + ` FROM users WHERE name = $1 AND ` + inTenant(2) + `;`
+	u, err := r.get(ctx, query, name, tenantArg(ctx))
 
 	if err != nil {
-		isEmpty := strings.Contains(err.Error(), "no rows in result set")
-		if isEmpty {
-			return nil, in_memory.NewNoSuchNameError(name)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, repo.NewNotFoundError(name)
 		}
 
 		return nil, NewUsersError(select_sql, err)
@@ -58,13 +64,20 @@ func (r *RepoUser) GetByUserName(ctx context.Context, name string) (*model.User,
 }
 
 func (r *RepoUser) GetById(ctx context.Context, uId model.Id) (*model.User, error) {
-	query := `SELECT id, name, time_zone, password_hash FROM users WHERE id = $1;`
-	u, err := r.get(ctx, query, uId)
+	return r.getById(ctx, `SELECT ` + userColumns + ` FROM users WHERE id = $1 AND ` + inTenant(2) + `;`, uId)
+}
+
+// GetByIdForUpdate locks the user until the end of the transaction of ctx
+func (r *RepoUser) GetByIdForUpdate(ctx context.Context, uId model.Id) (*model.User, error) {
+	return r.getById(ctx, `SELECT ` + userColumns + ` FROM users WHERE id = $1 AND ` + inTenant(2) + ` FOR UPDATE;`, uId)
+}
+
+func (r *RepoUser) getById(ctx context.Context, query string, uId model.Id) (*model.User, error) {
+	u, err := r.get(ctx, query, uId, tenantArg(ctx))
 
 	if err != nil {
-		isEmpty := strings.Contains(err.Error(), "no rows in result set")
-		if isEmpty {
-			return nil, in_memory.NewNoSuchElementError(uId)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, repo.NewNotFoundError(uId)
 		}
 
 		return nil, NewUsersError(select_sql, err)
@@ -74,12 +87,21 @@ func (r *RepoUser) GetById(ctx context.Context, uId model.Id) (*model.User, erro
 }
 
 func (r *RepoUser) Update(ctx context.Context, u *model.User) error {
-	query := `UPDATE users SET name = $1, time_zone = $2 WHERE id = $3;`
-	res, err := r.conn.Exec(ctx,
+	query := `
+UPDATE users SET name = $1, time_zone = $2, display_name = $3, locale = $4, week_start = $5,
+reminder_offset_minutes = $6, date_format = $7
+WHERE id = $8 AND ` + inTenant(9) + `;`
+	res, err := db(ctx, r.conn).Exec(ctx,
 		query,
 		u.Name,
 		u.TimeZone,
-		u.Id)
+		u.Preferences.DisplayName,
+		u.Preferences.Locale,
+		int(u.Preferences.WeekStart),
+		minutes(u.Preferences.ReminderOffset),
+		u.Preferences.DateFormat,
+		u.Id,
+		tenantArg(ctx))
 
 	if err != nil {
 		return NewUsersError(update, err)
@@ -93,10 +115,11 @@ func (r *RepoUser) Update(ctx context.Context, u *model.User) error {
 }
 
 func (r *RepoUser) Delete(ctx context.Context, uId model.Id) error {
-	query := `DELETE FROM users WHERE id = $1;`
-	res, err := r.conn.Exec(ctx,
+	query := `DELETE FROM users WHERE id = $1 AND ` + inTenant(2) + `;`
+	res, err := db(ctx, r.conn).Exec(ctx,
 		query,
-		uId)
+		uId,
+		tenantArg(ctx))
 
 	if err != nil {
 		return NewUsersError(delete_sql, err)
@@ -109,15 +132,31 @@ func (r *RepoUser) Delete(ctx context.Context, uId model.Id) error {
 	return nil
 }
 
-func (r *RepoUser) get(ctx context.Context, query string, param interface{}) (*model.User, error){
+const userColumns = `id, name, time_zone, password_hash, display_name, locale, week_start, reminder_offset_minutes, date_format,
+organization_id`
+
+func (r *RepoUser) get(ctx context.Context, query string, params ...interface{}) (*model.User, error){
 	var usr model.User
-	err := r.conn.QueryRow(ctx,
+	var weekStart, reminderOffset int
+	err := db(ctx, r.conn).QueryRow(ctx,
 		query,
-		param).
+		params...).
 		Scan(&usr.Id,
 			&usr.Name,
 			&usr.TimeZone,
-			&usr.PasswordHash)
-
+			&usr.PasswordHash,
+			&usr.Preferences.DisplayName,
+			&usr.Preferences.Locale,
+			&weekStart,
+			&reminderOffset,
+			&usr.Preferences.DateFormat,
+			&usr.OrganizationId)
+
+	usr.Preferences.WeekStart = time.Weekday(weekStart)
+	usr.Preferences.ReminderOffset = time.Duration(reminderOffset) * time.Minute
 	return &usr, err
 }
+
+func minutes(d time.Duration) int {
+	return int(d / time.Minute)
+}
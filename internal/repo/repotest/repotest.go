@@ -0,0 +1,767 @@
+// Package repotest is the behaviour every implementation of the note and user repositories shares.
+// A backend runs it against itself from its own tests:
+//
+//	func TestConformance(t *testing.T) {
+//		repotest.Run(t, func(t *testing.T) repotest.Repositories { ... })
+//	}
+package repotest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+	"todoNote/internal/model"
+	"todoNote/internal/repo"
+)
+
+// Repositories of one storage, notes refer to users of User
+type Repositories struct {
+	Note repo.IRepoNote
+	User repo.IRepoUser
+	// Transactor is optional, rollback is not checked without it
+	Transactor repo.ITransactor
+	// Outbox is optional, RunOutbox is skipped without it
+	Outbox repo.IRepoOutbox
+	// Organization is optional, RunOrganizations and RunTenants are skipped without it
+	Organization repo.IRepoOrganization
+}
+
+// Backend opens the repositories for a test. They may hold data of their own,
+// the suite only looks at users and notes it creates
+type Backend func(t *testing.T) Repositories
+
+// unknownId is an id no test creates
+const unknownId = model.Id(1) << 40
+
+var date = time.Date(2021, 10, 2, 10, 0, 0, 0, time.UTC)
+
+// Run runs the whole suite, every test opens repositories of its own
+func Run(t *testing.T, open Backend) {
+	t.Run("users", func(t *testing.T) { RunUsers(t, open) })
+	t.Run("notes", func(t *testing.T) { RunNotes(t, open) })
+	t.Run("outbox", func(t *testing.T) { RunOutbox(t, open) })
+	t.Run("organizations", func(t *testing.T) { RunOrganizations(t, open) })
+	t.Run("tenants", func(t *testing.T) { RunTenants(t, open) })
+}
+
+func RunUsers(t *testing.T, open Backend) {
+	ctx := context.Background()
+
+	t.Run("insert and get", func(t *testing.T) {
+		r := open(t)
+		u := model.NewUser(0, name("insert"), []byte("hash"), "Europe/Kyiv")
+		u.Preferences.DisplayName = "Ann"
+		u.Preferences.WeekStart = time.Sunday
+		u.Preferences.ReminderOffset = 15 * time.Minute
+
+		id, err := r.User.Insert(ctx, u)
+		assert.Nil(t, err)
+		u.Id = id
+
+		got, err := r.User.GetById(ctx, id)
+		assert.Nil(t, err)
+		assert.Equal(t, u, got)
+
+		got, err = r.User.GetByUserName(ctx, u.Name)
+		assert.Nil(t, err)
+		assert.Equal(t, u, got)
+
+		got, err = r.User.GetByIdForUpdate(ctx, id)
+		assert.Nil(t, err)
+		assert.Equal(t, u, got)
+	})
+
+	t.Run("name is unique", func(t *testing.T) {
+		r := open(t)
+		n := name("unique")
+		newUser(t, r, n)
+
+		_, err := r.User.Insert(ctx, model.NewUser(0, n, []byte("hash"), model.UTC))
+		assert.True(t, errors.Is(err, repo.ErrConflict), "%v", err)
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		r := open(t)
+
+		_, err := r.User.GetById(ctx, unknownId)
+		assert.True(t, errors.Is(err, repo.ErrNotFound), "%v", err)
+		_, err = r.User.GetByIdForUpdate(ctx, unknownId)
+		assert.True(t, errors.Is(err, repo.ErrNotFound), "%v", err)
+		_, err = r.User.GetByUserName(ctx, name("unknown"))
+		assert.True(t, errors.Is(err, repo.ErrNotFound), "%v", err)
+
+		err = r.User.Update(ctx, model.NewUser(unknownId, name("unknown"), []byte("hash"), model.UTC))
+		assert.True(t, errors.Is(err, repo.ErrNotFound), "%v", err)
+		err = r.User.Delete(ctx, unknownId)
+		assert.True(t, errors.Is(err, repo.ErrNotFound), "%v", err)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		r := open(t)
+		id := newUser(t, r, name("update"))
+
+		u, err := r.User.GetById(ctx, id)
+		assert.Nil(t, err)
+		u.TimeZone = "America/New_York"
+		u.Preferences.Locale = "uk-UA"
+		u.Preferences.DateFormat = "DD.MM.YYYY"
+		assert.Nil(t, r.User.Update(ctx, u))
+
+		got, err := r.User.GetById(ctx, id)
+		assert.Nil(t, err)
+		assert.Equal(t, u, got)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		r := open(t)
+		id := newUser(t, r, name("delete"))
+
+		assert.Nil(t, r.User.Delete(ctx, id))
+		_, err := r.User.GetById(ctx, id)
+		assert.True(t, errors.Is(err, repo.ErrNotFound), "%v", err)
+		assert.True(t, errors.Is(r.User.Delete(ctx, id), repo.ErrNotFound))
+	})
+}
+
+func RunNotes(t *testing.T, open Backend) {
+	ctx := context.Background()
+
+	t.Run("insert and get", func(t *testing.T) {
+		r := open(t)
+		uId := newUser(t, r, name("insert"))
+
+		finished := date.Add(time.Hour)
+		n := model.NewNote(0, uId, "title", "text", date, true)
+		n.TimeZone = "Europe/Kyiv"
+		n.IsFloating = true
+		n.ReminderOffset = 10 * time.Minute
+		n.Uid = "uid@example.com"
+		n.Priority = "A"
+		n.Projects = []string{"home", "garden"}
+		n.Contexts = []string{"phone"}
+		n.FinishedAt = &finished
+
+		id, err := r.Note.Insert(ctx, n)
+		assert.Nil(t, err)
+		assert.NotEqual(t, model.Id(0), id)
+		assert.True(t, n.Version > 0)
+		n.Id = id
+
+		got, err := r.Note.GetById(ctx, id)
+		assert.Nil(t, err)
+		assert.Equal(t, *n, got)
+
+		got, err = r.Note.GetByUid(ctx, uId, n.Uid)
+		assert.Nil(t, err)
+		assert.Equal(t, *n, got)
+
+		inTransaction(t, r, func(ctx context.Context) error {
+			got, err := r.Note.GetByIdForUpdate(ctx, id)
+			assert.Nil(t, err)
+			assert.Equal(t, *n, got)
+			got, err = r.Note.GetByUidForUpdate(ctx, uId, n.Uid)
+			assert.Nil(t, err)
+			assert.Equal(t, *n, got)
+			return nil
+		})
+	})
+
+	t.Run("tags are never nil", func(t *testing.T) {
+		r := open(t)
+		n := newNote(t, r, newUser(t, r, name("tags")), "title", date)
+
+		got, err := r.Note.GetById(ctx, n.Id)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{}, got.Projects)
+		assert.Equal(t, []string{}, got.Contexts)
+	})
+
+	t.Run("unknown note", func(t *testing.T) {
+		r := open(t)
+		uId := newUser(t, r, name("unknown"))
+
+		_, err := r.Note.GetById(ctx, unknownId)
+		assert.True(t, errors.Is(err, repo.ErrNotFound), "%v", err)
+		_, err = r.Note.GetByIdForUpdate(ctx, unknownId)
+		assert.True(t, errors.Is(err, repo.ErrNotFound), "%v", err)
+		_, err = r.Note.GetByUid(ctx, uId, "unknown@example.com")
+		assert.True(t, errors.Is(err, repo.ErrNotFound), "%v", err)
+		_, err = r.Note.GetByUidForUpdate(ctx, uId, "unknown@example.com")
+		assert.True(t, errors.Is(err, repo.ErrNotFound), "%v", err)
+
+		err = r.Note.Update(ctx, model.NewNote(unknownId, uId, "title", "", date, false))
+		assert.True(t, errors.Is(err, repo.ErrNotFound), "%v", err)
+		err = r.Note.Delete(ctx, unknownId)
+		assert.True(t, errors.Is(err, repo.ErrNotFound), "%v", err)
+	})
+
+	t.Run("uid of another user", func(t *testing.T) {
+		r := open(t)
+		n := model.NewNote(0, newUser(t, r, name("owner")), "title", "", date, false)
+		n.Uid = "shared@example.com"
+		_, err := r.Note.Insert(ctx, n)
+		assert.Nil(t, err)
+
+		_, err = r.Note.GetByUid(ctx, newUser(t, r, name("other")), n.Uid)
+		assert.True(t, errors.Is(err, repo.ErrNotFound), "%v", err)
+	})
+
+	t.Run("list", func(t *testing.T) {
+		r := open(t)
+		uId := newUser(t, r, name("list"))
+		other := newUser(t, r, name("other"))
+
+		// titles are the expected order, by date and then by id
+		newNote(t, r, uId, "d", date.Add(3 * time.Hour))
+		newNote(t, r, uId, "a", date)
+		newNote(t, r, uId, "b", date.Add(time.Hour))
+		finished := newNote(t, r, uId, "c", date.Add(time.Hour))
+		finished.IsFinished = true
+		assert.Nil(t, r.Note.Update(ctx, &finished))
+		newNote(t, r, other, "other", date.Add(time.Hour))
+
+		list := func(f repo.NoteFilter) []string {
+			t.Helper()
+			f.UserId = uId
+			notes, err := r.Note.GetAllOffset(ctx, f)
+			assert.Nil(t, err)
+
+			each := make([]string, 0)
+			assert.Nil(t, r.Note.Each(ctx, f, func(n model.Note) error {
+				each = append(each, n.Title)
+				return nil
+			}))
+			assert.Equal(t, titles(notes), each, "Each and GetAllOffset differ")
+
+			return each
+		}
+
+		from, to := date.Add(time.Hour), date.Add(3 * time.Hour)
+		yes, no := true, false
+		one, two := uint64(1), uint64(2)
+
+		assert.Equal(t, []string{"a", "b", "c", "d"}, list(repo.NoteFilter{}))
+		assert.Equal(t, []string{"b", "c"}, list(repo.NoteFilter{TakeFrom: &from, TakeTo: &to}), "from is inclusive, to is exclusive")
+		assert.Equal(t, []string{"c"}, list(repo.NoteFilter{IsFinished: &yes}))
+		assert.Equal(t, []string{"a", "b", "d"}, list(repo.NoteFilter{IsFinished: &no}))
+		assert.Equal(t, []string{"a", "b"}, list(repo.NoteFilter{Page: repo.PageFilter{Limit: &two}}))
+		assert.Equal(t, []string{"b", "c", "d"}, list(repo.NoteFilter{Page: repo.PageFilter{Offset: &one}}))
+		assert.Equal(t, []string{"c", "d"}, list(repo.NoteFilter{Page: repo.PageFilter{Offset: &two, Limit: &two}}))
+		assert.Equal(t, []string{"d"}, list(repo.NoteFilter{IsFinished: &no, Page: repo.PageFilter{Offset: &two}}), "offset skips filtered notes")
+
+		t.Run("no notes", func(t *testing.T) {
+			notes, err := r.Note.GetAllOffset(ctx, repo.NoteFilter{UserId: newUser(t, r, name("empty"))})
+			assert.Nil(t, err)
+			assert.NotNil(t, notes)
+			assert.Len(t, notes, 0)
+		})
+
+		t.Run("errors of fn stop each", func(t *testing.T) {
+			stop := fmt.Errorf("stop")
+			calls := 0
+			err := r.Note.Each(ctx, repo.NoteFilter{UserId: uId}, func(n model.Note) error {
+				calls++
+				return stop
+			})
+			assert.Equal(t, stop, err)
+			assert.Equal(t, 1, calls)
+		})
+	})
+
+	t.Run("floating bounds", func(t *testing.T) {
+		r := open(t)
+		uId := newUser(t, r, name("floating"))
+
+		newNote(t, r, uId, "fixed", date)
+		n := model.NewNote(0, uId, "floating", "", date.Add(2 * time.Hour), false)
+		n.IsFloating = true
+		_, err := r.Note.Insert(ctx, n)
+		assert.Nil(t, err)
+
+		list := func(f repo.NoteFilter) []string {
+			t.Helper()
+			f.UserId = uId
+			notes, err := r.Note.GetAllOffset(ctx, f)
+			assert.Nil(t, err)
+			return titles(notes)
+		}
+
+		from, to := date, date.Add(time.Hour)
+		floatingFrom, floatingTo := date.Add(2 * time.Hour), date.Add(3 * time.Hour)
+		assert.Equal(t, []string{"fixed"}, list(repo.NoteFilter{TakeFrom: &from, TakeTo: &to}), "floating notes are bounded like the others")
+		assert.Equal(t, []string{"fixed", "floating"}, list(repo.NoteFilter{TakeFrom: &from, TakeTo: &to, FloatingFrom: &floatingFrom, FloatingTo: &floatingTo}))
+		assert.Equal(t, []string{"floating"}, list(repo.NoteFilter{TakeFrom: &floatingFrom, TakeTo: &floatingTo, FloatingFrom: &floatingFrom, FloatingTo: &floatingTo}), "fixed notes keep their bounds")
+		assert.Equal(t, []string{"fixed"}, list(repo.NoteFilter{TakeFrom: &from, FloatingTo: &from}))
+	})
+
+	t.Run("update", func(t *testing.T) {
+		r := open(t)
+		n := newNote(t, r, newUser(t, r, name("update")), "title", date)
+		version := n.Version
+
+		finished := date.Add(time.Hour)
+		n.Title = "changed"
+		n.Date = date.Add(24 * time.Hour)
+		n.IsFinished = true
+		n.FinishedAt = &finished
+		n.Projects = []string{"work"}
+		assert.Nil(t, r.Note.Update(ctx, &n))
+		assert.True(t, n.Version > version)
+
+		got, err := r.Note.GetById(ctx, n.Id)
+		assert.Nil(t, err)
+		assert.Equal(t, n, got)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		r := open(t)
+		n := newNote(t, r, newUser(t, r, name("delete")), "title", date)
+
+		assert.Nil(t, r.Note.Delete(ctx, n.Id))
+		_, err := r.Note.GetById(ctx, n.Id)
+		assert.True(t, errors.Is(err, repo.ErrNotFound), "%v", err)
+		assert.True(t, errors.Is(r.Note.Delete(ctx, n.Id), repo.ErrNotFound))
+	})
+
+	t.Run("changes", func(t *testing.T) {
+		r := open(t)
+		uId := newUser(t, r, name("changes"))
+		other := newUser(t, r, name("other"))
+
+		a := newNote(t, r, uId, "a", date)
+		b := newNote(t, r, uId, "b", date)
+		newNote(t, r, other, "other", date)
+		a.Title = "changed"
+		assert.Nil(t, r.Note.Update(ctx, &a))
+		assert.Nil(t, r.Note.Delete(ctx, b.Id))
+
+		changes, err := r.Note.Changes(ctx, uId, 0, 10)
+		assert.Nil(t, err)
+		assert.Len(t, changes, 2)
+		assert.Equal(t, a.Id, changes[0].NoteId)
+		assert.Equal(t, a.Version, changes[0].Version)
+		assert.Equal(t, &a, changes[0].Note)
+		assert.Equal(t, model.NoteChange{Version: changes[1].Version, NoteId: b.Id, Deleted: true}, changes[1])
+		assert.True(t, changes[0].Version < changes[1].Version)
+
+		since, err := r.Note.Changes(ctx, uId, changes[0].Version, 10)
+		assert.Nil(t, err)
+		assert.Equal(t, changes[1:], since)
+
+		limited, err := r.Note.Changes(ctx, uId, 0, 1)
+		assert.Nil(t, err)
+		assert.Equal(t, changes[:1], limited)
+
+		none, err := r.Note.Changes(ctx, uId, changes[1].Version, 10)
+		assert.Nil(t, err)
+		assert.NotNil(t, none)
+		assert.Len(t, none, 0)
+	})
+
+	t.Run("rollback", func(t *testing.T) {
+		r := open(t)
+		if r.Transactor == nil {
+			t.Skip("no transactor")
+		}
+		uId := newUser(t, r, name("rollback"))
+		kept := newNote(t, r, uId, "kept", date)
+		removed := newNote(t, r, uId, "removed", date)
+
+		broken := fmt.Errorf("broken")
+		err := r.Transactor.InTransaction(ctx, func(ctx context.Context) error {
+			changed := kept
+			changed.Title = "changed"
+			if err := r.Note.Update(ctx, &changed); err != nil {
+				return err
+			}
+			if err := r.Note.Delete(ctx, removed.Id); err != nil {
+				return err
+			}
+			if _, err := r.Note.Insert(ctx, model.NewNote(0, uId, "inserted", "", date, false)); err != nil {
+				return err
+			}
+
+			return broken
+		})
+		assert.Equal(t, broken, err)
+
+		notes, err := r.Note.GetAllOffset(ctx, repo.NoteFilter{UserId: uId})
+		assert.Nil(t, err)
+		assert.Equal(t, []model.Note{kept, removed}, notes)
+	})
+}
+
+// name is unique for the test, backends keep users of other tests
+func name(prefix string) string {
+	return fmt.Sprintf("%v-%v", prefix, time.Now().UnixNano() % 1e9)
+}
+
+func newUser(t *testing.T, r Repositories, name string) model.Id {
+	t.Helper()
+	id, err := r.User.Insert(context.Background(), model.NewUser(0, name, []byte("hash"), model.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return id
+}
+
+// newNote inserts a note and returns it as the repository has stored it
+func newNote(t *testing.T, r Repositories, userId model.Id, title string, date time.Time) model.Note {
+	t.Helper()
+	id, err := r.Note.Insert(context.Background(), model.NewNote(0, userId, title, "", date, false))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := r.Note.GetById(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return n
+}
+
+func inTransaction(t *testing.T, r Repositories, fn func(ctx context.Context) error) {
+	t.Helper()
+	if r.Transactor == nil {
+		assert.Nil(t, fn(context.Background()))
+		return
+	}
+
+	assert.Nil(t, r.Transactor.InTransaction(context.Background(), fn))
+}
+
+func titles(notes []model.Note) []string {
+	res := make([]string, 0, len(notes))
+	for _, n := range notes {
+		res = append(res, n.Title)
+	}
+
+	return res
+}
+
+func RunOutbox(t *testing.T, open Backend) {
+	ctx := context.Background()
+	// events of the suite are due long before the ones the backend may hold
+	now := time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC)
+	lease := time.Minute
+
+	newEvent := func(t *testing.T, r Repositories, eventType model.EventType) model.Event {
+		t.Helper()
+		e := model.NewEvent(eventType, 1, 2, []byte(`{"id":2}`), now)
+		id, err := r.Outbox.Insert(ctx, e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		e.Id = id
+
+		return *e
+	}
+
+	outbox := func(t *testing.T) Repositories {
+		r := open(t)
+		if r.Outbox == nil {
+			t.Skip("no outbox")
+		}
+
+		return r
+	}
+
+	t.Run("claim", func(t *testing.T) {
+		r := outbox(t)
+		first := newEvent(t, r, model.EventNoteCreated)
+		second := newEvent(t, r, model.EventNoteUpdated)
+		assert.True(t, first.Id < second.Id)
+
+		claimed, err := r.Outbox.Claim(ctx, now, lease, 1)
+		assert.Nil(t, err)
+		first.NextAttemptAt = now.Add(lease)
+		assert.Equal(t, []model.Event{first}, claimed)
+
+		claimed, err = r.Outbox.Claim(ctx, now, lease, 10)
+		assert.Nil(t, err)
+		assert.Equal(t, []model.EventType{model.EventNoteUpdated}, types(claimed), "claimed events wait for the lease")
+
+		claimed, err = r.Outbox.Claim(ctx, now, lease, 10)
+		assert.Nil(t, err)
+		assert.Len(t, claimed, 0)
+
+		claimed, err = r.Outbox.Claim(ctx, now.Add(lease), lease, 10)
+		assert.Nil(t, err)
+		assert.Equal(t, []model.EventType{model.EventNoteCreated, model.EventNoteUpdated}, types(claimed), "events are claimed again after the lease")
+
+		for _, e := range claimed {
+			assert.Nil(t, r.Outbox.Delete(ctx, e.Id))
+		}
+	})
+
+	t.Run("retry", func(t *testing.T) {
+		r := outbox(t)
+		e := newEvent(t, r, model.EventNoteDeleted)
+
+		next := now.Add(time.Hour)
+		assert.Nil(t, r.Outbox.Retry(ctx, e.Id, next, "unavailable"))
+
+		claimed, err := r.Outbox.Claim(ctx, now.Add(time.Minute), lease, 10)
+		assert.Nil(t, err)
+		assert.Len(t, claimed, 0)
+
+		claimed, err = r.Outbox.Claim(ctx, next, lease, 10)
+		assert.Nil(t, err)
+		if assert.Len(t, claimed, 1) {
+			assert.Equal(t, 1, claimed[0].Attempts)
+			assert.Equal(t, "unavailable", claimed[0].LastError)
+		}
+
+		assert.Nil(t, r.Outbox.Delete(ctx, e.Id))
+	})
+
+	t.Run("unknown event", func(t *testing.T) {
+		r := outbox(t)
+
+		assert.True(t, errors.Is(r.Outbox.Delete(ctx, unknownId), repo.ErrNotFound))
+		assert.True(t, errors.Is(r.Outbox.Retry(ctx, unknownId, now, ""), repo.ErrNotFound))
+	})
+
+	t.Run("rollback", func(t *testing.T) {
+		r := outbox(t)
+		if r.Transactor == nil {
+			t.Skip("no transactor")
+		}
+
+		broken := fmt.Errorf("broken")
+		err := r.Transactor.InTransaction(ctx, func(ctx context.Context) error {
+			if _, err := r.Outbox.Insert(ctx, model.NewEvent(model.EventUserCreated, 1, 1, []byte(`{}`), now)); err != nil {
+				return err
+			}
+
+			return broken
+		})
+		assert.Equal(t, broken, err)
+
+		claimed, err := r.Outbox.Claim(ctx, now, lease, 10)
+		assert.Nil(t, err)
+		assert.Len(t, claimed, 0)
+	})
+}
+
+func types(events []model.Event) []model.EventType {
+	res := make([]model.EventType, 0, len(events))
+	for _, e := range events {
+		res = append(res, e.Type)
+	}
+
+	return res
+}
+
+func RunOrganizations(t *testing.T, open Backend) {
+	ctx := context.Background()
+
+	t.Run("default organization", func(t *testing.T) {
+		r := open(t)
+		if r.Organization == nil {
+			t.Skip("no organization repository")
+		}
+
+		o, err := r.Organization.GetById(ctx, model.DefaultOrganizationId)
+		assert.Nil(t, err)
+		assert.Equal(t, model.DefaultOrganizationName, o.Name)
+
+		o, err = r.Organization.GetByName(ctx, model.DefaultOrganizationName)
+		assert.Nil(t, err)
+		assert.Equal(t, model.DefaultOrganizationId, o.Id)
+	})
+
+	t.Run("insert and get", func(t *testing.T) {
+		r := open(t)
+		if r.Organization == nil {
+			t.Skip("no organization repository")
+		}
+
+		o := model.NewOrganization(name("org"), date)
+		id, err := r.Organization.Insert(ctx, o)
+		assert.Nil(t, err)
+		o.Id = id
+
+		got, err := r.Organization.GetById(ctx, id)
+		assert.Nil(t, err)
+		assert.Equal(t, *o, got)
+
+		got, err = r.Organization.GetByName(ctx, o.Name)
+		assert.Nil(t, err)
+		assert.Equal(t, *o, got)
+
+		_, err = r.Organization.Insert(ctx, model.NewOrganization(o.Name, date))
+		assert.True(t, errors.Is(err, repo.ErrConflict), "%v", err)
+	})
+
+	t.Run("unknown organization", func(t *testing.T) {
+		r := open(t)
+		if r.Organization == nil {
+			t.Skip("no organization repository")
+		}
+
+		_, err := r.Organization.GetById(ctx, unknownId)
+		assert.True(t, errors.Is(err, repo.ErrNotFound), "%v", err)
+		_, err = r.Organization.GetByName(ctx, name("unknown"))
+		assert.True(t, errors.Is(err, repo.ErrNotFound), "%v", err)
+	})
+}
+
+// RunTenants checks that nothing of one organization is visible to or changed by another one
+func RunTenants(t *testing.T, open Backend) {
+	// tenants opens the repositories with the contexts of two new organizations
+	tenants := func(t *testing.T) (Repositories, context.Context, context.Context) {
+		t.Helper()
+		r := open(t)
+		if r.Organization == nil {
+			t.Skip("no organization repository")
+		}
+
+		return r, repo.WithTenant(context.Background(), newOrganization(t, r)), repo.WithTenant(context.Background(), newOrganization(t, r))
+	}
+
+	t.Run("users", func(t *testing.T) {
+		r, a, b := tenants(t)
+		n := name("tenant")
+
+		u := model.NewUser(0, n, []byte("hash"), model.UTC)
+		id, err := r.User.Insert(a, u)
+		assert.Nil(t, err)
+		organizationId, _ := repo.Tenant(a)
+		assert.Equal(t, organizationId, u.OrganizationId)
+
+		// names are unique within an organization only
+		other, err := r.User.Insert(b, model.NewUser(0, n, []byte("hash"), model.UTC))
+		assert.Nil(t, err)
+
+		got, err := r.User.GetByUserName(a, n)
+		assert.Nil(t, err)
+		assert.Equal(t, id, got.Id)
+		got, err = r.User.GetByUserName(b, n)
+		assert.Nil(t, err)
+		assert.Equal(t, other, got.Id)
+
+		_, err = r.User.GetById(b, id)
+		assert.True(t, errors.Is(err, repo.ErrNotFound), "%v", err)
+		_, err = r.User.GetByIdForUpdate(b, id)
+		assert.True(t, errors.Is(err, repo.ErrNotFound), "%v", err)
+		_, err = r.User.GetByUserName(context.Background(), n)
+		assert.True(t, errors.Is(err, repo.ErrNotFound), "%v", err)
+
+		u.Id = id
+		u.TimeZone = "Europe/Kyiv"
+		assert.True(t, errors.Is(r.User.Update(b, u), repo.ErrNotFound))
+		assert.True(t, errors.Is(r.User.Delete(b, id), repo.ErrNotFound))
+
+		got, err = r.User.GetById(repo.AllTenants(context.Background()), id)
+		assert.Nil(t, err)
+		assert.Equal(t, model.UTC, got.TimeZone)
+		assert.Equal(t, organizationId, got.OrganizationId)
+	})
+
+	t.Run("default organization", func(t *testing.T) {
+		r, a, _ := tenants(t)
+
+		u := model.NewUser(0, name("default"), []byte("hash"), model.UTC)
+		id, err := r.User.Insert(context.Background(), u)
+		assert.Nil(t, err)
+		assert.Equal(t, model.DefaultOrganizationId, u.OrganizationId)
+
+		_, err = r.User.GetById(a, id)
+		assert.True(t, errors.Is(err, repo.ErrNotFound), "%v", err)
+		_, err = r.User.GetById(repo.WithTenant(context.Background(), model.DefaultOrganizationId), id)
+		assert.Nil(t, err)
+	})
+
+	t.Run("notes", func(t *testing.T) {
+		r, a, b := tenants(t)
+		organizationId, _ := repo.Tenant(a)
+		userId, err := r.User.Insert(a, model.NewUser(0, name("notes"), []byte("hash"), model.UTC))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		n := model.NewNote(0, userId, "tenant", "", date, false)
+		n.Uid = "tenant@example.com"
+		id, err := r.Note.Insert(a, n)
+		assert.Nil(t, err)
+		assert.Equal(t, organizationId, n.OrganizationId)
+
+		got, err := r.Note.GetById(a, id)
+		assert.Nil(t, err)
+		assert.Equal(t, organizationId, got.OrganizationId)
+
+		_, err = r.Note.GetById(b, id)
+		assert.True(t, errors.Is(err, repo.ErrNotFound), "%v", err)
+		_, err = r.Note.GetByIdForUpdate(b, id)
+		assert.True(t, errors.Is(err, repo.ErrNotFound), "%v", err)
+		_, err = r.Note.GetByUid(b, userId, n.Uid)
+		assert.True(t, errors.Is(err, repo.ErrNotFound), "%v", err)
+
+		notes, err := r.Note.GetAllOffset(b, repo.NoteFilter{UserId: userId})
+		assert.Nil(t, err)
+		assert.Len(t, notes, 0)
+		changes, err := r.Note.Changes(b, userId, 0, 10)
+		assert.Nil(t, err)
+		assert.Len(t, changes, 0)
+
+		got.Title = "changed"
+		assert.True(t, errors.Is(r.Note.Update(b, &got), repo.ErrNotFound))
+		assert.True(t, errors.Is(r.Note.Delete(b, id), repo.ErrNotFound))
+
+		assert.Nil(t, r.Note.Update(a, &got))
+		assert.Equal(t, organizationId, got.OrganizationId)
+		notes, err = r.Note.GetAllOffset(a, repo.NoteFilter{UserId: userId})
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"changed"}, titles(notes))
+
+		// tombstones stay in the organization too
+		assert.Nil(t, r.Note.Delete(a, id))
+		changes, err = r.Note.Changes(b, userId, 0, 10)
+		assert.Nil(t, err)
+		assert.Len(t, changes, 0)
+		changes, err = r.Note.Changes(a, userId, 0, 10)
+		assert.Nil(t, err)
+		if assert.Len(t, changes, 1) {
+			assert.True(t, changes[0].Deleted)
+		}
+	})
+
+	t.Run("all tenants", func(t *testing.T) {
+		r, a, _ := tenants(t)
+		all := repo.AllTenants(context.Background())
+		userId, err := r.User.Insert(a, model.NewUser(0, name("all"), []byte("hash"), model.UTC))
+		if err != nil {
+			t.Fatal(err)
+		}
+		id, err := r.Note.Insert(a, model.NewNote(0, userId, "all", "", date, false))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = r.Note.GetById(all, id)
+		assert.Nil(t, err)
+		notes, err := r.Note.GetAllOffset(all, repo.NoteFilter{UserId: userId})
+		assert.Nil(t, err)
+		assert.Len(t, notes, 1)
+
+		assert.Nil(t, r.User.Delete(all, userId))
+		_, err = r.User.GetById(a, userId)
+		assert.True(t, errors.Is(err, repo.ErrNotFound), "%v", err)
+	})
+}
+
+func newOrganization(t *testing.T, r Repositories) model.Id {
+	t.Helper()
+	id, err := r.Organization.Insert(context.Background(), model.NewOrganization(name("org"), date))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return id
+}
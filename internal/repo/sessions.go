@@ -0,0 +1,16 @@
+package repo
+
+import (
+	"context"
+	"time"
+	"todoNote/internal/model"
+)
+
+type IRepoSession interface {
+	Insert(ctx context.Context, s *model.Session) (model.Id, error)
+	GetById(ctx context.Context, id model.Id) (model.Session, error)
+	GetAllByUser(ctx context.Context, uId model.Id) ([]model.Session, error)
+	Touch(ctx context.Context, id model.Id, at time.Time) error
+	Delete(ctx context.Context, id model.Id) error
+	DeleteExpired(ctx context.Context, before time.Time) error
+}
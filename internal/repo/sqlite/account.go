@@ -0,0 +1,174 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+	"todoNote/internal/model"
+	"todoNote/internal/repo"
+)
+
+var _ repo.IRepoExport = &RepoExport{}
+
+type RepoExport struct {
+	conn *sql.DB
+}
+
+func NewRepoExport(conn *sql.DB) repo.IRepoExport {
+	return &RepoExport{
+		conn: conn,
+	}
+}
+
+func (r *RepoExport) Upsert(ctx context.Context, e *model.Export) error {
+	query := `
+INSERT INTO data_exports (user_id, status, archive, created_at, completed_at)
+VALUES (?, ?, ?, ?, ?)
+ON CONFLICT (user_id) DO UPDATE
+SET status = excluded.status, archive = excluded.archive,
+created_at = excluded.created_at, completed_at = excluded.completed_at;`
+
+	var completedAt *time.Time
+	if !e.CompletedAt.IsZero() {
+		completedAt = &e.CompletedAt
+	}
+
+	_, err := db(ctx, r.conn).ExecContext(ctx,
+		query,
+		e.UserId,
+		e.Status,
+		e.Archive,
+		timestamp(e.CreatedAt),
+		nullTimestamp(completedAt))
+
+	if err != nil {
+		return NewExportsError(insert, err)
+	}
+
+	return nil
+}
+
+func (r *RepoExport) GetByUserId(ctx context.Context, uId model.Id) (model.Export, error) {
+	query := `SELECT user_id, status, archive, created_at, completed_at FROM data_exports WHERE user_id = ?;`
+	var e model.Export
+	var createdAt string
+	var completedAt sql.NullString
+	err := db(ctx, r.conn).QueryRowContext(ctx, query, uId).Scan(
+		&e.UserId,
+		&e.Status,
+		&e.Archive,
+		&createdAt,
+		&completedAt)
+	if err == nil {
+		e.CreatedAt, err = parseTimestamp(createdAt)
+	}
+	if err == nil && completedAt.Valid {
+		e.CompletedAt, err = parseTimestamp(completedAt.String)
+	}
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.Export{}, repo.NewNotFoundError(uId)
+		}
+
+		return model.Export{}, NewExportsError(select_sql, err)
+	}
+
+	return e, nil
+}
+
+func (r *RepoExport) Delete(ctx context.Context, uId model.Id) error {
+	query := `DELETE FROM data_exports WHERE user_id = ?;`
+	if _, err := db(ctx, r.conn).ExecContext(ctx, query, uId); err != nil {
+		return NewExportsError(delete_sql, err)
+	}
+
+	return nil
+}
+
+var _ repo.IRepoAccountDeletion = &RepoAccountDeletion{}
+
+type RepoAccountDeletion struct {
+	conn *sql.DB
+}
+
+func NewRepoAccountDeletion(conn *sql.DB) repo.IRepoAccountDeletion {
+	return &RepoAccountDeletion{
+		conn: conn,
+	}
+}
+
+func (r *RepoAccountDeletion) Upsert(ctx context.Context, d *model.AccountDeletion) error {
+	query := `
+INSERT INTO account_deletions (user_id, delete_after)
+VALUES (?, ?)
+ON CONFLICT (user_id) DO UPDATE
+SET delete_after = excluded.delete_after;`
+
+	if _, err := db(ctx, r.conn).ExecContext(ctx, query, d.UserId, timestamp(d.DeleteAfter)); err != nil {
+		return NewAccountDeletionsError(insert, err)
+	}
+
+	return nil
+}
+
+func (r *RepoAccountDeletion) GetByUserId(ctx context.Context, uId model.Id) (model.AccountDeletion, error) {
+	query := `SELECT user_id, delete_after FROM account_deletions WHERE user_id = ?;`
+	d, err := scanAccountDeletion(db(ctx, r.conn).QueryRowContext(ctx, query, uId))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.AccountDeletion{}, repo.NewNotFoundError(uId)
+		}
+
+		return model.AccountDeletion{}, NewAccountDeletionsError(select_sql, err)
+	}
+
+	return d, nil
+}
+
+func (r *RepoAccountDeletion) Delete(ctx context.Context, uId model.Id) error {
+	query := `DELETE FROM account_deletions WHERE user_id = ?;`
+	if _, err := db(ctx, r.conn).ExecContext(ctx, query, uId); err != nil {
+		return NewAccountDeletionsError(delete_sql, err)
+	}
+
+	return nil
+}
+
+func (r *RepoAccountDeletion) GetDue(ctx context.Context, now time.Time) ([]model.AccountDeletion, error) {
+	query := `SELECT user_id, delete_after FROM account_deletions WHERE delete_after < ?;`
+	rows, err := db(ctx, r.conn).QueryContext(ctx, query, timestamp(now))
+	if err != nil {
+		return nil, NewAccountDeletionsError(select_sql, err)
+	}
+
+	res := make([]model.AccountDeletion, 0)
+	defer rows.Close()
+	for rows.Next() {
+		d, err := scanAccountDeletion(rows)
+		if err != nil {
+			return nil, NewAccountDeletionsError(select_sql, err)
+		}
+
+		res = append(res, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, NewAccountDeletionsError(select_sql, err)
+	}
+
+	return res, nil
+}
+
+func scanAccountDeletion(row scanner) (model.AccountDeletion, error) {
+	var d model.AccountDeletion
+	var deleteAfter string
+	if err := row.Scan(&d.UserId, &deleteAfter); err != nil {
+		return model.AccountDeletion{}, err
+	}
+
+	var err error
+	d.DeleteAfter, err = parseTimestamp(deleteAfter)
+	return d, err
+}
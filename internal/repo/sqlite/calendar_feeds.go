@@ -0,0 +1,64 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"todoNote/internal/model"
+	"todoNote/internal/repo"
+)
+
+var _ repo.IRepoCalendarFeed = &RepoCalendarFeed{}
+
+type RepoCalendarFeed struct {
+	conn *sql.DB
+}
+
+func NewRepoCalendarFeed(conn *sql.DB) repo.IRepoCalendarFeed {
+	return &RepoCalendarFeed{
+		conn: conn,
+	}
+}
+
+func (r *RepoCalendarFeed) Upsert(ctx context.Context, f *model.CalendarFeed) error {
+	query := `
+INSERT INTO calendar_feeds (user_id, token_hash, created_at)
+VALUES (?, ?, ?)
+ON CONFLICT (user_id) DO UPDATE
+SET token_hash = excluded.token_hash, created_at = excluded.created_at;`
+
+	if _, err := db(ctx, r.conn).ExecContext(ctx, query, f.UserId, f.TokenHash, timestamp(f.CreatedAt)); err != nil {
+		return NewCalendarFeedsError(insert, err)
+	}
+
+	return nil
+}
+
+func (r *RepoCalendarFeed) GetByTokenHash(ctx context.Context, hash []byte) (model.CalendarFeed, error) {
+	query := `SELECT user_id, token_hash, created_at FROM calendar_feeds WHERE token_hash = ?;`
+	var f model.CalendarFeed
+	var createdAt string
+	err := db(ctx, r.conn).QueryRowContext(ctx, query, hash).Scan(&f.UserId, &f.TokenHash, &createdAt)
+	if err == nil {
+		f.CreatedAt, err = parseTimestamp(createdAt)
+	}
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.CalendarFeed{}, repo.NewNotFoundError(0)
+		}
+
+		return model.CalendarFeed{}, NewCalendarFeedsError(select_sql, err)
+	}
+
+	return f, nil
+}
+
+func (r *RepoCalendarFeed) Delete(ctx context.Context, uId model.Id) error {
+	query := `DELETE FROM calendar_feeds WHERE user_id = ?;`
+	if _, err := db(ctx, r.conn).ExecContext(ctx, query, uId); err != nil {
+		return NewCalendarFeedsError(delete_sql, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,13 @@
+package sqlite
+
+import (
+	"testing"
+	"todoNote/internal/repo/repotest"
+)
+
+func TestConformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repotest.Repositories {
+		conn := open(t)
+		return repotest.Repositories{Note: NewRepoNote(conn), User: NewRepoUser(conn), Transactor: NewTransactor(conn), Outbox: NewRepoOutbox(conn), Organization: NewRepoOrganization(conn)}
+	})
+}
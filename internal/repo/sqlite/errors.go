@@ -0,0 +1,122 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	msqlite "modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+	"todoNote/internal/repo"
+)
+
+const (
+	users = "users:"
+	notes = "notes:"
+	mfa = "mfa:"
+	identities = "identities:"
+	loginAttempts = "login_attempts:"
+	sessions = "sessions:"
+	exports = "data_exports:"
+	accountDeletions = "account_deletions:"
+	calendarFeeds = "calendar_feeds:"
+	outbox = "outbox:"
+	organizations = "organizations:"
+	select_sql = "select:"
+	insert = "insert:"
+	delete_sql = "delete_sql:"
+	update = "update:"
+)
+
+var rowsAffectedNotOne = fmt.Errorf("rows affected != 1: %w", repo.ErrNotFound)
+
+func NewUsersError(method string, err error) error {
+	return fmt.Errorf("%v %v %w", users, method, mapError(err))
+}
+
+func NewOutboxError(method string, err error) error {
+	return fmt.Errorf("%v %v %w", outbox, method, mapError(err))
+}
+
+func NewNotesError(method string, err error) error {
+	return fmt.Errorf("%v %v %w", notes, method, mapError(err))
+}
+
+func NewOrganizationsError(method string, err error) error {
+	return fmt.Errorf("%v %v %w", organizations, method, mapError(err))
+}
+
+func NewMfaError(method string, err error) error {
+	return fmt.Errorf("%v %v %w", mfa, method, mapError(err))
+}
+
+func NewIdentitiesError(method string, err error) error {
+	return fmt.Errorf("%v %v %w", identities, method, mapError(err))
+}
+
+func NewLoginAttemptsError(method string, err error) error {
+	return fmt.Errorf("%v %v %w", loginAttempts, method, mapError(err))
+}
+
+func NewSessionsError(method string, err error) error {
+	return fmt.Errorf("%v %v %w", sessions, method, mapError(err))
+}
+
+func NewExportsError(method string, err error) error {
+	return fmt.Errorf("%v %v %w", exports, method, mapError(err))
+}
+
+func NewAccountDeletionsError(method string, err error) error {
+	return fmt.Errorf("%v %v %w", accountDeletions, method, mapError(err))
+}
+
+func NewCalendarFeedsError(method string, err error) error {
+	return fmt.Errorf("%v %v %w", calendarFeeds, method, mapError(err))
+}
+
+// sqliteError keeps the error of the driver for errors.As and matches the repo error of its kind for errors.Is
+type sqliteError struct {
+	kind error
+	err error
+}
+
+func (e sqliteError) Error() string {
+	return fmt.Sprintf("%v: %v", e.kind, e.err)
+}
+
+func (e sqliteError) Unwrap() error {
+	return e.err
+}
+
+func (e sqliteError) Is(target error) bool {
+	return target == e.kind
+}
+
+// mapError wraps errors of the driver with the repo error they stand for, others are returned as is
+func mapError(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return sqliteError{kind: repo.ErrNotFound, err: err}
+	}
+
+	var sqliteErr *msqlite.Error
+	if errors.As(err, &sqliteErr) {
+		switch sqliteErr.Code() {
+		case sqlite3.SQLITE_CONSTRAINT_UNIQUE, sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY:
+			return sqliteError{kind: repo.ErrConflict, err: err}
+		case sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY:
+			return sqliteError{kind: repo.ErrForeignKey, err: err}
+		}
+
+		// the primary code is the low byte of an extended one
+		switch sqliteErr.Code() & 0xff {
+		case sqlite3.SQLITE_BUSY, sqlite3.SQLITE_LOCKED:
+			return sqliteError{kind: repo.ErrTimeout, err: err}
+		}
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return sqliteError{kind: repo.ErrTimeout, err: err}
+	}
+
+	return err
+}
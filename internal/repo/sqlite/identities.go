@@ -0,0 +1,49 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"todoNote/internal/model"
+	"todoNote/internal/repo"
+)
+
+var _ repo.IRepoIdentity = &RepoIdentity{}
+
+type RepoIdentity struct {
+	conn *sql.DB
+}
+
+func NewRepoIdentity(conn *sql.DB) repo.IRepoIdentity {
+	return &RepoIdentity{
+		conn: conn,
+	}
+}
+
+func (r *RepoIdentity) Insert(ctx context.Context, i *model.UserIdentity) error {
+	query := `INSERT INTO user_identities (provider, subject, user_id) VALUES (?, ?, ?);`
+	if _, err := db(ctx, r.conn).ExecContext(ctx, query, i.Provider, i.Subject, i.UserId); err != nil {
+		return NewIdentitiesError(insert, err)
+	}
+
+	return nil
+}
+
+func (r *RepoIdentity) GetByProviderSubject(ctx context.Context, provider, subject string) (*model.UserIdentity, error) {
+	query := `SELECT provider, subject, user_id FROM user_identities WHERE provider = ? AND subject = ?;`
+	var i model.UserIdentity
+	err := db(ctx, r.conn).QueryRowContext(ctx, query, provider, subject).Scan(
+		&i.Provider,
+		&i.Subject,
+		&i.UserId)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, repo.NewNotFoundError(provider + ":" + subject)
+		}
+
+		return nil, NewIdentitiesError(select_sql, err)
+	}
+
+	return &i, nil
+}
@@ -0,0 +1,92 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+	"todoNote/internal/model"
+	"todoNote/internal/repo"
+)
+
+var _ repo.IRepoLoginAttempts = &RepoLoginAttempts{}
+
+type RepoLoginAttempts struct {
+	conn *sql.DB
+}
+
+func NewRepoLoginAttempts(conn *sql.DB) repo.IRepoLoginAttempts {
+	return &RepoLoginAttempts{
+		conn: conn,
+	}
+}
+
+func (r *RepoLoginAttempts) Get(ctx context.Context, key string) (model.LoginAttempts, error) {
+	query := `SELECT key, failures, last_failure, locked_until FROM login_attempts WHERE key = ?;`
+	var a model.LoginAttempts
+	var lastFailure, lockedUntil string
+	err := db(ctx, r.conn).QueryRowContext(ctx, query, key).Scan(
+		&a.Key,
+		&a.Failures,
+		&lastFailure,
+		&lockedUntil)
+	if err == nil {
+		a.LastFailure, err = parseTimestamp(lastFailure)
+	}
+	if err == nil {
+		a.LockedUntil, err = parseTimestamp(lockedUntil)
+	}
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.LoginAttempts{Key: key}, nil
+		}
+
+		return model.LoginAttempts{}, NewLoginAttemptsError(select_sql, err)
+	}
+
+	return a, nil
+}
+
+func (r *RepoLoginAttempts) AddFailure(ctx context.Context, key string, now time.Time, window time.Duration) (int, error) {
+	query := `
+INSERT INTO login_attempts (key, failures, last_failure, locked_until)
+VALUES (?, 1, ?, ?)
+ON CONFLICT (key) DO UPDATE
+SET failures = CASE WHEN login_attempts.last_failure < ? THEN 1 ELSE login_attempts.failures + 1 END,
+last_failure = excluded.last_failure
+RETURNING failures;`
+
+	var failures int
+	err := db(ctx, r.conn).QueryRowContext(ctx,
+		query,
+		key,
+		timestamp(now),
+		timestamp(time.Time{}),
+		timestamp(now.Add(-window))).
+		Scan(&failures)
+
+	if err != nil {
+		return 0, NewLoginAttemptsError(insert, err)
+	}
+
+	return failures, nil
+}
+
+func (r *RepoLoginAttempts) LockUntil(ctx context.Context, key string, until time.Time) error {
+	query := `UPDATE login_attempts SET locked_until = max(locked_until, ?) WHERE key = ?;`
+	if _, err := db(ctx, r.conn).ExecContext(ctx, query, timestamp(until), key); err != nil {
+		return NewLoginAttemptsError(update, err)
+	}
+
+	return nil
+}
+
+func (r *RepoLoginAttempts) Delete(ctx context.Context, key string) error {
+	query := `DELETE FROM login_attempts WHERE key = ?;`
+	if _, err := db(ctx, r.conn).ExecContext(ctx, query, key); err != nil {
+		return NewLoginAttemptsError(delete_sql, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,123 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"todoNote/internal/model"
+	"todoNote/internal/repo"
+)
+
+var _ repo.IRepoMfa = &RepoMfa{}
+
+type RepoMfa struct {
+	conn *sql.DB
+}
+
+func NewRepoMfa(conn *sql.DB) repo.IRepoMfa {
+	return &RepoMfa{
+		conn: conn,
+	}
+}
+
+func (r *RepoMfa) Upsert(ctx context.Context, m *model.Mfa) error {
+	query := `
+INSERT INTO user_mfa (user_id, secret, is_enabled, recovery_codes, last_step)
+VALUES (?, ?, ?, ?, ?)
+ON CONFLICT (user_id) DO UPDATE
+SET secret = excluded.secret, is_enabled = excluded.is_enabled, recovery_codes = excluded.recovery_codes, last_step = excluded.last_step;`
+
+	codes := m.RecoveryCodes
+	if codes == nil {
+		codes = [][]byte{}
+	}
+
+	encoded, err := json.Marshal(codes)
+	if err != nil {
+		return NewMfaError(insert, err)
+	}
+
+	_, err = db(ctx, r.conn).ExecContext(ctx,
+		query,
+		m.UserId,
+		m.Secret,
+		m.IsEnabled,
+		string(encoded),
+		m.LastStep)
+
+	if err != nil {
+		return NewMfaError(insert, err)
+	}
+
+	return nil
+}
+
+func (r *RepoMfa) GetByUserId(ctx context.Context, uId model.Id) (*model.Mfa, error) {
+	query := `SELECT user_id, secret, is_enabled, recovery_codes, last_step FROM user_mfa WHERE user_id = ?;`
+	var m model.Mfa
+	var codes string
+	err := db(ctx, r.conn).QueryRowContext(ctx, query, uId).Scan(
+		&m.UserId,
+		&m.Secret,
+		&m.IsEnabled,
+		&codes,
+		&m.LastStep)
+	if err == nil {
+		err = json.Unmarshal([]byte(codes), &m.RecoveryCodes)
+	}
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, repo.NewNotFoundError(uId)
+		}
+
+		return nil, NewMfaError(select_sql, err)
+	}
+
+	return &m, nil
+}
+
+func (r *RepoMfa) Delete(ctx context.Context, uId model.Id) error {
+	query := `DELETE FROM user_mfa WHERE user_id = ?;`
+	if _, err := db(ctx, r.conn).ExecContext(ctx, query, uId); err != nil {
+		return NewMfaError(delete_sql, err)
+	}
+
+	return nil
+}
+
+func (r *RepoMfa) UseStep(ctx context.Context, uId model.Id, step int64) (bool, error) {
+	query := `UPDATE user_mfa SET last_step = ? WHERE user_id = ? AND last_step < ?;`
+	res, err := db(ctx, r.conn).ExecContext(ctx, query, step, uId, step)
+	if err != nil {
+		return false, NewMfaError(update, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, NewMfaError(update, err)
+	}
+
+	return n == 1, nil
+}
+
+// UseRecoveryCode matches the hash in its JSON form, a base64 string
+func (r *RepoMfa) UseRecoveryCode(ctx context.Context, uId model.Id, hash []byte) (bool, error) {
+	query := `
+UPDATE user_mfa
+SET recovery_codes = (SELECT json_group_array(value) FROM json_each(user_mfa.recovery_codes) WHERE value <> ?1)
+WHERE user_id = ?2 AND EXISTS (SELECT 1 FROM json_each(user_mfa.recovery_codes) WHERE value = ?1);`
+	res, err := db(ctx, r.conn).ExecContext(ctx, query, base64.StdEncoding.EncodeToString(hash), uId)
+	if err != nil {
+		return false, NewMfaError(update, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, NewMfaError(update, err)
+	}
+
+	return n == 1, nil
+}
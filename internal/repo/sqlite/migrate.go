@@ -0,0 +1,75 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"sort"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrate applies migrations newer than the user_version of the database in one transaction,
+// the version of a migration is its place among the files ordered by name.
+// Foreign keys are off while they run so a migration can rebuild a table that others reference,
+// they are checked before the transaction commits
+func migrate(ctx context.Context, db *sql.DB) error {
+	names, err := fs.Glob(migrationFiles, "migrations/*.sql")
+	if err != nil {
+		return err
+	}
+	sort.Strings(names)
+
+	// the pragma is a setting of the connection and can not be changed inside a transaction
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("migrate: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `PRAGMA foreign_keys = OFF;`); err != nil {
+		return fmt.Errorf("migrate: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), `PRAGMA foreign_keys = ON;`)
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("migrate: %w", err)
+	}
+	defer tx.Rollback()
+
+	var version int
+	if err := tx.QueryRowContext(ctx, `PRAGMA user_version;`).Scan(&version); err != nil {
+		return fmt.Errorf("migrate: %w", err)
+	}
+	if version > len(names) {
+		return fmt.Errorf("migrate: database version %v is newer than %v known migrations", version, len(names))
+	}
+
+	for i, name := range names[version:] {
+		b, err := migrationFiles.ReadFile(name)
+		if err != nil {
+			return fmt.Errorf("migrate: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, string(b)); err != nil {
+			return fmt.Errorf("migrate %v: %w", name, err)
+		}
+		// PRAGMA takes no parameters
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(`PRAGMA user_version = %d;`, version + i + 1)); err != nil {
+			return fmt.Errorf("migrate %v: %w", name, err)
+		}
+	}
+
+	var violations int
+	if err := tx.QueryRowContext(ctx, `SELECT count(*) FROM pragma_foreign_key_check;`).Scan(&violations); err != nil {
+		return fmt.Errorf("migrate: %w", err)
+	}
+	if violations > 0 {
+		return fmt.Errorf("migrate: %v rows violate foreign keys", violations)
+	}
+
+	return tx.Commit()
+}
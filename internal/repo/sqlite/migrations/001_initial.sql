@@ -0,0 +1,50 @@
+CREATE TABLE users (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE,
+    time_zone TEXT NOT NULL DEFAULT 'UTC',
+    password_hash BLOB NOT NULL,
+    display_name TEXT NOT NULL DEFAULT '',
+    locale TEXT NOT NULL DEFAULT 'en',
+    -- 0 is sunday
+    week_start INTEGER NOT NULL DEFAULT 1,
+    reminder_offset_minutes INTEGER NOT NULL DEFAULT 0,
+    date_format TEXT NOT NULL DEFAULT 'YYYY-MM-DD'
+);
+
+-- dates are wall-clock TEXT of a fixed width, so they compare in time order
+CREATE TABLE notes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    title TEXT NOT NULL DEFAULT '',
+    text TEXT NOT NULL DEFAULT '',
+    date TEXT NOT NULL,
+    is_finished INTEGER NOT NULL DEFAULT 0,
+    time_zone TEXT NOT NULL DEFAULT '',
+    is_floating INTEGER NOT NULL DEFAULT 0,
+    reminder_offset_minutes INTEGER NOT NULL DEFAULT 0,
+    uid TEXT NOT NULL DEFAULT '',
+    priority TEXT NOT NULL DEFAULT '',
+    -- json arrays of tags
+    projects TEXT NOT NULL DEFAULT '[]',
+    contexts TEXT NOT NULL DEFAULT '[]',
+    finished_at TEXT,
+    version INTEGER NOT NULL
+);
+
+CREATE UNIQUE INDEX notes_user_id_uid_idx ON notes (user_id, uid) WHERE uid <> '';
+CREATE INDEX notes_user_id_date_idx ON notes (user_id, date);
+CREATE INDEX notes_user_id_version_idx ON notes (user_id, version);
+
+CREATE TABLE note_versions (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL
+);
+
+CREATE TABLE note_tombstones (
+    note_id INTEGER PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    deleted_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now'))
+);
+
+CREATE INDEX note_tombstones_user_id_version_idx ON note_tombstones (user_id, version);
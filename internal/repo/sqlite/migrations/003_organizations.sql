@@ -0,0 +1,36 @@
+-- every user and note belongs to an organization, those created before belong to the default one
+CREATE TABLE organizations (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE,
+    -- the wall clock with microseconds like the repositories write times
+    created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f000', 'now'))
+);
+
+INSERT INTO organizations (id, name) VALUES (1, 'default');
+
+-- names become unique within an organization, SQLite changes constraints by rebuilding the table
+CREATE TABLE users_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    organization_id INTEGER NOT NULL REFERENCES organizations(id),
+    name TEXT NOT NULL,
+    time_zone TEXT NOT NULL DEFAULT 'UTC',
+    password_hash BLOB NOT NULL,
+    display_name TEXT NOT NULL DEFAULT '',
+    locale TEXT NOT NULL DEFAULT 'en',
+    -- 0 is sunday
+    week_start INTEGER NOT NULL DEFAULT 1,
+    reminder_offset_minutes INTEGER NOT NULL DEFAULT 0,
+    date_format TEXT NOT NULL DEFAULT 'YYYY-MM-DD',
+    UNIQUE (organization_id, name)
+);
+
+INSERT INTO users_new (id, organization_id, name, time_zone, password_hash, display_name, locale, week_start,
+    reminder_offset_minutes, date_format)
+SELECT id, 1, name, time_zone, password_hash, display_name, locale, week_start, reminder_offset_minutes, date_format
+FROM users;
+
+DROP TABLE users;
+ALTER TABLE users_new RENAME TO users;
+
+-- notes are kept in the organization of their user by the repository
+ALTER TABLE notes ADD COLUMN organization_id INTEGER NOT NULL DEFAULT 1 REFERENCES organizations(id);
@@ -0,0 +1,2 @@
+-- the time step of the last accepted totp code, codes of it and of earlier steps are refused
+ALTER TABLE user_mfa ADD COLUMN last_step INTEGER NOT NULL DEFAULT 0;
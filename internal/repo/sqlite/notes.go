@@ -0,0 +1,442 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+	"todoNote/internal/model"
+	"todoNote/internal/repo"
+)
+
+var _ repo.IRepoNote = RepoNote{}
+type RepoNote struct {
+	conn *sql.DB
+	tx repo.ITransactor
+}
+
+func NewRepoNote(c *sql.DB) *RepoNote {
+	return &RepoNote{conn: c, tx: NewTransactor(c)}
+}
+
+func (r RepoNote) Insert(ctx context.Context, n *model.Note) (model.Id, error) {
+	query := `
+INSERT INTO notes (user_id, title, text, date, is_finished, time_zone, is_floating, reminder_offset_minutes, uid,
+priority, projects, contexts, finished_at, version, organization_id)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING id;`
+
+	var id model.Id
+	organizationId := repo.InsertTenant(ctx)
+	err := r.tx.InTransaction(ctx, func(ctx context.Context) error {
+		version, err := nextVersion(ctx, r.conn, n.UserId)
+		if err != nil {
+			return err
+		}
+
+		err = db(ctx, r.conn).QueryRowContext(ctx,
+			query,
+			n.UserId,
+			n.Title,
+			n.Text,
+			timestamp(n.Date),
+			n.IsFinished,
+			n.TimeZone,
+			n.IsFloating,
+			minutes(n.ReminderOffset),
+			n.Uid,
+			n.Priority,
+			tags(n.Projects),
+			tags(n.Contexts),
+			nullTimestamp(n.FinishedAt),
+			version,
+			organizationId).
+			Scan(&id)
+		if err != nil {
+			return err
+		}
+
+		n.Version = version
+		n.OrganizationId = organizationId
+		return nil
+	})
+
+	if err != nil {
+		return 0, NewNotesError(insert, err)
+	}
+
+	return id, nil
+}
+
+func (r RepoNote) GetById(ctx context.Context, noteId model.Id) (model.Note, error) {
+	query := `SELECT ` + noteColumns + ` FROM notes WHERE id = ? AND ` + inTenant + `;`
+	note, err := scanNote(db(ctx, r.conn).QueryRowContext(ctx, query, noteId, tenantArg(ctx)))
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.Note{}, repo.NewNotFoundError(noteId)
+		}
+
+		return model.Note{}, NewNotesError(select_sql, err)
+	}
+
+	return note, nil
+}
+
+// GetByIdForUpdate is GetById, the transaction of ctx holds the write lock of the whole database
+func (r RepoNote) GetByIdForUpdate(ctx context.Context, noteId model.Id) (model.Note, error) {
+	return r.GetById(ctx, noteId)
+}
+
+// GetByUidForUpdate is GetByUid, the transaction of ctx holds the write lock of the whole database
+func (r RepoNote) GetByUidForUpdate(ctx context.Context, userId model.Id, uid string) (model.Note, error) {
+	return r.GetByUid(ctx, userId, uid)
+}
+
+func (r RepoNote) GetByUid(ctx context.Context, userId model.Id, uid string) (model.Note, error) {
+	query := `SELECT ` + noteColumns + ` FROM notes WHERE user_id = ? AND uid = ? AND ` + inTenant + `;`
+	note, err := scanNote(db(ctx, r.conn).QueryRowContext(ctx, query, userId, uid, tenantArg(ctx)))
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.Note{}, repo.NewNotFoundError(0)
+		}
+
+		return model.Note{}, NewNotesError(select_sql, err)
+	}
+
+	return note, nil
+}
+
+func (r RepoNote) GetAllOffset(ctx context.Context, filter repo.NoteFilter) ([]model.Note, error) {
+	if filter.Page.Limit == nil {
+		limit := uint64(1000)
+		filter.Page.Limit = &limit
+	}
+
+	res := make([]model.Note, 0)
+	err := r.Each(ctx, filter, func(n model.Note) error {
+		res = append(res, n)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (r RepoNote) Each(ctx context.Context, filter repo.NoteFilter, fn func(n model.Note) error) error {
+	// NULL parameters do not filter, LIMIT -1 is no limit.
+	// The span of ?4 and ?5 lets the index serve the range, the dates of each kind of notes are bounded after it
+	q := `SELECT ` + noteColumns + ` FROM notes
+WHERE user_id = ?1
+AND organization_id = coalesce(?7, organization_id)
+AND (?3 IS NULL OR is_finished = ?3)
+AND (?4 IS NULL OR date >= ?4)
+AND (?5 IS NULL OR date < ?5)
+AND (is_floating OR ?8 IS NULL OR date >= ?8)
+AND (is_floating OR ?9 IS NULL OR date < ?9)
+AND (NOT is_floating OR ?10 IS NULL OR date >= ?10)
+AND (NOT is_floating OR ?11 IS NULL OR date < ?11)
+ORDER BY date, id
+LIMIT ?6 OFFSET ?2;`
+
+	var offset uint64
+	var isFinished interface{}
+	limit := int64(-1)
+
+	if filter.IsFinished != nil {
+		isFinished = *filter.IsFinished
+	}
+	from, to := filter.Span()
+	fixedFrom, fixedTo := filter.Bounds(false)
+	floatingFrom, floatingTo := filter.Bounds(true)
+	if filter.Page.Offset != nil {
+		offset = *filter.Page.Offset
+	}
+	if filter.Page.Limit != nil {
+		limit = int64(*filter.Page.Limit)
+	}
+
+	rows, err := db(ctx, r.conn).QueryContext(ctx,
+		q,
+		filter.UserId,
+		int64(offset),
+		isFinished,
+		nullTimestamp(from),
+		nullTimestamp(to),
+		limit,
+		tenantArg(ctx),
+		nullTimestamp(fixedFrom),
+		nullTimestamp(fixedTo),
+		nullTimestamp(floatingFrom),
+		nullTimestamp(floatingTo))
+
+	if err != nil {
+		return NewNotesError(select_sql, err)
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		row, err := scanNote(rows)
+		if err != nil {
+			return NewNotesError(select_sql, err)
+		}
+
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return NewNotesError(select_sql, err)
+	}
+
+	return nil
+}
+
+func (r RepoNote) Update(ctx context.Context, n *model.Note) error {
+	query := `
+UPDATE notes SET title = ?, text = ?, date = ?, is_finished = ?, time_zone = ?, is_floating = ?,
+reminder_offset_minutes = ?, uid = ?, priority = ?, projects = ?, contexts = ?, finished_at = ?, version = ?
+WHERE id = ?;`
+
+	err := r.tx.InTransaction(ctx, func(ctx context.Context) error {
+		userId, organizationId, err := r.owner(ctx, n.Id)
+		if err != nil {
+			return err
+		}
+
+		version, err := nextVersion(ctx, r.conn, userId)
+		if err != nil {
+			return err
+		}
+
+		_, err = db(ctx, r.conn).ExecContext(ctx,
+			query,
+			n.Title,
+			n.Text,
+			timestamp(n.Date),
+			n.IsFinished,
+			n.TimeZone,
+			n.IsFloating,
+			minutes(n.ReminderOffset),
+			n.Uid,
+			n.Priority,
+			tags(n.Projects),
+			tags(n.Contexts),
+			nullTimestamp(n.FinishedAt),
+			version,
+			n.Id)
+		if err != nil {
+			return err
+		}
+
+		n.Version = version
+		n.OrganizationId = organizationId
+		return nil
+	})
+
+	if err != nil {
+		return NewNotesError(update, err)
+	}
+
+	return nil
+}
+
+func (r RepoNote) Delete(ctx context.Context, noteId model.Id) error {
+	// the tombstone tells syncing clients about the deletion
+	query := `
+INSERT INTO note_tombstones (note_id, user_id, version) VALUES (?, ?, ?)
+ON CONFLICT (note_id) DO UPDATE SET version = excluded.version;`
+
+	err := r.tx.InTransaction(ctx, func(ctx context.Context) error {
+		userId, _, err := r.owner(ctx, noteId)
+		if err != nil {
+			return err
+		}
+
+		version, err := nextVersion(ctx, r.conn, userId)
+		if err != nil {
+			return err
+		}
+
+		if _, err := db(ctx, r.conn).ExecContext(ctx, `DELETE FROM notes WHERE id = ?;`, noteId); err != nil {
+			return err
+		}
+
+		_, err = db(ctx, r.conn).ExecContext(ctx, query, noteId, userId, version)
+		return err
+	})
+
+	if err != nil {
+		return NewNotesError(delete_sql, err)
+	}
+
+	return nil
+}
+
+func (r RepoNote) Changes(ctx context.Context, userId model.Id, since int64, limit uint64) ([]model.NoteChange, error) {
+	changes := make([]model.NoteChange, 0)
+
+	q := `SELECT ` + noteColumns + ` FROM notes WHERE user_id = ? AND version > ? AND ` + inTenant + `
+ORDER BY version LIMIT ?;`
+	rows, err := db(ctx, r.conn).QueryContext(ctx, q, userId, since, tenantArg(ctx), int64(limit))
+	if err != nil {
+		return nil, NewNotesError(select_sql, err)
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		n, err := scanNote(rows)
+		if err != nil {
+			return nil, NewNotesError(select_sql, err)
+		}
+		changes = append(changes, model.NoteChange{Version: n.Version, NoteId: n.Id, Note: &n})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, NewNotesError(select_sql, err)
+	}
+
+	// tombstones are in the organization of their user
+	q = `SELECT note_id, version FROM note_tombstones WHERE user_id = ? AND version > ?
+AND user_id IN (SELECT id FROM users WHERE ` + inTenant + `) ORDER BY version LIMIT ?;`
+	tombstones, err := db(ctx, r.conn).QueryContext(ctx, q, userId, since, tenantArg(ctx), int64(limit))
+	if err != nil {
+		return nil, NewNotesError(select_sql, err)
+	}
+
+	defer tombstones.Close()
+	for tombstones.Next() {
+		c := model.NoteChange{Deleted: true}
+		if err := tombstones.Scan(&c.NoteId, &c.Version); err != nil {
+			return nil, NewNotesError(select_sql, err)
+		}
+		changes = append(changes, c)
+	}
+	if err := tombstones.Err(); err != nil {
+		return nil, NewNotesError(select_sql, err)
+	}
+
+	// both lists are ordered, the first limit changes of their union are all taken
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Version < changes[j].Version })
+	if uint64(len(changes)) > limit {
+		changes = changes[:limit]
+	}
+
+	return changes, nil
+}
+
+// owner is the user and the organization of the note in the tenant of ctx,
+// a missing note is rowsAffectedNotOne like in the postgres repository
+func (r RepoNote) owner(ctx context.Context, noteId model.Id) (model.Id, model.Id, error) {
+	var userId, organizationId model.Id
+	query := `SELECT user_id, organization_id FROM notes WHERE id = ? AND ` + inTenant + `;`
+	err := db(ctx, r.conn).QueryRowContext(ctx, query, noteId, tenantArg(ctx)).Scan(&userId, &organizationId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, 0, rowsAffectedNotOne
+	}
+
+	return userId, organizationId, err
+}
+
+// nextVersion takes the next change version of the user, it has to run in the transaction of the change
+func nextVersion(ctx context.Context, conn *sql.DB, userId model.Id) (int64, error) {
+	query := `
+INSERT INTO note_versions (user_id, version) VALUES (?, 1)
+ON CONFLICT (user_id) DO UPDATE SET version = note_versions.version + 1 RETURNING version;`
+
+	var version int64
+	err := db(ctx, conn).QueryRowContext(ctx, query, userId).Scan(&version)
+	return version, err
+}
+
+const noteColumns = `id, user_id, title, text, date, is_finished, time_zone, is_floating, reminder_offset_minutes, uid,
+priority, projects, contexts, finished_at, version, organization_id`
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanNote(row scanner) (model.Note, error) {
+	var note model.Note
+	var reminderOffset int
+	var date, projects, contexts string
+	var finishedAt sql.NullString
+	err := row.Scan(
+		&note.Id,
+		&note.UserId,
+		&note.Title,
+		&note.Text,
+		&date,
+		&note.IsFinished,
+		&note.TimeZone,
+		&note.IsFloating,
+		&reminderOffset,
+		&note.Uid,
+		&note.Priority,
+		&projects,
+		&contexts,
+		&finishedAt,
+		&note.Version,
+		&note.OrganizationId)
+	if err != nil {
+		return model.Note{}, err
+	}
+
+	note.ReminderOffset = time.Duration(reminderOffset) * time.Minute
+	if note.Date, err = parseTimestamp(date); err != nil {
+		return model.Note{}, err
+	}
+	if finishedAt.Valid {
+		t, err := parseTimestamp(finishedAt.String)
+		if err != nil {
+			return model.Note{}, err
+		}
+		note.FinishedAt = &t
+	}
+	if err := json.Unmarshal([]byte(projects), &note.Projects); err != nil {
+		return model.Note{}, err
+	}
+	if err := json.Unmarshal([]byte(contexts), &note.Contexts); err != nil {
+		return model.Note{}, err
+	}
+
+	return note, nil
+}
+
+// tags are stored as a json array, never null
+func tags(t []string) string {
+	if t == nil {
+		t = []string{}
+	}
+
+	b, _ := json.Marshal(t)
+	return string(b)
+}
+
+// timeLayout has the microseconds of PostgreSQL and a fixed width, so stored times compare as text
+const timeLayout = "2006-01-02 15:04:05.000000"
+
+// timestamp is the wall clock of t, like a TIMESTAMP column of PostgreSQL keeps it
+func timestamp(t time.Time) string {
+	return t.Format(timeLayout)
+}
+
+func nullTimestamp(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+
+	return timestamp(*t)
+}
+
+func parseTimestamp(s string) (time.Time, error) {
+	return time.ParseInLocation(timeLayout, s, time.UTC)
+}
+
+func minutes(d time.Duration) int {
+	return int(d / time.Minute)
+}
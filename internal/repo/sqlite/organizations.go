@@ -0,0 +1,58 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"todoNote/internal/model"
+	"todoNote/internal/repo"
+)
+
+var _ repo.IRepoOrganization = &RepoOrganization{}
+
+type RepoOrganization struct {
+	conn *sql.DB
+}
+
+func NewRepoOrganization(conn *sql.DB) repo.IRepoOrganization {
+	return &RepoOrganization{
+		conn: conn,
+	}
+}
+
+func (r *RepoOrganization) Insert(ctx context.Context, o *model.Organization) (model.Id, error) {
+	query := `INSERT INTO organizations (name, created_at) VALUES (?, ?) RETURNING id;`
+	var id model.Id
+	if err := db(ctx, r.conn).QueryRowContext(ctx, query, o.Name, timestamp(o.CreatedAt)).Scan(&id); err != nil {
+		return 0, NewOrganizationsError(insert, err)
+	}
+
+	return id, nil
+}
+
+func (r *RepoOrganization) GetById(ctx context.Context, id model.Id) (model.Organization, error) {
+	return r.get(ctx, `SELECT id, name, created_at FROM organizations WHERE id = ?;`, id)
+}
+
+func (r *RepoOrganization) GetByName(ctx context.Context, name string) (model.Organization, error) {
+	return r.get(ctx, `SELECT id, name, created_at FROM organizations WHERE name = ?;`, name)
+}
+
+func (r *RepoOrganization) get(ctx context.Context, query string, key interface{}) (model.Organization, error) {
+	var o model.Organization
+	var createdAt string
+	err := db(ctx, r.conn).QueryRowContext(ctx, query, key).Scan(&o.Id, &o.Name, &createdAt)
+	if err == nil {
+		o.CreatedAt, err = parseTimestamp(createdAt)
+	}
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.Organization{}, repo.NewNotFoundError(key)
+		}
+
+		return model.Organization{}, NewOrganizationsError(select_sql, err)
+	}
+
+	return o, nil
+}
@@ -0,0 +1,150 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"time"
+	"todoNote/internal/model"
+	"todoNote/internal/repo"
+)
+
+var _ repo.IRepoOutbox = &RepoOutbox{}
+
+type RepoOutbox struct {
+	conn *sql.DB
+	tx repo.ITransactor
+}
+
+func NewRepoOutbox(conn *sql.DB) repo.IRepoOutbox {
+	return &RepoOutbox{conn: conn, tx: NewTransactor(conn)}
+}
+
+func (r *RepoOutbox) Insert(ctx context.Context, e *model.Event) (model.Id, error) {
+	query := `
+INSERT INTO outbox (type, user_id, subject_id, payload, created_at, attempts, next_attempt_at, last_error)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?) RETURNING id;`
+
+	var id model.Id
+	err := db(ctx, r.conn).QueryRowContext(ctx,
+		query,
+		e.Type,
+		e.UserId,
+		e.SubjectId,
+		string(e.Payload),
+		timestamp(e.CreatedAt.UTC()),
+		e.Attempts,
+		timestamp(e.NextAttemptAt.UTC()),
+		e.LastError).
+		Scan(&id)
+
+	if err != nil {
+		return 0, NewOutboxError(insert, err)
+	}
+
+	return id, nil
+}
+
+// Claim selects and postpones the events in one transaction, it holds the write lock of the database
+func (r *RepoOutbox) Claim(ctx context.Context, now time.Time, lease time.Duration, limit uint64) ([]model.Event, error) {
+	query := `
+SELECT id, type, user_id, subject_id, payload, created_at, attempts, next_attempt_at, last_error FROM outbox
+WHERE next_attempt_at <= ? ORDER BY next_attempt_at, id LIMIT ?;`
+
+	res := make([]model.Event, 0)
+	err := r.tx.InTransaction(ctx, func(ctx context.Context) error {
+		rows, err := db(ctx, r.conn).QueryContext(ctx, query, timestamp(now.UTC()), int64(limit))
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			e, err := scanEvent(rows)
+			if err != nil {
+				return err
+			}
+			res = append(res, e)
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		next := now.Add(lease).UTC()
+		for i := range res {
+			_, err := db(ctx, r.conn).ExecContext(ctx, `UPDATE outbox SET next_attempt_at = ? WHERE id = ?;`, timestamp(next), res[i].Id)
+			if err != nil {
+				return err
+			}
+			res[i].NextAttemptAt = next
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, NewOutboxError(update, err)
+	}
+
+	return res, nil
+}
+
+func (r *RepoOutbox) Delete(ctx context.Context, id model.Id) error {
+	res, err := db(ctx, r.conn).ExecContext(ctx, `DELETE FROM outbox WHERE id = ?;`, id)
+	if err != nil {
+		return NewOutboxError(delete_sql, err)
+	}
+
+	return r.affectedOne(res, delete_sql)
+}
+
+func (r *RepoOutbox) Retry(ctx context.Context, id model.Id, next time.Time, lastError string) error {
+	query := `UPDATE outbox SET attempts = attempts + 1, next_attempt_at = ?, last_error = ? WHERE id = ?;`
+
+	res, err := db(ctx, r.conn).ExecContext(ctx, query, timestamp(next.UTC()), lastError, id)
+	if err != nil {
+		return NewOutboxError(update, err)
+	}
+
+	return r.affectedOne(res, update)
+}
+
+func (r *RepoOutbox) affectedOne(res sql.Result, method string) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return NewOutboxError(method, err)
+	}
+
+	if n != 1 {
+		return NewOutboxError(method, rowsAffectedNotOne)
+	}
+
+	return nil
+}
+
+func scanEvent(row scanner) (model.Event, error) {
+	var e model.Event
+	var payload, createdAt, nextAttemptAt string
+	err := row.Scan(
+		&e.Id,
+		&e.Type,
+		&e.UserId,
+		&e.SubjectId,
+		&payload,
+		&createdAt,
+		&e.Attempts,
+		&nextAttemptAt,
+		&e.LastError)
+	if err != nil {
+		return model.Event{}, err
+	}
+
+	e.Payload = []byte(payload)
+	if e.CreatedAt, err = parseTimestamp(createdAt); err != nil {
+		return model.Event{}, err
+	}
+	if e.NextAttemptAt, err = parseTimestamp(nextAttemptAt); err != nil {
+		return model.Event{}, err
+	}
+
+	return e, nil
+}
@@ -0,0 +1,156 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+	"todoNote/internal/model"
+	"todoNote/internal/repo"
+)
+
+var _ repo.IRepoSession = &RepoSession{}
+
+type RepoSession struct {
+	conn *sql.DB
+}
+
+func NewRepoSession(conn *sql.DB) repo.IRepoSession {
+	return &RepoSession{
+		conn: conn,
+	}
+}
+
+func (r *RepoSession) Insert(ctx context.Context, s *model.Session) (model.Id, error) {
+	query := `
+INSERT INTO sessions (user_id, user_agent, ip, created_at, last_seen_at, expires_at)
+VALUES (?, ?, ?, ?, ?, ?) RETURNING id;`
+
+	var id model.Id
+	err := db(ctx, r.conn).QueryRowContext(ctx,
+		query,
+		s.UserId,
+		s.UserAgent,
+		s.Ip,
+		timestamp(s.CreatedAt),
+		timestamp(s.LastSeenAt),
+		timestamp(s.ExpiresAt)).
+		Scan(&id)
+
+	if err != nil {
+		return 0, NewSessionsError(insert, err)
+	}
+
+	return id, nil
+}
+
+func (r *RepoSession) GetById(ctx context.Context, id model.Id) (model.Session, error) {
+	query := `SELECT ` + sessionColumns + ` FROM sessions WHERE id = ?;`
+	s, err := scanSession(db(ctx, r.conn).QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.Session{}, repo.NewNotFoundError(id)
+		}
+
+		return model.Session{}, NewSessionsError(select_sql, err)
+	}
+
+	return s, nil
+}
+
+func (r *RepoSession) GetAllByUser(ctx context.Context, uId model.Id) ([]model.Session, error) {
+	query := `SELECT ` + sessionColumns + ` FROM sessions WHERE user_id = ? ORDER BY id;`
+	rows, err := db(ctx, r.conn).QueryContext(ctx, query, uId)
+	if err != nil {
+		return nil, NewSessionsError(select_sql, err)
+	}
+	defer rows.Close()
+
+	res := make([]model.Session, 0)
+	for rows.Next() {
+		s, err := scanSession(rows)
+		if err != nil {
+			return nil, NewSessionsError(select_sql, err)
+		}
+
+		res = append(res, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, NewSessionsError(select_sql, err)
+	}
+
+	return res, nil
+}
+
+func (r *RepoSession) Touch(ctx context.Context, id model.Id, at time.Time) error {
+	query := `UPDATE sessions SET last_seen_at = ? WHERE id = ?;`
+	res, err := db(ctx, r.conn).ExecContext(ctx, query, timestamp(at), id)
+	if err != nil {
+		return NewSessionsError(update, err)
+	}
+
+	return r.affectedOne(res, update)
+}
+
+func (r *RepoSession) Delete(ctx context.Context, id model.Id) error {
+	query := `DELETE FROM sessions WHERE id = ?;`
+	res, err := db(ctx, r.conn).ExecContext(ctx, query, id)
+	if err != nil {
+		return NewSessionsError(delete_sql, err)
+	}
+
+	return r.affectedOne(res, delete_sql)
+}
+
+func (r *RepoSession) DeleteExpired(ctx context.Context, before time.Time) error {
+	query := `DELETE FROM sessions WHERE expires_at < ?;`
+	if _, err := db(ctx, r.conn).ExecContext(ctx, query, timestamp(before)); err != nil {
+		return NewSessionsError(delete_sql, err)
+	}
+
+	return nil
+}
+
+func (r *RepoSession) affectedOne(res sql.Result, method string) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return NewSessionsError(method, err)
+	}
+
+	if n != 1 {
+		return NewSessionsError(method, rowsAffectedNotOne)
+	}
+
+	return nil
+}
+
+const sessionColumns = `id, user_id, user_agent, ip, created_at, last_seen_at, expires_at`
+
+func scanSession(row scanner) (model.Session, error) {
+	var s model.Session
+	var createdAt, lastSeenAt, expiresAt string
+	err := row.Scan(
+		&s.Id,
+		&s.UserId,
+		&s.UserAgent,
+		&s.Ip,
+		&createdAt,
+		&lastSeenAt,
+		&expiresAt)
+	if err != nil {
+		return model.Session{}, err
+	}
+
+	if s.CreatedAt, err = parseTimestamp(createdAt); err != nil {
+		return model.Session{}, err
+	}
+	if s.LastSeenAt, err = parseTimestamp(lastSeenAt); err != nil {
+		return model.Session{}, err
+	}
+	if s.ExpiresAt, err = parseTimestamp(expiresAt); err != nil {
+		return model.Session{}, err
+	}
+
+	return s, nil
+}
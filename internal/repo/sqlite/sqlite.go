@@ -0,0 +1,50 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	_ "modernc.org/sqlite"
+)
+
+const (
+	pathEnv = "SQLITE_PATH"
+	defaultPath = "todoNote.db"
+	// busyTimeoutMillis is how long a statement waits for the lock of another connection
+	busyTimeoutMillis = 5000
+)
+
+// Connect opens the database file of SQLITE_PATH, todoNote.db by default
+func Connect(ctx context.Context) (*sql.DB, error) {
+	path := os.Getenv(pathEnv)
+	if path == "" {
+		path = defaultPath
+	}
+
+	return Open(ctx, path)
+}
+
+// Open opens the database file at path and applies pending migrations.
+// Transactions take the write lock when they begin, so one of them changes the database at a time
+// and reads inside it are as good as locked
+func Open(ctx context.Context, path string) (*sql.DB, error) {
+	q := url.Values{}
+	q.Add("_pragma", "foreign_keys(1)")
+	q.Add("_pragma", fmt.Sprintf("busy_timeout(%d)", busyTimeoutMillis))
+	q.Add("_pragma", "journal_mode(WAL)")
+	q.Add("_txlock", "immediate")
+
+	conn, err := sql.Open("sqlite", "file:" + path + "?" + q.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("open %v: %w", path, err)
+	}
+
+	if err := migrate(ctx, conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("open %v: %w", path, err)
+	}
+
+	return conn, nil
+}
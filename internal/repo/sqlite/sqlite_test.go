@@ -0,0 +1,387 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"testing"
+	"time"
+	"todoNote/internal/model"
+	"todoNote/internal/repo"
+)
+
+func open(t *testing.T) *sql.DB {
+	conn, err := Open(context.Background(), filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return conn
+}
+
+func insertUser(t *testing.T, conn *sql.DB, name string) model.Id {
+	id, err := NewRepoUser(conn).Insert(context.Background(), model.NewUser(0, name, []byte("hash"), model.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return id
+}
+
+func TestOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	conn, err := Open(context.Background(), path)
+	assert.Nil(t, err)
+	conn.Close()
+
+	// migrations are not applied again
+	conn, err = Open(context.Background(), path)
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	var version int
+	assert.Nil(t, conn.QueryRow(`PRAGMA user_version;`).Scan(&version))
+	assert.Equal(t, 5, version)
+}
+
+func TestRepoUser(t *testing.T) {
+	ctx := context.Background()
+	conn := open(t)
+	r := NewRepoUser(conn)
+
+	u := model.NewUser(0, "user", []byte("hash"), model.UTCm4)
+	u.Preferences.WeekStart = time.Sunday
+	u.Preferences.ReminderOffset = 15 * time.Minute
+	id, err := r.Insert(ctx, u)
+	assert.Nil(t, err)
+
+	got, err := r.GetByUserName(ctx, "user")
+	assert.Nil(t, err)
+	u.Id = id
+	assert.Equal(t, u, got)
+
+	_, err = r.Insert(ctx, model.NewUser(0, "user", []byte("hash"), model.UTC))
+	assert.True(t, errors.Is(err, repo.ErrConflict))
+
+	got.TimeZone = model.UTC
+	assert.Nil(t, r.Update(ctx, got))
+	got, err = r.GetById(ctx, id)
+	assert.Nil(t, err)
+	assert.Equal(t, model.UTC, got.TimeZone)
+
+	assert.Nil(t, r.Delete(ctx, id))
+	_, err = r.GetById(ctx, id)
+	assert.True(t, errors.Is(err, repo.ErrNotFound))
+	assert.True(t, errors.Is(r.Delete(ctx, id), repo.ErrNotFound))
+	assert.True(t, errors.Is(r.Update(ctx, got), repo.ErrNotFound))
+}
+
+func TestRepoNote(t *testing.T) {
+	ctx := context.Background()
+	conn := open(t)
+	uId := insertUser(t, conn, "user")
+	r := NewRepoNote(conn)
+
+	date := time.Date(2021, 10, 2, 10, 0, 0, 0, time.UTC)
+	finished := date.Add(time.Hour)
+	n := model.NewNote(0, uId, "title", "text", date, true)
+	n.TimeZone = "Europe/Kyiv"
+	n.ReminderOffset = 10 * time.Minute
+	n.Priority = "A"
+	n.Projects = []string{"home"}
+	n.Contexts = []string{"phone"}
+	n.FinishedAt = &finished
+
+	id, err := r.Insert(ctx, n)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), n.Version)
+
+	got, err := r.GetById(ctx, id)
+	assert.Nil(t, err)
+	n.Id = id
+	assert.Equal(t, *n, got)
+
+	got.Title = "changed"
+	got.FinishedAt = nil
+	assert.Nil(t, r.Update(ctx, &got))
+	assert.Equal(t, int64(2), got.Version)
+	updated, err := r.GetByIdForUpdate(ctx, id)
+	assert.Nil(t, err)
+	assert.Equal(t, got, updated)
+
+	assert.Nil(t, r.Delete(ctx, id))
+	_, err = r.GetById(ctx, id)
+	assert.True(t, errors.Is(err, repo.ErrNotFound))
+	assert.True(t, errors.Is(r.Delete(ctx, id), repo.ErrNotFound))
+	assert.True(t, errors.Is(r.Update(ctx, &got), repo.ErrNotFound))
+
+	changes, err := r.Changes(ctx, uId, 1, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, []model.NoteChange{{Version: 3, NoteId: id, Deleted: true}}, changes)
+
+	t.Run("notes of unknown users", func(t *testing.T) {
+		_, err := r.Insert(ctx, model.NewNote(0, uId + 1, "title", "", date, false))
+		assert.True(t, errors.Is(err, repo.ErrForeignKey))
+	})
+}
+
+func TestRepoNote_GetAllOffset(t *testing.T) {
+	ctx := context.Background()
+	conn := open(t)
+	uId := insertUser(t, conn, "user")
+	other := insertUser(t, conn, "other")
+	r := NewRepoNote(conn)
+
+	date := time.Date(2021, 10, 2, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 4; i++ {
+		_, err := r.Insert(ctx, model.NewNote(0, uId, fmt.Sprint(i), "", date.Add(time.Duration(3 - i) * time.Hour), i % 2 == 0))
+		assert.Nil(t, err)
+	}
+	_, err := r.Insert(ctx, model.NewNote(0, other, "other", "", date, false))
+	assert.Nil(t, err)
+
+	titles := func(notes []model.Note) []string {
+		res := make([]string, 0, len(notes))
+		for _, n := range notes {
+			res = append(res, n.Title)
+		}
+		return res
+	}
+
+	all, err := r.GetAllOffset(ctx, repo.NoteFilter{UserId: uId})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"3", "2", "1", "0"}, titles(all))
+
+	finished := true
+	from, to := date.Add(time.Hour), date.Add(3 * time.Hour)
+	limit := uint64(1)
+	filtered, err := r.GetAllOffset(ctx, repo.NoteFilter{UserId: uId, TakeFrom: &from, TakeTo: &to, IsFinished: &finished})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"2"}, titles(filtered))
+
+	limited, err := r.GetAllOffset(ctx, repo.NoteFilter{UserId: uId, Page: repo.PageFilter{Limit: &limit}})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"3"}, titles(limited))
+}
+
+func TestTransactor(t *testing.T) {
+	ctx := context.Background()
+	conn := open(t)
+	uId := insertUser(t, conn, "user")
+	r := NewRepoNote(conn)
+
+	broken := fmt.Errorf("broken")
+	err := NewTransactor(conn).InTransaction(ctx, func(ctx context.Context) error {
+		if _, err := r.Insert(ctx, model.NewNote(0, uId, "title", "", time.Now(), false)); err != nil {
+			return err
+		}
+
+		return broken
+	})
+	assert.Equal(t, broken, err)
+
+	notes, err := r.GetAllOffset(ctx, repo.NoteFilter{UserId: uId})
+	assert.Nil(t, err)
+	assert.Len(t, notes, 0)
+
+	changes, err := r.Changes(ctx, uId, 0, 10)
+	assert.Nil(t, err)
+	assert.Len(t, changes, 0)
+
+	t.Run("panic rolls back", func(t *testing.T) {
+		assert.Panics(t, func() {
+			NewTransactor(conn).InTransaction(ctx, func(ctx context.Context) error {
+				if _, err := r.Insert(ctx, model.NewNote(0, uId, "title", "", time.Now(), false)); err != nil {
+					return err
+				}
+
+				panic("broken")
+			})
+		})
+
+		notes, err := r.GetAllOffset(ctx, repo.NoteFilter{UserId: uId})
+		assert.Nil(t, err)
+		assert.Len(t, notes, 0)
+	})
+}
+
+func TestRepoMfa(t *testing.T) {
+	ctx := context.Background()
+	conn := open(t)
+	uId := insertUser(t, conn, "user")
+	r := NewRepoMfa(conn)
+
+	m := model.NewMfa(uId, "secret")
+	assert.Nil(t, r.Upsert(ctx, m))
+	got, err := r.GetByUserId(ctx, uId)
+	assert.Nil(t, err)
+	assert.Equal(t, "secret", got.Secret)
+	assert.False(t, got.IsEnabled)
+	assert.Empty(t, got.RecoveryCodes)
+
+	m.IsEnabled = true
+	m.RecoveryCodes = [][]byte{[]byte("first"), []byte("second")}
+	assert.Nil(t, r.Upsert(ctx, m))
+	got, err = r.GetByUserId(ctx, uId)
+	assert.Nil(t, err)
+	assert.Equal(t, m, got)
+
+	used, err := r.UseStep(ctx, uId, 10)
+	assert.Nil(t, err)
+	assert.True(t, used)
+	used, err = r.UseStep(ctx, uId, 10)
+	assert.Nil(t, err)
+	assert.False(t, used)
+	got, err = r.GetByUserId(ctx, uId)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(10), got.LastStep)
+
+	used, err = r.UseRecoveryCode(ctx, uId, []byte("first"))
+	assert.Nil(t, err)
+	assert.True(t, used)
+	used, err = r.UseRecoveryCode(ctx, uId, []byte("first"))
+	assert.Nil(t, err)
+	assert.False(t, used)
+	got, err = r.GetByUserId(ctx, uId)
+	assert.Nil(t, err)
+	assert.Equal(t, [][]byte{[]byte("second")}, got.RecoveryCodes)
+
+	assert.Nil(t, r.Delete(ctx, uId))
+	_, err = r.GetByUserId(ctx, uId)
+	assert.True(t, errors.Is(err, repo.ErrNotFound))
+}
+
+func TestRepoIdentity(t *testing.T) {
+	ctx := context.Background()
+	conn := open(t)
+	uId := insertUser(t, conn, "user")
+	r := NewRepoIdentity(conn)
+
+	i := model.NewUserIdentity(uId, "google", "subject")
+	assert.Nil(t, r.Insert(ctx, i))
+	assert.True(t, errors.Is(r.Insert(ctx, i), repo.ErrConflict))
+
+	got, err := r.GetByProviderSubject(ctx, "google", "subject")
+	assert.Nil(t, err)
+	assert.Equal(t, i, got)
+
+	_, err = r.GetByProviderSubject(ctx, "github", "subject")
+	assert.True(t, errors.Is(err, repo.ErrNotFound))
+}
+
+func TestRepoLoginAttempts(t *testing.T) {
+	ctx := context.Background()
+	r := NewRepoLoginAttempts(open(t))
+
+	got, err := r.Get(ctx, "user")
+	assert.Nil(t, err)
+	assert.Equal(t, model.LoginAttempts{Key: "user"}, got)
+
+	now := time.Date(2021, 10, 2, 10, 0, 0, 0, time.UTC)
+	for i := 1; i <= 3; i++ {
+		failures, err := r.AddFailure(ctx, "user", now, time.Hour)
+		assert.Nil(t, err)
+		assert.Equal(t, i, failures)
+	}
+
+	assert.Nil(t, r.LockUntil(ctx, "user", now.Add(time.Minute)))
+	// an earlier lock keeps the later one
+	assert.Nil(t, r.LockUntil(ctx, "user", now.Add(time.Second)))
+	got, err = r.Get(ctx, "user")
+	assert.Nil(t, err)
+	assert.Equal(t, model.LoginAttempts{Key: "user", Failures: 3, LastFailure: now, LockedUntil: now.Add(time.Minute)}, got)
+
+	// failures out of the window are forgotten
+	failures, err := r.AddFailure(ctx, "user", now.Add(2 * time.Hour), time.Hour)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, failures)
+
+	assert.Nil(t, r.Delete(ctx, "user"))
+	got, err = r.Get(ctx, "user")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, got.Failures)
+}
+
+func TestRepoSession(t *testing.T) {
+	ctx := context.Background()
+	conn := open(t)
+	uId := insertUser(t, conn, "user")
+	r := NewRepoSession(conn)
+
+	now := time.Date(2021, 10, 2, 10, 0, 0, 0, time.UTC)
+	s := model.Session{UserId: uId, UserAgent: "agent", Ip: "127.0.0.1", CreatedAt: now, LastSeenAt: now, ExpiresAt: now.Add(time.Hour)}
+	id, err := r.Insert(ctx, &s)
+	assert.Nil(t, err)
+	s.Id = id
+
+	expired := model.Session{UserId: uId, CreatedAt: now, LastSeenAt: now, ExpiresAt: now.Add(time.Minute)}
+	_, err = r.Insert(ctx, &expired)
+	assert.Nil(t, err)
+
+	assert.Nil(t, r.Touch(ctx, id, now.Add(time.Second)))
+	s.LastSeenAt = now.Add(time.Second)
+	got, err := r.GetById(ctx, id)
+	assert.Nil(t, err)
+	assert.Equal(t, s, got)
+
+	assert.Nil(t, r.DeleteExpired(ctx, now.Add(30 * time.Minute)))
+	all, err := r.GetAllByUser(ctx, uId)
+	assert.Nil(t, err)
+	assert.Equal(t, []model.Session{s}, all)
+
+	assert.Nil(t, r.Delete(ctx, id))
+	assert.True(t, errors.Is(r.Delete(ctx, id), repo.ErrNotFound))
+	assert.True(t, errors.Is(r.Touch(ctx, id, now), repo.ErrNotFound))
+	_, err = r.GetById(ctx, id)
+	assert.True(t, errors.Is(err, repo.ErrNotFound))
+}
+
+func TestRepoAccount(t *testing.T) {
+	ctx := context.Background()
+	conn := open(t)
+	uId := insertUser(t, conn, "user")
+	exports := NewRepoExport(conn)
+	deletions := NewRepoAccountDeletion(conn)
+	feeds := NewRepoCalendarFeed(conn)
+
+	now := time.Date(2021, 10, 2, 10, 0, 0, 0, time.UTC)
+	e := model.Export{UserId: uId, Status: model.ExportPending, CreatedAt: now}
+	assert.Nil(t, exports.Upsert(ctx, &e))
+	got, err := exports.GetByUserId(ctx, uId)
+	assert.Nil(t, err)
+	assert.Equal(t, e, got)
+
+	e.Status, e.Archive, e.CompletedAt = model.ExportReady, []byte("archive"), now.Add(time.Minute)
+	assert.Nil(t, exports.Upsert(ctx, &e))
+	got, err = exports.GetByUserId(ctx, uId)
+	assert.Nil(t, err)
+	assert.Equal(t, e, got)
+
+	d := model.AccountDeletion{UserId: uId, DeleteAfter: now.Add(time.Hour)}
+	assert.Nil(t, deletions.Upsert(ctx, &d))
+	due, err := deletions.GetDue(ctx, now)
+	assert.Nil(t, err)
+	assert.Len(t, due, 0)
+	due, err = deletions.GetDue(ctx, now.Add(2 * time.Hour))
+	assert.Nil(t, err)
+	assert.Equal(t, []model.AccountDeletion{d}, due)
+
+	f := model.CalendarFeed{UserId: uId, TokenHash: []byte("hash"), CreatedAt: now}
+	assert.Nil(t, feeds.Upsert(ctx, &f))
+	feed, err := feeds.GetByTokenHash(ctx, []byte("hash"))
+	assert.Nil(t, err)
+	assert.Equal(t, f, feed)
+
+	// the account state goes with its user
+	assert.Nil(t, NewRepoUser(conn).Delete(ctx, uId))
+	_, err = exports.GetByUserId(ctx, uId)
+	assert.True(t, errors.Is(err, repo.ErrNotFound))
+	_, err = deletions.GetByUserId(ctx, uId)
+	assert.True(t, errors.Is(err, repo.ErrNotFound))
+	_, err = feeds.GetByTokenHash(ctx, []byte("hash"))
+	assert.True(t, errors.Is(err, repo.ErrNotFound))
+}
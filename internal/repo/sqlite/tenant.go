@@ -0,0 +1,19 @@
+package sqlite
+
+import (
+	"context"
+	"todoNote/internal/repo"
+)
+
+// inTenant is the condition of the tenant passed as the next argument, see tenantArg
+const inTenant = `organization_id = coalesce(?, organization_id)`
+
+// tenantArg is the organization queries of ctx are scoped to, nil for repo.AllTenants which matches every row
+func tenantArg(ctx context.Context) interface{} {
+	id, ok := repo.Tenant(ctx)
+	if !ok {
+		return nil
+	}
+
+	return id
+}
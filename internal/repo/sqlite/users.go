@@ -0,0 +1,160 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+	"todoNote/internal/model"
+	"todoNote/internal/repo"
+)
+var _ repo.IRepoUser = &RepoUser{}
+
+type RepoUser struct {
+	conn *sql.DB
+}
+
+func NewRepoUser(conn *sql.DB) repo.IRepoUser {
+	return &RepoUser{
+		conn: conn,
+	}
+}
+
+func (r *RepoUser) Insert(ctx context.Context, u *model.User) (model.Id, error) {
+	query := `
+INSERT INTO users (name, password_hash, time_zone, display_name, locale, week_start, reminder_offset_minutes, date_format,
+organization_id)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING id;`
+	var id model.Id
+	organizationId := repo.InsertTenant(ctx)
+	err := db(ctx, r.conn).QueryRowContext(ctx,
+		query,
+		u.Name,
+		u.PasswordHash,
+		u.TimeZone,
+		u.Preferences.DisplayName,
+		u.Preferences.Locale,
+		int(u.Preferences.WeekStart),
+		minutes(u.Preferences.ReminderOffset),
+		u.Preferences.DateFormat,
+		organizationId).
+		Scan(&id)
+
+	if err != nil {
+		return 0, NewUsersError(insert, err)
+	}
+
+	u.OrganizationId = organizationId
+	return id, nil
+}
+
+func (r *RepoUser) GetByUserName(ctx context.Context, name string) (*model.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE name = ? AND ` + inTenant + `;`
+	u, err := r.get(ctx, query, name, tenantArg(ctx))
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, repo.NewNotFoundError(name)
+		}
+
+		return nil, NewUsersError(select_sql, err)
+	}
+
+	return u, nil
+}
+
+func (r *RepoUser) GetById(ctx context.Context, uId model.Id) (*model.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = ? AND ` + inTenant + `;`
+	u, err := r.get(ctx, query, uId, tenantArg(ctx))
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, repo.NewNotFoundError(uId)
+		}
+
+		return nil, NewUsersError(select_sql, err)
+	}
+
+	return u, nil
+}
+
+// GetByIdForUpdate is GetById, the transaction of ctx holds the write lock of the whole database
+func (r *RepoUser) GetByIdForUpdate(ctx context.Context, uId model.Id) (*model.User, error) {
+	return r.GetById(ctx, uId)
+}
+
+func (r *RepoUser) Update(ctx context.Context, u *model.User) error {
+	query := `
+UPDATE users SET name = ?, time_zone = ?, display_name = ?, locale = ?, week_start = ?,
+reminder_offset_minutes = ?, date_format = ?
+WHERE id = ? AND ` + inTenant + `;`
+	res, err := db(ctx, r.conn).ExecContext(ctx,
+		query,
+		u.Name,
+		u.TimeZone,
+		u.Preferences.DisplayName,
+		u.Preferences.Locale,
+		int(u.Preferences.WeekStart),
+		minutes(u.Preferences.ReminderOffset),
+		u.Preferences.DateFormat,
+		u.Id,
+		tenantArg(ctx))
+
+	if err != nil {
+		return NewUsersError(update, err)
+	}
+
+	return r.affectedOne(res, update)
+}
+
+func (r *RepoUser) Delete(ctx context.Context, uId model.Id) error {
+	query := `DELETE FROM users WHERE id = ? AND ` + inTenant + `;`
+	res, err := db(ctx, r.conn).ExecContext(ctx,
+		query,
+		uId,
+		tenantArg(ctx))
+
+	if err != nil {
+		return NewUsersError(delete_sql, err)
+	}
+
+	return r.affectedOne(res, delete_sql)
+}
+
+func (r *RepoUser) affectedOne(res sql.Result, method string) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return NewUsersError(method, err)
+	}
+
+	if n != 1 {
+		return NewUsersError(method, rowsAffectedNotOne)
+	}
+
+	return nil
+}
+
+const userColumns = `id, name, time_zone, password_hash, display_name, locale, week_start, reminder_offset_minutes, date_format,
+organization_id`
+
+func (r *RepoUser) get(ctx context.Context, query string, params ...interface{}) (*model.User, error){
+	var usr model.User
+	var weekStart, reminderOffset int
+	err := db(ctx, r.conn).QueryRowContext(ctx,
+		query,
+		params...).
+		Scan(&usr.Id,
+			&usr.Name,
+			&usr.TimeZone,
+			&usr.PasswordHash,
+			&usr.Preferences.DisplayName,
+			&usr.Preferences.Locale,
+			&weekStart,
+			&reminderOffset,
+			&usr.Preferences.DateFormat,
+			&usr.OrganizationId)
+
+	usr.Preferences.WeekStart = time.Weekday(weekStart)
+	usr.Preferences.ReminderOffset = time.Duration(reminderOffset) * time.Minute
+	return &usr, err
+}
@@ -0,0 +1,60 @@
+package repo
+
+import (
+	"context"
+	"todoNote/internal/model"
+)
+
+type tenantKey struct{}
+
+// tenant is the scope of a context, all is set by AllTenants
+type tenant struct {
+	id model.Id
+	all bool
+}
+
+// WithTenant scopes the note and user repositories called with ctx to the organization:
+// they neither return nor change elements of other organizations and insert into this one
+func WithTenant(ctx context.Context, organizationId model.Id) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant{id: organizationId})
+}
+
+// AllTenants lifts the scope for work of the service itself that is keyed by ids it trusts,
+// like purging deleted accounts. Elements inserted with it go to the default organization
+func AllTenants(ctx context.Context) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant{all: true})
+}
+
+// WithTenantOf gives ctx the scope of from, for work that outlives the request it was started by
+func WithTenantOf(ctx, from context.Context) context.Context {
+	t, ok := from.Value(tenantKey{}).(tenant)
+	if !ok {
+		return ctx
+	}
+
+	return context.WithValue(ctx, tenantKey{}, t)
+}
+
+// Tenant is the organization the repositories called with ctx are scoped to, the default
+// organization when ctx has none so a forgotten scope never shows other organizations.
+// It is false for AllTenants
+func Tenant(ctx context.Context) (model.Id, bool) {
+	t, ok := ctx.Value(tenantKey{}).(tenant)
+	if !ok {
+		return model.DefaultOrganizationId, true
+	}
+	if t.all {
+		return 0, false
+	}
+
+	return t.id, true
+}
+
+// InsertTenant is the organization an element inserted with ctx belongs to
+func InsertTenant(ctx context.Context) model.Id {
+	if id, ok := Tenant(ctx); ok {
+		return id
+	}
+
+	return model.DefaultOrganizationId
+}
@@ -0,0 +1,9 @@
+package repo
+
+import "context"
+
+// ITransactor runs fn in a transaction, repositories called with the context passed to fn take part in it.
+// A transaction started inside another one joins it
+type ITransactor interface {
+	InTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
@@ -5,10 +5,16 @@ import (
 	"todoNote/internal/model"
 )
 
+// IRepoUser is scoped to the tenant of the context, see WithTenant. Names are unique within an organization
 type IRepoUser interface {
+	// Insert of a name taken in the organization fails with ErrConflict, it sets the OrganizationId of u
 	Insert(ctx context.Context, u *model.User) (model.Id, error)
+	// GetByUserName with AllTenants finds a user of the name in any organization
 	GetByUserName(ctx context.Context, name string) (*model.User, error)
 	GetById(ctx context.Context, uId model.Id) (*model.User, error)
+	// GetByIdForUpdate is GetById that keeps others from changing the user until the transaction of ctx ends
+	GetByIdForUpdate(ctx context.Context, uId model.Id) (*model.User, error)
+	// Update and Delete of an unknown user fail with ErrNotFound
 	Update(ctx context.Context, u *model.User) error
 	Delete(ctx context.Context, uId model.Id) error
 }
@@ -1,7 +1,6 @@
 package auth
 
 import (
-	"crypto/rsa"
 	"encoding/base64"
 	"fmt"
 	"github.com/golang-jwt/jwt"
@@ -11,6 +10,7 @@ import (
 
 const (
 	issuer = "todo notes service"
+	mfaTokenLifetime = 5 * time.Minute
 )
 
 var _ IAuth = &JwtAuth{}
@@ -19,59 +19,119 @@ type JwtToken = string
 
 type Claims struct {
 	UserId model.Id
+	SessionId model.Id `json:",omitempty"`
+	MfaPending bool `json:",omitempty"`
+	// OrganizationId is the tenant of the user, tokens issued before organizations have none and are of the default one
+	OrganizationId model.Id `json:",omitempty"`
 	jwt.StandardClaims
 }
 
 type IAuth interface {
 	CreateToken(user model.UserInReq) (JwtToken, error)
 	ValidateToken(t JwtToken) (model.UserInReq, error)
+	CreateMfaToken(user model.UserInReq) (JwtToken, error)
+	ValidateMfaToken(t JwtToken) (model.UserInReq, error)
 }
 
 
 type JwtAuth struct {
 	Lifetime time.Duration
-	verifyKey *rsa.PublicKey
-	signKey *rsa.PrivateKey
+	keys *KeySet
 }
 
 func NewJwtAuth(lifetime time.Duration, privateKey, publicKey string) (IAuth, error) {
-	a := JwtAuth{
+	keys, err := NewKeySetFromPem(privateKey, publicKey)
+	if err != nil {
+		return &JwtAuth{Lifetime: lifetime}, err
+	}
+
+	return NewJwtAuthWithKeys(lifetime, keys), nil
+}
+
+func NewJwtAuthWithKeys(lifetime time.Duration, keys *KeySet) IAuth {
+	return &JwtAuth{
 		Lifetime: lifetime,
+		keys: keys,
 	}
-	err := a.initAuthKeys(privateKey, publicKey)
-	return &a, err
 }
 
-func (auth *JwtAuth) initAuthKeys(privateKey, publicKey string) error {
+// NewKeySetFromPem builds key set from base64 encoded PEM keys kept in PRIVATE_KEY and PUBLIC_KEY
+func NewKeySetFromPem(privateKey, publicKey string) (*KeySet, error) {
 	pk, _ := base64.StdEncoding.DecodeString(privateKey)
 	sk, err := jwt.ParseRSAPrivateKeyFromPEM(pk)
 	if err != nil {
-		return fmt.Errorf("initKeys read private key: %w", err)
+		return nil, fmt.Errorf("initKeys read private key: %w", err)
 	}
-	auth.signKey = sk
 
 	pk, _ = base64.StdEncoding.DecodeString(publicKey)
 	vk, err := jwt.ParseRSAPublicKeyFromPEM(pk)
 	if err != nil {
-		return fmt.Errorf("initKeys read public key: %w", err)
+		return nil, fmt.Errorf("initKeys read public key: %w", err)
 	}
-	auth.verifyKey = vk
 
-	return nil
+	return NewKeySet(sk, vk), nil
 }
 
 func(auth *JwtAuth) CreateToken(user model.UserInReq) (JwtToken, error) {
+	return auth.sign(user, auth.Lifetime, false)
+}
+
+// CreateMfaToken issues a short-lived token which only allows to finish login with the second factor
+func(auth *JwtAuth) CreateMfaToken(user model.UserInReq) (JwtToken, error) {
+	return auth.sign(user, mfaTokenLifetime, true)
+}
+
+func(auth *JwtAuth) ValidateToken(t JwtToken) (model.UserInReq, error) {
+	c, err := auth.parse(t)
+	if err != nil {
+		return model.UserInReq{}, err
+	}
+
+	if c.MfaPending {
+		return model.UserInReq{}, fmt.Errorf("validate token: mfa is not passed")
+	}
+
+	return model.UserInReq{Id: c.UserId, SessionId: c.SessionId, OrganizationId: c.organization()}, nil
+}
+
+func(auth *JwtAuth) ValidateMfaToken(t JwtToken) (model.UserInReq, error) {
+	c, err := auth.parse(t)
+	if err != nil {
+		return model.UserInReq{}, err
+	}
+
+	if !c.MfaPending {
+		return model.UserInReq{}, fmt.Errorf("validate token: not mfa token")
+	}
+
+	return model.UserInReq{Id: c.UserId, OrganizationId: c.organization()}, nil
+}
+
+func(c *Claims) organization() model.Id {
+	if c.OrganizationId == 0 {
+		return model.DefaultOrganizationId
+	}
+
+	return c.OrganizationId
+}
+
+func(auth *JwtAuth) sign(user model.UserInReq, lifetime time.Duration, mfaPending bool) (JwtToken, error) {
 	c := &Claims{
 		user.Id,
+		user.SessionId,
+		mfaPending,
+		user.OrganizationId,
 		jwt.StandardClaims{
 			IssuedAt: time.Now().Unix(),
-			ExpiresAt: time.Now().Add(auth.Lifetime).Unix(),
+			ExpiresAt: time.Now().Add(lifetime).Unix(),
 			Issuer:    issuer,
 		},
 	}
 
+	kid, key := auth.keys.SigningKey()
 	tk := jwt.NewWithClaims(jwt.SigningMethodRS256, c)
-	t, err := tk.SignedString(auth.signKey)
+	tk.Header["kid"] = kid
+	t, err := tk.SignedString(key)
 	if err != nil {
 		return "", fmt.Errorf("sign token for user.go %v : %w", user.Id, err)
 	}
@@ -79,32 +139,37 @@ func(auth *JwtAuth) CreateToken(user model.UserInReq) (JwtToken, error) {
 	return t, err
 }
 
-func(auth *JwtAuth) ValidateToken(t JwtToken) (model.UserInReq, error) {
+func(auth *JwtAuth) parse(t JwtToken) (*Claims, error) {
 	tk, err := jwt.ParseWithClaims(t, &Claims{}, func(t *jwt.Token) (interface{}, error) {
-		return auth.verifyKey, nil
+		if _, ok := t.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("unexpected signing method %v", t.Header["alg"])
+		}
+
+		kid, _ := t.Header["kid"].(string)
+		k, ok := auth.keys.VerifyKey(kid)
+		if !ok {
+			return nil, fmt.Errorf("unknown key %v", kid)
+		}
+
+		return k, nil
 	})
 
 	if err != nil {
-		return model.UserInReq{}, fmt.Errorf("validate token: %w", err)
+		return nil, fmt.Errorf("validate token: %w", err)
 	}
 
 	if !tk.Valid {
-		return model.UserInReq{}, fmt.Errorf("validate token: not valid token: %w", err)
+		return nil, fmt.Errorf("validate token: not valid token: %w", err)
 	}
 
 	c, ok := tk.Claims.(*Claims)
 	if !ok {
-		return model.UserInReq{}, fmt.Errorf("validate token: invalid token structure: %w", err)
+		return nil, fmt.Errorf("validate token: invalid token structure: %w", err)
 	}
 
 	if c.Issuer != issuer {
-		return model.UserInReq{}, fmt.Errorf("validate token: not valid token: %w", err)
-	}
-
-	u := model.UserInReq{
-		Id: c.UserId,
+		return nil, fmt.Errorf("validate token: not valid token: %w", err)
 	}
 
-	return u, nil
+	return c, nil
 }
-
@@ -44,3 +44,52 @@ func TestCreateValidateToken(t *testing.T) {
 		})
 	}
 }
+
+func TestCreateValidateMfaToken(t *testing.T) {
+	auth, err := NewJwtAuth(time.Minute, private, public)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mfaToken, err := auth.CreateMfaToken(model.UserInReq{Id: 1})
+	assert.Nil(t, err)
+
+	_, err = auth.ValidateToken(mfaToken)
+	assert.NotNil(t, err)
+
+	u, err := auth.ValidateMfaToken(mfaToken)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1, u.Id)
+
+	token, err := auth.CreateToken(model.UserInReq{Id: 1})
+	assert.Nil(t, err)
+
+	_, err = auth.ValidateMfaToken(token)
+	assert.NotNil(t, err)
+}
+
+func TestTokenOrganization(t *testing.T) {
+	auth, err := NewJwtAuth(time.Minute, private, public)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := auth.CreateToken(model.UserInReq{Id: 1, SessionId: 2, OrganizationId: 3})
+	assert.Nil(t, err)
+	u, err := auth.ValidateToken(token)
+	assert.Nil(t, err)
+	assert.Equal(t, model.UserInReq{Id: 1, SessionId: 2, OrganizationId: 3}, u)
+
+	mfaToken, err := auth.CreateMfaToken(model.UserInReq{Id: 1, OrganizationId: 3})
+	assert.Nil(t, err)
+	u, err = auth.ValidateMfaToken(mfaToken)
+	assert.Nil(t, err)
+	assert.Equal(t, model.Id(3), u.OrganizationId)
+
+	// tokens issued before organizations are of the default one
+	token, err = auth.CreateToken(model.UserInReq{Id: 1})
+	assert.Nil(t, err)
+	u, err = auth.ValidateToken(token)
+	assert.Nil(t, err)
+	assert.Equal(t, model.DefaultOrganizationId, u.OrganizationId)
+}
@@ -0,0 +1,240 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/golang-jwt/jwt"
+	"io/ioutil"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const pemExt = ".pem"
+
+// KeySet keeps every key that tokens may be verified with and the one new tokens are signed with
+type KeySet struct {
+	sync.RWMutex
+	activeKid  string
+	signKey    *rsa.PrivateKey
+	verifyKeys map[string]*rsa.PublicKey
+	// loaded describes files of the last successful LoadDir
+	loaded string
+}
+
+func NewKeySet(signKey *rsa.PrivateKey, verifyKeys ...*rsa.PublicKey) *KeySet {
+	s := &KeySet{}
+	s.set(signKey, verifyKeys)
+	return s
+}
+
+func (s *KeySet) set(signKey *rsa.PrivateKey, verifyKeys []*rsa.PublicKey) {
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range verifyKeys {
+		keys[Thumbprint(k)] = k
+	}
+
+	kid := Thumbprint(&signKey.PublicKey)
+	keys[kid] = &signKey.PublicKey
+
+	s.Lock()
+	s.activeKid = kid
+	s.signKey = signKey
+	s.verifyKeys = keys
+	s.Unlock()
+}
+
+func (s *KeySet) SigningKey() (string, *rsa.PrivateKey) {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.activeKid, s.signKey
+}
+
+// VerifyKey falls back to the active key for tokens issued before kid header was introduced
+func (s *KeySet) VerifyKey(kid string) (*rsa.PublicKey, bool) {
+	s.RLock()
+	defer s.RUnlock()
+
+	if kid == "" {
+		kid = s.activeKid
+	}
+
+	k, ok := s.verifyKeys[kid]
+	return k, ok
+}
+
+func (s *KeySet) Jwks() Jwks {
+	s.RLock()
+	defer s.RUnlock()
+
+	kids := make([]string, 0, len(s.verifyKeys))
+	for kid := range s.verifyKeys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+
+	set := Jwks{Keys: make([]Jwk, 0, len(kids))}
+	for _, kid := range kids {
+		set.Keys = append(set.Keys, NewRsaJwk(kid, s.verifyKeys[kid]))
+	}
+
+	return set
+}
+
+// LoadDir replaces keys with the ones from dir: every private key is used for verification,
+// the private key with the greatest file name signs new tokens, public keys are verification only
+func (s *KeySet) LoadDir(dir string) error {
+	state, err := dirState(dir)
+	if err != nil {
+		return err
+	}
+
+	files, err := pemFiles(dir)
+	if err != nil {
+		return err
+	}
+
+	var signKey *rsa.PrivateKey
+	verifyKeys := make([]*rsa.PublicKey, 0, len(files))
+	for _, f := range files {
+		b, err := ioutil.ReadFile(f)
+		if err != nil {
+			return fmt.Errorf("load keys: %w", err)
+		}
+
+		if strings.Contains(string(b), "PRIVATE KEY") {
+			k, err := jwt.ParseRSAPrivateKeyFromPEM(b)
+			if err != nil {
+				return fmt.Errorf("load keys: %v: %w", f, err)
+			}
+
+			signKey = k
+			verifyKeys = append(verifyKeys, &k.PublicKey)
+			continue
+		}
+
+		k, err := jwt.ParseRSAPublicKeyFromPEM(b)
+		if err != nil {
+			return fmt.Errorf("load keys: %v: %w", f, err)
+		}
+		verifyKeys = append(verifyKeys, k)
+	}
+
+	if signKey == nil {
+		return fmt.Errorf("load keys: no private key in %v", dir)
+	}
+
+	s.set(signKey, verifyKeys)
+
+	s.Lock()
+	s.loaded = state
+	s.Unlock()
+
+	return nil
+}
+
+// WatchDir polls dir and reloads keys when files are added, removed or modified
+func (s *KeySet) WatchDir(ctx context.Context, dir string, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+
+		state, err := dirState(dir)
+		if err != nil {
+			log.Printf("watch keys: %v", err)
+			continue
+		}
+
+		s.RLock()
+		changed := state != s.loaded
+		s.RUnlock()
+		if !changed {
+			continue
+		}
+
+		if err := s.LoadDir(dir); err != nil {
+			log.Printf("watch keys: keep previous keys: %v", err)
+			continue
+		}
+
+		log.Printf("watch keys: keys reloaded from %v", dir)
+	}
+}
+
+func pemFiles(dir string) ([]string, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("load keys: %w", err)
+	}
+
+	files := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != pemExt {
+			continue
+		}
+		files = append(files, filepath.Join(dir, e.Name()))
+	}
+	sort.Strings(files)
+
+	return files, nil
+}
+
+func dirState(dir string) (string, error) {
+	files, err := pemFiles(dir)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	for _, f := range files {
+		info, err := os.Stat(f)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "%v:%v:%v;", f, info.Size(), info.ModTime().UnixNano())
+	}
+
+	return b.String(), nil
+}
+
+// Thumbprint is RFC 7638 JWK thumbprint, it is used as kid
+func Thumbprint(k *rsa.PublicKey) string {
+	e := base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes())
+	n := base64.RawURLEncoding.EncodeToString(k.N.Bytes())
+
+	b, _ := json.Marshal(struct {
+		E   string `json:"e"`
+		Kty string `json:"kty"`
+		N   string `json:"n"`
+	}{e, "RSA", n})
+
+	h := sha256.Sum256(b)
+	return base64.RawURLEncoding.EncodeToString(h[:])
+}
+
+func NewRsaJwk(kid string, k *rsa.PublicKey) Jwk {
+	return Jwk{
+		Kty: "RSA",
+		Kid: kid,
+		Use: "sig",
+		Alg: jwt.SigningMethodRS256.Alg(),
+		N:   base64.RawURLEncoding.EncodeToString(k.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes()),
+	}
+}
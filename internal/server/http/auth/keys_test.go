@@ -0,0 +1,112 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"todoNote/internal/model"
+)
+
+func writeKey(t *testing.T, dir, name string, private bool) *rsa.PrivateKey {
+	k, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	block := &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(k)}
+	if !private {
+		der, _ := x509.MarshalPKIXPublicKey(&k.PublicKey)
+		block = &pem.Block{Type: "PUBLIC KEY", Bytes: der}
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, name), pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return k
+}
+
+func TestKeySet_LoadDir(t *testing.T) {
+	dir := t.TempDir()
+	old := writeKey(t, dir, "2021-01.pem", true)
+	active := writeKey(t, dir, "2021-02.pem", true)
+	retired := writeKey(t, dir, "2020-12.pem", false)
+	writeKey(t, dir, "ignored.txt", true)
+
+	keys := &KeySet{}
+	assert.Nil(t, keys.LoadDir(dir))
+
+	kid, sk := keys.SigningKey()
+	assert.Equal(t, Thumbprint(&active.PublicKey), kid)
+	assert.Equal(t, active, sk)
+
+	jwks := keys.Jwks()
+	assert.Equal(t, 3, len(jwks.Keys))
+	for _, k := range []*rsa.PublicKey{&old.PublicKey, &active.PublicKey, &retired.PublicKey} {
+		jwk, ok := jwks.Find(Thumbprint(k))
+		assert.True(t, ok)
+
+		pk, err := jwk.RsaPublicKey()
+		assert.Nil(t, err)
+		assert.True(t, k.Equal(pk))
+	}
+}
+
+func TestJwtAuth_Rotation(t *testing.T) {
+	dir := t.TempDir()
+	writeKey(t, dir, "1.pem", true)
+
+	keys := &KeySet{}
+	assert.Nil(t, keys.LoadDir(dir))
+	auth := NewJwtAuthWithKeys(time.Minute, keys)
+
+	before, err := auth.CreateToken(model.UserInReq{Id: 1})
+	assert.Nil(t, err)
+
+	writeKey(t, dir, "2.pem", true)
+	assert.Nil(t, keys.LoadDir(dir))
+
+	after, err := auth.CreateToken(model.UserInReq{Id: 1})
+	assert.Nil(t, err)
+
+	_, err = auth.ValidateToken(before)
+	assert.Nil(t, err, "token signed with previous key is still valid")
+	_, err = auth.ValidateToken(after)
+	assert.Nil(t, err)
+
+	assert.Nil(t, os.Remove(filepath.Join(dir, "1.pem")))
+	assert.Nil(t, keys.LoadDir(dir))
+
+	_, err = auth.ValidateToken(before)
+	assert.NotNil(t, err, "token signed with removed key is rejected")
+}
+
+func TestKeySet_WatchDir(t *testing.T) {
+	dir := t.TempDir()
+	writeKey(t, dir, "1.pem", true)
+
+	keys := &KeySet{}
+	assert.Nil(t, keys.LoadDir(dir))
+	kid, _ := keys.SigningKey()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go keys.WatchDir(ctx, dir, 10*time.Millisecond)
+
+	next := writeKey(t, dir, "2.pem", true)
+	assert.Eventually(t, func() bool {
+		k, _ := keys.SigningKey()
+		return k == Thumbprint(&next.PublicKey)
+	}, time.Second, 10*time.Millisecond)
+
+	_, ok := keys.VerifyKey(kid)
+	assert.True(t, ok)
+}
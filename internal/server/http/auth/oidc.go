@@ -0,0 +1,371 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/golang-jwt/jwt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	OidcProvidersEnv = "OIDC_PROVIDERS"
+
+	oidcDiscoveryPath = "/.well-known/openid-configuration"
+	oidcLeeway = time.Minute
+	// jwksRefetchInterval keeps callbacks with unknown kids from making us fetch jwks on every request
+	jwksRefetchInterval = time.Minute
+)
+
+type OidcConfig struct {
+	Name         string
+	Issuer       string
+	ClientId     string
+	ClientSecret string
+	RedirectUrl  string
+	Scopes       []string
+}
+
+// OidcConfigsFromEnv reads providers listed in OIDC_PROVIDERS,
+// e.g. OIDC_PROVIDERS=corp needs OIDC_CORP_ISSUER, OIDC_CORP_CLIENT_ID, OIDC_CORP_CLIENT_SECRET and OIDC_CORP_REDIRECT_URL
+func OidcConfigsFromEnv() ([]OidcConfig, error) {
+	names := os.Getenv(OidcProvidersEnv)
+	if names == "" {
+		return nil, nil
+	}
+
+	configs := make([]OidcConfig, 0)
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		c := OidcConfig{
+			Name:         name,
+			Issuer:       os.Getenv(prefix + "ISSUER"),
+			ClientId:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectUrl:  os.Getenv(prefix + "REDIRECT_URL"),
+			Scopes:       []string{"openid", "profile", "email"},
+		}
+
+		if c.Issuer == "" || c.ClientId == "" || c.RedirectUrl == "" {
+			return nil, fmt.Errorf("oidc provider %v: issuer, client id and redirect url are required", name)
+		}
+
+		configs = append(configs, c)
+	}
+
+	return configs, nil
+}
+
+type OidcClaims struct {
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	PreferredUsername string
+	Name              string
+}
+
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JwksUri               string `json:"jwks_uri"`
+}
+
+type OidcProvider struct {
+	config OidcConfig
+	client *http.Client
+	now    func() time.Time
+
+	mu        sync.Mutex
+	discovery *oidcDiscovery
+	jwks      Jwks
+	jwksFetchedAt time.Time
+}
+
+func NewOidcProvider(c OidcConfig, client *http.Client) *OidcProvider {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return &OidcProvider{
+		config: c,
+		client: client,
+		now:    time.Now,
+	}
+}
+
+func (p *OidcProvider) Name() string {
+	return p.config.Name
+}
+
+// AuthCodeUrl builds the authorization request with PKCE (S256) challenge derived from verifier
+func (p *OidcProvider) AuthCodeUrl(ctx context.Context, state, nonce, verifier string) (string, error) {
+	d, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	q := url.Values{}
+	q.Set("response_type", "code")
+	q.Set("client_id", p.config.ClientId)
+	q.Set("redirect_uri", p.config.RedirectUrl)
+	q.Set("scope", strings.Join(p.config.Scopes, " "))
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	q.Set("code_challenge", PkceChallenge(verifier))
+	q.Set("code_challenge_method", "S256")
+
+	sep := "?"
+	if strings.Contains(d.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+
+	return d.AuthorizationEndpoint + sep + q.Encode(), nil
+}
+
+// Exchange redeems the authorization code and returns claims of the validated id token
+func (p *OidcProvider) Exchange(ctx context.Context, code, verifier, nonce string) (OidcClaims, error) {
+	d, err := p.discover(ctx)
+	if err != nil {
+		return OidcClaims{}, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.config.RedirectUrl)
+	form.Set("client_id", p.config.ClientId)
+	form.Set("code_verifier", verifier)
+	if p.config.ClientSecret != "" {
+		form.Set("client_secret", p.config.ClientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return OidcClaims{}, fmt.Errorf("oidc exchange: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return OidcClaims{}, fmt.Errorf("oidc exchange: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return OidcClaims{}, fmt.Errorf("oidc exchange: token endpoint returned %v", resp.StatusCode)
+	}
+
+	var tk struct {
+		IdToken string `json:"id_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tk); err != nil {
+		return OidcClaims{}, fmt.Errorf("oidc exchange: decode token response: %w", err)
+	}
+
+	if tk.IdToken == "" {
+		return OidcClaims{}, fmt.Errorf("oidc exchange: no id token in response")
+	}
+
+	return p.VerifyIdToken(ctx, tk.IdToken, nonce)
+}
+
+type audience []string
+
+func (a *audience) UnmarshalJSON(b []byte) error {
+	var single string
+	if err := json.Unmarshal(b, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+
+	var many []string
+	if err := json.Unmarshal(b, &many); err != nil {
+		return err
+	}
+	*a = many
+
+	return nil
+}
+
+type idTokenClaims struct {
+	Issuer            string   `json:"iss"`
+	Subject           string   `json:"sub"`
+	Audience          audience `json:"aud"`
+	ExpiresAt         int64    `json:"exp"`
+	IssuedAt          int64    `json:"iat"`
+	Nonce             string   `json:"nonce"`
+	Email             string   `json:"email"`
+	EmailVerified     bool     `json:"email_verified"`
+	PreferredUsername string   `json:"preferred_username"`
+	Name              string   `json:"name"`
+}
+
+// Valid is checked by VerifyIdToken against the provider clock
+func (c *idTokenClaims) Valid() error {
+	return nil
+}
+
+func (p *OidcProvider) VerifyIdToken(ctx context.Context, raw, nonce string) (OidcClaims, error) {
+	d, err := p.discover(ctx)
+	if err != nil {
+		return OidcClaims{}, err
+	}
+
+	parser := jwt.Parser{
+		ValidMethods:         []string{jwt.SigningMethodRS256.Alg()},
+		SkipClaimsValidation: true,
+	}
+
+	var c idTokenClaims
+	_, err = parser.ParseWithClaims(raw, &c, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return p.key(ctx, kid)
+	})
+	if err != nil {
+		return OidcClaims{}, fmt.Errorf("verify id token: %w", err)
+	}
+
+	if c.Issuer != d.Issuer {
+		return OidcClaims{}, fmt.Errorf("verify id token: unexpected issuer %v", c.Issuer)
+	}
+
+	if !c.Audience.contains(p.config.ClientId) {
+		return OidcClaims{}, fmt.Errorf("verify id token: token is issued for another client")
+	}
+
+	if p.now().After(time.Unix(c.ExpiresAt, 0).Add(oidcLeeway)) {
+		return OidcClaims{}, fmt.Errorf("verify id token: token is expired")
+	}
+
+	if c.Nonce != nonce {
+		return OidcClaims{}, fmt.Errorf("verify id token: nonce mismatch")
+	}
+
+	if c.Subject == "" {
+		return OidcClaims{}, fmt.Errorf("verify id token: no subject")
+	}
+
+	return OidcClaims{
+		Subject:           c.Subject,
+		Email:             c.Email,
+		EmailVerified:     c.EmailVerified,
+		PreferredUsername: c.PreferredUsername,
+		Name:              c.Name,
+	}, nil
+}
+
+func (a audience) contains(clientId string) bool {
+	for _, v := range a {
+		if v == clientId {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *OidcProvider) discover(ctx context.Context) (*oidcDiscovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	var d oidcDiscovery
+	issuer := strings.TrimSuffix(p.config.Issuer, "/")
+	if err := p.getJson(ctx, issuer+oidcDiscoveryPath, &d); err != nil {
+		return nil, fmt.Errorf("oidc discovery %v: %w", p.config.Name, err)
+	}
+
+	if strings.TrimSuffix(d.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("oidc discovery %v: issuer mismatch %v", p.config.Name, d.Issuer)
+	}
+
+	p.discovery = &d
+	return p.discovery, nil
+}
+
+// key looks up the signing key, jwks is refetched for an unknown kid to follow key rotation of the provider,
+// at most once per jwksRefetchInterval
+func (p *OidcProvider) key(ctx context.Context, kid string) (interface{}, error) {
+	p.mu.Lock()
+	k, ok := p.jwks.Find(kid)
+	jwksUri := p.discovery.JwksUri
+	now := p.now()
+	refetch := !ok && !now.Before(p.jwksFetchedAt.Add(jwksRefetchInterval))
+	if refetch {
+		p.jwksFetchedAt = now
+	}
+	p.mu.Unlock()
+
+	if !ok && !refetch {
+		return nil, fmt.Errorf("no jwk with kid %v", kid)
+	}
+
+	if !ok {
+		var s Jwks
+		if err := p.getJson(ctx, jwksUri, &s); err != nil {
+			return nil, fmt.Errorf("fetch jwks: %w", err)
+		}
+
+		p.mu.Lock()
+		p.jwks = s
+		p.mu.Unlock()
+
+		if k, ok = s.Find(kid); !ok {
+			return nil, fmt.Errorf("no jwk with kid %v", kid)
+		}
+	}
+
+	return k.RsaPublicKey()
+}
+
+func (p *OidcProvider) getJson(ctx context.Context, u string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("get %v: status %v", u, resp.StatusCode)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// RandomString returns url safe random value used for state, nonce and PKCE verifier
+func RandomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func PkceChallenge(verifier string) string {
+	h := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(h[:])
+}
@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"fmt"
+	"sync"
+	"time"
+	"todoNote/internal/model"
+)
+
+const OidcStateLifetime = 10 * time.Minute
+
+// OidcState is kept between redirect to the provider and the callback
+type OidcState struct {
+	Provider   string
+	Verifier   string
+	Nonce      string
+	LinkUserId model.Id
+	LinkOrganizationId model.Id
+	expiresAt  time.Time
+}
+
+type OidcStateStore struct {
+	sync.Mutex
+	states map[string]OidcState
+	now    func() time.Time
+}
+
+func NewOidcStateStore() *OidcStateStore {
+	return &OidcStateStore{
+		states: make(map[string]OidcState),
+		now:    time.Now,
+	}
+}
+
+func (s *OidcStateStore) Put(st OidcState) (string, error) {
+	key, err := RandomString()
+	if err != nil {
+		return "", fmt.Errorf("oidc state: %w", err)
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	now := s.now()
+	for k, v := range s.states {
+		if now.After(v.expiresAt) {
+			delete(s.states, k)
+		}
+	}
+
+	st.expiresAt = now.Add(OidcStateLifetime)
+	s.states[key] = st
+
+	return key, nil
+}
+
+// Take returns the state only once so that the callback can not be replayed
+func (s *OidcStateStore) Take(key string) (OidcState, bool) {
+	s.Lock()
+	defer s.Unlock()
+
+	st, ok := s.states[key]
+	if !ok {
+		return OidcState{}, false
+	}
+	delete(s.states, key)
+
+	if s.now().After(st.expiresAt) {
+		return OidcState{}, false
+	}
+
+	return st, true
+}
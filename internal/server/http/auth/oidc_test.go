@@ -0,0 +1,221 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+const (
+	idpClientId = "todo"
+	idpKid = "idp-key"
+)
+
+// mockIdp is a minimal OpenID provider which issues id tokens for any code when PKCE verifier matches
+type mockIdp struct {
+	*httptest.Server
+	key *rsa.PrivateKey
+	challenge string
+	nonce string
+	audience string
+	expiresAt time.Time
+	kid string
+	jwksFetches int
+}
+
+func newMockIdp(t *testing.T) *mockIdp {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idp := &mockIdp{key: key, audience: idpClientId, expiresAt: time.Now().Add(time.Hour), kid: idpKid}
+	mux := http.NewServeMux()
+	mux.HandleFunc(oidcDiscoveryPath, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(oidcDiscovery{
+			Issuer: idp.URL,
+			AuthorizationEndpoint: idp.URL + "/authorize",
+			TokenEndpoint: idp.URL + "/token",
+			JwksUri: idp.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		idp.jwksFetches++
+		json.NewEncoder(w).Encode(Jwks{Keys: []Jwk{{
+			Kty: "RSA",
+			Kid: idpKid,
+			N: base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E: base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if PkceChallenge(r.PostForm.Get("code_verifier")) != idp.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		tk := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+			"iss": idp.URL,
+			"sub": "subject-1",
+			"aud": []string{idp.audience},
+			"exp": idp.expiresAt.Unix(),
+			"iat": time.Now().Unix(),
+			"nonce": idp.nonce,
+			"email": "user@example.com",
+			"preferred_username": "user",
+		})
+		tk.Header["kid"] = idp.kid
+		s, _ := tk.SignedString(idp.key)
+
+		json.NewEncoder(w).Encode(map[string]string{"id_token": s, "access_token": "access"})
+	})
+
+	idp.Server = httptest.NewServer(mux)
+	return idp
+}
+
+func (idp *mockIdp) provider() *OidcProvider {
+	return NewOidcProvider(OidcConfig{
+		Name: "mock",
+		Issuer: idp.URL,
+		ClientId: idpClientId,
+		RedirectUrl: "http://localhost:8080/api/v1/oidc/mock/callback",
+		Scopes: []string{"openid"},
+	}, idp.Client())
+}
+
+// authorize emulates the browser round trip to the provider
+func (idp *mockIdp) authorize(t *testing.T, p *OidcProvider, verifier, nonce string) {
+	u, err := p.AuthCodeUrl(context.Background(), "state", nonce, verifier)
+	assert.Nil(t, err)
+
+	parsed, err := url.Parse(u)
+	assert.Nil(t, err)
+	q := parsed.Query()
+	assert.Equal(t, "S256", q.Get("code_challenge_method"))
+	assert.Equal(t, idpClientId, q.Get("client_id"))
+	assert.Equal(t, "state", q.Get("state"))
+
+	idp.challenge = q.Get("code_challenge")
+	idp.nonce = q.Get("nonce")
+}
+
+func TestOidcProvider_Exchange(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		idp := newMockIdp(t)
+		defer idp.Close()
+
+		p := idp.provider()
+		idp.authorize(t, p, "verifier", "nonce")
+
+		c, err := p.Exchange(context.Background(), "code", "verifier", "nonce")
+		assert.Nil(t, err)
+		assert.Equal(t, "subject-1", c.Subject)
+		assert.Equal(t, "user", c.PreferredUsername)
+		assert.Equal(t, "user@example.com", c.Email)
+	})
+
+	t.Run("wrong verifier", func(t *testing.T) {
+		idp := newMockIdp(t)
+		defer idp.Close()
+
+		p := idp.provider()
+		idp.authorize(t, p, "verifier", "nonce")
+
+		_, err := p.Exchange(context.Background(), "code", "other verifier", "nonce")
+		assert.NotNil(t, err)
+	})
+
+	t.Run("wrong nonce", func(t *testing.T) {
+		idp := newMockIdp(t)
+		defer idp.Close()
+
+		p := idp.provider()
+		idp.authorize(t, p, "verifier", "nonce")
+
+		_, err := p.Exchange(context.Background(), "code", "verifier", "other nonce")
+		assert.NotNil(t, err)
+	})
+
+	t.Run("other audience", func(t *testing.T) {
+		idp := newMockIdp(t)
+		defer idp.Close()
+		idp.audience = "other client"
+
+		p := idp.provider()
+		idp.authorize(t, p, "verifier", "nonce")
+
+		_, err := p.Exchange(context.Background(), "code", "verifier", "nonce")
+		assert.NotNil(t, err)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		idp := newMockIdp(t)
+		defer idp.Close()
+
+		p := idp.provider()
+		p.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+		idp.authorize(t, p, "verifier", "nonce")
+
+		_, err := p.Exchange(context.Background(), "code", "verifier", "nonce")
+		assert.NotNil(t, err)
+	})
+
+	t.Run("unknown kid refetches jwks at most once a minute", func(t *testing.T) {
+		idp := newMockIdp(t)
+		defer idp.Close()
+		idp.kid = "unknown"
+
+		now := time.Now()
+		p := idp.provider()
+		p.now = func() time.Time { return now }
+		for i := 0; i < 3; i++ {
+			idp.authorize(t, p, "verifier", "nonce")
+			_, err := p.Exchange(context.Background(), "code", "verifier", "nonce")
+			assert.NotNil(t, err)
+		}
+		assert.Equal(t, 1, idp.jwksFetches)
+
+		now = now.Add(jwksRefetchInterval)
+		idp.authorize(t, p, "verifier", "nonce")
+		_, err := p.Exchange(context.Background(), "code", "verifier", "nonce")
+		assert.NotNil(t, err)
+		assert.Equal(t, 2, idp.jwksFetches)
+
+		// known keys are used without fetching
+		idp.kid = idpKid
+		idp.authorize(t, p, "verifier", "nonce")
+		_, err = p.Exchange(context.Background(), "code", "verifier", "nonce")
+		assert.Nil(t, err)
+		assert.Equal(t, 2, idp.jwksFetches)
+	})
+}
+
+func TestOidcStateStore(t *testing.T) {
+	s := NewOidcStateStore()
+	key, err := s.Put(OidcState{Provider: "mock", Verifier: "v"})
+	assert.Nil(t, err)
+
+	st, ok := s.Take(key)
+	assert.True(t, ok)
+	assert.Equal(t, "v", st.Verifier)
+
+	_, ok = s.Take(key)
+	assert.False(t, ok)
+
+	key, _ = s.Put(OidcState{Provider: "mock"})
+	s.now = func() time.Time { return time.Now().Add(OidcStateLifetime + time.Minute) }
+	_, ok = s.Take(key)
+	assert.False(t, ok)
+}
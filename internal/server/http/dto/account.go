@@ -0,0 +1,13 @@
+package dto
+
+import "time"
+
+type Export struct {
+	Status string `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+type AccountDeletion struct {
+	DeleteAfter time.Time `json:"delete_after"`
+}
@@ -0,0 +1,20 @@
+package dto
+
+type CalendarFeed struct {
+	Url string `json:"url"`
+}
+
+type ImportItem struct {
+	Line int `json:"line,omitempty"`
+	Uid string `json:"uid,omitempty"`
+	NoteId int64 `json:"note_id,omitempty"`
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+}
+
+type ImportReport struct {
+	Created int `json:"created"`
+	Updated int `json:"updated"`
+	Skipped int `json:"skipped"`
+	Items []ImportItem `json:"items"`
+}
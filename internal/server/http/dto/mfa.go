@@ -0,0 +1,19 @@
+package dto
+
+type MfaEnrollment struct {
+	Secret string `json:"secret"`
+	Uri string `json:"uri"`
+}
+
+type MfaCode struct {
+	Code string `json:"code"`
+}
+
+type MfaLogin struct {
+	Token string `json:"token"`
+	Code string `json:"code"`
+}
+
+type RecoveryCodes struct {
+	Codes []string `json:"recovery_codes"`
+}
@@ -1,11 +1,18 @@
 package dto
 
-import "time"
+import (
+	"time"
+	"todoNote/internal/model"
+)
 
 type NewNote struct {
 	Title string `json:"title"`
 	Text string `json:"text"`
 	Date time.Time `json:"date"`
+	TimeZone string `json:"time_zone,omitempty"`
+	IsFloating bool `json:"is_floating,omitempty"`
+	// ReminderOffsetMinutes defaults to the user preference
+	ReminderOffsetMinutes *int `json:"reminder_offset_minutes,omitempty"`
 }
 
 type Note struct {
@@ -14,6 +21,9 @@ type Note struct {
 	Text string `json:"text,omitempty"`
 	Date time.Time `json:"date,omitempty"`
 	IsFinished bool `json:"is_finished,omitempty"`
+	TimeZone string `json:"time_zone,omitempty"`
+	IsFloating bool `json:"is_floating,omitempty"`
+	ReminderOffsetMinutes int `json:"reminder_offset_minutes,omitempty"`
 }
 
 type Notes = []Note
@@ -23,5 +33,14 @@ type NoteUpdate struct {
 	Text string `json:"text,omitempty"`
 	Date time.Time `json:"date,omitempty"`
 	IsFinished bool `json:"is_finished,omitempty"`
+	// TimeZone and IsFloating keep those of the note when missing
+	TimeZone *string `json:"time_zone,omitempty"`
+	IsFloating *bool `json:"is_floating,omitempty"`
 }
 
+
+// RenderedNote has the note text rendered from Markdown as sanitized HTML
+type RenderedNote struct {
+	*model.Note
+	Html string
+}
@@ -0,0 +1,5 @@
+package dto
+
+type OidcRedirect struct {
+	Url string `json:"url"`
+}
@@ -0,0 +1,14 @@
+package dto
+
+import "time"
+
+type Session struct {
+	Id int64 `json:"id"`
+	UserAgent string `json:"user_agent"`
+	Ip string `json:"ip"`
+	CreatedAt time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	IsCurrent bool `json:"is_current"`
+}
+
+type Sessions = []Session
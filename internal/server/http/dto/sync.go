@@ -0,0 +1,56 @@
+package dto
+
+import "time"
+
+// SyncNote is a note as stored, floating notes have their wall clock in UTC
+type SyncNote struct {
+	Title string `json:"title"`
+	Text string `json:"text,omitempty"`
+	Date time.Time `json:"date"`
+	IsFinished bool `json:"is_finished"`
+	TimeZone string `json:"time_zone,omitempty"`
+	IsFloating bool `json:"is_floating,omitempty"`
+	ReminderOffsetMinutes *int `json:"reminder_offset_minutes,omitempty"`
+	Priority string `json:"priority,omitempty"`
+	Projects []string `json:"projects,omitempty"`
+	Contexts []string `json:"contexts,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+type NoteChange struct {
+	Version int64 `json:"version"`
+	Id int64 `json:"id"`
+	Deleted bool `json:"deleted,omitempty"`
+	Note *SyncNote `json:"note,omitempty"`
+}
+
+type SyncChanges struct {
+	Changes []NoteChange `json:"changes"`
+	Token string `json:"token"`
+	More bool `json:"more"`
+}
+
+type ClientChange struct {
+	ClientId string `json:"client_id,omitempty"`
+	Id int64 `json:"id,omitempty"`
+	BaseVersion int64 `json:"base_version,omitempty"`
+	Deleted bool `json:"deleted,omitempty"`
+	Note SyncNote `json:"note"`
+}
+
+type SyncPush struct {
+	Changes []ClientChange `json:"changes"`
+}
+
+type SyncResult struct {
+	ClientId string `json:"client_id,omitempty"`
+	Id int64 `json:"id,omitempty"`
+	Status string `json:"status"`
+	Version int64 `json:"version,omitempty"`
+	Reason string `json:"reason,omitempty"`
+	Current *NoteChange `json:"current,omitempty"`
+}
+
+type SyncPushResult struct {
+	Results []SyncResult `json:"results"`
+}
@@ -1,20 +1,46 @@
 package dto
 
-const bearer = "Bearer"
+const (
+	bearer = "Bearer"
+	mfaPending = "MfaPending"
+)
 
 type UserRegistration struct {
 	UserName string `json:"username"`
 	Password string `json:"password"`
 	TimeZone string `json:"time_zone"`
+	// Organization is the name of the organization the user joins, the default one when empty
+	Organization string `json:"organization,omitempty"`
 }
 
 type UserLogin struct {
 	UserName string `json:"username"`
 	Password string `json:"password"`
+	Organization string `json:"organization,omitempty"`
 }
 
 type UserUpdate struct {
 	TimeZone string `json:"time_zone,omitempty"`
+	DisplayName *string `json:"display_name,omitempty"`
+	Locale *string `json:"locale,omitempty"`
+	WeekStart *string `json:"week_start,omitempty"`
+	ReminderOffsetMinutes *int `json:"reminder_offset_minutes,omitempty"`
+	DateFormat *string `json:"date_format,omitempty"`
+}
+
+type Preferences struct {
+	DisplayName string `json:"display_name"`
+	Locale string `json:"locale"`
+	WeekStart string `json:"week_start"`
+	ReminderOffsetMinutes int `json:"reminder_offset_minutes"`
+	DateFormat string `json:"date_format"`
+}
+
+type UserProfile struct {
+	Id int64 `json:"id"`
+	UserName string `json:"username"`
+	TimeZone string `json:"time_zone"`
+	Preferences Preferences `json:"preferences"`
 }
 
 type IdObject struct {
@@ -32,4 +58,11 @@ func NewTokenBearer(tk JwtToken) *Token {
 		Token:  tk,
 		Type: bearer,
 	}
-}
\ No newline at end of file
+}
+
+func NewTokenMfaPending(tk JwtToken) *Token {
+	return &Token{
+		Token: tk,
+		Type: mfaPending,
+	}
+}
@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"todoNote/internal/model"
+	"todoNote/internal/server/http/dto"
+	"todoNote/internal/server/http/log"
+	"todoNote/internal/server/http/middleware"
+	"todoNote/internal/usecase"
+)
+
+const exportFileName = "todonote-export.zip"
+
+type Account struct {
+	accounts usecase.IAccountUsecase
+	log log.Logger
+}
+
+func NewAccountHandler(a usecase.IAccountUsecase, log log.Logger) *Account {
+	return &Account{
+		accounts: a,
+		log: log,
+	}
+}
+
+func(h *Account) RequestExport(w http.ResponseWriter, r *http.Request) {
+	u, ok := middleware.UserFromContext(r, h.log, "request export")
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	e, err := h.accounts.RequestExport(r.Context(), u.Id)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		h.log.Error(fmt.Sprintf("request export: user(id: %v) err: %v", u.Id, err))
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(exportDto(e))
+}
+
+func(h *Account) GetExport(w http.ResponseWriter, r *http.Request) {
+	u, ok := middleware.UserFromContext(r, h.log, "get export")
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	e, err := h.accounts.FindExport(r.Context(), u.Id)
+	if errors.Is(err, usecase.ErrExportNotFound) {
+		writeErrorMessage(w, http.StatusNotFound, noExportFound)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		h.log.Error(fmt.Sprintf("get export: user(id: %v) err: %v", u.Id, err))
+		return
+	}
+
+	switch e.Status {
+	case model.ExportPending:
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(exportDto(e))
+	case model.ExportFailed:
+		writeErrorMessage(w, http.StatusInternalServerError, exportFailed)
+	default:
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", exportFileName))
+		w.Header().Set("Content-Length", strconv.Itoa(len(e.Archive)))
+		w.Write(e.Archive)
+	}
+}
+
+func(h *Account) ScheduleDeletion(w http.ResponseWriter, r *http.Request) {
+	u, ok := middleware.UserFromContext(r, h.log, "schedule deletion")
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	d, err := h.accounts.ScheduleDeletion(r.Context(), u.Id)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		h.log.Error(fmt.Sprintf("schedule deletion: user(id: %v) err: %v", u.Id, err))
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(dto.AccountDeletion{DeleteAfter: d.DeleteAfter})
+}
+
+func(h *Account) CancelDeletion(w http.ResponseWriter, r *http.Request) {
+	u, ok := middleware.UserFromContext(r, h.log, "cancel deletion")
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	err := h.accounts.CancelDeletion(r.Context(), u.Id)
+	if errors.Is(err, usecase.ErrDeletionNotScheduled) {
+		writeErrorMessage(w, http.StatusNotFound, deletionNotScheduled)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		h.log.Error(fmt.Sprintf("cancel deletion: user(id: %v) err: %v", u.Id, err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func exportDto(e model.Export) dto.Export {
+	res := dto.Export{Status: e.Status, CreatedAt: e.CreatedAt}
+	if !e.CompletedAt.IsZero() {
+		res.CompletedAt = &e.CompletedAt
+	}
+
+	return res
+}
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"context"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"todoNote/internal/model"
+	"todoNote/internal/server/http/handler/mocks"
+	"todoNote/internal/server/http/middleware"
+	"todoNote/internal/usecase"
+)
+
+//go:generate mockgen -package=mocks -destination=mocks/account.go todoNote/internal/usecase IAccountUsecase
+
+func TestAccount_GetExport(t *testing.T) {
+	now := time.Now()
+
+	tts := []struct{
+		name string
+		export model.Export
+		err error
+		code int
+	}{
+		{"test ready", model.Export{Status: model.ExportReady, Archive: []byte("zip"), CreatedAt: now, CompletedAt: now}, nil, http.StatusOK},
+		{"test pending", model.Export{Status: model.ExportPending, CreatedAt: now}, nil, http.StatusAccepted},
+		{"test failed", model.Export{Status: model.ExportFailed, CreatedAt: now, CompletedAt: now}, nil, http.StatusInternalServerError},
+		{"test not requested", model.Export{}, usecase.ErrExportNotFound, http.StatusNotFound},
+	}
+
+	for _, tt := range tts {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, "/api/v1/users/me/export", nil)
+
+			ctr := gomock.NewController(t)
+			defer ctr.Finish()
+			mockAccount := mocks.NewMockIAccountUsecase(ctr)
+			mockAccount.EXPECT().FindExport(gomock.Any(), model.Id(2)).Return(tt.export, tt.err)
+
+			h := Account{accounts: mockAccount}
+
+			rr := httptest.NewRecorder()
+			ctx := context.WithValue(req.Context(), middleware.UserAuthorized, model.UserInReq{Id: 2})
+			http.HandlerFunc(h.GetExport).ServeHTTP(rr, req.WithContext(ctx))
+
+			assert.Equal(t, tt.code, rr.Code)
+			if tt.code == http.StatusOK {
+				assert.Equal(t, "application/zip", rr.Header().Get("Content-Type"))
+				assert.Equal(t, "zip", rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestAccount_CancelDeletion(t *testing.T) {
+	tts := []struct{
+		name string
+		err error
+		code int
+	}{
+		{"test success", nil, http.StatusNoContent},
+		{"test not scheduled", usecase.ErrDeletionNotScheduled, http.StatusNotFound},
+	}
+
+	for _, tt := range tts {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodDelete, "/api/v1/users/me/deletion", nil)
+
+			ctr := gomock.NewController(t)
+			defer ctr.Finish()
+			mockAccount := mocks.NewMockIAccountUsecase(ctr)
+			mockAccount.EXPECT().CancelDeletion(gomock.Any(), model.Id(2)).Return(tt.err)
+
+			h := Account{accounts: mockAccount}
+
+			rr := httptest.NewRecorder()
+			ctx := context.WithValue(req.Context(), middleware.UserAuthorized, model.UserInReq{Id: 2})
+			http.HandlerFunc(h.CancelDeletion).ServeHTTP(rr, req.WithContext(ctx))
+
+			assert.Equal(t, tt.code, rr.Code)
+		})
+	}
+}
@@ -5,9 +5,12 @@ import (
 	"errors"
 	"fmt"
 	"golang.org/x/crypto/bcrypt"
+	"math"
+	"net"
 	"net/http"
+	"strconv"
 	"todoNote/internal/model"
-	in_memory "todoNote/internal/repo/in-memory"
+	"todoNote/internal/repo"
 	"todoNote/internal/server/http/dto"
 	"todoNote/internal/server/http/log"
 	"todoNote/internal/usecase"
@@ -16,6 +19,10 @@ import (
 
 type Auth struct {
 	usecaseUser usecase.IUserUsecase
+	usecaseMfa usecase.IMfaUsecase
+	usecaseOrganization usecase.IOrganizationUsecase
+	guard usecase.ILoginGuardUsecase
+	sessions usecase.ISessionUsecase
 	auth IAuth
 	log log.Logger
 }
@@ -23,11 +30,17 @@ type Auth struct {
 type IAuth interface {
 	CreateToken(user model.UserInReq) (string, error)
 	ValidateToken(t string) (model.UserInReq, error)
+	CreateMfaToken(user model.UserInReq) (string, error)
+	ValidateMfaToken(t string) (model.UserInReq, error)
 }
 
-func NewAuthHandler(u usecase.IUserUsecase, a IAuth, log log.Logger) *Auth {
+func NewAuthHandler(u usecase.IUserUsecase, m usecase.IMfaUsecase, o usecase.IOrganizationUsecase, g usecase.ILoginGuardUsecase, s usecase.ISessionUsecase, a IAuth, log log.Logger) *Auth {
 	return &Auth{
 		usecaseUser: u,
+		usecaseMfa: m,
+		usecaseOrganization: o,
+		guard: g,
+		sessions: s,
 		auth: a,
 		log: log,
 	}
@@ -40,8 +53,30 @@ func(h *Auth) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	userKey := usecase.UserAttemptKey(usecase.QualifiedUserName(u.Organization, u.UserName))
+	ipKey := usecase.IpAttemptKey(clientIp(r))
+	if !h.allowAttempt(w, r, userKey, ipKey) {
+		return
+	}
+
+	// an unknown organization is told apart from an unknown user by nothing
+	ctx, err := h.usecaseOrganization.Tenant(r.Context(), u.Organization)
+	if errors.Is(err, usecase.ErrOrganizationNotFound) {
+		h.failedAttempt(r, userKey, ipKey)
+		writeErrorMessage(w, http.StatusBadRequest, incorrectLoginOrPassword)
+		return
+	}
+
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		h.log.Error(fmt.Sprintf("login: organization(name: %v) error: %v", u.Organization, err))
+		return
+	}
+	r = r.WithContext(ctx)
+
 	usr, err := h.usecaseUser.FindByName(r.Context(), u.UserName)
-	if errors.As(err, &in_memory.NoSuchNameError{}) {
+	if errors.Is(err, repo.ErrNotFound) {
+		h.failedAttempt(r, userKey, ipKey)
 		writeErrorMessage(w, http.StatusBadRequest, incorrectLoginOrPassword)
 		return
 	}
@@ -53,16 +88,130 @@ func(h *Auth) Login(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := bcrypt.CompareHashAndPassword(usr.PasswordHash, []byte(u.Password)); err != nil {
+		h.failedAttempt(r, userKey, ipKey)
 		writeErrorMessage(w, http.StatusBadRequest, incorrectLoginOrPassword)
 		return
 	}
 
-	tk, err := h.auth.CreateToken(model.UserInReq{Id: usr.Id})
+	// the address is not reset so that one known account can not unlock guessing of others
+	h.succeededAttempt(r, userKey)
+
+	enabled, err := h.usecaseMfa.IsEnabled(r.Context(), usr.Id)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
-		h.log.Error(fmt.Sprintf("login: create token: user(id: %v) error: %v", usr.Id, err))
+		h.log.Error(fmt.Sprintf("login: check mfa: user(id: %v) error: %v", usr.Id, err))
+		return
+	}
+
+	if enabled {
+		tk, err := h.auth.CreateMfaToken(model.UserInReq{Id: usr.Id, OrganizationId: usr.OrganizationId})
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			h.log.Error(fmt.Sprintf("login: create mfa token: user(id: %v) error: %v", usr.Id, err))
+			return
+		}
+
+		json.NewEncoder(w).Encode(dto.NewTokenMfaPending(tk))
+		return
+	}
+
+	h.writeToken(w, r, model.UserInReq{Id: usr.Id, OrganizationId: usr.OrganizationId})
+}
+
+func(h *Auth) LoginMfa(w http.ResponseWriter, r *http.Request) {
+	var l dto.MfaLogin
+	if err := json.NewDecoder(r.Body).Decode(&l); err != nil {
+		writeErrorMessage(w, http.StatusBadRequest, wrongBody)
+		return
+	}
+
+	u, err := h.auth.ValidateMfaToken(l.Token)
+	if err != nil {
+		writeErrorMessage(w, http.StatusUnauthorized, notValidToken)
+		return
+	}
+	r = r.WithContext(repo.WithTenant(r.Context(), u.OrganizationId))
+
+	mfaKey := usecase.MfaAttemptKey(u.Id)
+	ipKey := usecase.IpAttemptKey(clientIp(r))
+	if !h.allowAttempt(w, r, mfaKey, ipKey) {
+		return
+	}
+
+	err = h.usecaseMfa.Verify(r.Context(), u.Id, l.Code)
+	if errors.Is(err, usecase.ErrMfaInvalidCode) || errors.Is(err, usecase.ErrMfaNotEnrolled) {
+		h.failedAttempt(r, mfaKey, ipKey)
+		writeErrorMessage(w, http.StatusBadRequest, wrongMfaCode)
+		return
+	}
+
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		h.log.Error(fmt.Sprintf("login mfa: verify code: user(id: %v) error: %v", u.Id, err))
+		return
+	}
+
+	h.succeededAttempt(r, mfaKey)
+	h.writeToken(w, r, u)
+}
+
+// allowAttempt answers 429 with Retry-After while any of the keys is locked
+func(h *Auth) allowAttempt(w http.ResponseWriter, r *http.Request, keys ...string) bool {
+	wait, err := h.guard.Check(r.Context(), keys...)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		h.log.Error(fmt.Sprintf("login: check attempts: error: %v", err))
+		return false
+	}
+
+	if wait > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		writeErrorMessage(w, http.StatusTooManyRequests, tooManyAttempts)
+		return false
+	}
+
+	return true
+}
+
+func(h *Auth) failedAttempt(r *http.Request, keys ...string) {
+	if err := h.guard.Failure(r.Context(), keys...); err != nil {
+		h.log.Error(fmt.Sprintf("login: register failed attempt: error: %v", err))
+	}
+}
+
+func(h *Auth) succeededAttempt(r *http.Request, keys ...string) {
+	if err := h.guard.Success(r.Context(), keys...); err != nil {
+		h.log.Error(fmt.Sprintf("login: reset attempts: error: %v", err))
+	}
+}
+
+func clientIp(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+func(h *Auth) writeToken(w http.ResponseWriter, r *http.Request, u model.UserInReq) {
+	tk, err := sessionToken(r, h.auth, h.sessions, u)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		h.log.Error(fmt.Sprintf("login: create token: user(id: %v) error: %v", u.Id, err))
 		return
 	}
 
 	json.NewEncoder(w).Encode(dto.NewTokenBearer(tk))
 }
+
+// sessionToken starts a session for the client of the request and issues a token bound to it
+func sessionToken(r *http.Request, a IAuth, s usecase.ISessionUsecase, u model.UserInReq) (string, error) {
+	sId, err := s.Start(r.Context(), u.Id, r.UserAgent(), clientIp(r))
+	if err != nil {
+		return "", err
+	}
+
+	u.SessionId = sId
+	return a.CreateToken(u)
+}
@@ -12,15 +12,41 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 	"todoNote/internal/model"
+	"todoNote/internal/repo"
 	"todoNote/internal/server/http/dto"
 	"todoNote/internal/server/http/handler/mocks"
 	"todoNote/internal/server/http/middleware"
+	"todoNote/internal/usecase"
 )
 
 //go:generate mockgen -package=mocks -destination=mocks/user.go todoNote/internal/usecase IUserUsecase
 //go:generate mockgen -package=mocks -destination=mocks/log.go todoNote/internal/server/http/log Logger
 //go:generate mockgen -package=mocks -destination=mocks/auth.go todoNote/internal/server/http/auth IAuth
+//go:generate mockgen -package=mocks -destination=mocks/mfa.go todoNote/internal/usecase IMfaUsecase
+//go:generate mockgen -package=mocks -destination=mocks/login_guard.go todoNote/internal/usecase ILoginGuardUsecase
+//go:generate mockgen -package=mocks -destination=mocks/session.go todoNote/internal/usecase ISessionUsecase
+//go:generate mockgen -package=mocks -destination=mocks/organization.go todoNote/internal/usecase IOrganizationUsecase
+
+// allowingGuard expects a successful login attempt which is never locked
+func allowingGuard(ctr *gomock.Controller) *mocks.MockILoginGuardUsecase {
+	g := mocks.NewMockILoginGuardUsecase(ctr)
+	g.EXPECT().Check(gomock.Any(), gomock.Any()).Return(time.Duration(0), nil).AnyTimes()
+	g.EXPECT().Failure(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	g.EXPECT().Success(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	return g
+}
+
+// defaultTenant scopes names without organization to the default one
+func defaultTenant(ctr *gomock.Controller) *mocks.MockIOrganizationUsecase {
+	o := mocks.NewMockIOrganizationUsecase(ctr)
+	o.EXPECT().Tenant(gomock.Any(), "").
+		DoAndReturn(func(ctx context.Context, _ string) (context.Context, error) {
+			return repo.WithTenant(ctx, model.DefaultOrganizationId), nil
+		}).AnyTimes()
+	return o
+}
 
 
 func TestAuth_Login(t *testing.T) {
@@ -44,9 +70,16 @@ func TestAuth_Login(t *testing.T) {
 			Return(dto.JwtToken("token"), nil).
 			Do(func(u model.UserInReq) {
 				assert.Equal(t, model.Id(1), u.Id)
+				assert.Equal(t, model.Id(7), u.SessionId)
 		})
 
-		h := Auth{usecaseUser: mockCase, auth: mockAuth}
+		mockMfa := mocks.NewMockIMfaUsecase(ctr)
+		mockMfa.EXPECT().IsEnabled(gomock.Any(), model.Id(1)).Return(false, nil)
+
+		mockSession := mocks.NewMockISessionUsecase(ctr)
+		mockSession.EXPECT().Start(gomock.Any(), model.Id(1), gomock.Any(), gomock.Any()).Return(model.Id(7), nil)
+
+		h := Auth{usecaseUser: mockCase, usecaseMfa: mockMfa, usecaseOrganization: defaultTenant(ctr), guard: allowingGuard(ctr), sessions: mockSession, auth: mockAuth}
 
 		rr := httptest.NewRecorder()
 		ch := chi.NewRouter()
@@ -62,6 +95,193 @@ func TestAuth_Login(t *testing.T) {
 		assert.Equal(t, "token", tk.Token)
 		assert.Equal(t, http.StatusOK, rr.Code)
 	})
+
+	t.Run("test locked", func(t *testing.T) {
+		b := dto.UserLogin{UserName: "user", Password: "123"}
+		js, _ := json.Marshal(b)
+		req, _ := http.NewRequest(http.MethodPost, "/api/v1/login", bytes.NewReader(js))
+		req.RemoteAddr = "10.0.0.1:5000"
+
+		ctr := gomock.NewController(t)
+		defer ctr.Finish()
+		mockGuard := mocks.NewMockILoginGuardUsecase(ctr)
+		mockGuard.EXPECT().Check(gomock.Any(), "user:user", "ip:10.0.0.1").
+			Return(1500 * time.Millisecond, nil)
+
+		h := Auth{usecaseUser: mocks.NewMockIUserUsecase(ctr), guard: mockGuard}
+
+		rr := httptest.NewRecorder()
+		ch := chi.NewRouter()
+		ch.HandleFunc("/api/v1/login", h.Login)
+		ch.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+		assert.Equal(t, "2", rr.Header().Get("Retry-After"))
+	})
+
+	t.Run("test wrong password is counted", func(t *testing.T) {
+		b := dto.UserLogin{UserName: "user", Password: "wrong"}
+		js, _ := json.Marshal(b)
+		req, _ := http.NewRequest(http.MethodPost, "/api/v1/login", bytes.NewReader(js))
+		req.RemoteAddr = "10.0.0.1:5000"
+
+		ctr := gomock.NewController(t)
+		defer ctr.Finish()
+		mockCase := mocks.NewMockIUserUsecase(ctr)
+		hash, _ := bcrypt.GenerateFromPassword([]byte("123"), bcrypt.MinCost)
+		mockCase.EXPECT().FindByName(gomock.Any(), "user").
+			Return(&model.User{Id: 1, Name: "user", PasswordHash: hash}, nil)
+
+		mockGuard := mocks.NewMockILoginGuardUsecase(ctr)
+		mockGuard.EXPECT().Check(gomock.Any(), gomock.Any(), gomock.Any()).Return(time.Duration(0), nil)
+		mockGuard.EXPECT().Failure(gomock.Any(), "user:user", "ip:10.0.0.1").Return(nil)
+
+		h := Auth{usecaseUser: mockCase, usecaseOrganization: defaultTenant(ctr), guard: mockGuard}
+
+		rr := httptest.NewRecorder()
+		ch := chi.NewRouter()
+		ch.HandleFunc("/api/v1/login", h.Login)
+		ch.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("test mfa pending", func(t *testing.T) {
+		b := dto.UserLogin{UserName: "user", Password: "123"}
+		js, _ := json.Marshal(b)
+		req, _ := http.NewRequest(http.MethodPost, "/api/v1/login", bytes.NewReader(js))
+
+		ctr := gomock.NewController(t)
+		defer ctr.Finish()
+		mockCase := mocks.NewMockIUserUsecase(ctr)
+		hash, _ := bcrypt.GenerateFromPassword([]byte("123"), bcrypt.MinCost)
+		mockCase.EXPECT().FindByName(gomock.Any(), "user").
+			Return(&model.User{Id: 1, Name: "user", PasswordHash: hash}, nil)
+
+		mockMfa := mocks.NewMockIMfaUsecase(ctr)
+		mockMfa.EXPECT().IsEnabled(gomock.Any(), model.Id(1)).Return(true, nil)
+
+		mockAuth := mocks.NewMockIAuth(ctr)
+		mockAuth.EXPECT().CreateMfaToken(model.UserInReq{Id: 1}).Return(dto.JwtToken("pending"), nil)
+
+		h := Auth{usecaseUser: mockCase, usecaseMfa: mockMfa, usecaseOrganization: defaultTenant(ctr), guard: allowingGuard(ctr), auth: mockAuth}
+
+		rr := httptest.NewRecorder()
+		ch := chi.NewRouter()
+		ch.HandleFunc("/api/v1/login", h.Login)
+		ch.ServeHTTP(rr, req)
+
+		var tk dto.Token
+		json.NewDecoder(rr.Body).Decode(&tk)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "pending", tk.Token)
+		assert.Equal(t, "MfaPending", tk.Type)
+	})
+
+	t.Run("test organization", func(t *testing.T) {
+		b := dto.UserLogin{UserName: "user", Password: "123", Organization: "sales"}
+		js, _ := json.Marshal(b)
+		req, _ := http.NewRequest(http.MethodPost, "/api/v1/login", bytes.NewReader(js))
+
+		ctr := gomock.NewController(t)
+		defer ctr.Finish()
+		mockOrganization := mocks.NewMockIOrganizationUsecase(ctr)
+		mockOrganization.EXPECT().Tenant(gomock.Any(), "sales").
+			DoAndReturn(func(ctx context.Context, _ string) (context.Context, error) {
+				return repo.WithTenant(ctx, 5), nil
+			})
+
+		mockCase := mocks.NewMockIUserUsecase(ctr)
+		hash, _ := bcrypt.GenerateFromPassword([]byte("123"), bcrypt.MinCost)
+		mockCase.EXPECT().FindByName(gomock.Any(), "user").
+			DoAndReturn(func(ctx context.Context, _ string) (*model.User, error) {
+				id, _ := repo.Tenant(ctx)
+				assert.Equal(t, model.Id(5), id)
+				return &model.User{Id: 1, Name: "user", PasswordHash: hash, OrganizationId: 5}, nil
+			})
+
+		mockMfa := mocks.NewMockIMfaUsecase(ctr)
+		mockMfa.EXPECT().IsEnabled(gomock.Any(), model.Id(1)).Return(false, nil)
+
+		mockSession := mocks.NewMockISessionUsecase(ctr)
+		mockSession.EXPECT().Start(gomock.Any(), model.Id(1), gomock.Any(), gomock.Any()).Return(model.Id(7), nil)
+
+		mockAuth := mocks.NewMockIAuth(ctr)
+		mockAuth.EXPECT().CreateToken(model.UserInReq{Id: 1, SessionId: 7, OrganizationId: 5}).Return(dto.JwtToken("token"), nil)
+
+		h := Auth{usecaseUser: mockCase, usecaseMfa: mockMfa, usecaseOrganization: mockOrganization, guard: allowingGuard(ctr), sessions: mockSession, auth: mockAuth}
+
+		rr := httptest.NewRecorder()
+		ch := chi.NewRouter()
+		ch.HandleFunc("/api/v1/login", h.Login)
+		ch.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("test unknown organization is counted", func(t *testing.T) {
+		b := dto.UserLogin{UserName: "user", Password: "123", Organization: "sales"}
+		js, _ := json.Marshal(b)
+		req, _ := http.NewRequest(http.MethodPost, "/api/v1/login", bytes.NewReader(js))
+		req.RemoteAddr = "10.0.0.1:5000"
+
+		ctr := gomock.NewController(t)
+		defer ctr.Finish()
+		mockOrganization := mocks.NewMockIOrganizationUsecase(ctr)
+		mockOrganization.EXPECT().Tenant(gomock.Any(), "sales").Return(nil, usecase.ErrOrganizationNotFound)
+
+		mockGuard := mocks.NewMockILoginGuardUsecase(ctr)
+		mockGuard.EXPECT().Check(gomock.Any(), "user:sales/user", "ip:10.0.0.1").Return(time.Duration(0), nil)
+		mockGuard.EXPECT().Failure(gomock.Any(), "user:sales/user", "ip:10.0.0.1").Return(nil)
+
+		h := Auth{usecaseUser: mocks.NewMockIUserUsecase(ctr), usecaseOrganization: mockOrganization, guard: mockGuard}
+
+		rr := httptest.NewRecorder()
+		ch := chi.NewRouter()
+		ch.HandleFunc("/api/v1/login", h.Login)
+		ch.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
 }
 
+func TestAuth_LoginMfa(t *testing.T) {
+	tts := []struct{
+		name string
+		verifyErr error
+		code int
+	}{
+		{"test success", nil, http.StatusOK},
+		{"test wrong code", usecase.ErrMfaInvalidCode, http.StatusBadRequest},
+	}
+
+	for _, tt := range tts {
+		t.Run(tt.name, func(t *testing.T) {
+			b := dto.MfaLogin{Token: "pending", Code: "123456"}
+			js, _ := json.Marshal(b)
+			req, _ := http.NewRequest(http.MethodPost, "/api/v1/login/mfa", bytes.NewReader(js))
 
+			ctr := gomock.NewController(t)
+			defer ctr.Finish()
+			mockAuth := mocks.NewMockIAuth(ctr)
+			mockAuth.EXPECT().ValidateMfaToken("pending").Return(model.UserInReq{Id: 1}, nil)
+			mockSession := mocks.NewMockISessionUsecase(ctr)
+			if tt.verifyErr == nil {
+				mockSession.EXPECT().Start(gomock.Any(), model.Id(1), gomock.Any(), gomock.Any()).Return(model.Id(7), nil)
+				mockAuth.EXPECT().CreateToken(model.UserInReq{Id: 1, SessionId: 7}).Return(dto.JwtToken("token"), nil)
+			}
+
+			mockMfa := mocks.NewMockIMfaUsecase(ctr)
+			mockMfa.EXPECT().Verify(gomock.Any(), model.Id(1), "123456").Return(tt.verifyErr)
+
+			h := Auth{usecaseMfa: mockMfa, guard: allowingGuard(ctr), sessions: mockSession, auth: mockAuth}
+
+			rr := httptest.NewRecorder()
+			ch := chi.NewRouter()
+			ch.HandleFunc("/api/v1/login/mfa", h.LoginMfa)
+			ch.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.code, rr.Code)
+		})
+	}
+}
@@ -0,0 +1,781 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/bcrypt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"todoNote/internal/model"
+	"todoNote/internal/repo"
+	"todoNote/internal/server/http/log"
+	"todoNote/internal/usecase"
+)
+
+const (
+	DavRoot = "/dav/"
+	davPrincipal = DavRoot + "principal/"
+	davHome = DavRoot + "calendars/"
+	davCollection = davHome + "notes/"
+
+	davRealm = "todoNote"
+	davCollectionName = "Notes"
+	xmlContentType = "application/xml; charset=utf-8"
+	calendarObjectContentType = "text/calendar; charset=utf-8; component=VEVENT"
+
+	maxCalendarObjectSize = 1 << 20
+	maxDavRequestSize = 1 << 20
+	// bcrypt is too slow for clients which authenticate every request,
+	// cached credentials are still checked against the stored user on each of them
+	davCredentialLifetime = 5 * time.Minute
+
+	nsDav = "DAV:"
+	nsCalDav = "urn:ietf:params:xml:ns:caldav"
+	nsCalendarServer = "http://calendarserver.org/ns/"
+)
+
+var (
+	davResourceType = xml.Name{Space: nsDav, Local: "resourcetype"}
+	davDisplayName = xml.Name{Space: nsDav, Local: "displayname"}
+	davCurrentUserPrincipal = xml.Name{Space: nsDav, Local: "current-user-principal"}
+	davPrincipalUrl = xml.Name{Space: nsDav, Local: "principal-URL"}
+	davOwner = xml.Name{Space: nsDav, Local: "owner"}
+	davPrivilegeSet = xml.Name{Space: nsDav, Local: "current-user-privilege-set"}
+	davSupportedReportSet = xml.Name{Space: nsDav, Local: "supported-report-set"}
+	davGetEtag = xml.Name{Space: nsDav, Local: "getetag"}
+	davGetContentType = xml.Name{Space: nsDav, Local: "getcontenttype"}
+	davGetContentLength = xml.Name{Space: nsDav, Local: "getcontentlength"}
+	calHomeSet = xml.Name{Space: nsCalDav, Local: "calendar-home-set"}
+	calUserAddressSet = xml.Name{Space: nsCalDav, Local: "calendar-user-address-set"}
+	calComponentSet = xml.Name{Space: nsCalDav, Local: "supported-calendar-component-set"}
+	calData = xml.Name{Space: nsCalDav, Local: "calendar-data"}
+	csGetCtag = xml.Name{Space: nsCalendarServer, Local: "getctag"}
+
+	calQuery = xml.Name{Space: nsCalDav, Local: "calendar-query"}
+	calMultiget = xml.Name{Space: nsCalDav, Local: "calendar-multiget"}
+)
+
+// CalDav serves the notes of a user as one calendar collection (RFC 4791), clients authenticate with HTTP Basic
+type CalDav struct {
+	calendars usecase.ICalDavUsecase
+	usecaseUser usecase.IUserUsecase
+	usecaseMfa usecase.IMfaUsecase
+	usecaseOrganization usecase.IOrganizationUsecase
+	guard usecase.ILoginGuardUsecase
+	log log.Logger
+
+	mu sync.Mutex
+	// credentials keeps users by a hash of recently checked name and password
+	credentials map[[sha256.Size]byte]davCredential
+	now func() time.Time
+}
+
+type davCredential struct {
+	user model.User
+	expires time.Time
+}
+
+func NewCalDavHandler(c usecase.ICalDavUsecase, u usecase.IUserUsecase, m usecase.IMfaUsecase, o usecase.IOrganizationUsecase, g usecase.ILoginGuardUsecase, log log.Logger) *CalDav {
+	return &CalDav{
+		calendars: c,
+		usecaseUser: u,
+		usecaseMfa: m,
+		usecaseOrganization: o,
+		guard: g,
+		log: log,
+		credentials: make(map[[sha256.Size]byte]davCredential),
+		now: time.Now,
+	}
+}
+
+// WellKnown points clients to the CalDAV root (RFC 6764)
+func(h *CalDav) WellKnown(w http.ResponseWriter, r *http.Request) {
+	http.Redirect(w, r, DavRoot, http.StatusMovedPermanently)
+}
+
+func(h *CalDav) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		h.options(w)
+		return
+	}
+
+	u, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+	r = r.WithContext(repo.WithTenant(r.Context(), u.OrganizationId))
+
+	p := r.URL.Path
+	isObject := strings.HasPrefix(p, davCollection) && len(p) > len(davCollection) && !strings.Contains(p[len(davCollection):], "/")
+
+	switch {
+	case r.Method == "PROPFIND":
+		h.propfind(w, r, u)
+	case r.Method == "REPORT" && p == davCollection:
+		h.report(w, r, u)
+	case (r.Method == http.MethodGet || r.Method == http.MethodHead) && isObject:
+		h.getObject(w, r, u)
+	case r.Method == http.MethodPut && isObject:
+		h.putObject(w, r, u)
+	case r.Method == http.MethodDelete && isObject:
+		h.deleteObject(w, r, u)
+	default:
+		w.Header().Set("Allow", "OPTIONS, PROPFIND, REPORT, GET, HEAD, PUT, DELETE")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func(h *CalDav) options(w http.ResponseWriter) {
+	w.Header().Set("DAV", "1, 3, calendar-access")
+	w.Header().Set("Allow", "OPTIONS, PROPFIND, REPORT, GET, HEAD, PUT, DELETE")
+	w.WriteHeader(http.StatusOK)
+}
+
+// authenticate checks Basic credentials with the same attempt limits as login,
+// accounts with two-factor authentication are refused because a password alone must not be enough for them.
+// Users of other organizations than the default one give their name as organization/name
+func(h *CalDav) authenticate(w http.ResponseWriter, r *http.Request) (model.User, bool) {
+	name, password, ok := r.BasicAuth()
+	if !ok {
+		h.unauthorized(w)
+		return model.User{}, false
+	}
+
+	key := sha256.Sum256([]byte(name + "\x00" + password))
+	if u, ok := h.cachedUser(key); ok {
+		valid, err := h.stillValid(r.Context(), u)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			h.log.Error(fmt.Sprintf("caldav: check cached user(id: %v) error: %v", u.Id, err))
+			return model.User{}, false
+		}
+		if valid {
+			return u, true
+		}
+		h.forgetUser(key)
+	}
+
+	userKey := usecase.UserAttemptKey(name)
+	ipKey := usecase.IpAttemptKey(clientIp(r))
+	wait, err := h.guard.Check(r.Context(), userKey, ipKey)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		h.log.Error(fmt.Sprintf("caldav: check attempts: error: %v", err))
+		return model.User{}, false
+	}
+	if wait > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		writeErrorMessage(w, http.StatusTooManyRequests, tooManyAttempts)
+		return model.User{}, false
+	}
+
+	ctx, userName, err := h.tenant(r, name)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		h.log.Error(fmt.Sprintf("caldav: organization of user(name: %v) error: %v", name, err))
+		return model.User{}, false
+	}
+
+	usr, err := h.usecaseUser.FindByName(ctx, userName)
+	if err != nil && !errors.Is(err, repo.ErrNotFound) {
+		w.WriteHeader(http.StatusInternalServerError)
+		h.log.Error(fmt.Sprintf("caldav: find user(name: %v) error: %v", name, err))
+		return model.User{}, false
+	}
+	if err != nil || bcrypt.CompareHashAndPassword(usr.PasswordHash, []byte(password)) != nil {
+		if err := h.guard.Failure(r.Context(), userKey, ipKey); err != nil {
+			h.log.Error(fmt.Sprintf("caldav: register failed attempt: error: %v", err))
+		}
+		h.unauthorized(w)
+		return model.User{}, false
+	}
+
+	if err := h.guard.Success(r.Context(), userKey); err != nil {
+		h.log.Error(fmt.Sprintf("caldav: reset attempts: error: %v", err))
+	}
+
+	enabled, err := h.usecaseMfa.IsEnabled(r.Context(), usr.Id)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		h.log.Error(fmt.Sprintf("caldav: check mfa: user(id: %v) error: %v", usr.Id, err))
+		return model.User{}, false
+	}
+	if enabled {
+		writeErrorMessage(w, http.StatusForbidden, davMfaEnabled)
+		return model.User{}, false
+	}
+
+	h.cacheUser(key, *usr)
+	return *usr, true
+}
+
+// tenant scopes the request to the organization of the Basic user name, a name whose prefix is
+// no organization is taken whole as the name of a user of the default organization
+func(h *CalDav) tenant(r *http.Request, name string) (context.Context, string, error) {
+	organization, userName := usecase.SplitUserName(name)
+	ctx, err := h.usecaseOrganization.Tenant(r.Context(), organization)
+	if errors.Is(err, usecase.ErrOrganizationNotFound) {
+		return repo.WithTenant(r.Context(), model.DefaultOrganizationId), name, nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	return ctx, userName, nil
+}
+
+func(h *CalDav) unauthorized(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Basic realm="%v", charset="UTF-8"`, davRealm))
+	writeErrorMessage(w, http.StatusUnauthorized, incorrectLoginOrPassword)
+}
+
+func(h *CalDav) cachedUser(key [sha256.Size]byte) (model.User, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	c, ok := h.credentials[key]
+	if !ok || !h.now().Before(c.expires) {
+		return model.User{}, false
+	}
+
+	return c.user, true
+}
+
+// stillValid tells whether cached credentials of the user hold yet,
+// they don't once the user is removed, changes the password or enables two-factor authentication
+func(h *CalDav) stillValid(ctx context.Context, u model.User) (bool, error) {
+	ctx = repo.WithTenant(ctx, u.OrganizationId)
+	current, err := h.usecaseUser.FindById(ctx, u.Id)
+	if errors.Is(err, repo.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if !bytes.Equal(current.PasswordHash, u.PasswordHash) {
+		return false, nil
+	}
+
+	enabled, err := h.usecaseMfa.IsEnabled(ctx, u.Id)
+	if err != nil {
+		return false, err
+	}
+
+	return !enabled, nil
+}
+
+func(h *CalDav) forgetUser(key [sha256.Size]byte) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.credentials, key)
+}
+
+func(h *CalDav) cacheUser(key [sha256.Size]byte, u model.User) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := h.now()
+	for k, c := range h.credentials {
+		if !now.Before(c.expires) {
+			delete(h.credentials, k)
+		}
+	}
+
+	h.credentials[key] = davCredential{user: u, expires: now.Add(davCredentialLifetime)}
+}
+
+func(h *CalDav) propfind(w http.ResponseWriter, r *http.Request, u model.User) {
+	req, err := readPropfind(limitBody(w, r, maxDavRequestSize))
+	if err != nil {
+		writeErrorMessage(w, http.StatusBadRequest, wrongBody)
+		return
+	}
+
+	children := r.Header.Get("Depth") != "0"
+
+	var resources []davResource
+	switch p := r.URL.Path; {
+	case p == DavRoot:
+		resources = append(resources, h.rootResource())
+		if children {
+			resources = append(resources, h.principalResource(u), h.homeResource())
+		}
+	case p == davPrincipal:
+		resources = append(resources, h.principalResource(u))
+	case p == davHome:
+		resources = append(resources, h.homeResource())
+		if children {
+			c, ok := h.collectionResource(w, r, u)
+			if !ok {
+				return
+			}
+			resources = append(resources, c)
+		}
+	case p == davCollection:
+		c, ok := h.collectionResource(w, r, u)
+		if !ok {
+			return
+		}
+		resources = append(resources, c)
+
+		if children {
+			objects, err := h.calendars.Objects(r.Context(), u.Id, nil, nil)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				h.log.Error(fmt.Sprintf("caldav: propfind: user(id: %v) error: %v", u.Id, err))
+				return
+			}
+			for _, o := range objects {
+				resources = append(resources, objectResource(o))
+			}
+		}
+	case strings.HasPrefix(p, davCollection):
+		o, err := h.calendars.Object(r.Context(), u.Id, path.Base(p))
+		if errors.Is(err, usecase.ErrObjectNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			h.log.Error(fmt.Sprintf("caldav: propfind: user(id: %v) error: %v", u.Id, err))
+			return
+		}
+		resources = append(resources, objectResource(o))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	writeMultistatus(w, resources, req)
+}
+
+func(h *CalDav) report(w http.ResponseWriter, r *http.Request, u model.User) {
+	var req davReport
+	if err := xml.NewDecoder(limitBody(w, r, maxDavRequestSize)).Decode(&req); err != nil {
+		writeErrorMessage(w, http.StatusBadRequest, wrongBody)
+		return
+	}
+
+	var objects []usecase.CalendarObject
+	var missing []string
+	switch req.XMLName {
+	case calQuery:
+		from, to, events, err := req.Filter.timeRange()
+		if err != nil {
+			writeErrorMessage(w, http.StatusBadRequest, wrongDateFormat)
+			return
+		}
+		if events {
+			if objects, err = h.calendars.Objects(r.Context(), u.Id, from, to); err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				h.log.Error(fmt.Sprintf("caldav: calendar query: user(id: %v) error: %v", u.Id, err))
+				return
+			}
+		}
+	case calMultiget:
+		for _, href := range req.Hrefs {
+			name, ok := objectName(href)
+			if !ok {
+				missing = append(missing, href)
+				continue
+			}
+
+			o, err := h.calendars.Object(r.Context(), u.Id, name)
+			if errors.Is(err, usecase.ErrObjectNotFound) {
+				missing = append(missing, href)
+				continue
+			}
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				h.log.Error(fmt.Sprintf("caldav: calendar multiget: user(id: %v) error: %v", u.Id, err))
+				return
+			}
+			objects = append(objects, o)
+		}
+	default:
+		writeDavError(w, http.StatusForbidden, xml.Name{Space: nsDav, Local: "supported-report"})
+		return
+	}
+
+	resources := make([]davResource, 0, len(objects) + len(missing))
+	for _, o := range objects {
+		resources = append(resources, objectResource(o))
+	}
+	for _, href := range missing {
+		resources = append(resources, davResource{href: href, status: http.StatusNotFound})
+	}
+
+	writeMultistatus(w, resources, davPropRequest{names: req.Prop.names()})
+}
+
+func(h *CalDav) getObject(w http.ResponseWriter, r *http.Request, u model.User) {
+	o, err := h.calendars.Object(r.Context(), u.Id, path.Base(r.URL.Path))
+	if errors.Is(err, usecase.ErrObjectNotFound) {
+		writeErrorMessage(w, http.StatusNotFound, noNoteFound)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		h.log.Error(fmt.Sprintf("caldav: get object: user(id: %v) error: %v", u.Id, err))
+		return
+	}
+
+	w.Header().Set("Content-Type", calendarObjectContentType)
+	w.Header().Set("ETag", o.ETag)
+	if r.Header.Get("If-None-Match") == o.ETag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Length", strconv.Itoa(len(o.Data)))
+	w.WriteHeader(http.StatusOK)
+	if r.Method != http.MethodHead {
+		w.Write(o.Data)
+	}
+}
+
+func(h *CalDav) putObject(w http.ResponseWriter, r *http.Request, u model.User) {
+	c := usecase.PutCondition{
+		IfMatch: r.Header.Get("If-Match"),
+		IfNoneMatch: r.Header.Get("If-None-Match") == "*",
+	}
+
+	o, created, err := h.calendars.PutObject(r.Context(), u.Id, path.Base(r.URL.Path), limitBody(w, r, maxCalendarObjectSize), c)
+	switch {
+	case err == nil:
+	case errors.Is(err, usecase.ErrPreconditionFailed):
+		w.WriteHeader(http.StatusPreconditionFailed)
+		return
+	case errors.Is(err, usecase.ErrUnsupportedComponent):
+		writeDavError(w, http.StatusForbidden, xml.Name{Space: nsCalDav, Local: "supported-calendar-component"})
+		return
+	case errors.Is(err, usecase.ErrUidMismatch):
+		writeDavError(w, http.StatusConflict, xml.Name{Space: nsCalDav, Local: "no-uid-conflict"})
+		return
+	case errors.Is(err, usecase.ErrInvalidCalendar):
+		writeDavError(w, http.StatusForbidden, xml.Name{Space: nsCalDav, Local: "valid-calendar-data"})
+		return
+	case errors.Is(err, ErrBodyTooLarge):
+		writeDavError(w, http.StatusForbidden, xml.Name{Space: nsCalDav, Local: "max-resource-size"})
+		return
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+		h.log.Error(fmt.Sprintf("caldav: put object: user(id: %v) error: %v", u.Id, err))
+		return
+	}
+
+	w.Header().Set("ETag", o.ETag)
+	if created {
+		w.WriteHeader(http.StatusCreated)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func(h *CalDav) deleteObject(w http.ResponseWriter, r *http.Request, u model.User) {
+	err := h.calendars.DeleteObject(r.Context(), u.Id, path.Base(r.URL.Path), r.Header.Get("If-Match"))
+	switch {
+	case err == nil:
+		w.WriteHeader(http.StatusNoContent)
+	case errors.Is(err, usecase.ErrObjectNotFound):
+		writeErrorMessage(w, http.StatusNotFound, noNoteFound)
+	case errors.Is(err, usecase.ErrPreconditionFailed):
+		w.WriteHeader(http.StatusPreconditionFailed)
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+		h.log.Error(fmt.Sprintf("caldav: delete object: user(id: %v) error: %v", u.Id, err))
+	}
+}
+
+func(h *CalDav) rootResource() davResource {
+	return davResource{href: DavRoot, props: map[xml.Name]string{
+		davResourceType: "<d:collection/>",
+		davCurrentUserPrincipal: davHref(davPrincipal),
+	}}
+}
+
+func(h *CalDav) principalResource(u model.User) davResource {
+	return davResource{href: davPrincipal, props: map[xml.Name]string{
+		davResourceType: "<d:principal/>",
+		davDisplayName: xmlText(u.Name),
+		davCurrentUserPrincipal: davHref(davPrincipal),
+		davPrincipalUrl: davHref(davPrincipal),
+		calHomeSet: davHref(davHome),
+		calUserAddressSet: "",
+	}}
+}
+
+func(h *CalDav) homeResource() davResource {
+	return davResource{href: davHome, props: map[xml.Name]string{
+		davResourceType: "<d:collection/>",
+		davCurrentUserPrincipal: davHref(davPrincipal),
+		davOwner: davHref(davPrincipal),
+	}}
+}
+
+func(h *CalDav) collectionResource(w http.ResponseWriter, r *http.Request, u model.User) (davResource, bool) {
+	ctag, err := h.calendars.CTag(r.Context(), u.Id)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		h.log.Error(fmt.Sprintf("caldav: ctag: user(id: %v) error: %v", u.Id, err))
+		return davResource{}, false
+	}
+
+	return davResource{href: davCollection, props: map[xml.Name]string{
+		davResourceType: "<d:collection/><c:calendar/>",
+		davDisplayName: davCollectionName,
+		davCurrentUserPrincipal: davHref(davPrincipal),
+		davOwner: davHref(davPrincipal),
+		davPrivilegeSet: "<d:privilege><d:read/></d:privilege><d:privilege><d:write/></d:privilege>" +
+			"<d:privilege><d:write-content/></d:privilege><d:privilege><d:bind/></d:privilege><d:privilege><d:unbind/></d:privilege>",
+		davSupportedReportSet: "<d:supported-report><d:report><c:calendar-query/></d:report></d:supported-report>" +
+			"<d:supported-report><d:report><c:calendar-multiget/></d:report></d:supported-report>",
+		calComponentSet: `<c:comp name="VEVENT"/>`,
+		csGetCtag: xmlText(ctag),
+		davGetEtag: xmlText(`"` + ctag + `"`),
+	}}, true
+}
+
+func objectResource(o usecase.CalendarObject) davResource {
+	return davResource{href: davCollection + url.PathEscape(o.Name), props: map[xml.Name]string{
+		davResourceType: "",
+		davGetEtag: xmlText(o.ETag),
+		davGetContentType: calendarObjectContentType,
+		davGetContentLength: strconv.Itoa(len(o.Data)),
+		calData: xmlText(string(o.Data)),
+	}}
+}
+
+// objectName is the object of the notes collection an absolute or relative href points to
+func objectName(href string) (string, bool) {
+	u, err := url.Parse(href)
+	if err != nil {
+		return "", false
+	}
+
+	dir, name := path.Split(u.Path)
+	return name, dir == davCollection && name != ""
+}
+
+type davResource struct {
+	href string
+	// props holds the inner xml of every property the resource has
+	props map[xml.Name]string
+	// status answers for the whole resource when it is not found
+	status int
+}
+
+type davPropRequest struct {
+	// allProp lists every property but calendar data
+	allProp bool
+	propName bool
+	names []xml.Name
+}
+
+func readPropfind(r io.Reader) (davPropRequest, error) {
+	var req struct {
+		AllProp *struct{} `xml:"DAV: allprop"`
+		PropName *struct{} `xml:"DAV: propname"`
+		Prop davProp `xml:"DAV: prop"`
+	}
+
+	err := xml.NewDecoder(r).Decode(&req)
+	// an empty body asks for all properties
+	if err == io.EOF {
+		return davPropRequest{allProp: true}, nil
+	}
+	if err != nil {
+		return davPropRequest{}, err
+	}
+
+	names := req.Prop.names()
+	return davPropRequest{
+		allProp: req.AllProp != nil || req.PropName == nil && len(names) == 0,
+		propName: req.PropName != nil,
+		names: names,
+	}, nil
+}
+
+type davProp struct {
+	Props []struct {
+		XMLName xml.Name
+	} `xml:",any"`
+}
+
+func(p davProp) names() []xml.Name {
+	names := make([]xml.Name, 0, len(p.Props))
+	for _, n := range p.Props {
+		names = append(names, n.XMLName)
+	}
+
+	return names
+}
+
+type davReport struct {
+	XMLName xml.Name
+	Prop davProp `xml:"DAV: prop"`
+	Hrefs []string `xml:"DAV: href"`
+	Filter *davCompFilter `xml:"urn:ietf:params:xml:ns:caldav filter>comp-filter"`
+}
+
+type davCompFilter struct {
+	Name string `xml:"name,attr"`
+	CompFilters []davCompFilter `xml:"urn:ietf:params:xml:ns:caldav comp-filter"`
+	TimeRange *struct {
+		Start string `xml:"start,attr"`
+		End string `xml:"end,attr"`
+	} `xml:"urn:ietf:params:xml:ns:caldav time-range"`
+}
+
+// timeRange of a VCALENDAR filter, events is false when the filter asks for other components only
+func(f *davCompFilter) timeRange() (*time.Time, *time.Time, bool, error) {
+	if f == nil || len(f.CompFilters) == 0 {
+		return nil, nil, true, nil
+	}
+
+	for _, c := range f.CompFilters {
+		if !strings.EqualFold(c.Name, usecase.IcalEvent) {
+			continue
+		}
+		if c.TimeRange == nil {
+			return nil, nil, true, nil
+		}
+
+		from, err := davTime(c.TimeRange.Start)
+		if err != nil {
+			return nil, nil, false, err
+		}
+		to, err := davTime(c.TimeRange.End)
+		if err != nil {
+			return nil, nil, false, err
+		}
+
+		return from, to, true, nil
+	}
+
+	return nil, nil, false, nil
+}
+
+func davTime(v string) (*time.Time, error) {
+	if v == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse("20060102T150405Z", v)
+	if err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+var davPrefixes = map[string]string{nsDav: "d", nsCalDav: "c", nsCalendarServer: "cs"}
+
+// writeMultistatus answers found properties with 200 and requested unknown ones with 404
+func writeMultistatus(w http.ResponseWriter, resources []davResource, req davPropRequest) {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	fmt.Fprintf(&b, `<d:multistatus xmlns:d="%v" xmlns:c="%v" xmlns:cs="%v">`, nsDav, nsCalDav, nsCalendarServer)
+
+	for _, res := range resources {
+		b.WriteString("<d:response>")
+		b.WriteString(davHref(res.href))
+		if res.status != 0 {
+			fmt.Fprintf(&b, "<d:status>HTTP/1.1 %v %v</d:status></d:response>", res.status, http.StatusText(res.status))
+			continue
+		}
+
+		var found strings.Builder
+		var missing strings.Builder
+		for _, n := range requestedProps(res, req) {
+			v, ok := res.props[n]
+			switch {
+			case !ok:
+				writeProp(&missing, n, "")
+			case req.propName:
+				writeProp(&found, n, "")
+			default:
+				writeProp(&found, n, v)
+			}
+		}
+
+		if found.Len() > 0 {
+			fmt.Fprintf(&b, "<d:propstat><d:prop>%v</d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat>", found.String())
+		}
+		if missing.Len() > 0 {
+			fmt.Fprintf(&b, "<d:propstat><d:prop>%v</d:prop><d:status>HTTP/1.1 404 Not Found</d:status></d:propstat>", missing.String())
+		}
+		b.WriteString("</d:response>")
+	}
+	b.WriteString("</d:multistatus>")
+
+	w.Header().Set("Content-Type", xmlContentType)
+	w.WriteHeader(http.StatusMultiStatus)
+	io.WriteString(w, b.String())
+}
+
+func requestedProps(res davResource, req davPropRequest) []xml.Name {
+	if !req.allProp && !req.propName {
+		return req.names
+	}
+
+	names := make([]xml.Name, 0, len(res.props))
+	for n := range res.props {
+		if n != calData || req.propName {
+			names = append(names, n)
+		}
+	}
+
+	// properties in a stable order keep responses comparable
+	sort.Slice(names, func(i, j int) bool {
+		return names[i].Space + names[i].Local < names[j].Space + names[j].Local
+	})
+
+	return names
+}
+
+func writeProp(b *strings.Builder, n xml.Name, inner string) {
+	prefix, ok := davPrefixes[n.Space]
+	if !ok {
+		fmt.Fprintf(b, `<%v xmlns="%v">%v</%v>`, n.Local, xmlText(n.Space), inner, n.Local)
+		return
+	}
+
+	fmt.Fprintf(b, "<%v:%v>%v</%v:%v>", prefix, n.Local, inner, prefix, n.Local)
+}
+
+// writeDavError answers a failed precondition or postcondition of RFC 4918 and RFC 4791
+func writeDavError(w http.ResponseWriter, code int, condition xml.Name) {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	fmt.Fprintf(&b, `<d:error xmlns:d="%v" xmlns:c="%v">`, nsDav, nsCalDav)
+	writeProp(&b, condition, "")
+	b.WriteString("</d:error>")
+
+	w.Header().Set("Content-Type", xmlContentType)
+	w.WriteHeader(code)
+	io.WriteString(w, b.String())
+}
+
+func davHref(href string) string {
+	return "<d:href>" + xmlText(href) + "</d:href>"
+}
+
+func xmlText(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
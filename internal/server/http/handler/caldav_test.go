@@ -0,0 +1,377 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"todoNote/internal/model"
+	"todoNote/internal/repo"
+	"todoNote/internal/server/http/handler/mocks"
+	"todoNote/internal/usecase"
+)
+
+//go:generate mockgen -package=mocks -destination=mocks/caldav.go todoNote/internal/usecase ICalDavUsecase
+
+type calDavMocks struct {
+	calendars *mocks.MockICalDavUsecase
+	users *mocks.MockIUserUsecase
+	mfa *mocks.MockIMfaUsecase
+	organizations *mocks.MockIOrganizationUsecase
+	guard *mocks.MockILoginGuardUsecase
+	log *mocks.MockLogger
+}
+
+func newCalDavHandler(t *testing.T) (*CalDav, calDavMocks) {
+	ctr := gomock.NewController(t)
+	m := calDavMocks{
+		calendars: mocks.NewMockICalDavUsecase(ctr),
+		users: mocks.NewMockIUserUsecase(ctr),
+		mfa: mocks.NewMockIMfaUsecase(ctr),
+		organizations: mocks.NewMockIOrganizationUsecase(ctr),
+		guard: mocks.NewMockILoginGuardUsecase(ctr),
+		log: mocks.NewMockLogger(ctr),
+	}
+
+	return NewCalDavHandler(m.calendars, m.users, m.mfa, m.organizations, m.guard, m.log), m
+}
+
+// expectLogin lets the password "secret" of user 2 in once
+func(m calDavMocks) expectLogin(t *testing.T) []byte {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	assert.Nil(t, err)
+
+	m.guard.EXPECT().Check(gomock.Any(), gomock.Any()).Return(time.Duration(0), nil)
+	m.organizations.EXPECT().Tenant(gomock.Any(), "").Return(context.Background(), nil)
+	m.users.EXPECT().FindByName(gomock.Any(), "user").Return(&model.User{Id: 2, Name: "user", PasswordHash: hash}, nil)
+	m.guard.EXPECT().Success(gomock.Any(), usecase.UserAttemptKey("user")).Return(nil)
+	m.mfa.EXPECT().IsEnabled(gomock.Any(), model.Id(2)).Return(false, nil)
+	return hash
+}
+
+// expectCached lets cached credentials of user 2 with the password hash in once more
+func(m calDavMocks) expectCached(hash []byte) {
+	m.users.EXPECT().FindById(gomock.Any(), model.Id(2)).Return(&model.User{Id: 2, Name: "user", PasswordHash: hash}, nil)
+	m.mfa.EXPECT().IsEnabled(gomock.Any(), model.Id(2)).Return(false, nil)
+}
+
+func davRequest(method, path, body string) *http.Request {
+	req, _ := http.NewRequest(method, path, strings.NewReader(body))
+	req.SetBasicAuth("user", "secret")
+	return req
+}
+
+func TestCalDav_Authenticate(t *testing.T) {
+	t.Run("test no credentials", func(t *testing.T) {
+		h, _ := newCalDavHandler(t)
+		req, _ := http.NewRequest("PROPFIND", DavRoot, nil)
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		assert.Contains(t, rr.Header().Get("WWW-Authenticate"), "Basic")
+	})
+
+	t.Run("test unknown user", func(t *testing.T) {
+		h, m := newCalDavHandler(t)
+		m.guard.EXPECT().Check(gomock.Any(), gomock.Any()).Return(time.Duration(0), nil)
+		m.organizations.EXPECT().Tenant(gomock.Any(), "").Return(context.Background(), nil)
+		m.users.EXPECT().FindByName(gomock.Any(), "user").Return(nil, repo.NewNotFoundError("user"))
+		m.guard.EXPECT().Failure(gomock.Any(), gomock.Any()).Return(nil)
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, davRequest("PROPFIND", DavRoot, ""))
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("test locked", func(t *testing.T) {
+		h, m := newCalDavHandler(t)
+		m.guard.EXPECT().Check(gomock.Any(), gomock.Any()).Return(1500*time.Millisecond, nil)
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, davRequest("PROPFIND", DavRoot, ""))
+
+		assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+		assert.Equal(t, "2", rr.Header().Get("Retry-After"))
+	})
+
+	t.Run("test mfa enabled", func(t *testing.T) {
+		h, m := newCalDavHandler(t)
+		hash, _ := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+		m.guard.EXPECT().Check(gomock.Any(), gomock.Any()).Return(time.Duration(0), nil)
+		m.organizations.EXPECT().Tenant(gomock.Any(), "").Return(context.Background(), nil)
+		m.users.EXPECT().FindByName(gomock.Any(), "user").Return(&model.User{Id: 2, PasswordHash: hash}, nil)
+		m.guard.EXPECT().Success(gomock.Any(), gomock.Any()).Return(nil)
+		m.mfa.EXPECT().IsEnabled(gomock.Any(), model.Id(2)).Return(true, nil)
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, davRequest("PROPFIND", DavRoot, ""))
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+
+	t.Run("test organization", func(t *testing.T) {
+		h, m := newCalDavHandler(t)
+		hash, _ := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+		m.guard.EXPECT().Check(gomock.Any(), usecase.UserAttemptKey("sales/user"), gomock.Any()).Return(time.Duration(0), nil)
+		m.organizations.EXPECT().Tenant(gomock.Any(), "sales").
+			DoAndReturn(func(ctx context.Context, _ string) (context.Context, error) {
+				return repo.WithTenant(ctx, 5), nil
+			})
+		m.users.EXPECT().FindByName(gomock.Any(), "user").
+			DoAndReturn(func(ctx context.Context, _ string) (*model.User, error) {
+				id, _ := repo.Tenant(ctx)
+				assert.Equal(t, model.Id(5), id)
+				return &model.User{Id: 2, PasswordHash: hash, OrganizationId: 5}, nil
+			})
+		m.guard.EXPECT().Success(gomock.Any(), gomock.Any()).Return(nil)
+		m.mfa.EXPECT().IsEnabled(gomock.Any(), model.Id(2)).Return(false, nil)
+
+		req := davRequest("PROPFIND", DavRoot, "")
+		req.SetBasicAuth("sales/user", "secret")
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusMultiStatus, rr.Code)
+	})
+
+	t.Run("test name with slash of the default organization", func(t *testing.T) {
+		h, m := newCalDavHandler(t)
+		m.guard.EXPECT().Check(gomock.Any(), gomock.Any()).Return(time.Duration(0), nil)
+		m.organizations.EXPECT().Tenant(gomock.Any(), "a").Return(nil, usecase.ErrOrganizationNotFound)
+		m.users.EXPECT().FindByName(gomock.Any(), "a/b").Return(nil, repo.NewNotFoundError("a/b"))
+		m.guard.EXPECT().Failure(gomock.Any(), gomock.Any()).Return(nil)
+
+		req := davRequest("PROPFIND", DavRoot, "")
+		req.SetBasicAuth("a/b", "secret")
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("test credentials are cached", func(t *testing.T) {
+		h, m := newCalDavHandler(t)
+		m.expectCached(m.expectLogin(t))
+
+		for i := 0; i < 2; i++ {
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, davRequest("PROPFIND", DavRoot, ""))
+			assert.Equal(t, http.StatusMultiStatus, rr.Code)
+		}
+	})
+
+	t.Run("test cached credentials after password change", func(t *testing.T) {
+		h, m := newCalDavHandler(t)
+		m.expectLogin(t)
+		changed, err := bcrypt.GenerateFromPassword([]byte("other"), bcrypt.MinCost)
+		assert.Nil(t, err)
+		m.users.EXPECT().FindById(gomock.Any(), model.Id(2)).Return(&model.User{Id: 2, Name: "user", PasswordHash: changed}, nil)
+		m.guard.EXPECT().Check(gomock.Any(), gomock.Any()).Return(time.Duration(0), nil)
+		m.organizations.EXPECT().Tenant(gomock.Any(), "").Return(context.Background(), nil)
+		m.users.EXPECT().FindByName(gomock.Any(), "user").Return(&model.User{Id: 2, Name: "user", PasswordHash: changed}, nil)
+		m.guard.EXPECT().Failure(gomock.Any(), gomock.Any()).Return(nil)
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, davRequest("PROPFIND", DavRoot, ""))
+		assert.Equal(t, http.StatusMultiStatus, rr.Code)
+
+		rr = httptest.NewRecorder()
+		h.ServeHTTP(rr, davRequest("PROPFIND", DavRoot, ""))
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("test cached credentials after mfa enrollment", func(t *testing.T) {
+		h, m := newCalDavHandler(t)
+		hash := m.expectLogin(t)
+		m.users.EXPECT().FindById(gomock.Any(), model.Id(2)).Return(&model.User{Id: 2, Name: "user", PasswordHash: hash}, nil)
+		m.mfa.EXPECT().IsEnabled(gomock.Any(), model.Id(2)).Return(true, nil)
+		m.guard.EXPECT().Check(gomock.Any(), gomock.Any()).Return(time.Duration(0), nil)
+		m.organizations.EXPECT().Tenant(gomock.Any(), "").Return(context.Background(), nil)
+		m.users.EXPECT().FindByName(gomock.Any(), "user").Return(&model.User{Id: 2, Name: "user", PasswordHash: hash}, nil)
+		m.guard.EXPECT().Success(gomock.Any(), usecase.UserAttemptKey("user")).Return(nil)
+		m.mfa.EXPECT().IsEnabled(gomock.Any(), model.Id(2)).Return(true, nil)
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, davRequest("PROPFIND", DavRoot, ""))
+		assert.Equal(t, http.StatusMultiStatus, rr.Code)
+
+		rr = httptest.NewRecorder()
+		h.ServeHTTP(rr, davRequest("PROPFIND", DavRoot, ""))
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+
+	t.Run("test cached credentials of removed user", func(t *testing.T) {
+		h, m := newCalDavHandler(t)
+		m.expectLogin(t)
+		m.users.EXPECT().FindById(gomock.Any(), model.Id(2)).Return(nil, repo.NewNotFoundError("2"))
+		m.guard.EXPECT().Check(gomock.Any(), gomock.Any()).Return(time.Duration(0), nil)
+		m.organizations.EXPECT().Tenant(gomock.Any(), "").Return(context.Background(), nil)
+		m.users.EXPECT().FindByName(gomock.Any(), "user").Return(nil, repo.NewNotFoundError("user"))
+		m.guard.EXPECT().Failure(gomock.Any(), gomock.Any()).Return(nil)
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, davRequest("PROPFIND", DavRoot, ""))
+		assert.Equal(t, http.StatusMultiStatus, rr.Code)
+
+		rr = httptest.NewRecorder()
+		h.ServeHTTP(rr, davRequest("PROPFIND", DavRoot, ""))
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+}
+
+func TestCalDav_Propfind(t *testing.T) {
+	object := usecase.CalendarObject{Name: "a@example.com.ics", ETag: `"1"`, Data: []byte("BEGIN:VCALENDAR")}
+
+	t.Run("test collection with objects", func(t *testing.T) {
+		h, m := newCalDavHandler(t)
+		m.expectLogin(t)
+		m.calendars.EXPECT().CTag(gomock.Any(), model.Id(2)).Return("ctag", nil)
+		m.calendars.EXPECT().Objects(gomock.Any(), model.Id(2), nil, nil).Return([]usecase.CalendarObject{object}, nil)
+
+		req := davRequest("PROPFIND", davCollection, "")
+		req.Header.Set("Depth", "1")
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusMultiStatus, rr.Code)
+		body := rr.Body.String()
+		assert.Contains(t, body, "<d:href>/dav/calendars/notes/a@example.com.ics</d:href>")
+		assert.Contains(t, body, "<d:getetag>&#34;1&#34;</d:getetag>")
+		assert.Contains(t, body, "<cs:getctag>ctag</cs:getctag>")
+		assert.Contains(t, body, "<c:calendar/>")
+		// calendar data is sent on request only
+		assert.NotContains(t, body, "calendar-data")
+	})
+
+	t.Run("test unknown property", func(t *testing.T) {
+		h, m := newCalDavHandler(t)
+		m.expectLogin(t)
+
+		body := `<d:propfind xmlns:d="DAV:" xmlns:x="urn:example"><d:prop><d:current-user-principal/><x:color/></d:prop></d:propfind>`
+		req := davRequest("PROPFIND", DavRoot, body)
+		req.Header.Set("Depth", "0")
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusMultiStatus, rr.Code)
+		assert.Contains(t, rr.Body.String(), "<d:current-user-principal><d:href>/dav/principal/</d:href></d:current-user-principal>")
+		assert.Contains(t, rr.Body.String(), `<d:prop><color xmlns="urn:example"></color></d:prop><d:status>HTTP/1.1 404 Not Found</d:status>`)
+	})
+}
+
+func TestCalDav_Report(t *testing.T) {
+	object := usecase.CalendarObject{Name: "a.ics", ETag: `"1"`, Data: []byte("BEGIN:VCALENDAR\r\n")}
+
+	t.Run("test calendar query", func(t *testing.T) {
+		h, m := newCalDavHandler(t)
+		m.expectLogin(t)
+
+		from := time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC)
+		m.calendars.EXPECT().Objects(gomock.Any(), model.Id(2), &from, nil).Return([]usecase.CalendarObject{object}, nil)
+
+		body := `<c:calendar-query xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+			<d:prop><d:getetag/><c:calendar-data/></d:prop>
+			<c:filter><c:comp-filter name="VCALENDAR"><c:comp-filter name="VEVENT">
+				<c:time-range start="20211001T000000Z"/>
+			</c:comp-filter></c:comp-filter></c:filter>
+		</c:calendar-query>`
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, davRequest("REPORT", davCollection, body))
+
+		assert.Equal(t, http.StatusMultiStatus, rr.Code)
+		assert.Contains(t, rr.Body.String(), "<c:calendar-data>BEGIN:VCALENDAR&#xD;&#xA;</c:calendar-data>")
+	})
+
+	t.Run("test todos are not in the collection", func(t *testing.T) {
+		h, m := newCalDavHandler(t)
+		m.expectLogin(t)
+
+		body := `<c:calendar-query xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+			<d:prop><d:getetag/></d:prop>
+			<c:filter><c:comp-filter name="VCALENDAR"><c:comp-filter name="VTODO"/></c:comp-filter></c:filter>
+		</c:calendar-query>`
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, davRequest("REPORT", davCollection, body))
+
+		assert.Equal(t, http.StatusMultiStatus, rr.Code)
+		assert.NotContains(t, rr.Body.String(), "<d:response>")
+	})
+
+	t.Run("test multiget", func(t *testing.T) {
+		h, m := newCalDavHandler(t)
+		m.expectLogin(t)
+		m.calendars.EXPECT().Object(gomock.Any(), model.Id(2), "a.ics").Return(object, nil)
+		m.calendars.EXPECT().Object(gomock.Any(), model.Id(2), "gone.ics").Return(usecase.CalendarObject{}, usecase.ErrObjectNotFound)
+
+		body := `<c:calendar-multiget xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+			<d:prop><d:getetag/></d:prop>
+			<d:href>/dav/calendars/notes/a.ics</d:href>
+			<d:href>https://notes.example.com/dav/calendars/notes/gone.ics</d:href>
+			<d:href>/dav/calendars/other/b.ics</d:href>
+		</c:calendar-multiget>`
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, davRequest("REPORT", davCollection, body))
+
+		assert.Equal(t, http.StatusMultiStatus, rr.Code)
+		assert.Contains(t, rr.Body.String(), "<d:getetag>&#34;1&#34;</d:getetag>")
+		assert.Equal(t, 2, strings.Count(rr.Body.String(), "<d:status>HTTP/1.1 404 Not Found</d:status></d:response>"))
+	})
+}
+
+func TestCalDav_PutObject(t *testing.T) {
+	tts := []struct{
+		name string
+		putErr error
+		created bool
+		code int
+	}{
+		{"test created", nil, true, http.StatusCreated},
+		{"test updated", nil, false, http.StatusNoContent},
+		{"test stale etag", usecase.ErrPreconditionFailed, false, http.StatusPreconditionFailed},
+		{"test todo", usecase.ErrUnsupportedComponent, false, http.StatusForbidden},
+		{"test invalid", fmt.Errorf("put: %w", usecase.ErrInvalidCalendar), false, http.StatusForbidden},
+	}
+
+	for _, tt := range tts {
+		t.Run(tt.name, func(t *testing.T) {
+			h, m := newCalDavHandler(t)
+			m.expectLogin(t)
+			m.calendars.EXPECT().PutObject(gomock.Any(), model.Id(2), "a.ics", gomock.Any(), usecase.PutCondition{IfMatch: `"1"`}).
+				Return(usecase.CalendarObject{ETag: `"2"`}, tt.created, tt.putErr)
+
+			req := davRequest(http.MethodPut, davCollection + "a.ics", "BEGIN:VCALENDAR")
+			req.Header.Set("If-Match", `"1"`)
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.code, rr.Code)
+			if tt.putErr == nil {
+				assert.Equal(t, `"2"`, rr.Header().Get("ETag"))
+			}
+		})
+	}
+}
+
+func TestCalDav_DeleteObject(t *testing.T) {
+	h, m := newCalDavHandler(t)
+	m.expectCached(m.expectLogin(t))
+	m.calendars.EXPECT().DeleteObject(gomock.Any(), model.Id(2), "a.ics", "").Return(nil)
+	m.calendars.EXPECT().DeleteObject(gomock.Any(), model.Id(2), "gone.ics", "").Return(usecase.ErrObjectNotFound)
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, davRequest(http.MethodDelete, davCollection + "a.ics", ""))
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, davRequest(http.MethodDelete, davCollection + "gone.ics", ""))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
@@ -0,0 +1,185 @@
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"strings"
+	"todoNote/internal/model"
+	"todoNote/internal/repo"
+	"todoNote/internal/server/http/dto"
+	"todoNote/internal/server/http/log"
+	"todoNote/internal/server/http/middleware"
+	"todoNote/internal/usecase"
+)
+
+const (
+	feedTokenParam = "feedToken"
+	componentQueryParam = "component"
+
+	calendarContentType = "text/calendar; charset=utf-8"
+
+	maxCalendarImportSize = 10 << 20
+)
+
+type Calendar struct {
+	calendars usecase.ICalendarUsecase
+	// baseUrl of feed links, taken from the request when empty
+	baseUrl string
+	log log.Logger
+}
+
+func NewCalendarHandler(c usecase.ICalendarUsecase, baseUrl string, log log.Logger) *Calendar {
+	return &Calendar{
+		calendars: c,
+		baseUrl: strings.TrimSuffix(baseUrl, "/"),
+		log: log,
+	}
+}
+
+func(h *Calendar) ExportCalendar(w http.ResponseWriter, r *http.Request) {
+	component, ok := icalComponent(r.URL.Query().Get(componentQueryParam))
+	if !ok {
+		writeErrorMessage(w, http.StatusBadRequest, wrongComponent)
+		return
+	}
+
+	u, ok := middleware.UserFromContext(r, h.log, "export calendar")
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	h.writeCalendar(w, r, u.Id, component)
+}
+
+func(h *Calendar) GetFeed(w http.ResponseWriter, r *http.Request) {
+	component, ok := icalComponent(r.URL.Query().Get(componentQueryParam))
+	if !ok {
+		writeErrorMessage(w, http.StatusBadRequest, wrongComponent)
+		return
+	}
+
+	uId, err := h.calendars.FindFeedUser(r.Context(), chi.URLParam(r, feedTokenParam))
+	if errors.Is(err, usecase.ErrFeedNotFound) {
+		writeErrorMessage(w, http.StatusNotFound, noFeedFound)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		h.log.Error(fmt.Sprintf("get feed: err: %v", err))
+		return
+	}
+
+	// the secret token names the user, whichever organization it belongs to
+	h.writeCalendar(w, r.WithContext(repo.AllTenants(r.Context())), uId, component)
+}
+
+func(h *Calendar) CreateFeed(w http.ResponseWriter, r *http.Request) {
+	u, ok := middleware.UserFromContext(r, h.log, "create feed")
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	token, err := h.calendars.CreateFeed(r.Context(), u.Id)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		h.log.Error(fmt.Sprintf("create feed: user(id: %v) err: %v", u.Id, err))
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(dto.CalendarFeed{Url: fmt.Sprintf("%v/api/v1/feeds/%v.ics", h.base(r), token)})
+}
+
+func(h *Calendar) RevokeFeed(w http.ResponseWriter, r *http.Request) {
+	u, ok := middleware.UserFromContext(r, h.log, "revoke feed")
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.calendars.RevokeFeed(r.Context(), u.Id); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		h.log.Error(fmt.Sprintf("revoke feed: user(id: %v) err: %v", u.Id, err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func(h *Calendar) ImportCalendar(w http.ResponseWriter, r *http.Request) {
+	u, ok := middleware.UserFromContext(r, h.log, "import calendar")
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	report, err := h.calendars.Import(r.Context(), limitBody(w, r, maxCalendarImportSize), u.Id)
+	if errors.Is(err, usecase.ErrInvalidCalendar) || errors.Is(err, bufio.ErrTooLong) {
+		writeErrorMessage(w, http.StatusBadRequest, wrongCalendar)
+		return
+	}
+	if errors.Is(err, ErrBodyTooLarge) {
+		writeErrorMessage(w, http.StatusRequestEntityTooLarge, calendarTooLarge)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		h.log.Error(fmt.Sprintf("import calendar: user(id: %v) err: %v", u.Id, err))
+		return
+	}
+
+	json.NewEncoder(w).Encode(importReportDto(report))
+}
+
+// writeCalendar buffers the calendar so a failure can still be reported with a status
+func(h *Calendar) writeCalendar(w http.ResponseWriter, r *http.Request, uId model.Id, component usecase.IcalComponent) {
+	var buf bytes.Buffer
+	if err := h.calendars.Export(r.Context(), &buf, uId, component); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		h.log.Error(fmt.Sprintf("export calendar: user(id: %v) err: %v", uId, err))
+		return
+	}
+
+	w.Header().Set("Content-Type", calendarContentType)
+	buf.WriteTo(w)
+}
+
+func(h *Calendar) base(r *http.Request) string {
+	if h.baseUrl != "" {
+		return h.baseUrl
+	}
+
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+
+	return scheme + "://" + r.Host
+}
+
+func icalComponent(c string) (usecase.IcalComponent, bool) {
+	switch c {
+	case "", "event":
+		return usecase.IcalEvent, true
+	case "todo":
+		return usecase.IcalTodo, true
+	}
+
+	return "", false
+}
+
+func importReportDto(r model.ImportReport) dto.ImportReport {
+	items := make([]dto.ImportItem, 0, len(r.Items))
+	for _, i := range r.Items {
+		items = append(items, dto.ImportItem{Line: i.Line, Uid: i.Uid, NoteId: i.NoteId, Status: i.Status, Reason: i.Reason})
+	}
+
+	return dto.ImportReport{Created: r.Created, Updated: r.Updated, Skipped: r.Skipped, Items: items}
+}
@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"todoNote/internal/model"
+	"todoNote/internal/server/http/dto"
+	"todoNote/internal/server/http/handler/mocks"
+	"todoNote/internal/server/http/middleware"
+	"todoNote/internal/usecase"
+)
+
+//go:generate mockgen -package=mocks -destination=mocks/calendar.go todoNote/internal/usecase ICalendarUsecase
+
+func TestCalendar_GetFeed(t *testing.T) {
+	tts := []struct{
+		name string
+		path string
+		findErr error
+		code int
+	}{
+		{"test success", "/api/v1/feeds/secret.ics?component=todo", nil, http.StatusOK},
+		{"test revoked", "/api/v1/feeds/secret.ics", usecase.ErrFeedNotFound, http.StatusNotFound},
+		{"test unknown component", "/api/v1/feeds/secret.ics?component=journal", nil, http.StatusBadRequest},
+	}
+
+	for _, tt := range tts {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, tt.path, nil)
+
+			ctr := gomock.NewController(t)
+			defer ctr.Finish()
+			mockCalendar := mocks.NewMockICalendarUsecase(ctr)
+			if tt.code != http.StatusBadRequest {
+				mockCalendar.EXPECT().FindFeedUser(gomock.Any(), "secret").Return(model.Id(2), tt.findErr)
+			}
+			if tt.code == http.StatusOK {
+				mockCalendar.EXPECT().Export(gomock.Any(), gomock.Any(), model.Id(2), usecase.IcalTodo).
+					DoAndReturn(func(_ context.Context, w io.Writer, _ model.Id, _ usecase.IcalComponent) error {
+						_, err := w.Write([]byte("BEGIN:VCALENDAR"))
+						return err
+					})
+			}
+
+			h := Calendar{calendars: mockCalendar}
+
+			rr := httptest.NewRecorder()
+			ch := chi.NewRouter()
+			ch.Get("/api/v1/feeds/{feedToken}.ics", h.GetFeed)
+			ch.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.code, rr.Code)
+			if tt.code == http.StatusOK {
+				assert.Equal(t, calendarContentType, rr.Header().Get("Content-Type"))
+				assert.Equal(t, "BEGIN:VCALENDAR", rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestCalendar_CreateFeed(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/notes/feed", nil)
+	req.Host = "notes.example.com"
+
+	ctr := gomock.NewController(t)
+	defer ctr.Finish()
+	mockCalendar := mocks.NewMockICalendarUsecase(ctr)
+	mockCalendar.EXPECT().CreateFeed(gomock.Any(), model.Id(2)).Return("secret", nil)
+
+	h := Calendar{calendars: mockCalendar}
+
+	rr := httptest.NewRecorder()
+	ctx := context.WithValue(req.Context(), middleware.UserAuthorized, model.UserInReq{Id: 2})
+	http.HandlerFunc(h.CreateFeed).ServeHTTP(rr, req.WithContext(ctx))
+
+	var feed dto.CalendarFeed
+	json.NewDecoder(rr.Body).Decode(&feed)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Equal(t, "http://notes.example.com/api/v1/feeds/secret.ics", feed.Url)
+}
+
+func TestCalendar_ImportCalendar(t *testing.T) {
+	tts := []struct{
+		name string
+		importErr error
+		code int
+	}{
+		{"test success", nil, http.StatusOK},
+		{"test invalid calendar", fmt.Errorf("import calendar: %w", usecase.ErrInvalidCalendar), http.StatusBadRequest},
+		{"test failure", fmt.Errorf("import calendar: broken"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tts {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodPost, "/api/v1/notes/import", strings.NewReader("BEGIN:VCALENDAR"))
+
+			ctr := gomock.NewController(t)
+			defer ctr.Finish()
+			mockCalendar := mocks.NewMockICalendarUsecase(ctr)
+			report := model.ImportReport{Created: 1, Items: []model.ImportItem{{Uid: "a@example.com", NoteId: 7, Status: model.ImportCreated}}}
+			mockCalendar.EXPECT().Import(gomock.Any(), gomock.Any(), model.Id(2)).Return(report, tt.importErr)
+
+			mockLog := mocks.NewMockLogger(ctr)
+			if tt.code == http.StatusInternalServerError {
+				mockLog.EXPECT().Error(gomock.Any())
+			}
+
+			h := Calendar{calendars: mockCalendar, log: mockLog}
+
+			rr := httptest.NewRecorder()
+			ctx := context.WithValue(req.Context(), middleware.UserAuthorized, model.UserInReq{Id: 2})
+			http.HandlerFunc(h.ImportCalendar).ServeHTTP(rr, req.WithContext(ctx))
+
+			assert.Equal(t, tt.code, rr.Code)
+			if tt.code == http.StatusOK {
+				var got dto.ImportReport
+				json.NewDecoder(rr.Body).Decode(&got)
+				assert.Equal(t, 1, got.Created)
+				assert.Equal(t, []dto.ImportItem{{Uid: "a@example.com", NoteId: 7, Status: model.ImportCreated}}, got.Items)
+			}
+		})
+	}
+}
@@ -2,8 +2,10 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/go-chi/chi/v5"
+	"io"
 	"net/http"
 	"strconv"
 	"todoNote/internal/model"
@@ -16,12 +18,65 @@ const (
 	incorrectLoginOrPassword = "incorrect login or password"
 	passwordNotEqual = "password and confirm password are not equal"
 	wrongDateFormat = "invalid date-time format"
+	wrongTimeZone = "unknown time zone"
+	wrongPreferences = "invalid preferences"
+	wrongPeriod = "period is one of day, week, month, year"
 
 	noNoteFound = "no such note found"
+	notValidToken = "Not valid token"
+	wrongMfaCode = "invalid authentication code"
+	mfaAlreadyEnabled = "two-factor authentication is already enabled"
+	mfaNotEnrolled = "two-factor authentication is not enrolled"
+	noOidcProvider = "no such identity provider"
+	wrongOidcState = "invalid or expired login state"
+	oidcLoginFailed = "identity provider login failed"
+	identityLinked = "identity is linked to another user"
+	tooManyAttempts = "too many login attempts, try again later"
+	noSessionFound = "no such session found"
+	noExportFound = "no data export requested"
+	exportFailed = "data export failed, request a new one"
+	deletionNotScheduled = "account deletion is not scheduled"
+	noFeedFound = "no such calendar feed"
+	wrongComponent = "component is one of event, todo"
+	wrongCalendar = "body is not a valid iCalendar file"
+	calendarTooLarge = "calendar file is too large"
+	wrongFormat = "format is one of csv, json, ndjson, todotxt"
+	wrongImportFormat = "format is one of ics, csv, json, ndjson, todotxt"
+	wrongRender = "render is html when set"
+	notesFileTooLarge = "notes file is too large"
+	wrongSyncToken = "invalid sync token, sync again without since"
+	tooManyChanges = "too many changes, push at most 1000 at once"
+	davMfaEnabled = "accounts with two-factor authentication can not use CalDAV"
+	noOrganizationFound = "no such organization"
 )
 
+// ErrBodyTooLarge is returned by reads of a limitBody past its limit
+var ErrBodyTooLarge = errors.New("request body too large")
+
+// limitBody is http.MaxBytesReader which reports the exceeded limit as ErrBodyTooLarge
+func limitBody(w http.ResponseWriter, r *http.Request, limit int64) io.ReadCloser {
+	return &limitedBody{ReadCloser: http.MaxBytesReader(w, r.Body, limit), limit: limit}
+}
+
+type limitedBody struct {
+	io.ReadCloser
+	limit int64
+	read int64
+}
+
+func(b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.read += int64(n)
+	// MaxBytesReader fails only a read past the limit, any other error comes before it is reached
+	if err != nil && err != io.EOF && b.read >= b.limit {
+		return n, ErrBodyTooLarge
+	}
+
+	return n, err
+}
+
 func getIdFromRequest(r *http.Request, urlParam string) (model.Id, error){
-	urlId := chi.URLParam(r, noteIdParam)
+	urlId := chi.URLParam(r, urlParam)
 	id, err := strconv.Atoi(urlId)
 	if err != nil || id < 0 {
 		return 0, fmt.Errorf("bad id path param")
@@ -0,0 +1,21 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+)
+
+const icsFormat = "ics"
+
+// Import serves calendar and notes files on one endpoint, ?format= or Content-Type tells them apart
+func Import(c *Calendar, n *Note) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		format := r.URL.Query().Get(formatQueryParam)
+		if format == icsFormat || format == "" && strings.HasPrefix(r.Header.Get("Content-Type"), "text/calendar") {
+			c.ImportCalendar(w, r)
+			return
+		}
+
+		n.ImportNotes(w, r)
+	}
+}
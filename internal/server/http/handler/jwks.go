@@ -0,0 +1,28 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"todoNote/internal/server/http/auth"
+)
+
+type IJwks interface {
+	Jwks() auth.Jwks
+}
+
+type Jwks struct {
+	keys IJwks
+}
+
+func NewJwksHandler(k IJwks) *Jwks {
+	return &Jwks{
+		keys: k,
+	}
+}
+
+func(h *Jwks) GetJwks(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	// short max-age so that verifiers pick up a rotated key soon
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	json.NewEncoder(w).Encode(h.keys.Jwks())
+}
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"todoNote/internal/server/http/dto"
+	"todoNote/internal/server/http/log"
+	"todoNote/internal/server/http/middleware"
+	"todoNote/internal/usecase"
+)
+
+type Mfa struct {
+	usecaseMfa usecase.IMfaUsecase
+	log log.Logger
+}
+
+func NewMfaHandler(m usecase.IMfaUsecase, log log.Logger) *Mfa {
+	return &Mfa{
+		usecaseMfa: m,
+		log: log,
+	}
+}
+
+func(h *Mfa) Enroll(w http.ResponseWriter, r *http.Request) {
+	u, ok := middleware.UserFromContext(r, h.log, "enroll mfa")
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	e, err := h.usecaseMfa.Enroll(r.Context(), u.Id)
+	if errors.Is(err, usecase.ErrMfaAlreadyEnabled) {
+		writeErrorMessage(w, http.StatusConflict, mfaAlreadyEnabled)
+		return
+	}
+
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		h.log.Error(fmt.Sprintf("enroll mfa: user(id: %v) err: %v", u.Id, err))
+		return
+	}
+
+	json.NewEncoder(w).Encode(dto.MfaEnrollment{Secret: e.Secret, Uri: e.Uri})
+}
+
+func(h *Mfa) Confirm(w http.ResponseWriter, r *http.Request) {
+	var c dto.MfaCode
+	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+		writeErrorMessage(w, http.StatusBadRequest, wrongBody)
+		return
+	}
+
+	u, ok := middleware.UserFromContext(r, h.log, "confirm mfa")
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	codes, err := h.usecaseMfa.Confirm(r.Context(), u.Id, c.Code)
+	if !h.handleError(w, u.Id, "confirm mfa", err) {
+		return
+	}
+
+	json.NewEncoder(w).Encode(dto.RecoveryCodes{Codes: codes})
+}
+
+func(h *Mfa) Disable(w http.ResponseWriter, r *http.Request) {
+	var c dto.MfaCode
+	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+		writeErrorMessage(w, http.StatusBadRequest, wrongBody)
+		return
+	}
+
+	u, ok := middleware.UserFromContext(r, h.log, "disable mfa")
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	err := h.usecaseMfa.Disable(r.Context(), u.Id, c.Code)
+	if !h.handleError(w, u.Id, "disable mfa", err) {
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func(h *Mfa) handleError(w http.ResponseWriter, uId int64, method string, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, usecase.ErrMfaInvalidCode):
+		writeErrorMessage(w, http.StatusBadRequest, wrongMfaCode)
+	case errors.Is(err, usecase.ErrMfaNotEnrolled):
+		writeErrorMessage(w, http.StatusBadRequest, mfaNotEnrolled)
+	case errors.Is(err, usecase.ErrMfaAlreadyEnabled):
+		writeErrorMessage(w, http.StatusConflict, mfaAlreadyEnabled)
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+		h.log.Error(fmt.Sprintf("%v: user(id: %v) err: %v", method, uId, err))
+	}
+
+	return false
+}
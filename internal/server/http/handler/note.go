@@ -3,9 +3,11 @@ package handler
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 	"todoNote/internal/model"
 	"todoNote/internal/repo"
@@ -23,19 +25,47 @@ const (
 	limitQueryParam = "limit"
 	offsetQueryParam = "offset"
 	isFinishedQueryParam = "is_finished"
+	periodQueryParam = "period"
+	formatQueryParam = "format"
+	renderQueryParam = "render"
+
+	renderHtml = "html"
+
+	maxNotesImportSize = 10 << 20
 )
 
+var notesContentTypes = map[usecase.NotesFormat]string{
+	usecase.NotesCsv: "text/csv; charset=utf-8",
+	usecase.NotesJson: "application/json",
+	usecase.NotesNdjson: "application/x-ndjson",
+	usecase.NotesTodoTxt: "text/plain; charset=utf-8",
+}
+
+// notesFormat guesses the import format from the Content-Type header
+func notesFormat(contentType string) usecase.NotesFormat {
+	mediaType := strings.TrimSpace(strings.Split(contentType, ";")[0])
+	for f, t := range notesContentTypes {
+		if strings.HasPrefix(t, mediaType + ";") || t == mediaType {
+			return f
+		}
+	}
+
+	return ""
+}
+
 
 type Note struct {
 	usecaseNote usecase.INoteUsecase
 	usecaseUser usecase.IUserUsecase
+	usecaseBulk usecase.IBulkUsecase
 	log log.Logger
 }
 
-func NewNoteHandler(n usecase.INoteUsecase, u usecase.IUserUsecase, log log.Logger) *Note {
+func NewNoteHandler(n usecase.INoteUsecase, u usecase.IUserUsecase, b usecase.IBulkUsecase, log log.Logger) *Note {
 	return &Note{
 		usecaseUser: u,
 		usecaseNote: n,
+		usecaseBulk: b,
 		log: log,
 	}
 }
@@ -55,7 +85,46 @@ func(h *Note) CreateNote(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	zone := n.TimeZone
+	if zone != "" {
+		if _, ok := usecase.ValidateZone(zone); !ok {
+			writeErrorMessage(w, http.StatusBadRequest, wrongTimeZone)
+			return
+		}
+	} else if z, ok := usecase.ValidateZone(r.URL.Query().Get(timezoneQueryParam)); ok {
+		zone = z
+	}
+
+	var reminder time.Duration
+	if n.ReminderOffsetMinutes != nil {
+		reminder = time.Duration(*n.ReminderOffsetMinutes) * time.Minute
+		if !usecase.ValidateReminderOffset(reminder) {
+			writeErrorMessage(w, http.StatusBadRequest, wrongBody)
+			return
+		}
+	}
+
+	// user preferences fill what the request does not specify
+	if zone == "" || n.ReminderOffsetMinutes == nil {
+		usr, err := h.usecaseUser.FindById(r.Context(), u.Id)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			h.log.Error(fmt.Sprintf("create note: get user(id: %v): db err %v", u.Id, err))
+			return
+		}
+
+		if zone == "" {
+			zone = usr.TimeZone
+		}
+		if n.ReminderOffsetMinutes == nil {
+			reminder = usr.Preferences.ReminderOffset
+		}
+	}
+
 	note := model.NewNote(0, u.Id, n.Title, n.Text, n.Date, false)
+	note.TimeZone = zone
+	note.IsFloating = n.IsFloating
+	note.ReminderOffset = reminder
 	uId, err := h.usecaseNote.CreateNote(r.Context(), note)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
@@ -68,32 +137,113 @@ func(h *Note) CreateNote(w http.ResponseWriter, r *http.Request) {
 }
 
 func(h *Note) GetNotes(w http.ResponseWriter, r *http.Request) {
-	startFrom := r.URL.Query().Get(startFromQueryParam)
-	limit := r.URL.Query().Get(limitQueryParam)
-	offset := r.URL.Query().Get(offsetQueryParam)
-	timezone := r.URL.Query().Get(timezoneQueryParam)
-	isFinished := r.URL.Query().Get(isFinishedQueryParam)
-
 	u, ok := middleware.UserFromContext(r, h.log, "get notes")
 	if !ok {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
-	p := usecase.FindParams{}
-	zone, ok := h.checkZoneRule(r.Context(), timezone, u.Id)
+	p, ok := h.findParams(w, r, u.Id)
 	if !ok {
+		return
+	}
+
+	notes, err := h.usecaseNote.FindAll(r.Context(), p)
+	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
+		h.log.Error("get notes: find all: db err: %v", err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(notes)
+}
+
+func(h *Note) ExportNotes(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get(formatQueryParam)
+	if format == "" {
+		format = usecase.NotesJson
+	}
+	if !usecase.ValidNotesFormat(format) {
+		writeErrorMessage(w, http.StatusBadRequest, wrongFormat)
 		return
 	}
 
+	u, ok := middleware.UserFromContext(r, h.log, "export notes")
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	p, ok := h.findParams(w, r, u.Id)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", notesContentTypes[format])
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="notes.%v"`, format))
+	// rows are streamed so a failure can only cut the response short
+	if err := h.usecaseBulk.Export(r.Context(), w, p, format); err != nil {
+		h.log.Error(fmt.Sprintf("export notes: user(id: %v) err: %v", u.Id, err))
+	}
+}
+
+func(h *Note) ImportNotes(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get(formatQueryParam)
+	if format == "" {
+		format = notesFormat(r.Header.Get("Content-Type"))
+	}
+	if !usecase.ValidNotesFormat(format) {
+		writeErrorMessage(w, http.StatusBadRequest, wrongImportFormat)
+		return
+	}
+
+	u, ok := middleware.UserFromContext(r, h.log, "import notes")
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	report, err := h.usecaseBulk.Import(r.Context(), limitBody(w, r, maxNotesImportSize), u.Id, format)
+	if errors.Is(err, ErrBodyTooLarge) {
+		writeErrorMessage(w, http.StatusRequestEntityTooLarge, notesFileTooLarge)
+		return
+	}
+	if errors.Is(err, usecase.ErrInvalidNotesFile) {
+		writeErrorMessage(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		h.log.Error(fmt.Sprintf("import notes: user(id: %v) err: %v", u.Id, err))
+		return
+	}
+
+	json.NewEncoder(w).Encode(importReportDto(report))
+}
+
+// findParams reads the filters of note listing and export, it writes the error response itself
+func(h *Note) findParams(w http.ResponseWriter, r *http.Request, uId model.Id) (usecase.FindParams, bool) {
+	startFrom := r.URL.Query().Get(startFromQueryParam)
+	limit := r.URL.Query().Get(limitQueryParam)
+	offset := r.URL.Query().Get(offsetQueryParam)
+	timezone := r.URL.Query().Get(timezoneQueryParam)
+	isFinished := r.URL.Query().Get(isFinishedQueryParam)
+	period := r.URL.Query().Get(periodQueryParam)
+
+	p := usecase.FindParams{}
+	zone, ok := h.checkZoneRule(r.Context(), timezone, uId)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return p, false
+	}
+
 	p.Zone = zone
 	p.Filter = repo.NoteFilter{}
 	page := repo.PageFilter{
-		Limit: repo.GetUIntParamPointer(offset),
-		Offset: repo.GetUIntParamPointer(limit)}
+		Limit: repo.GetUIntParamPointer(limit),
+		Offset: repo.GetUIntParamPointer(offset)}
 	p.Filter.Page = page
-	p.Filter.UserId = u.Id
+	p.Filter.UserId = uId
 
 	if startFrom != "" {
 		if time, err := time.Parse(time.RFC3339, startFrom); err == nil {
@@ -106,14 +256,29 @@ func(h *Note) GetNotes(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	notes, err := h.usecaseNote.FindAll(r.Context(), p)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		h.log.Error("get notes: find all: db err: %v", err)
-		return
+	if period != "" {
+		usr, err := h.usecaseUser.FindById(r.Context(), uId)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			h.log.Error(fmt.Sprintf("get notes: get user(id: %v): db err %v", uId, err))
+			return p, false
+		}
+
+		at := time.Now()
+		if p.Filter.TakeFrom != nil {
+			at = *p.Filter.TakeFrom
+		}
+
+		from, to, ok := usecase.PeriodRange(usecase.Convert(at, zone), period, usr.Preferences.WeekStart)
+		if !ok {
+			writeErrorMessage(w, http.StatusBadRequest, wrongPeriod)
+			return p, false
+		}
+		p.Filter.TakeFrom = &from
+		p.Filter.TakeTo = &to
 	}
 
-	json.NewEncoder(w).Encode(notes)
+	return p, true
 }
 
 func(h *Note) GetNote(w http.ResponseWriter, r *http.Request) {
@@ -123,6 +288,12 @@ func(h *Note) GetNote(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	render := r.URL.Query().Get(renderQueryParam)
+	if render != "" && render != renderHtml {
+		writeErrorMessage(w, http.StatusBadRequest, wrongRender)
+		return
+	}
+
 	u, ok := middleware.UserFromContext(r, h.log, "get note")
 	if !ok {
 		w.WriteHeader(http.StatusInternalServerError)
@@ -149,7 +320,19 @@ func(h *Note) GetNote(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	json.NewEncoder(w).Encode(note)
+	if render != renderHtml {
+		json.NewEncoder(w).Encode(note)
+		return
+	}
+
+	html, err := usecase.RenderMarkdown(note.Text)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		h.log.Error(fmt.Sprintf("get note: user(id: %v) note(id: %v) err: %v", u.Id, noteId, err))
+		return
+	}
+
+	json.NewEncoder(w).Encode(dto.RenderedNote{Note: note, Html: html})
 }
 
 func(h *Note) PartialUpdateNote(w http.ResponseWriter, r *http.Request) {
@@ -173,7 +356,11 @@ func(h *Note) PartialUpdateNote(w http.ResponseWriter, r *http.Request) {
 
 	note := model.NewNote(noteId, u.Id, n.Title, n.Text, n.Date, n.IsFinished)
 
-	err = h.usecaseNote.UpdateNote(r.Context(), note)
+	err = h.usecaseNote.UpdateNote(r.Context(), note, usecase.NoteZone{TimeZone: n.TimeZone, IsFloating: n.IsFloating})
+	if errors.Is(err, usecase.ErrInvalidTimeZone) {
+		writeErrorMessage(w, http.StatusBadRequest, wrongTimeZone)
+		return
+	}
 	if _, ok := err.(*usecase.ElemNotFound); ok {
 		w.WriteHeader(http.StatusNotFound)
 		h.log.Warn(fmt.Sprintf("update note: note not found: user(id: %v) note(id: %v)", u.Id, noteId))
@@ -8,11 +8,13 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 	"todoNote/internal/model"
+	"todoNote/internal/repo"
 	"todoNote/internal/server/http/dto"
 	"todoNote/internal/server/http/handler/mocks"
 	"todoNote/internal/server/http/middleware"
@@ -20,6 +22,7 @@ import (
 )
 
 //go:generate mockgen -package=mocks -destination=mocks/user.go todoNote/internal/usecase INoteUsecase,IUserUsecase
+//go:generate mockgen -package=mocks -destination=mocks/bulk.go todoNote/internal/usecase IBulkUsecase
 //go:generate mockgen -package=mocks -destination=mocks/log.go todoNote/internal/server/http/log Logger
 
 
@@ -45,9 +48,15 @@ func TestNote_CreateNote(t *testing.T) {
 				assert.Equal(t, date.Format(time.RFC3339), note.Date.Format(time.RFC3339))
 				assert.Equal(t, "text", note.Text)
 				assert.Equal(t, "title", note.Title)
+				assert.Equal(t, model.UTC, note.TimeZone)
+				assert.Equal(t, 15 * time.Minute, note.ReminderOffset)
 		})
 
-		h := Note{usecaseNote: mockCase}
+		mockUserCase := mocks.NewMockIUserUsecase(ctr)
+		mockUserCase.EXPECT().FindById(gomock.Any(), model.Id(2)).
+			Return(&model.User{Id: 2, TimeZone: model.UTCp3, Preferences: model.Preferences{ReminderOffset: 15 * time.Minute}}, nil)
+
+		h := Note{usecaseNote: mockCase, usecaseUser: mockUserCase}
 
 		rr := httptest.NewRecorder()
 		ch := chi.NewRouter()
@@ -63,6 +72,58 @@ func TestNote_CreateNote(t *testing.T) {
 		assert.Equal(t, model.Id(1), note.Id)
 		assert.Equal(t, http.StatusOK, rr.Code)
 	})
+
+	t.Run("test floating note in query time zone", func(t *testing.T) {
+		offset := 0
+		b := dto.NewNote{Title: "stand-up", Date: time.Now(), IsFloating: true, ReminderOffsetMinutes: &offset}
+
+		js, _ := json.Marshal(b)
+		req, _ := http.NewRequest("POST", "/api/v1/notes", bytes.NewReader(js))
+		q := req.URL.Query()
+		q.Add(timezoneQueryParam, "Europe/Kyiv")
+		req.URL.RawQuery = q.Encode()
+
+		ctr := gomock.NewController(t)
+		defer ctr.Finish()
+		mockCase := mocks.NewMockINoteUsecase(ctr)
+		mockCase.EXPECT().CreateNote(gomock.Any(), gomock.Any()).
+			Return(model.Id(1), nil).
+			Do(func(_ context.Context, note *model.Note) {
+				assert.Equal(t, "Europe/Kyiv", note.TimeZone)
+				assert.True(t, note.IsFloating)
+				assert.Equal(t, time.Duration(0), note.ReminderOffset)
+		})
+
+		h := Note{usecaseNote: mockCase}
+
+		rr := httptest.NewRecorder()
+		ch := chi.NewRouter()
+		ch.HandleFunc("/api/v1/notes", h.CreateNote)
+		ctx := context.WithValue(req.Context(), middleware.UserAuthorized, model.UserInReq{Id: 2})
+		ch.ServeHTTP(rr, req.WithContext(ctx))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("test unknown note time zone", func(t *testing.T) {
+		b := dto.NewNote{Title: "title", Date: time.Now(), TimeZone: "Mars/Olympus"}
+
+		js, _ := json.Marshal(b)
+		req, _ := http.NewRequest("POST", "/api/v1/notes", bytes.NewReader(js))
+
+		ctr := gomock.NewController(t)
+		defer ctr.Finish()
+
+		h := Note{usecaseNote: mocks.NewMockINoteUsecase(ctr)}
+
+		rr := httptest.NewRecorder()
+		ch := chi.NewRouter()
+		ch.HandleFunc("/api/v1/notes", h.CreateNote)
+		ctx := context.WithValue(req.Context(), middleware.UserAuthorized, model.UserInReq{Id: 2})
+		ch.ServeHTTP(rr, req.WithContext(ctx))
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
 }
 
 func TestNote_GetNote(t *testing.T) {
@@ -120,7 +181,7 @@ func TestNote_GetNote(t *testing.T) {
 		mockLog := mocks.NewMockLogger(ctr)
 		mockLog.EXPECT().Warn(gomock.Any())
 
-		h := NewNoteHandler(mockCase, mockUserCase, mockLog)
+		h := NewNoteHandler(mockCase, mockUserCase, nil, mockLog)
 
 		rr := httptest.NewRecorder()
 		ch := chi.NewRouter()
@@ -135,6 +196,42 @@ func TestNote_GetNote(t *testing.T) {
 		fmt.Println(rr.Body)
 		assert.Equal(t, http.StatusNotFound, rr.Code)
 	})
+	t.Run("test render html", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/api/v1/notes/1?timezone=UTC&render=html", nil)
+
+		ctr := gomock.NewController(t)
+		defer ctr.Finish()
+		mockCase := mocks.NewMockINoteUsecase(ctr)
+		mockCase.EXPECT().FindNote(gomock.Any(), model.Id(1), model.Id(2), model.UTC).
+			Return(&model.Note{Id: 1, Text: "**milk** <script>alert(1)</script>"}, nil)
+
+		h := Note{usecaseNote: mockCase}
+
+		rr := httptest.NewRecorder()
+		ch := chi.NewRouter()
+		ch.HandleFunc("/api/v1/notes/{noteId}", h.GetNote)
+		ctx := context.WithValue(req.Context(), middleware.UserAuthorized, model.UserInReq{Id: 2})
+		ch.ServeHTTP(rr, req.WithContext(ctx))
+
+		var note dto.RenderedNote
+		json.NewDecoder(rr.Body).Decode(&note)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, model.Id(1), note.Id)
+		assert.Equal(t, "<p><strong>milk</strong> alert(1)</p>\n", note.Html)
+	})
+
+	t.Run("test unknown render", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/api/v1/notes/1?render=pdf", nil)
+
+		h := Note{}
+
+		rr := httptest.NewRecorder()
+		ch := chi.NewRouter()
+		ch.HandleFunc("/api/v1/notes/{noteId}", h.GetNote)
+		ch.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
 }
 
 func TestNote_GetNotes(t *testing.T) {
@@ -169,6 +266,37 @@ func TestNote_GetNotes(t *testing.T) {
 		assert.Equal(t, http.StatusOK, rr.Code)
 	})
 
+	t.Run("test week period starts on preferred day", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "api/v1/notes", nil)
+		q := req.URL.Query()
+		q.Add(timezoneQueryParam, "Europe/Kyiv")
+		q.Add(startFromQueryParam, "2021-10-06T12:00:00Z")
+		q.Add(periodQueryParam, "week")
+		req.URL.RawQuery = q.Encode()
+
+		ctr := gomock.NewController(t)
+		defer ctr.Finish()
+		kyiv, _ := usecase.Location("Europe/Kyiv")
+		mockCase := mocks.NewMockINoteUsecase(ctr)
+		mockCase.EXPECT().FindAll(gomock.Any(), gomock.Any()).Return([]model.Note{}, nil).
+			Do(func(ctx context.Context, p usecase.FindParams) {
+				assert.True(t, time.Date(2021, 10, 3, 0, 0, 0, 0, kyiv).Equal(*p.Filter.TakeFrom))
+				assert.True(t, time.Date(2021, 10, 10, 0, 0, 0, 0, kyiv).Equal(*p.Filter.TakeTo))
+		})
+
+		mockUserCase := mocks.NewMockIUserUsecase(ctr)
+		mockUserCase.EXPECT().FindById(gomock.Any(), model.Id(1)).
+			Return(&model.User{Id: 1, Preferences: model.Preferences{WeekStart: time.Sunday}}, nil)
+
+		h := Note{usecaseNote: mockCase, usecaseUser: mockUserCase}
+
+		rr := httptest.NewRecorder()
+		ctx := context.WithValue(req.Context(), middleware.UserAuthorized, model.UserInReq{Id: 1})
+		http.HandlerFunc(h.GetNotes).ServeHTTP(rr, req.WithContext(ctx))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
 	t.Run("test without timezone query param", func(t *testing.T) {
 		req, _ := http.NewRequest("GET", "api/v1/notes", nil)
 
@@ -254,7 +382,8 @@ func TestNote_GetNotes(t *testing.T) {
 func TestNote_PartialUpdateNote(t *testing.T) {
 	t.Run("note exists", func(t *testing.T) {
 		date := time.Now()
-		b := dto.NoteUpdate{Title: "new title", Text: "new text", Date: date, IsFinished: true}
+		zone, floating := "Europe/Kyiv", true
+		b := dto.NoteUpdate{Title: "new title", Text: "new text", Date: date, IsFinished: true, TimeZone: &zone, IsFloating: &floating}
 
 		js, _ := json.Marshal(b)
 		req, _ := http.NewRequest(http.MethodPatch, "/api/v1/notes/2", bytes.NewReader(js))
@@ -262,9 +391,9 @@ func TestNote_PartialUpdateNote(t *testing.T) {
 		ctr := gomock.NewController(t)
 		defer ctr.Finish()
 		mockCase := mocks.NewMockINoteUsecase(ctr)
-		mockCase.EXPECT().UpdateNote(gomock.Any(), gomock.Any()).
+		mockCase.EXPECT().UpdateNote(gomock.Any(), gomock.Any(), usecase.NoteZone{TimeZone: &zone, IsFloating: &floating}).
 			Return(nil).
-			Do(func(_ context.Context, note *model.Note) {
+			Do(func(_ context.Context, note *model.Note, _ usecase.NoteZone) {
 			assert.Equal(t, model.Id(2), note.UserId)
 			assert.Equal(t, true, note.IsFinished)
 			assert.Equal(t, date.Format(time.RFC3339), note.Date.Format(time.RFC3339))
@@ -296,9 +425,9 @@ func TestNote_PartialUpdateNote(t *testing.T) {
 		ctr := gomock.NewController(t)
 		defer ctr.Finish()
 		mockCase := mocks.NewMockINoteUsecase(ctr)
-		mockCase.EXPECT().UpdateNote(gomock.Any(), gomock.Any()).
+		mockCase.EXPECT().UpdateNote(gomock.Any(), gomock.Any(), gomock.Any()).
 			Return(usecase.NewNoteNotFoundError(2, 2)).
-			Do(func(_ context.Context, note *model.Note) {
+			Do(func(_ context.Context, note *model.Note, _ usecase.NoteZone) {
 				assert.Equal(t, model.Id(2), note.UserId)
 				assert.Equal(t, true, note.IsFinished)
 				assert.Equal(t, date.Format(time.RFC3339), note.Date.Format(time.RFC3339))
@@ -323,6 +452,25 @@ func TestNote_PartialUpdateNote(t *testing.T) {
 		assert.Equal(t, http.StatusNotFound, rr.Code)
 	})
 
+	t.Run("unknown time zone", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodPatch, "/api/v1/notes/2", bytes.NewReader([]byte(`{"time_zone":"Mars/Olympus"}`)))
+
+		ctr := gomock.NewController(t)
+		defer ctr.Finish()
+		mockCase := mocks.NewMockINoteUsecase(ctr)
+		mockCase.EXPECT().UpdateNote(gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(fmt.Errorf("update note: %w", usecase.ErrInvalidTimeZone))
+
+		h := Note{usecaseNote: mockCase}
+
+		rr := httptest.NewRecorder()
+		ch := chi.NewRouter()
+		ch.HandleFunc("/api/v1/notes/{noteId}", h.PartialUpdateNote)
+		req = req.WithContext(context.WithValue(req.Context(), middleware.UserAuthorized, model.UserInReq{Id: 2}))
+		ch.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
 }
 
 
@@ -381,3 +529,106 @@ func TestNote_DeleteNote(t *testing.T) {
 		assert.Equal(t, http.StatusNoContent, rr.Code)
 	})
 }
+
+func TestNote_ExportNotes(t *testing.T) {
+	tts := []struct{
+		name string
+		path string
+		format string
+		code int
+		contentType string
+		page repo.PageFilter
+	}{
+		{"test csv", "/api/v1/notes/export?format=csv&is_finished=true", usecase.NotesCsv, http.StatusOK, "text/csv; charset=utf-8", repo.PageFilter{}},
+		{"test default json", "/api/v1/notes/export?is_finished=true", usecase.NotesJson, http.StatusOK, "application/json", repo.PageFilter{}},
+		{"test page", "/api/v1/notes/export?is_finished=true&limit=10&offset=20", usecase.NotesJson, http.StatusOK, "application/json",
+			repo.PageFilter{Limit: repo.GetUIntParamPointer("10"), Offset: repo.GetUIntParamPointer("20")}},
+		{"test unknown format", "/api/v1/notes/export?format=xlsx", "", http.StatusBadRequest, "", repo.PageFilter{}},
+	}
+
+	for _, tt := range tts {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, tt.path, nil)
+
+			ctr := gomock.NewController(t)
+			defer ctr.Finish()
+			mockBulk := mocks.NewMockIBulkUsecase(ctr)
+			mockUser := mocks.NewMockIUserUsecase(ctr)
+			if tt.code == http.StatusOK {
+				mockUser.EXPECT().FindById(gomock.Any(), model.Id(2)).Return(&model.User{Id: 2, TimeZone: "Europe/Kyiv"}, nil)
+				mockBulk.EXPECT().Export(gomock.Any(), gomock.Any(), gomock.Any(), tt.format).
+					DoAndReturn(func(_ context.Context, w io.Writer, p usecase.FindParams, _ usecase.NotesFormat) error {
+						assert.Equal(t, model.Id(2), p.Filter.UserId)
+						assert.Equal(t, "Europe/Kyiv", p.Zone)
+						assert.True(t, *p.Filter.IsFinished)
+						assert.Equal(t, tt.page, p.Filter.Page)
+						_, err := w.Write([]byte("id,title"))
+						return err
+					})
+			}
+
+			h := Note{usecaseBulk: mockBulk, usecaseUser: mockUser}
+
+			rr := httptest.NewRecorder()
+			ctx := context.WithValue(req.Context(), middleware.UserAuthorized, model.UserInReq{Id: 2})
+			http.HandlerFunc(h.ExportNotes).ServeHTTP(rr, req.WithContext(ctx))
+
+			assert.Equal(t, tt.code, rr.Code)
+			if tt.code == http.StatusOK {
+				assert.Equal(t, tt.contentType, rr.Header().Get("Content-Type"))
+				assert.Equal(t, "id,title", rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestNote_ImportNotes(t *testing.T) {
+	tts := []struct{
+		name string
+		path string
+		contentType string
+		format string
+		importErr error
+		code int
+	}{
+		{"test format param", "/api/v1/notes/import?format=ndjson", "", usecase.NotesNdjson, nil, http.StatusOK},
+		{"test content type", "/api/v1/notes/import", "text/csv; charset=utf-8", usecase.NotesCsv, nil, http.StatusOK},
+		{"test unknown format", "/api/v1/notes/import", "application/xml", "", nil, http.StatusBadRequest},
+		{"test broken file", "/api/v1/notes/import?format=json", "", usecase.NotesJson,
+			fmt.Errorf("import notes: %w", usecase.ErrInvalidNotesFile), http.StatusBadRequest},
+		{"test too large file", "/api/v1/notes/import?format=csv", "", usecase.NotesCsv,
+			fmt.Errorf("import notes: %w", ErrBodyTooLarge), http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tts {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodPost, tt.path, bytes.NewBufferString("title\nmilk"))
+			req.Header.Set("Content-Type", tt.contentType)
+
+			ctr := gomock.NewController(t)
+			defer ctr.Finish()
+			mockBulk := mocks.NewMockIBulkUsecase(ctr)
+			report := model.ImportReport{Created: 1, Skipped: 1, Items: []model.ImportItem{
+				{Line: 2, NoteId: 7, Status: model.ImportCreated},
+				{Line: 3, Status: model.ImportSkipped, Reason: "title is empty"},
+			}}
+			if tt.format != "" {
+				mockBulk.EXPECT().Import(gomock.Any(), gomock.Any(), model.Id(2), tt.format).Return(report, tt.importErr)
+			}
+
+			h := Note{usecaseBulk: mockBulk}
+
+			rr := httptest.NewRecorder()
+			ctx := context.WithValue(req.Context(), middleware.UserAuthorized, model.UserInReq{Id: 2})
+			Import(&Calendar{}, &h).ServeHTTP(rr, req.WithContext(ctx))
+
+			assert.Equal(t, tt.code, rr.Code)
+			if tt.code == http.StatusOK {
+				var got dto.ImportReport
+				json.NewDecoder(rr.Body).Decode(&got)
+				assert.Equal(t, 1, got.Skipped)
+				assert.Equal(t, dto.ImportItem{Line: 3, Status: model.ImportSkipped, Reason: "title is empty"}, got.Items[1])
+			}
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package handler
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"path"
+	"time"
+	"todoNote/internal/model"
+	"todoNote/internal/server/http/auth"
+	"todoNote/internal/server/http/dto"
+	"todoNote/internal/server/http/log"
+	"todoNote/internal/server/http/middleware"
+	"todoNote/internal/usecase"
+)
+
+const (
+	providerParam = "provider"
+	// oidcStateCookie ties the state to the browser which started the login, so that no one can finish a login in another one
+	oidcStateCookie = "oidc_state"
+)
+
+type IOidcProvider interface {
+	AuthCodeUrl(ctx context.Context, state, nonce, verifier string) (string, error)
+	Exchange(ctx context.Context, code, verifier, nonce string) (auth.OidcClaims, error)
+}
+
+type Oidc struct {
+	providers map[string]IOidcProvider
+	states *auth.OidcStateStore
+	usecaseOidc usecase.IOidcUsecase
+	sessions usecase.ISessionUsecase
+	auth IAuth
+	log log.Logger
+}
+
+func NewOidcHandler(p map[string]IOidcProvider, o usecase.IOidcUsecase, s usecase.ISessionUsecase, a IAuth, log log.Logger) *Oidc {
+	return &Oidc{
+		providers: p,
+		states: auth.NewOidcStateStore(),
+		usecaseOidc: o,
+		sessions: s,
+		auth: a,
+		log: log,
+	}
+}
+
+// Login redirects the browser to the identity provider
+func(h *Oidc) Login(w http.ResponseWriter, r *http.Request) {
+	u, ok := h.authCodeUrl(w, r, model.UserInReq{})
+	if !ok {
+		return
+	}
+
+	http.Redirect(w, r, u, http.StatusFound)
+}
+
+// Link returns the provider url, after the callback the identity is linked to the current user
+func(h *Oidc) Link(w http.ResponseWriter, r *http.Request) {
+	usr, ok := middleware.UserFromContext(r, h.log, "oidc link")
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	u, ok := h.authCodeUrl(w, r, usr)
+	if !ok {
+		return
+	}
+
+	json.NewEncoder(w).Encode(dto.OidcRedirect{Url: u})
+}
+
+func(h *Oidc) Callback(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, providerParam)
+	p, ok := h.providers[name]
+	if !ok {
+		writeErrorMessage(w, http.StatusNotFound, noOidcProvider)
+		return
+	}
+
+	q := r.URL.Query()
+	if e := q.Get("error"); e != "" {
+		h.log.Warn(fmt.Sprintf("oidc callback: provider(%v) error: %v", name, e))
+		writeErrorMessage(w, http.StatusBadRequest, oidcLoginFailed)
+		return
+	}
+
+	state := q.Get("state")
+	c, err := r.Cookie(oidcStateCookie)
+	http.SetCookie(w, stateCookie(r, "", -1))
+	if err != nil || subtle.ConstantTimeCompare([]byte(c.Value), []byte(state)) != 1 {
+		writeErrorMessage(w, http.StatusBadRequest, wrongOidcState)
+		return
+	}
+
+	st, ok := h.states.Take(state)
+	if !ok || st.Provider != name {
+		writeErrorMessage(w, http.StatusBadRequest, wrongOidcState)
+		return
+	}
+
+	claims, err := p.Exchange(r.Context(), q.Get("code"), st.Verifier, st.Nonce)
+	if err != nil {
+		h.log.Warn(fmt.Sprintf("oidc callback: provider(%v) exchange: %v", name, err))
+		writeErrorMessage(w, http.StatusUnauthorized, oidcLoginFailed)
+		return
+	}
+
+	identity := model.NewUserIdentity(st.LinkUserId, name, claims.Subject)
+	usr := model.UserInReq{Id: st.LinkUserId, OrganizationId: st.LinkOrganizationId}
+	if usr.Id != 0 {
+		err = h.usecaseOidc.Link(r.Context(), *identity)
+	} else {
+		usr, err = h.usecaseOidc.Authenticate(r.Context(), *identity, preferredName(claims))
+	}
+
+	if errors.Is(err, usecase.ErrIdentityLinked) {
+		writeErrorMessage(w, http.StatusConflict, identityLinked)
+		return
+	}
+
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		h.log.Error(fmt.Sprintf("oidc callback: provider(%v) err: %v", name, err))
+		return
+	}
+
+	tk, err := sessionToken(r, h.auth, h.sessions, usr)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		h.log.Error(fmt.Sprintf("oidc callback: create token: user(id: %v) error: %v", usr.Id, err))
+		return
+	}
+
+	json.NewEncoder(w).Encode(dto.NewTokenBearer(tk))
+}
+
+// authCodeUrl links the identity to the user after the callback, a zero user logs in with it
+func(h *Oidc) authCodeUrl(w http.ResponseWriter, r *http.Request, link model.UserInReq) (string, bool) {
+	name := chi.URLParam(r, providerParam)
+	p, ok := h.providers[name]
+	if !ok {
+		writeErrorMessage(w, http.StatusNotFound, noOidcProvider)
+		return "", false
+	}
+
+	verifier, err := auth.RandomString()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		h.log.Error(fmt.Sprintf("oidc login: provider(%v) verifier: %v", name, err))
+		return "", false
+	}
+
+	nonce, err := auth.RandomString()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		h.log.Error(fmt.Sprintf("oidc login: provider(%v) nonce: %v", name, err))
+		return "", false
+	}
+
+	state, err := h.states.Put(auth.OidcState{
+		Provider: name,
+		Verifier: verifier,
+		Nonce: nonce,
+		LinkUserId: link.Id,
+		LinkOrganizationId: link.OrganizationId,
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		h.log.Error(fmt.Sprintf("oidc login: provider(%v) state: %v", name, err))
+		return "", false
+	}
+
+	u, err := p.AuthCodeUrl(r.Context(), state, nonce, verifier)
+	if err != nil {
+		w.WriteHeader(http.StatusBadGateway)
+		h.log.Error(fmt.Sprintf("oidc login: provider(%v) auth url: %v", name, err))
+		return "", false
+	}
+
+	http.SetCookie(w, stateCookie(r, state, int(auth.OidcStateLifetime / time.Second)))
+	return u, true
+}
+
+// stateCookie is sent to the callback of the provider only, Lax lets it along with the redirect from the provider
+func stateCookie(r *http.Request, state string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name: oidcStateCookie,
+		Value: state,
+		Path: path.Dir(r.URL.Path) + "/",
+		MaxAge: maxAge,
+		Secure: r.TLS != nil,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+func preferredName(c auth.OidcClaims) string {
+	if c.PreferredUsername != "" {
+		return c.PreferredUsername
+	}
+
+	return c.Email
+}
@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"todoNote/internal/server/http/auth"
+	"todoNote/internal/server/http/handler/mocks"
+)
+
+// fakeOidcProvider redirects with the state in the url and fails every exchange
+type fakeOidcProvider struct{}
+
+func(fakeOidcProvider) AuthCodeUrl(_ context.Context, state, _, _ string) (string, error) {
+	return "https://idp.example.com/auth?state=" + url.QueryEscape(state), nil
+}
+
+func(fakeOidcProvider) Exchange(_ context.Context, _, _, _ string) (auth.OidcClaims, error) {
+	return auth.OidcClaims{}, fmt.Errorf("exchange failed")
+}
+
+func TestOidc_Callback(t *testing.T) {
+	ctr := gomock.NewController(t)
+	defer ctr.Finish()
+	mockLog := mocks.NewMockLogger(ctr)
+	mockLog.EXPECT().Warn(gomock.Any()).AnyTimes()
+
+	h := NewOidcHandler(map[string]IOidcProvider{"idp": fakeOidcProvider{}}, nil, nil, nil, mockLog)
+	ch := chi.NewRouter()
+	ch.Get("/api/v1/oidc/{provider}/login", h.Login)
+	ch.Get("/api/v1/oidc/{provider}/callback", h.Callback)
+
+	login := func(t *testing.T) (string, *http.Cookie) {
+		req, _ := http.NewRequest(http.MethodGet, "/api/v1/oidc/idp/login", nil)
+		rr := httptest.NewRecorder()
+		ch.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusFound, rr.Code)
+
+		loc, err := url.Parse(rr.Header().Get("Location"))
+		assert.Nil(t, err)
+		cookies := rr.Result().Cookies()
+		if assert.Len(t, cookies, 1) {
+			assert.Equal(t, oidcStateCookie, cookies[0].Name)
+			assert.Equal(t, "/api/v1/oidc/idp/", cookies[0].Path)
+			assert.True(t, cookies[0].HttpOnly)
+			assert.Equal(t, http.SameSiteLaxMode, cookies[0].SameSite)
+		}
+
+		return loc.Query().Get("state"), cookies[0]
+	}
+
+	callback := func(state string, c *http.Cookie) int {
+		req, _ := http.NewRequest(http.MethodGet, "/api/v1/oidc/idp/callback?code=code&state=" + url.QueryEscape(state), nil)
+		if c != nil {
+			req.AddCookie(c)
+		}
+		rr := httptest.NewRecorder()
+		ch.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	t.Run("test state of the browser", func(t *testing.T) {
+		state, c := login(t)
+		assert.Equal(t, c.Value, state)
+		// the state passes, the fake provider refuses the code
+		assert.Equal(t, http.StatusUnauthorized, callback(state, c))
+	})
+
+	t.Run("test state without cookie", func(t *testing.T) {
+		state, _ := login(t)
+		assert.Equal(t, http.StatusBadRequest, callback(state, nil))
+	})
+
+	t.Run("test state of another browser", func(t *testing.T) {
+		state, _ := login(t)
+		_, other := login(t)
+		assert.Equal(t, http.StatusBadRequest, callback(state, other))
+	})
+}
@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"todoNote/internal/server/http/dto"
+	"todoNote/internal/server/http/log"
+	"todoNote/internal/server/http/middleware"
+	"todoNote/internal/usecase"
+)
+
+const sessionIdParam = "sessionId"
+
+type Session struct {
+	sessions usecase.ISessionUsecase
+	log log.Logger
+}
+
+func NewSessionHandler(s usecase.ISessionUsecase, log log.Logger) *Session {
+	return &Session{
+		sessions: s,
+		log: log,
+	}
+}
+
+func(h *Session) GetSessions(w http.ResponseWriter, r *http.Request) {
+	u, ok := middleware.UserFromContext(r, h.log, "get sessions")
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	sessions, err := h.sessions.FindAll(r.Context(), u.Id)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		h.log.Error(fmt.Sprintf("get sessions: user(id: %v) err: %v", u.Id, err))
+		return
+	}
+
+	res := make(dto.Sessions, 0, len(sessions))
+	for _, s := range sessions {
+		res = append(res, dto.Session{
+			Id: s.Id,
+			UserAgent: s.UserAgent,
+			Ip: s.Ip,
+			CreatedAt: s.CreatedAt,
+			LastSeenAt: s.LastSeenAt,
+			IsCurrent: s.Id == u.SessionId,
+		})
+	}
+
+	json.NewEncoder(w).Encode(res)
+}
+
+func(h *Session) DeleteSession(w http.ResponseWriter, r *http.Request) {
+	sessionId, err := getIdFromRequest(r, sessionIdParam)
+	if err != nil {
+		writeErrorMessage(w, http.StatusBadRequest, wrongPathParams)
+		return
+	}
+
+	u, ok := middleware.UserFromContext(r, h.log, "delete session")
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	err = h.sessions.Revoke(r.Context(), sessionId, u.Id)
+	if _, ok := err.(*usecase.ElemNotFound); ok {
+		writeErrorMessage(w, http.StatusNotFound, noSessionFound)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		h.log.Error(fmt.Sprintf("delete session: user(id: %v) session(id: %v) err: %v", u.Id, sessionId, err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
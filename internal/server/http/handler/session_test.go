@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"todoNote/internal/model"
+	"todoNote/internal/server/http/dto"
+	"todoNote/internal/server/http/handler/mocks"
+	"todoNote/internal/server/http/middleware"
+	"todoNote/internal/usecase"
+)
+
+func TestSession_GetSessions(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/sessions", nil)
+
+	ctr := gomock.NewController(t)
+	defer ctr.Finish()
+	mockSession := mocks.NewMockISessionUsecase(ctr)
+	mockSession.EXPECT().FindAll(gomock.Any(), model.Id(2)).
+		Return([]model.Session{{Id: 1, UserId: 2, UserAgent: "curl"}, {Id: 3, UserId: 2}}, nil)
+
+	h := Session{sessions: mockSession}
+
+	rr := httptest.NewRecorder()
+	ch := chi.NewRouter()
+	ch.HandleFunc("/api/v1/sessions", h.GetSessions)
+	ctx := context.WithValue(req.Context(), middleware.UserAuthorized, model.UserInReq{Id: 2, SessionId: 3})
+	ch.ServeHTTP(rr, req.WithContext(ctx))
+
+	var sessions dto.Sessions
+	json.NewDecoder(rr.Body).Decode(&sessions)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Len(t, sessions, 2)
+	assert.Equal(t, "curl", sessions[0].UserAgent)
+	assert.False(t, sessions[0].IsCurrent)
+	assert.True(t, sessions[1].IsCurrent)
+}
+
+func TestSession_DeleteSession(t *testing.T) {
+	tts := []struct{
+		name string
+		path string
+		err error
+		code int
+	}{
+		{"test success", "/api/v1/sessions/3", nil, http.StatusNoContent},
+		{"test not found", "/api/v1/sessions/3", usecase.NewSessionNotFoundError(3, 2), http.StatusNotFound},
+		{"test bad request", "/api/v1/sessions/abc", nil, http.StatusBadRequest},
+	}
+
+	for _, tt := range tts {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodDelete, tt.path, nil)
+
+			ctr := gomock.NewController(t)
+			defer ctr.Finish()
+			mockSession := mocks.NewMockISessionUsecase(ctr)
+			if tt.code != http.StatusBadRequest {
+				mockSession.EXPECT().Revoke(gomock.Any(), model.Id(3), model.Id(2)).Return(tt.err)
+			}
+
+			h := Session{sessions: mockSession}
+
+			rr := httptest.NewRecorder()
+			ch := chi.NewRouter()
+			ch.Delete("/api/v1/sessions/{sessionId}", h.DeleteSession)
+			ctx := context.WithValue(req.Context(), middleware.UserAuthorized, model.UserInReq{Id: 2})
+			ch.ServeHTTP(rr, req.WithContext(ctx))
+
+			assert.Equal(t, tt.code, rr.Code)
+		})
+	}
+}
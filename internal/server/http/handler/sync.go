@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+	"todoNote/internal/model"
+	"todoNote/internal/server/http/dto"
+	"todoNote/internal/server/http/log"
+	"todoNote/internal/server/http/middleware"
+	"todoNote/internal/usecase"
+)
+
+const (
+	sinceQueryParam = "since"
+
+	maxSyncPushSize = 10 << 20
+	maxSyncPushChanges = 1000
+)
+
+type Sync struct {
+	usecaseSync usecase.ISyncUsecase
+	log log.Logger
+}
+
+func NewSyncHandler(s usecase.ISyncUsecase, log log.Logger) *Sync {
+	return &Sync{
+		usecaseSync: s,
+		log: log,
+	}
+}
+
+func(h *Sync) GetChanges(w http.ResponseWriter, r *http.Request) {
+	u, ok := middleware.UserFromContext(r, h.log, "get changes")
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	changes, err := h.usecaseSync.Changes(r.Context(), u.Id, r.URL.Query().Get(sinceQueryParam))
+	if errors.Is(err, usecase.ErrInvalidSyncToken) {
+		writeErrorMessage(w, http.StatusBadRequest, wrongSyncToken)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		h.log.Error(fmt.Sprintf("get changes: user(id: %v) err: %v", u.Id, err))
+		return
+	}
+
+	res := dto.SyncChanges{Changes: make([]dto.NoteChange, 0, len(changes.Changes)), Token: changes.Token, More: changes.More}
+	for _, c := range changes.Changes {
+		res.Changes = append(res.Changes, noteChangeDto(c))
+	}
+
+	json.NewEncoder(w).Encode(res)
+}
+
+func(h *Sync) PushChanges(w http.ResponseWriter, r *http.Request) {
+	var push dto.SyncPush
+	if err := json.NewDecoder(limitBody(w, r, maxSyncPushSize)).Decode(&push); err != nil {
+		if errors.Is(err, ErrBodyTooLarge) {
+			writeErrorMessage(w, http.StatusRequestEntityTooLarge, tooManyChanges)
+			return
+		}
+
+		writeErrorMessage(w, http.StatusBadRequest, wrongBody)
+		return
+	}
+	if len(push.Changes) > maxSyncPushChanges {
+		writeErrorMessage(w, http.StatusRequestEntityTooLarge, tooManyChanges)
+		return
+	}
+
+	u, ok := middleware.UserFromContext(r, h.log, "push changes")
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	changes := make([]usecase.ClientChange, 0, len(push.Changes))
+	for _, c := range push.Changes {
+		changes = append(changes, usecase.ClientChange{
+			ClientId: c.ClientId,
+			NoteId: c.Id,
+			BaseVersion: c.BaseVersion,
+			Deleted: c.Deleted,
+			Note: syncNoteRecord(c.Note),
+		})
+	}
+
+	results, err := h.usecaseSync.Push(r.Context(), u.Id, changes)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		h.log.Error(fmt.Sprintf("push changes: user(id: %v) err: %v", u.Id, err))
+		return
+	}
+
+	res := dto.SyncPushResult{Results: make([]dto.SyncResult, 0, len(results))}
+	for _, s := range results {
+		item := dto.SyncResult{ClientId: s.ClientId, Id: s.NoteId, Status: s.Status, Version: s.Version, Reason: s.Reason}
+		if s.Current != nil {
+			current := noteChangeDto(*s.Current)
+			item.Current = &current
+		}
+		res.Results = append(res.Results, item)
+	}
+
+	json.NewEncoder(w).Encode(res)
+}
+
+func noteChangeDto(c model.NoteChange) dto.NoteChange {
+	res := dto.NoteChange{Version: c.Version, Id: c.NoteId, Deleted: c.Deleted}
+	if c.Note != nil {
+		minutes := int(c.Note.ReminderOffset / time.Minute)
+		res.Note = &dto.SyncNote{
+			Title: c.Note.Title,
+			Text: c.Note.Text,
+			Date: c.Note.Date,
+			IsFinished: c.Note.IsFinished,
+			TimeZone: c.Note.TimeZone,
+			IsFloating: c.Note.IsFloating,
+			ReminderOffsetMinutes: &minutes,
+			Priority: c.Note.Priority,
+			Projects: c.Note.Projects,
+			Contexts: c.Note.Contexts,
+			FinishedAt: c.Note.FinishedAt,
+		}
+	}
+
+	return res
+}
+
+func syncNoteRecord(n dto.SyncNote) usecase.NoteRecord {
+	return usecase.NoteRecord{
+		Title: n.Title,
+		Text: n.Text,
+		Date: n.Date,
+		IsFinished: n.IsFinished,
+		TimeZone: n.TimeZone,
+		IsFloating: n.IsFloating,
+		ReminderOffsetMinutes: n.ReminderOffsetMinutes,
+		Priority: n.Priority,
+		Projects: n.Projects,
+		Contexts: n.Contexts,
+		FinishedAt: n.FinishedAt,
+	}
+}
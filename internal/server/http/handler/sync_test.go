@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"todoNote/internal/model"
+	"todoNote/internal/server/http/dto"
+	"todoNote/internal/server/http/handler/mocks"
+	"todoNote/internal/server/http/middleware"
+	"todoNote/internal/usecase"
+)
+
+//go:generate mockgen -package=mocks -destination=mocks/sync.go todoNote/internal/usecase ISyncUsecase
+
+func TestSync_GetChanges(t *testing.T) {
+	tts := []struct{
+		name string
+		changesErr error
+		code int
+	}{
+		{"test success", nil, http.StatusOK},
+		{"test invalid token", usecase.ErrInvalidSyncToken, http.StatusBadRequest},
+		{"test failure", fmt.Errorf("sync changes: broken"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tts {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, "/api/v1/sync?since=token", nil)
+
+			ctr := gomock.NewController(t)
+			defer ctr.Finish()
+			mockSync := mocks.NewMockISyncUsecase(ctr)
+			changes := usecase.SyncChanges{
+				Changes: []model.NoteChange{
+					{Version: 4, NoteId: 2, Note: &model.Note{Id: 2, Title: "title"}},
+					{Version: 5, NoteId: 1, Deleted: true},
+				},
+				Token: "next",
+			}
+			mockSync.EXPECT().Changes(gomock.Any(), model.Id(2), "token").Return(changes, tt.changesErr)
+
+			mockLog := mocks.NewMockLogger(ctr)
+			if tt.code == http.StatusInternalServerError {
+				mockLog.EXPECT().Error(gomock.Any())
+			}
+
+			h := Sync{usecaseSync: mockSync, log: mockLog}
+
+			rr := httptest.NewRecorder()
+			ctx := context.WithValue(req.Context(), middleware.UserAuthorized, model.UserInReq{Id: 2})
+			http.HandlerFunc(h.GetChanges).ServeHTTP(rr, req.WithContext(ctx))
+
+			assert.Equal(t, tt.code, rr.Code)
+			if tt.code == http.StatusOK {
+				var got dto.SyncChanges
+				json.NewDecoder(rr.Body).Decode(&got)
+				assert.Equal(t, "next", got.Token)
+				assert.Len(t, got.Changes, 2)
+				assert.Equal(t, "title", got.Changes[0].Note.Title)
+				assert.Equal(t, dto.NoteChange{Version: 5, Id: 1, Deleted: true}, got.Changes[1])
+			}
+		})
+	}
+}
+
+func TestSync_PushChanges(t *testing.T) {
+	tts := []struct{
+		name string
+		body string
+		code int
+	}{
+		{"test success", `{"changes":[{"client_id":"a","note":{"title":"new"}},{"id":1,"base_version":3,"deleted":true}]}`, http.StatusOK},
+		{"test bad body", `{"changes":`, http.StatusBadRequest},
+		{"test too many changes", `{"changes":[` + strings.Repeat(`{"id":1},`, maxSyncPushChanges) + `{"id":1}]}`, http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tts {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodPost, "/api/v1/sync", strings.NewReader(tt.body))
+
+			ctr := gomock.NewController(t)
+			defer ctr.Finish()
+			mockSync := mocks.NewMockISyncUsecase(ctr)
+			if tt.code == http.StatusOK {
+				changes := []usecase.ClientChange{
+					{ClientId: "a", Note: usecase.NoteRecord{Title: "new"}},
+					{NoteId: 1, BaseVersion: 3, Deleted: true},
+				}
+				results := []usecase.SyncResult{
+					{ClientId: "a", NoteId: 7, Status: usecase.SyncApplied, Version: 8},
+					{NoteId: 1, Status: usecase.SyncConflict, Reason: "note was changed", Current: &model.NoteChange{Version: 4, NoteId: 1, Note: &model.Note{Id: 1}}},
+				}
+				mockSync.EXPECT().Push(gomock.Any(), model.Id(2), changes).Return(results, nil)
+			}
+
+			h := Sync{usecaseSync: mockSync}
+
+			rr := httptest.NewRecorder()
+			ctx := context.WithValue(req.Context(), middleware.UserAuthorized, model.UserInReq{Id: 2})
+			http.HandlerFunc(h.PushChanges).ServeHTTP(rr, req.WithContext(ctx))
+
+			assert.Equal(t, tt.code, rr.Code)
+			if tt.code == http.StatusOK {
+				var got dto.SyncPushResult
+				json.NewDecoder(rr.Body).Decode(&got)
+				assert.Len(t, got.Results, 2)
+				assert.Equal(t, dto.SyncResult{ClientId: "a", Id: 7, Status: usecase.SyncApplied, Version: 8}, got.Results[0])
+				assert.Equal(t, int64(4), got.Results[1].Current.Version)
+			}
+		})
+	}
+}
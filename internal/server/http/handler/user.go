@@ -3,9 +3,12 @@ package handler
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
+	"strings"
+	"time"
 	"todoNote/internal/model"
-	"todoNote/internal/repo/postgres"
+	"todoNote/internal/repo"
 	"todoNote/internal/server/http/dto"
 	"todoNote/internal/server/http/log"
 	"todoNote/internal/server/http/middleware"
@@ -15,12 +18,14 @@ import (
 
 type User struct {
 	userCase usecase.IUserUsecase
+	organizationCase usecase.IOrganizationUsecase
 	log log.Logger
 }
 
-func NewUserHandler(u usecase.IUserUsecase, log log.Logger) *User {
+func NewUserHandler(u usecase.IUserUsecase, o usecase.IOrganizationUsecase, log log.Logger) *User {
 	return &User{
 		userCase: u,
+		organizationCase: o,
 		log: log,
 	}
 }
@@ -37,13 +42,25 @@ func(h *User) CreateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	id, err := h.userCase.Create(r.Context(), &model.UserNew{
+	ctx, err := h.organizationCase.Tenant(r.Context(), u.Organization)
+	if errors.Is(err, usecase.ErrOrganizationNotFound) {
+		writeErrorMessage(w, http.StatusBadRequest, noOrganizationFound)
+		return
+	}
+
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		h.log.Error(fmt.Sprintf("create user: organization(name: %v) error: %v", u.Organization, err))
+		return
+	}
+
+	id, err := h.userCase.Create(ctx, &model.UserNew{
 		Name: u.UserName,
 		TimeZone: u.TimeZone,
 		Password: u.Password,
 	})
 
-	if errors.As(err, &postgres.UserExistsError{}) {
+	if errors.Is(err, repo.ErrConflict) {
 		writeErrorMessage(w, http.StatusBadRequest, "such username is taken")
 		return
 	}
@@ -65,19 +82,30 @@ func(h *User) PartialUpdateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if _, ok:= usecase.ValidateZone(u.TimeZone); !ok {
+	if _, ok:= usecase.ValidateZone(u.TimeZone); u.TimeZone != "" && !ok {
 		writeErrorMessage(w, http.StatusBadRequest, wrongDateFormat)
 		return
 	}
 
+	prefs, ok := preferencesUpdate(u)
+	if !ok {
+		writeErrorMessage(w, http.StatusBadRequest, wrongPreferences)
+		return
+	}
+
 	usr, ok := middleware.UserFromContext(r, h.log,"update user")
 	if !ok {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
-	user := model.UserUpdate{Id: usr.Id, TimeZone: u.TimeZone}
-	if err := h.userCase.Update(r.Context(), user); err != nil {
+	user := model.UserUpdate{Id: usr.Id, TimeZone: u.TimeZone, Preferences: prefs}
+	err := h.userCase.Update(r.Context(), user)
+	if errors.Is(err, usecase.ErrInvalidPreferences) {
+		writeErrorMessage(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		h.log.Error("patch update user: user(id: %v) db err: %v", usr.Id, err)
 		return
@@ -86,6 +114,34 @@ func(h *User) PartialUpdateUser(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+func(h *User) GetMe(w http.ResponseWriter, r *http.Request) {
+	u, ok := middleware.UserFromContext(r, h.log,"get me")
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	usr, err := h.userCase.FindById(r.Context(), u.Id)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		h.log.Error(fmt.Sprintf("get me: user(id: %v) db err: %v", u.Id, err))
+		return
+	}
+
+	json.NewEncoder(w).Encode(dto.UserProfile{
+		Id: usr.Id,
+		UserName: usr.Name,
+		TimeZone: usr.TimeZone,
+		Preferences: dto.Preferences{
+			DisplayName: usr.Preferences.DisplayName,
+			Locale: usr.Preferences.Locale,
+			WeekStart: strings.ToLower(usr.Preferences.WeekStart.String()),
+			ReminderOffsetMinutes: int(usr.Preferences.ReminderOffset / time.Minute),
+			DateFormat: usr.Preferences.DateFormat,
+		},
+	})
+}
+
 func(h *User) DeleteUser(w http.ResponseWriter, r *http.Request) {
 	u, ok := middleware.UserFromContext(r, h.log,"delete user")
 	if !ok {
@@ -103,3 +159,26 @@ func(h *User) DeleteUser(w http.ResponseWriter, r *http.Request) {
 }
 
 
+
+func preferencesUpdate(u dto.UserUpdate) (model.PreferencesUpdate, bool) {
+	p := model.PreferencesUpdate{
+		DisplayName: u.DisplayName,
+		Locale: u.Locale,
+		DateFormat: u.DateFormat,
+	}
+
+	if u.WeekStart != nil {
+		d, ok := usecase.ParseWeekday(*u.WeekStart)
+		if !ok {
+			return p, false
+		}
+		p.WeekStart = &d
+	}
+
+	if u.ReminderOffsetMinutes != nil {
+		d := time.Duration(*u.ReminderOffsetMinutes) * time.Minute
+		p.ReminderOffset = &d
+	}
+
+	return p, true
+}
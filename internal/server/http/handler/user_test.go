@@ -13,9 +13,12 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 	"todoNote/internal/model"
+	"todoNote/internal/repo"
 	"todoNote/internal/server/http/dto"
 	"todoNote/internal/server/http/handler/mocks"
+	"todoNote/internal/usecase"
 )
 
 //go:generate mockgen -package=mocks -destination=mocks/user.go todoNote/internal/usecase INoteUsecase,IUserUsecase
@@ -39,7 +42,7 @@ func TestUser_CreateUser(t *testing.T) {
 				assert.Equal(t, model.UTCp3, u.TimeZone)
 		})
 
-		h := User{userCase: mockCase}
+		h := User{userCase: mockCase, organizationCase: defaultTenant(ctr)}
 
 		rr := httptest.NewRecorder()
 		ch := chi.NewRouter()
@@ -73,6 +76,49 @@ func TestUser_CreateUser(t *testing.T) {
 
 		assert.Equal(t, http.StatusBadRequest, rr.Code)
 	})
+
+	t.Run("test unknown organization", func(t *testing.T) {
+		b := dto.UserRegistration{UserName: "user", Password: "123", TimeZone: model.UTCp3, Organization: "sales"}
+
+		js, _ := json.Marshal(b)
+		req, _ := http.NewRequest(http.MethodPost, "/api/v1/users", bytes.NewReader(js))
+
+		ctr := gomock.NewController(t)
+		defer ctr.Finish()
+		mockOrganization := mocks.NewMockIOrganizationUsecase(ctr)
+		mockOrganization.EXPECT().Tenant(gomock.Any(), "sales").Return(nil, usecase.ErrOrganizationNotFound)
+
+		h := User{userCase: mocks.NewMockIUserUsecase(ctr), organizationCase: mockOrganization}
+
+		rr := httptest.NewRecorder()
+		ch := chi.NewRouter()
+		ch.HandleFunc("/api/v1/users", h.CreateUser)
+		ch.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("test name taken", func(t *testing.T) {
+		b := dto.UserRegistration{UserName: "user", Password: "123", TimeZone: model.UTCp3}
+
+		js, _ := json.Marshal(b)
+		req, _ := http.NewRequest(http.MethodPost, "/api/v1/users", bytes.NewReader(js))
+
+		ctr := gomock.NewController(t)
+		defer ctr.Finish()
+		mockCase := mocks.NewMockIUserUsecase(ctr)
+		mockCase.EXPECT().Create(gomock.Any(), gomock.Any()).
+			Return(model.Id(0), fmt.Errorf("create user.go: %w", repo.ErrConflict))
+
+		h := User{userCase: mockCase, organizationCase: defaultTenant(ctr)}
+
+		rr := httptest.NewRecorder()
+		ch := chi.NewRouter()
+		ch.HandleFunc("/api/v1/users", h.CreateUser)
+		ch.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
 }
 
 func TestUser_PartialUpdateUser(t *testing.T) {
@@ -127,6 +173,77 @@ func TestUser_PartialUpdateUser(t *testing.T) {
 	})
 }
 
+func TestUser_PartialUpdateUserPreferences(t *testing.T) {
+	tts := []struct{
+		name string
+		body string
+		code int
+	}{
+		{"test success", `{"display_name": "Ann", "locale": "uk-UA", "week_start": "sunday", "reminder_offset_minutes": 30, "date_format": "DD.MM.YYYY"}`, http.StatusNoContent},
+		{"test unknown week day", `{"week_start": "someday"}`, http.StatusBadRequest},
+		{"test invalid preferences", `{"date_format": "yy"}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tts {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodPatch, "/api/v1/users/me", bytes.NewReader([]byte(tt.body)))
+
+			ctr := gomock.NewController(t)
+			defer ctr.Finish()
+			mockCase := mocks.NewMockIUserUsecase(ctr)
+			if tt.name == "test success" {
+				mockCase.EXPECT().Update(gomock.Any(), gomock.Any()).
+					Return(nil).
+					Do(func(_ context.Context, user model.UserUpdate) {
+						assert.Equal(t, model.Id(2), user.Id)
+						assert.Equal(t, "", user.TimeZone)
+						assert.Equal(t, "Ann", *user.Preferences.DisplayName)
+						assert.Equal(t, time.Sunday, *user.Preferences.WeekStart)
+						assert.Equal(t, 30 * time.Minute, *user.Preferences.ReminderOffset)
+				})
+			}
+			if tt.name == "test invalid preferences" {
+				mockCase.EXPECT().Update(gomock.Any(), gomock.Any()).
+					Return(fmt.Errorf("update user: %w", usecase.ErrInvalidPreferences))
+			}
+
+			h := User{userCase: mockCase}
+
+			rr := httptest.NewRecorder()
+			ctx := context.WithValue(req.Context(), middleware.UserAuthorized, model.UserInReq{Id: 2})
+			http.HandlerFunc(h.PartialUpdateUser).ServeHTTP(rr, req.WithContext(ctx))
+
+			assert.Equal(t, tt.code, rr.Code)
+		})
+	}
+}
+
+func TestUser_GetMe(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/users/me", nil)
+
+	ctr := gomock.NewController(t)
+	defer ctr.Finish()
+	mockCase := mocks.NewMockIUserUsecase(ctr)
+	usr := model.NewUser(2, "user", nil, "Europe/Kyiv")
+	usr.Preferences.ReminderOffset = 10 * time.Minute
+	mockCase.EXPECT().FindById(gomock.Any(), model.Id(2)).Return(usr, nil)
+
+	h := User{userCase: mockCase}
+
+	rr := httptest.NewRecorder()
+	ctx := context.WithValue(req.Context(), middleware.UserAuthorized, model.UserInReq{Id: 2})
+	http.HandlerFunc(h.GetMe).ServeHTTP(rr, req.WithContext(ctx))
+
+	var profile dto.UserProfile
+	json.NewDecoder(rr.Body).Decode(&profile)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "user", profile.UserName)
+	assert.Equal(t, "Europe/Kyiv", profile.TimeZone)
+	assert.Equal(t, "monday", profile.Preferences.WeekStart)
+	assert.Equal(t, 10, profile.Preferences.ReminderOffsetMinutes)
+	assert.Equal(t, model.DateFormatIso, profile.Preferences.DateFormat)
+}
+
 func TestUser_DeleteUser(t *testing.T) {
 	t.Run("test success", func(t *testing.T) {
 		req, _ := http.NewRequest(http.MethodDelete, "/api/v1/users", nil)
@@ -3,23 +3,31 @@ package middleware
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strings"
 	"todoNote/internal/model"
+	"todoNote/internal/repo"
 	"todoNote/internal/server/http/auth"
 	"todoNote/internal/server/http/dto"
 	"todoNote/internal/server/http/log"
+	"todoNote/internal/usecase"
 )
 
 const UserAuthorized = "UserAuthorized"
 
 type Middleware struct {
 	auth auth.IAuth
+	sessions usecase.ISessionUsecase
+	log log.Logger
 }
 
-func New(auth auth.IAuth) *Middleware {
+func New(auth auth.IAuth, sessions usecase.ISessionUsecase, log log.Logger) *Middleware {
 	return &Middleware{
 		auth: auth,
+		sessions: sessions,
+		log: log,
 	}
 }
 
@@ -37,7 +45,25 @@ func(md *Middleware) AuthMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
-		ctx := contextWithUser(r.Context(), u)
+		// every token is bound to a session, one without could not be revoked
+		if u.SessionId == 0 {
+			writeErrorMessage(w, http.StatusUnauthorized, "Not valid token")
+			return
+		}
+
+		err = md.sessions.Validate(r.Context(), u.SessionId, u.Id)
+		if errors.Is(err, usecase.ErrSessionRevoked) {
+			writeErrorMessage(w, http.StatusUnauthorized, "Session is revoked")
+			return
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			md.log.Error(fmt.Sprintf("auth middleware: validate session(id: %v): %v", u.SessionId, err))
+			return
+		}
+
+		// repositories called for the request see only the organization of the token
+		ctx := contextWithUser(repo.WithTenant(r.Context(), u.OrganizationId), u)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
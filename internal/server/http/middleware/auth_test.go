@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"todoNote/internal/model"
+	"todoNote/internal/server/http/handler/mocks"
+	"todoNote/internal/usecase"
+)
+
+func TestMiddleware_AuthMiddleware(t *testing.T) {
+	tts := []struct{
+		name string
+		user model.UserInReq
+		validateErr error
+		code int
+	}{
+		{"test valid session", model.UserInReq{Id: 2, SessionId: 3}, nil, http.StatusOK},
+		{"test revoked session", model.UserInReq{Id: 2, SessionId: 3}, usecase.ErrSessionRevoked, http.StatusUnauthorized},
+		{"test token without session", model.UserInReq{Id: 2}, nil, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tts {
+		t.Run(tt.name, func(t *testing.T) {
+			ctr := gomock.NewController(t)
+			defer ctr.Finish()
+
+			mockAuth := mocks.NewMockIAuth(ctr)
+			mockAuth.EXPECT().ValidateToken(gomock.Any()).Return(tt.user, nil)
+			mockSessions := mocks.NewMockISessionUsecase(ctr)
+			if tt.user.SessionId != 0 {
+				mockSessions.EXPECT().Validate(gomock.Any(), tt.user.SessionId, tt.user.Id).Return(tt.validateErr)
+			}
+
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				u, ok := r.Context().Value(UserAuthorized).(model.UserInReq)
+				assert.True(t, ok)
+				assert.Equal(t, tt.user, u)
+			})
+
+			req, _ := http.NewRequest(http.MethodGet, "/api/v1/notes", nil)
+			req.Header.Set("Authorization", "Bearer token")
+			rr := httptest.NewRecorder()
+			New(mockAuth, mockSessions, nil).AuthMiddleware(next).ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.code, rr.Code)
+		})
+	}
+}
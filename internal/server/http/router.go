@@ -1,12 +1,15 @@
 package http
 
 import (
+	"context"
+	"fmt"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"os"
 	"strconv"
 	"time"
+	"todoNote/internal/outbox"
 	"todoNote/internal/repo"
 	auth2 "todoNote/internal/server/http/auth"
 	"todoNote/internal/server/http/log"
@@ -20,34 +23,85 @@ const(
 	JwtLifetimeMillisEnv = "JWT_LIFETIME_MILLIS"
 	publicKeyEnv = "PUBLIC_KEY"
 	privateKeyEnv = "PRIVATE_KEY"
+	keyDirEnv = "KEY_DIR"
+	keyReloadSecondsEnv = "KEY_RELOAD_SECONDS"
+	deletionGraceHoursEnv = "ACCOUNT_DELETION_GRACE_HOURS"
+	publicBaseUrlEnv = "PUBLIC_BASE_URL"
+
+	defaultKeyReload = time.Minute
+	defaultDeletionGrace = 30 * 24 * time.Hour
+	purgeInterval = time.Hour
+	outboxInterval = time.Second
 )
 
-func NewRouter(repo Repositories) (chi.Router, error) {
+// NewRouter starts background work which stops when ctx is done, the returned func waits for the account exports in flight
+func NewRouter(ctx context.Context, repo Repositories) (chi.Router, func(), error) {
+	// WebDAV methods are routed to the CalDAV handler
+	chi.RegisterMethod("PROPFIND")
+	chi.RegisterMethod("REPORT")
 	r := chi.NewRouter()
 
 	l, err := strconv.Atoi(os.Getenv(JwtLifetimeMillisEnv))
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	auth, err := auth2.NewJwtAuth(
-		time.Duration(l) * time.Millisecond,
-		os.Getenv(privateKeyEnv),
-		os.Getenv(publicKeyEnv))
+	keys, err := newKeySet(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
 
+	lifetime := time.Duration(l) * time.Millisecond
+	auth := auth2.NewJwtAuthWithKeys(lifetime, keys)
+
+	usecaseUser := usecase.NewUserUsecase(repo.User, repo.Outbox, repo.Transactor)
+	usecaseNote := usecase.NewNoteUsecase(repo.Note, repo.Outbox, repo.Transactor)
+	usecaseMfa := usecase.NewMfaUsecase(repo.Mfa, repo.User)
+	usecaseOidc := usecase.NewOidcUsecase(repo.Identity, repo.User, repo.Outbox, repo.Transactor)
+	usecaseGuard := usecase.NewLoginGuardUsecase(repo.LoginAttempts)
+	usecaseSession := usecase.NewSessionUsecase(repo.Session, lifetime)
+	usecaseOrganization := usecase.NewOrganizationUsecase(repo.Organization)
+
+	grace, err := deletionGrace()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	usecaseAccount := usecase.NewAccountUsecase(ctx, repo.User, repo.Note, repo.Export, repo.AccountDeletion, repo.Outbox, repo.Transactor, grace)
+	usecaseBulk := usecase.NewBulkUsecase(usecaseNote, repo.User, repo.Transactor)
+	usecaseCalendar := usecase.NewCalendarUsecase(repo.Note, repo.CalendarFeed, usecaseNote, repo.Outbox, repo.Transactor)
+	usecaseSync := usecase.NewSyncUsecase(repo.Note, repo.User, repo.Outbox, repo.Transactor)
 
-	usecaseUser := usecase.NewUserUsecase(repo.User)
-	usecaseNote := usecase.NewNoteUsecase(repo.Note)
+	oidcConfigs, err := auth2.OidcConfigsFromEnv()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	providers := make(map[string]handler.IOidcProvider)
+	for _, c := range oidcConfigs {
+		providers[c.Name] = auth2.NewOidcProvider(c, nil)
+	}
 
 	logger := log.MyLogger{}
+	go purgeDeletedAccounts(ctx, usecaseAccount, logger)
 
-	ah := handler.NewAuthHandler(usecaseUser, auth, logger)
-	uh := handler.NewUserHandler(usecaseUser, logger)
-	nh := handler.NewNoteHandler(usecaseNote, usecaseUser, logger)
-	md := md.New(auth)
+	sink, err := outbox.SinkFromEnv(logger)
+	if err != nil {
+		return nil, nil, err
+	}
+	go outbox.NewRelay(repo.Outbox, sink, logger).Run(ctx, outboxInterval)
+
+	ah := handler.NewAuthHandler(usecaseUser, usecaseMfa, usecaseOrganization, usecaseGuard, usecaseSession, auth, logger)
+	mh := handler.NewMfaHandler(usecaseMfa, logger)
+	oh := handler.NewOidcHandler(providers, usecaseOidc, usecaseSession, auth, logger)
+	jh := handler.NewJwksHandler(keys)
+	uh := handler.NewUserHandler(usecaseUser, usecaseOrganization, logger)
+	nh := handler.NewNoteHandler(usecaseNote, usecaseUser, usecaseBulk, logger)
+	sh := handler.NewSessionHandler(usecaseSession, logger)
+	ach := handler.NewAccountHandler(usecaseAccount, logger)
+	ch := handler.NewCalendarHandler(usecaseCalendar, os.Getenv(publicBaseUrlEnv), logger)
+	syh := handler.NewSyncHandler(usecaseSync, logger)
+	cdh := handler.NewCalDavHandler(usecaseCalendar, usecaseUser, usecaseMfa, usecaseOrganization, usecaseGuard, logger)
+	md := md.New(auth, usecaseSession, logger)
 
 	r.Group(func(r chi.Router) {
 		r.Use(middleware.Timeout(30 * time.Second))
@@ -56,6 +110,12 @@ func NewRouter(repo Repositories) (chi.Router, error) {
 		r.Use(middleware.Logger)
 		r.Use(md.ApmMiddleware)
 		//r.Use(apmchi.Middleware())
+		r.Get("/.well-known/jwks.json", jh.GetJwks)
+
+		// CalDAV clients authenticate with HTTP Basic in the handler
+		r.HandleFunc("/.well-known/caldav", cdh.WellKnown)
+		r.Handle(handler.DavRoot + "*", cdh)
+
 		r.Route("/api/v1", func(r chi.Router) {
 
 			r.Route("/notes", func(r chi.Router) {
@@ -63,6 +123,11 @@ func NewRouter(repo Repositories) (chi.Router, error) {
 
 				r.Post("/", nh.CreateNote)
 				r.Get("/", nh.GetNotes)
+				r.Get("/export.ics", ch.ExportCalendar)
+				r.Get("/export", nh.ExportNotes)
+				r.Post("/import", handler.Import(ch, nh))
+				r.Post("/feed", ch.CreateFeed)
+				r.Delete("/feed", ch.RevokeFeed)
 
 				r.Route("/{noteId}", func(r chi.Router) {
 					r.Get("/", nh.GetNote)
@@ -79,19 +144,131 @@ func NewRouter(repo Repositories) (chi.Router, error) {
 
 					r.Patch("/", uh.PartialUpdateUser)
 					r.Delete("/", uh.DeleteUser)
+
+					r.Get("/me", uh.GetMe)
+					r.Patch("/me", uh.PartialUpdateUser)
+					r.Post("/me/export", ach.RequestExport)
+					r.Get("/me/export", ach.GetExport)
+					r.Post("/me/deletion", ach.ScheduleDeletion)
+					r.Delete("/me/deletion", ach.CancelDeletion)
+
+					r.Route("/mfa", func(r chi.Router) {
+						r.Post("/", mh.Enroll)
+						r.Post("/confirm", mh.Confirm)
+						r.Delete("/", mh.Disable)
+					})
 				})
 			})
 
+			r.Route("/sync", func(r chi.Router) {
+				r.Use(md.AuthMiddleware)
+
+				r.Get("/", syh.GetChanges)
+				r.Post("/", syh.PushChanges)
+			})
+
+			r.Route("/sessions", func(r chi.Router) {
+				r.Use(md.AuthMiddleware)
+
+				r.Get("/", sh.GetSessions)
+				r.Delete("/{sessionId}", sh.DeleteSession)
+			})
+
+			// feeds are read by calendar clients which can not send a bearer token
+			r.Get("/feeds/{feedToken}.ics", ch.GetFeed)
+
 			r.Post("/login", ah.Login)
+			r.Post("/login/mfa", ah.LoginMfa)
+
+			r.Route("/oidc/{provider}", func(r chi.Router) {
+				r.Get("/login", oh.Login)
+				r.Get("/callback", oh.Callback)
+				r.With(md.AuthMiddleware).Post("/link", oh.Link)
+			})
 		})
 	})
 
 	r.Handle("/metrics", promhttp.Handler())
 
-	return r, nil
+	return r, usecaseAccount.Wait, nil
+}
+
+// newKeySet loads signing keys from KEY_DIR and keeps them in sync with the directory,
+// PRIVATE_KEY and PUBLIC_KEY are used when no directory is configured
+func newKeySet(ctx context.Context) (*auth2.KeySet, error) {
+	dir := os.Getenv(keyDirEnv)
+	if dir == "" {
+		return auth2.NewKeySetFromPem(os.Getenv(privateKeyEnv), os.Getenv(publicKeyEnv))
+	}
+
+	keys := &auth2.KeySet{}
+	if err := keys.LoadDir(dir); err != nil {
+		return nil, err
+	}
+
+	reload := defaultKeyReload
+	if s := os.Getenv(keyReloadSecondsEnv); s != "" {
+		sec, err := strconv.Atoi(s)
+		if err != nil || sec <= 0 {
+			return nil, fmt.Errorf("%v must be a positive number of seconds", keyReloadSecondsEnv)
+		}
+		reload = time.Duration(sec) * time.Second
+	}
+
+	go keys.WatchDir(ctx, dir, reload)
+
+	return keys, nil
+}
+
+func deletionGrace() (time.Duration, error) {
+	s := os.Getenv(deletionGraceHoursEnv)
+	if s == "" {
+		return defaultDeletionGrace, nil
+	}
+
+	h, err := strconv.Atoi(s)
+	if err != nil || h < 0 {
+		return 0, fmt.Errorf("%v must be a non-negative number of hours", deletionGraceHoursEnv)
+	}
+
+	return time.Duration(h) * time.Hour, nil
+}
+
+// purgeDeletedAccounts removes accounts whose deletion grace period is over until ctx is done
+func purgeDeletedAccounts(ctx context.Context, a usecase.IAccountUsecase, logger log.Logger) {
+	t := time.NewTicker(purgeInterval)
+	defer t.Stop()
+
+	for {
+		n, err := a.PurgeDeleted(ctx)
+		if err != nil {
+			logger.Error(fmt.Sprintf("purge deleted accounts: %v", err))
+		}
+		if n > 0 {
+			logger.Info(fmt.Sprintf("purge deleted accounts: removed %v", n))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
 }
 
 type Repositories struct {
 	User repo.IRepoUser
 	Note repo.IRepoNote
+	Mfa repo.IRepoMfa
+	Identity repo.IRepoIdentity
+	LoginAttempts repo.IRepoLoginAttempts
+	Session repo.IRepoSession
+	Export repo.IRepoExport
+	AccountDeletion repo.IRepoAccountDeletion
+	CalendarFeed repo.IRepoCalendarFeed
+	// Organization has to be in the storage of Note and User, they reference it
+	Organization repo.IRepoOrganization
+	// Outbox has to be in the storage of Note and User, events are written in their transactions
+	Outbox repo.IRepoOutbox
+	Transactor repo.ITransactor
 }
@@ -0,0 +1,372 @@
+package usecase
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+	"todoNote/internal/model"
+	"todoNote/internal/repo"
+)
+
+const (
+	// ready archives are kept for download this long
+	exportLifetime = 7 * 24 * time.Hour
+	// a pending export older than this is considered lost and can be requested again
+	exportTimeout = 10 * time.Minute
+	exportNotesLimit = 1000000
+
+	floatingDateFormat = "2006-01-02T15:04:05"
+)
+
+type IAccountUsecase interface {
+	RequestExport(ctx context.Context, uId model.Id) (model.Export, error)
+	FindExport(ctx context.Context, uId model.Id) (model.Export, error)
+	ScheduleDeletion(ctx context.Context, uId model.Id) (model.AccountDeletion, error)
+	CancelDeletion(ctx context.Context, uId model.Id) error
+	PurgeDeleted(ctx context.Context) (int, error)
+}
+var _ IAccountUsecase = &AccountUsecase{}
+
+// AccountUsecase writes user.deleted of purged accounts to the outbox in their transaction
+type AccountUsecase struct {
+	userRepo repo.IRepoUser
+	noteRepo repo.IRepoNote
+	exportRepo repo.IRepoExport
+	deletionRepo repo.IRepoAccountDeletion
+	outbox repo.IRepoOutbox
+	tx repo.ITransactor
+	grace time.Duration
+	now func() time.Time
+	// async runs export building in background
+	async func(func())
+	// ctx stops the builds, Wait waits for them
+	ctx context.Context
+	builds sync.WaitGroup
+}
+
+// NewAccountUsecase takes the grace period during which a scheduled deletion can be cancelled,
+// export builds stop when ctx is done
+func NewAccountUsecase(ctx context.Context, u repo.IRepoUser, n repo.IRepoNote, e repo.IRepoExport, d repo.IRepoAccountDeletion, outbox repo.IRepoOutbox, tx repo.ITransactor, grace time.Duration) *AccountUsecase {
+	return &AccountUsecase{
+		userRepo: u,
+		noteRepo: n,
+		exportRepo: e,
+		deletionRepo: d,
+		outbox: outbox,
+		tx: tx,
+		grace: grace,
+		now: time.Now,
+		async: func(f func()) { go f() },
+		ctx: ctx,
+	}
+}
+
+func(u *AccountUsecase) RequestExport(ctx context.Context, uId model.Id) (model.Export, error) {
+	now := u.now().UTC()
+
+	e, err := u.exportRepo.GetByUserId(ctx, uId)
+	if err != nil && !errors.Is(err, repo.ErrNotFound) {
+		return model.Export{}, fmt.Errorf("request export: %w", err)
+	}
+	if err == nil && e.Status == model.ExportPending && now.Sub(e.CreatedAt) < exportTimeout {
+		return e, nil
+	}
+
+	e = model.Export{UserId: uId, Status: model.ExportPending, CreatedAt: now}
+	if err := u.exportRepo.Upsert(ctx, &e); err != nil {
+		return model.Export{}, fmt.Errorf("request export: %w", err)
+	}
+
+	u.builds.Add(1)
+	u.async(func() {
+		defer u.builds.Done()
+		// the request context is gone by the time the export is built, its tenant is kept
+		u.buildExport(repo.WithTenantOf(u.ctx, ctx), e)
+	})
+
+	return e, nil
+}
+
+func(u *AccountUsecase) FindExport(ctx context.Context, uId model.Id) (model.Export, error) {
+	e, err := u.exportRepo.GetByUserId(ctx, uId)
+	if errors.Is(err, repo.ErrNotFound) {
+		return model.Export{}, ErrExportNotFound
+	}
+	if err != nil {
+		return model.Export{}, fmt.Errorf("find export: %w", err)
+	}
+
+	if e.Status == model.ExportReady && u.now().UTC().Sub(e.CompletedAt) > exportLifetime {
+		if err := u.exportRepo.Delete(ctx, uId); err != nil {
+			return model.Export{}, fmt.Errorf("find export: %w", err)
+		}
+
+		return model.Export{}, ErrExportNotFound
+	}
+
+	return e, nil
+}
+
+// Wait blocks until the export builds in flight are finished
+func(u *AccountUsecase) Wait() {
+	u.builds.Wait()
+}
+
+func(u *AccountUsecase) buildExport(ctx context.Context, e model.Export) {
+	archive, err := u.archive(ctx, e.UserId)
+
+	e.CompletedAt = u.now().UTC()
+	e.Status = model.ExportReady
+	e.Archive = archive
+	if err != nil {
+		e.Status = model.ExportFailed
+		e.Archive = nil
+	}
+
+	// a build stopped on shutdown is failed so that it can be requested again at once,
+	// nothing is waiting for the result, a failed write leaves the export pending until it times out
+	u.exportRepo.Upsert(repo.WithTenantOf(context.Background(), ctx), &e)
+}
+
+type exportProfile struct {
+	Id model.Id `json:"id"`
+	UserName string `json:"username"`
+	TimeZone string `json:"time_zone"`
+	DisplayName string `json:"display_name"`
+	Locale string `json:"locale"`
+	WeekStart string `json:"week_start"`
+	ReminderOffsetMinutes int `json:"reminder_offset_minutes"`
+	DateFormat string `json:"date_format"`
+}
+
+type exportNote struct {
+	Id model.Id `json:"id"`
+	Title string `json:"title"`
+	Text string `json:"text"`
+	// Date has no offset for floating notes
+	Date string `json:"date"`
+	TimeZone string `json:"time_zone,omitempty"`
+	IsFloating bool `json:"is_floating"`
+	IsFinished bool `json:"is_finished"`
+	ReminderOffsetMinutes int `json:"reminder_offset_minutes"`
+	Uid string `json:"uid,omitempty"`
+	Priority string `json:"priority,omitempty"`
+	Projects []string `json:"projects,omitempty"`
+	Contexts []string `json:"contexts,omitempty"`
+	FinishedAt *string `json:"finished_at,omitempty"`
+}
+
+// archive is a zip of profile.json, notes.json and notes.ics, notes are streamed into it
+func(u *AccountUsecase) archive(ctx context.Context, uId model.Id) ([]byte, error) {
+	usr, err := u.userRepo.GetById(ctx, uId)
+	if err != nil {
+		return nil, fmt.Errorf("archive: %w", err)
+	}
+
+	profile := exportProfile{
+		Id: usr.Id,
+		UserName: usr.Name,
+		TimeZone: usr.TimeZone,
+		DisplayName: usr.Preferences.DisplayName,
+		Locale: usr.Preferences.Locale,
+		WeekStart: usr.Preferences.WeekStart.String(),
+		ReminderOffsetMinutes: int(usr.Preferences.ReminderOffset / time.Minute),
+		DateFormat: usr.Preferences.DateFormat,
+	}
+
+	var buf bytes.Buffer
+	z := zip.NewWriter(&buf)
+
+	if err := writeJsonEntry(z, "profile.json", profile); err != nil {
+		return nil, fmt.Errorf("archive: %w", err)
+	}
+
+	filter := repo.NoteFilter{UserId: uId}
+	f, err := z.Create("notes.json")
+	if err != nil {
+		return nil, fmt.Errorf("archive: %w", err)
+	}
+	notes := jsonArrayWriter{w: f}
+	err = u.noteRepo.Each(ctx, filter, func(n model.Note) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		return notes.write(newExportNote(n))
+	})
+	if err == nil {
+		err = notes.close()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("archive: %w", err)
+	}
+
+	f, err = z.Create("notes.ics")
+	if err != nil {
+		return nil, fmt.Errorf("archive: %w", err)
+	}
+	cal := NewCalendarEncoder(f, u.now(), IcalEvent)
+	if err := u.noteRepo.Each(ctx, filter, cal.Encode); err != nil {
+		return nil, fmt.Errorf("archive: %w", err)
+	}
+	if err := cal.Close(); err != nil {
+		return nil, fmt.Errorf("archive: %w", err)
+	}
+
+	if err := z.Close(); err != nil {
+		return nil, fmt.Errorf("archive: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func newExportNote(n model.Note) exportNote {
+	date := n.Date.UTC().Format(time.RFC3339)
+	if n.IsFloating {
+		date = n.Date.Format(floatingDateFormat)
+	}
+
+	var finishedAt *string
+	if n.FinishedAt != nil {
+		at := n.FinishedAt.UTC().Format(time.RFC3339)
+		finishedAt = &at
+	}
+
+	return exportNote{
+		Id: n.Id,
+		Title: n.Title,
+		Text: n.Text,
+		Date: date,
+		TimeZone: n.TimeZone,
+		IsFloating: n.IsFloating,
+		IsFinished: n.IsFinished,
+		ReminderOffsetMinutes: int(n.ReminderOffset / time.Minute),
+		Uid: n.Uid,
+		Priority: n.Priority,
+		Projects: n.Projects,
+		Contexts: n.Contexts,
+		FinishedAt: finishedAt,
+	}
+}
+
+// jsonArrayWriter writes an array element by element the way writeJsonEntry indents a whole one
+type jsonArrayWriter struct {
+	w io.Writer
+	count int
+}
+
+func(a *jsonArrayWriter) write(v interface{}) error {
+	b, err := json.MarshalIndent(v, "  ", "  ")
+	if err != nil {
+		return err
+	}
+
+	sep := ",\n  "
+	if a.count == 0 {
+		sep = "[\n  "
+	}
+	a.count++
+
+	if _, err := io.WriteString(a.w, sep); err != nil {
+		return err
+	}
+	_, err = a.w.Write(b)
+	return err
+}
+
+func(a *jsonArrayWriter) close() error {
+	end := "\n]\n"
+	if a.count == 0 {
+		end = "[]\n"
+	}
+
+	_, err := io.WriteString(a.w, end)
+	return err
+}
+
+func writeJsonEntry(z *zip.Writer, name string, v interface{}) error {
+	f, err := z.Create(name)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func(u *AccountUsecase) ScheduleDeletion(ctx context.Context, uId model.Id) (model.AccountDeletion, error) {
+	d, err := u.deletionRepo.GetByUserId(ctx, uId)
+	if err == nil {
+		return d, nil
+	}
+	if !errors.Is(err, repo.ErrNotFound) {
+		return model.AccountDeletion{}, fmt.Errorf("schedule deletion: %w", err)
+	}
+
+	d = model.AccountDeletion{UserId: uId, DeleteAfter: u.now().UTC().Add(u.grace)}
+	if err := u.deletionRepo.Upsert(ctx, &d); err != nil {
+		return model.AccountDeletion{}, fmt.Errorf("schedule deletion: %w", err)
+	}
+
+	return d, nil
+}
+
+func(u *AccountUsecase) CancelDeletion(ctx context.Context, uId model.Id) error {
+	_, err := u.deletionRepo.GetByUserId(ctx, uId)
+	if errors.Is(err, repo.ErrNotFound) {
+		return ErrDeletionNotScheduled
+	}
+	if err != nil {
+		return fmt.Errorf("cancel deletion: %w", err)
+	}
+
+	if err := u.deletionRepo.Delete(ctx, uId); err != nil {
+		return fmt.Errorf("cancel deletion: %w", err)
+	}
+
+	return nil
+}
+
+// PurgeDeleted removes users whose grace period is over and returns how many were removed
+func(u *AccountUsecase) PurgeDeleted(ctx context.Context) (int, error) {
+	// due deletions are of every organization
+	ctx = repo.AllTenants(ctx)
+	due, err := u.deletionRepo.GetDue(ctx, u.now().UTC())
+	if err != nil {
+		return 0, fmt.Errorf("purge deleted: %w", err)
+	}
+
+	for i, d := range due {
+		if err := u.purge(ctx, d.UserId); err != nil {
+			return i, fmt.Errorf("purge deleted: user(id: %v): %w", d.UserId, err)
+		}
+	}
+
+	return len(due), nil
+}
+
+// purge removes the user with the export and the deletion record, all of them or none
+func(u *AccountUsecase) purge(ctx context.Context, uId model.Id) error {
+	return u.tx.InTransaction(ctx, func(ctx context.Context) error {
+		if err := u.userRepo.Delete(ctx, uId); err != nil {
+			return err
+		}
+
+		if err := u.exportRepo.Delete(ctx, uId); err != nil {
+			return err
+		}
+
+		if err := u.deletionRepo.Delete(ctx, uId); err != nil {
+			return err
+		}
+
+		return emit(ctx, u.outbox, u.now(), model.EventUserDeleted, uId, uId, UserPayload{Id: uId})
+	})
+}
@@ -0,0 +1,187 @@
+package usecase
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"strings"
+	"testing"
+	"time"
+	"todoNote/internal/model"
+	"todoNote/internal/repo"
+	in_memory "todoNote/internal/repo/in-memory"
+)
+
+func newTestAccountUsecase(now time.Time) *AccountUsecase {
+	uc := NewAccountUsecase(context.Background(), in_memory.NewRepoUser(), in_memory.NewRepoNote(),
+		in_memory.NewRepoExport(), in_memory.NewRepoAccountDeletion(), in_memory.NewRepoOutbox(), in_memory.NewTransactor(), 24 * time.Hour)
+	uc.now = fixedClock(now)
+	return uc
+}
+
+func TestAccountUsecase_Export(t *testing.T) {
+	now := time.Date(2021, 10, 1, 10, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+	uc := newTestAccountUsecase(now)
+
+	var build func()
+	uc.async = func(f func()) { build = f }
+
+	finished := time.Date(2021, 9, 30, 18, 0, 0, 0, time.UTC)
+	n := model.NewNote(0, 1, "todo", "", now, true)
+	n.Uid = "todo@example.com"
+	n.Priority = "A"
+	n.Projects = []string{"home"}
+	n.Contexts = []string{"phone"}
+	n.FinishedAt = &finished
+	_, err := uc.noteRepo.Insert(ctx, n)
+	assert.Nil(t, err)
+
+	_, err = uc.FindExport(ctx, 1)
+	assert.True(t, errors.Is(err, ErrExportNotFound))
+
+	e, err := uc.RequestExport(ctx, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, model.ExportPending, e.Status)
+
+	e, err = uc.FindExport(ctx, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, model.ExportPending, e.Status)
+
+	build()
+	e, err = uc.FindExport(ctx, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, model.ExportReady, e.Status)
+
+	z, err := zip.NewReader(bytes.NewReader(e.Archive), int64(len(e.Archive)))
+	assert.Nil(t, err)
+
+	files := make(map[string]string)
+	for _, f := range z.File {
+		r, _ := f.Open()
+		b, _ := io.ReadAll(r)
+		files[f.Name] = string(b)
+	}
+
+	assert.Contains(t, files["profile.json"], `"username": "user"`)
+	assert.Equal(t, 3, strings.Count(files["notes.json"], `"title": "title"`))
+	assert.Equal(t, 4, strings.Count(files["notes.ics"], "BEGIN:VEVENT"))
+
+	var notes []exportNote
+	assert.Nil(t, json.Unmarshal([]byte(files["notes.json"]), &notes))
+	assert.Len(t, notes, 4)
+	finishedAt := "2021-09-30T18:00:00Z"
+	assert.Contains(t, notes, exportNote{
+		Id: n.Id,
+		Title: "todo",
+		Date: "2021-10-01T10:00:00Z",
+		IsFinished: true,
+		Uid: "todo@example.com",
+		Priority: "A",
+		Projects: []string{"home"},
+		Contexts: []string{"phone"},
+		FinishedAt: &finishedAt,
+	})
+
+	t.Run("ready export expires", func(t *testing.T) {
+		uc.now = fixedClock(now.Add(exportLifetime + time.Minute))
+		_, err := uc.FindExport(ctx, 1)
+		assert.True(t, errors.Is(err, ErrExportNotFound))
+	})
+}
+
+func TestAccountUsecase_ExportShutdown(t *testing.T) {
+	now := time.Date(2021, 10, 1, 10, 0, 0, 0, time.UTC)
+	ctx, cancel := context.WithCancel(context.Background())
+	uc := newTestAccountUsecase(now)
+	uc.ctx = ctx
+
+	var build func()
+	uc.async = func(f func()) { build = f }
+
+	_, err := uc.RequestExport(context.Background(), 1)
+	assert.Nil(t, err)
+
+	cancel()
+	build()
+	uc.Wait()
+
+	// a stopped build can be requested again without waiting for the timeout
+	e, err := uc.FindExport(context.Background(), 1)
+	assert.Nil(t, err)
+	assert.Equal(t, model.ExportFailed, e.Status)
+}
+
+func TestAccountUsecase_Deletion(t *testing.T) {
+	now := time.Date(2021, 10, 1, 10, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+	uc := newTestAccountUsecase(now)
+
+	assert.True(t, errors.Is(uc.CancelDeletion(ctx, 1), ErrDeletionNotScheduled))
+
+	d, err := uc.ScheduleDeletion(ctx, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, now.Add(24 * time.Hour), d.DeleteAfter)
+
+	t.Run("cancelled deletion does not remove the user", func(t *testing.T) {
+		assert.Nil(t, uc.CancelDeletion(ctx, 1))
+
+		uc.now = fixedClock(now.Add(25 * time.Hour))
+		n, err := uc.PurgeDeleted(ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, n)
+	})
+
+	t.Run("user is removed after grace period", func(t *testing.T) {
+		uc.now = fixedClock(now)
+		_, err := uc.ScheduleDeletion(ctx, 1)
+		assert.Nil(t, err)
+
+		n, err := uc.PurgeDeleted(ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, n)
+
+		uc.now = fixedClock(now.Add(25 * time.Hour))
+		n, err = uc.PurgeDeleted(ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, n)
+
+		_, err = uc.userRepo.GetById(ctx, 1)
+		assert.NotNil(t, err)
+		assert.Equal(t, []model.EventType{model.EventUserDeleted}, claimedTypes(t, uc.outbox))
+	})
+}
+
+// brokenDeletions fail to remove deletion records
+type brokenDeletions struct {
+	repo.IRepoAccountDeletion
+}
+
+func(brokenDeletions) Delete(context.Context, model.Id) error {
+	return fmt.Errorf("broken")
+}
+
+func TestAccountUsecase_PurgeRollback(t *testing.T) {
+	now := time.Date(2021, 10, 1, 10, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+	uc := newTestAccountUsecase(now)
+
+	_, err := uc.ScheduleDeletion(ctx, 1)
+	assert.Nil(t, err)
+	uc.deletionRepo = brokenDeletions{uc.deletionRepo}
+
+	uc.now = fixedClock(now.Add(25 * time.Hour))
+	n, err := uc.PurgeDeleted(ctx)
+	assert.NotNil(t, err)
+	assert.Equal(t, 0, n)
+
+	// a purge which fails partway leaves the user as it was and no event
+	_, err = uc.userRepo.GetById(ctx, 1)
+	assert.Nil(t, err)
+	assert.Empty(t, claimedTypes(t, uc.outbox))
+}
@@ -0,0 +1,140 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+	"unicode/utf8"
+	"todoNote/internal/model"
+	"todoNote/internal/repo"
+)
+
+// maxTitleLength matches notes.title column
+const maxTitleLength = 50
+
+type IBulkUsecase interface {
+	// Export streams notes in csv, json or ndjson, an error after the first note leaves w incomplete
+	Export(ctx context.Context, w io.Writer, p FindParams, format NotesFormat) error
+	// Import creates a note of every valid record, invalid records are reported by line
+	Import(ctx context.Context, r io.Reader, uId model.Id, format NotesFormat) (model.ImportReport, error)
+}
+var _ IBulkUsecase = &BulkUsecase{}
+
+type BulkUsecase struct {
+	notes INoteUsecase
+	userRepo repo.IRepoUser
+	tx repo.ITransactor
+}
+
+func NewBulkUsecase(n INoteUsecase, u repo.IRepoUser, tx repo.ITransactor) *BulkUsecase {
+	return &BulkUsecase{
+		notes: n,
+		userRepo: u,
+		tx: tx,
+	}
+}
+
+func(u *BulkUsecase) Export(ctx context.Context, w io.Writer, p FindParams, format NotesFormat) error {
+	enc := NewNoteEncoder(w, format)
+	if err := u.notes.Each(ctx, p, enc.Encode); err != nil {
+		return fmt.Errorf("export notes: %w", err)
+	}
+
+	if err := enc.Close(); err != nil {
+		return fmt.Errorf("export notes: %w", err)
+	}
+
+	return nil
+}
+
+func(u *BulkUsecase) Import(ctx context.Context, r io.Reader, uId model.Id, format NotesFormat) (model.ImportReport, error) {
+	var report model.ImportReport
+
+	usr, err := u.userRepo.GetById(ctx, uId)
+	if err != nil {
+		return report, fmt.Errorf("import notes: %w", err)
+	}
+
+	// a broken file or a failed note rolls back the whole import so it can be retried
+	err = u.tx.InTransaction(ctx, func(ctx context.Context) error {
+		return DecodeNotes(r, format, func(line int, rec NoteRecord, err error) error {
+			item := model.ImportItem{Line: line, Status: model.ImportSkipped}
+			if err == nil {
+				err = validateRecord(rec)
+			}
+			if err != nil {
+				item.Reason = err.Error()
+				report.Add(item)
+				return nil
+			}
+
+			n := recordNote(rec, usr)
+			id, err := u.notes.CreateNote(ctx, n)
+			if err != nil {
+				return err
+			}
+
+			item.NoteId = id
+			item.Status = model.ImportCreated
+			report.Add(item)
+			return nil
+		})
+	})
+	if err != nil {
+		return model.ImportReport{}, fmt.Errorf("import notes: %w", err)
+	}
+
+	return report, nil
+}
+
+func validateRecord(rec NoteRecord) error {
+	if rec.Title == "" {
+		return fmt.Errorf("title is empty")
+	}
+	if utf8.RuneCountInString(rec.Title) > maxTitleLength {
+		return fmt.Errorf("title is longer than %v characters", maxTitleLength)
+	}
+	if rec.TimeZone != "" {
+		if _, ok := ValidateZone(rec.TimeZone); !ok {
+			return fmt.Errorf("unknown time_zone %q", rec.TimeZone)
+		}
+	}
+	if rec.ReminderOffsetMinutes != nil && !ValidateReminderOffset(time.Duration(*rec.ReminderOffsetMinutes) * time.Minute) {
+		return fmt.Errorf("reminder_offset_minutes is out of range")
+	}
+	if !ValidPriority(rec.Priority) {
+		return fmt.Errorf("priority is not a letter from A to Z")
+	}
+	for _, t := range append(append([]string{}, rec.Projects...), rec.Contexts...) {
+		if !ValidTag(t) {
+			return fmt.Errorf("tag %q is empty or has spaces", t)
+		}
+	}
+
+	return nil
+}
+
+// recordNote fills what the record does not specify from the user like note creation does
+func recordNote(rec NoteRecord, usr *model.User) *model.Note {
+	n := model.NewNote(0, usr.Id, rec.Title, rec.Text, rec.Date, rec.IsFinished)
+	n.TimeZone = NormalizeZone(rec.TimeZone)
+	if n.TimeZone == "" {
+		n.TimeZone = usr.TimeZone
+	}
+	n.IsFloating = rec.IsFloating
+	n.Priority = rec.Priority
+	n.Projects = rec.Projects
+	n.Contexts = rec.Contexts
+	if rec.FinishedAt != nil {
+		finishedAt := rec.FinishedAt.UTC()
+		n.FinishedAt = &finishedAt
+	}
+
+	n.ReminderOffset = usr.Preferences.ReminderOffset
+	if rec.ReminderOffsetMinutes != nil {
+		n.ReminderOffset = time.Duration(*rec.ReminderOffsetMinutes) * time.Minute
+	}
+
+	return n
+}
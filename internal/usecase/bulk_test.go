@@ -0,0 +1,84 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+	"todoNote/internal/model"
+	"todoNote/internal/repo"
+	in_memory "todoNote/internal/repo/in-memory"
+)
+
+func newBulkUsecase() (*BulkUsecase, repo.IRepoNote) {
+	notes := in_memory.NewRepoNote()
+	return NewBulkUsecase(NewNoteUsecase(notes, in_memory.NewRepoOutbox(), in_memory.NewTransactor()), in_memory.NewRepoUser(), in_memory.NewTransactor()), notes
+}
+
+func TestBulkUsecase_Export(t *testing.T) {
+	ctx := context.Background()
+	uc, _ := newBulkUsecase()
+
+	var buf bytes.Buffer
+	p := FindParams{Filter: repo.NoteFilter{UserId: 1}, Zone: model.UTC}
+	assert.Nil(t, uc.Export(ctx, &buf, p, NotesCsv))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 4)
+	assert.Equal(t, strings.Join(csvHeader, ","), lines[0])
+
+	buf.Reset()
+	p.Filter.UserId = 2
+	assert.Nil(t, uc.Export(ctx, &buf, p, NotesJson))
+	assert.Equal(t, "[]\n", buf.String())
+}
+
+func TestBulkUsecase_Import(t *testing.T) {
+	ctx := context.Background()
+	uc, notes := newBulkUsecase()
+
+	in := strings.Join([]string{
+		`{"title":"milk","date":"2021-10-02T10:30:00+03:00","is_finished":true}`,
+		`{"title":""}`,
+		`{"title":"bread","time_zone":"Mars/Olympus"}`,
+		`{"title":"eggs","reminder_offset_minutes":5,"time_zone":"Europe/Kyiv"}`,
+		`{"title":` + `"` + strings.Repeat("й", maxTitleLength + 1) + `"}`,
+	}, "\n")
+
+	report, err := uc.Import(ctx, strings.NewReader(in), 1, NotesNdjson)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, report.Created)
+	assert.Equal(t, 3, report.Skipped)
+
+	skipped := map[int]string{}
+	for _, i := range report.Items {
+		if i.Status == model.ImportSkipped {
+			skipped[i.Line] = i.Reason
+		}
+	}
+	assert.Equal(t, map[int]string{
+		2: "title is empty",
+		3: `unknown time_zone "Mars/Olympus"`,
+		5: "title is longer than 50 characters",
+	}, skipped)
+
+	milk, err := notes.GetById(ctx, report.Items[0].NoteId)
+	assert.Nil(t, err)
+	assert.True(t, milk.IsFinished)
+	assert.Equal(t, time.Date(2021, 10, 2, 7, 30, 0, 0, time.UTC), milk.Date.UTC())
+	// the user's zone fills what the record does not specify, its legacy offset is stored as the IANA zone
+	assert.Equal(t, "Etc/GMT+4", milk.TimeZone)
+
+	eggs, err := notes.GetById(ctx, report.Items[3].NoteId)
+	assert.Nil(t, err)
+	assert.Equal(t, "Europe/Kyiv", eggs.TimeZone)
+	assert.Equal(t, 5 * time.Minute, eggs.ReminderOffset)
+
+	t.Run("broken file", func(t *testing.T) {
+		_, err := uc.Import(ctx, strings.NewReader(`[{"title":"milk"`), 1, NotesJson)
+		assert.True(t, errors.Is(err, ErrInvalidNotesFile))
+	})
+}
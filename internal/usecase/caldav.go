@@ -0,0 +1,244 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+	"todoNote/internal/model"
+	"todoNote/internal/repo"
+)
+
+const calendarObjectExt = ".ics"
+
+var (
+	ErrObjectNotFound = fmt.Errorf("no such calendar object")
+	ErrPreconditionFailed = fmt.Errorf("calendar object precondition failed")
+	ErrUnsupportedComponent = fmt.Errorf("only VEVENT calendar objects are supported")
+	ErrUidMismatch = fmt.Errorf("calendar object UID does not match its name")
+)
+
+// CalendarObject is a note as a CalDAV resource of the user's calendar collection
+type CalendarObject struct {
+	// Name of the resource in the collection, its UID with .ics
+	Name string
+	// ETag is quoted and changes with the note content
+	ETag string
+	Data []byte
+	NoteId model.Id
+}
+
+// PutCondition holds If-Match and If-None-Match: * of a PUT request
+type PutCondition struct {
+	IfMatch string
+	IfNoneMatch bool
+}
+
+type ICalDavUsecase interface {
+	// Objects of notes dated in [from, to), nil bounds are open
+	Objects(ctx context.Context, uId model.Id, from, to *time.Time) ([]CalendarObject, error)
+	Object(ctx context.Context, uId model.Id, name string) (CalendarObject, error)
+	// PutObject creates or replaces the note of a single event calendar, true when created
+	PutObject(ctx context.Context, uId model.Id, name string, r io.Reader, c PutCondition) (CalendarObject, bool, error)
+	DeleteObject(ctx context.Context, uId model.Id, name string, ifMatch string) error
+	// CTag changes whenever any object of the collection changes
+	CTag(ctx context.Context, uId model.Id) (string, error)
+}
+var _ ICalDavUsecase = &CalendarUsecase{}
+
+func(u *CalendarUsecase) Objects(ctx context.Context, uId model.Id, from, to *time.Time) ([]CalendarObject, error) {
+	objects := make([]CalendarObject, 0)
+	filter := repo.NoteFilter{UserId: uId, TakeFrom: from, TakeTo: to}
+	err := u.noteRepo.Each(ctx, filter, func(n model.Note) error {
+		o, err := calendarObject(n)
+		if err != nil {
+			return err
+		}
+
+		objects = append(objects, o)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("calendar objects: %w", err)
+	}
+
+	return objects, nil
+}
+
+func(u *CalendarUsecase) Object(ctx context.Context, uId model.Id, name string) (CalendarObject, error) {
+	n, found, err := u.objectNote(ctx, uId, name, false)
+	if err != nil {
+		return CalendarObject{}, fmt.Errorf("calendar object: %w", err)
+	}
+	if !found {
+		return CalendarObject{}, ErrObjectNotFound
+	}
+
+	o, err := calendarObject(n)
+	if err != nil {
+		return CalendarObject{}, fmt.Errorf("calendar object: %w", err)
+	}
+
+	return o, nil
+}
+
+func(u *CalendarUsecase) PutObject(ctx context.Context, uId model.Id, name string, r io.Reader, c PutCondition) (CalendarObject, bool, error) {
+	items, err := DecodeCalendar(r)
+	if err != nil {
+		return CalendarObject{}, false, fmt.Errorf("put calendar object: %w", err)
+	}
+
+	item, err := objectItem(items, name)
+	if err != nil {
+		return CalendarObject{}, false, fmt.Errorf("put calendar object: %w", err)
+	}
+
+	var res CalendarObject
+	var created bool
+	err = u.tx.InTransaction(ctx, func(ctx context.Context) error {
+		// the lock keeps concurrent requests with the same If-Match from both passing the condition
+		old, found, err := u.objectNote(ctx, uId, name, true)
+		if err != nil {
+			return err
+		}
+		if err := checkCondition(old, found, c); err != nil {
+			return err
+		}
+
+		n := item.Note
+		n.UserId = uId
+		n.Uid = item.Uid
+		if !found {
+			if _, err := u.notes.CreateNote(ctx, &n); err != nil {
+				return err
+			}
+			created = true
+		} else {
+			n = mergeCalendarNote(old, n, u.now())
+			if err := u.replaceNote(ctx, old, &n); err != nil {
+				return err
+			}
+		}
+
+		stored, err := u.noteRepo.GetById(ctx, n.Id)
+		if err != nil {
+			return err
+		}
+
+		res, err = calendarObject(stored)
+		return err
+	})
+	if err != nil {
+		return CalendarObject{}, false, fmt.Errorf("put calendar object: %w", err)
+	}
+
+	return res, created, nil
+}
+
+func(u *CalendarUsecase) DeleteObject(ctx context.Context, uId model.Id, name string, ifMatch string) error {
+	err := u.tx.InTransaction(ctx, func(ctx context.Context) error {
+		n, found, err := u.objectNote(ctx, uId, name, true)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return ErrObjectNotFound
+		}
+		if err := checkCondition(n, found, PutCondition{IfMatch: ifMatch}); err != nil {
+			return err
+		}
+
+		return u.notes.RemoveNote(ctx, n.Id, uId)
+	})
+	if err != nil {
+		return fmt.Errorf("delete calendar object: %w", err)
+	}
+
+	return nil
+}
+
+func(u *CalendarUsecase) CTag(ctx context.Context, uId model.Id) (string, error) {
+	objects, err := u.Objects(ctx, uId, nil, nil)
+	if err != nil {
+		return "", fmt.Errorf("calendar ctag: %w", err)
+	}
+
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Name < objects[j].Name })
+	h := sha256.New()
+	for _, o := range objects {
+		fmt.Fprintf(h, "%v %v\n", o.Name, o.ETag)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)[:16]), nil
+}
+
+func(u *CalendarUsecase) objectNote(ctx context.Context, uId model.Id, name string, forUpdate bool) (model.Note, bool, error) {
+	if !strings.HasSuffix(name, calendarObjectExt) {
+		return model.Note{}, false, nil
+	}
+
+	return u.importedNote(ctx, strings.TrimSuffix(name, calendarObjectExt), uId, forUpdate)
+}
+
+// objectItem is the only event of a calendar object, clients name objects by their UID
+func objectItem(items []CalendarItem, name string) (CalendarItem, error) {
+	if len(items) != 1 {
+		return CalendarItem{}, fmt.Errorf("%w: one item per object", ErrInvalidCalendar)
+	}
+
+	item := items[0]
+	if item.Component != IcalEvent {
+		return CalendarItem{}, ErrUnsupportedComponent
+	}
+	if item.Skip != "" {
+		return CalendarItem{}, fmt.Errorf("%w: %v", ErrInvalidCalendar, item.Skip)
+	}
+	if item.Uid + calendarObjectExt != name {
+		return CalendarItem{}, ErrUidMismatch
+	}
+
+	return item, nil
+}
+
+func checkCondition(n model.Note, found bool, c PutCondition) error {
+	if c.IfNoneMatch && found {
+		return ErrPreconditionFailed
+	}
+	if c.IfMatch == "" || c.IfMatch == "*" && found {
+		return nil
+	}
+	if !found {
+		return ErrPreconditionFailed
+	}
+
+	o, err := calendarObject(n)
+	if err != nil {
+		return err
+	}
+	if o.ETag != c.IfMatch {
+		return ErrPreconditionFailed
+	}
+
+	return nil
+}
+
+// calendarObject encodes the note with its date as DTSTAMP so that equal notes give equal data and ETags
+func calendarObject(n model.Note) (CalendarObject, error) {
+	var buf bytes.Buffer
+	if err := EncodeCalendar(&buf, []model.Note{n}, n.Date, IcalEvent); err != nil {
+		return CalendarObject{}, err
+	}
+
+	sum := sha256.Sum256(buf.Bytes())
+	return CalendarObject{
+		Name: NoteUid(n) + calendarObjectExt,
+		ETag: `"` + hex.EncodeToString(sum[:16]) + `"`,
+		Data: buf.Bytes(),
+		NoteId: n.Id,
+	}, nil
+}
@@ -0,0 +1,141 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+	"todoNote/internal/model"
+)
+
+func calendarObjectData(uid, start, summary string) string {
+	return strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"BEGIN:VEVENT",
+		"UID:" + uid,
+		"DTSTART:" + start,
+		"SUMMARY:" + summary,
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+}
+
+func TestCalendarUsecase_PutObject(t *testing.T) {
+	ctx := context.Background()
+	uc := newCalendarUsecase()
+
+	name := "dav@example.com.ics"
+	o, created, err := uc.PutObject(ctx, 1, name, strings.NewReader(calendarObjectData("dav@example.com", "20211002T100000Z", "dentist")), PutCondition{IfNoneMatch: true})
+	assert.Nil(t, err)
+	assert.True(t, created)
+	assert.Equal(t, name, o.Name)
+
+	got, err := uc.Object(ctx, 1, name)
+	assert.Nil(t, err)
+	assert.Equal(t, o.ETag, got.ETag)
+	assert.Equal(t, o.Data, got.Data)
+
+	t.Run("create of an existing object fails", func(t *testing.T) {
+		_, _, err := uc.PutObject(ctx, 1, name, strings.NewReader(calendarObjectData("dav@example.com", "20211002T100000Z", "dentist")), PutCondition{IfNoneMatch: true})
+		assert.True(t, errors.Is(err, ErrPreconditionFailed))
+	})
+
+	t.Run("update with a stale etag fails", func(t *testing.T) {
+		_, _, err := uc.PutObject(ctx, 1, name, strings.NewReader(calendarObjectData("dav@example.com", "20211002T100000Z", "doctor")), PutCondition{IfMatch: `"stale"`})
+		assert.True(t, errors.Is(err, ErrPreconditionFailed))
+	})
+
+	t.Run("update changes the etag", func(t *testing.T) {
+		updated, created, err := uc.PutObject(ctx, 1, name, strings.NewReader(calendarObjectData("dav@example.com", "20211002T100000Z", "doctor")), PutCondition{IfMatch: o.ETag})
+		assert.Nil(t, err)
+		assert.False(t, created)
+		assert.Equal(t, o.NoteId, updated.NoteId)
+		assert.NotEqual(t, o.ETag, updated.ETag)
+	})
+
+	t.Run("finishing a note sets its finish time", func(t *testing.T) {
+		now := time.Date(2021, 10, 2, 12, 0, 0, 0, time.UTC)
+		uc.now = fixedClock(now)
+		finished := strings.Replace(calendarObjectData("dav@example.com", "20211002T100000Z", "doctor"), "END:VEVENT", "X-TODONOTE-FINISHED:TRUE\r\nEND:VEVENT", 1)
+		_, _, err := uc.PutObject(ctx, 1, name, strings.NewReader(finished), PutCondition{})
+		assert.Nil(t, err)
+
+		n, err := uc.noteRepo.GetById(ctx, o.NoteId)
+		assert.Nil(t, err)
+		assert.True(t, n.IsFinished)
+		if assert.NotNil(t, n.FinishedAt) {
+			assert.True(t, now.Equal(*n.FinishedAt))
+		}
+	})
+
+	t.Run("uid must match the name", func(t *testing.T) {
+		_, _, err := uc.PutObject(ctx, 1, "other.ics", strings.NewReader(calendarObjectData("dav@example.com", "20211002T100000Z", "doctor")), PutCondition{})
+		assert.True(t, errors.Is(err, ErrUidMismatch))
+	})
+
+	t.Run("todo is not supported", func(t *testing.T) {
+		todo := strings.Replace(calendarObjectData("todo@example.com", "20211002T100000Z", "todo"), "VEVENT", "VTODO", -1)
+		_, _, err := uc.PutObject(ctx, 1, "todo@example.com.ics", strings.NewReader(todo), PutCondition{})
+		assert.True(t, errors.Is(err, ErrUnsupportedComponent))
+	})
+
+	t.Run("objects of another user are not found", func(t *testing.T) {
+		_, err := uc.Object(ctx, 2, name)
+		assert.True(t, errors.Is(err, ErrObjectNotFound))
+	})
+}
+
+func TestCalendarUsecase_ObjectEvents(t *testing.T) {
+	ctx := context.Background()
+	uc := newCalendarUsecase()
+
+	name := "dav@example.com.ics"
+	o, _, err := uc.PutObject(ctx, 1, name, strings.NewReader(calendarObjectData("dav@example.com", "20211002T100000Z", "dentist")), PutCondition{})
+	assert.Nil(t, err)
+	o, _, err = uc.PutObject(ctx, 1, name, strings.NewReader(calendarObjectData("dav@example.com", "20211002T100000Z", "doctor")), PutCondition{})
+	assert.Nil(t, err)
+	assert.Nil(t, uc.DeleteObject(ctx, 1, name, o.ETag))
+
+	assert.Equal(t, []model.EventType{model.EventNoteCreated, model.EventNoteUpdated, model.EventNoteDeleted}, claimedTypes(t, uc.outbox))
+}
+
+func TestCalendarUsecase_Objects(t *testing.T) {
+	ctx := context.Background()
+	uc := newCalendarUsecase()
+
+	_, _, err := uc.PutObject(ctx, 3, "a.ics", strings.NewReader(calendarObjectData("a", "20211002T100000Z", "a")), PutCondition{})
+	assert.Nil(t, err)
+	_, _, err = uc.PutObject(ctx, 3, "b.ics", strings.NewReader(calendarObjectData("b", "20211102T100000Z", "b")), PutCondition{})
+	assert.Nil(t, err)
+
+	all, err := uc.Objects(ctx, 3, nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(all))
+
+	from := time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2021, 11, 1, 0, 0, 0, 0, time.UTC)
+	october, err := uc.Objects(ctx, 3, &from, &to)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(october))
+	assert.Equal(t, "a.ics", october[0].Name)
+
+	ctag, err := uc.CTag(ctx, 3)
+	assert.Nil(t, err)
+
+	assert.True(t, errors.Is(uc.DeleteObject(ctx, 3, "a.ics", `"stale"`), ErrPreconditionFailed))
+	assert.Nil(t, uc.DeleteObject(ctx, 3, "a.ics", october[0].ETag))
+	assert.True(t, errors.Is(uc.DeleteObject(ctx, 3, "a.ics", ""), ErrObjectNotFound))
+
+	changed, err := uc.CTag(ctx, 3)
+	assert.Nil(t, err)
+	assert.NotEqual(t, ctag, changed)
+
+	t.Run("notes exported before keep their id based name", func(t *testing.T) {
+		o, err := uc.Object(ctx, 1, NoteUid(model.Note{Id: 1}) + ".ics")
+		assert.Nil(t, err)
+		assert.Equal(t, model.Id(1), o.NoteId)
+	})
+}
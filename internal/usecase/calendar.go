@@ -0,0 +1,250 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+	"todoNote/internal/model"
+	"todoNote/internal/repo"
+)
+
+const feedTokenLength = 32
+
+type ICalendarUsecase interface {
+	Export(ctx context.Context, w io.Writer, uId model.Id, component IcalComponent) error
+	// CreateFeed returns a new feed token, the previous one stops working
+	CreateFeed(ctx context.Context, uId model.Id) (string, error)
+	RevokeFeed(ctx context.Context, uId model.Id) error
+	FindFeedUser(ctx context.Context, token string) (model.Id, error)
+	// Import creates notes from events and todos, items imported before are updated by UID
+	Import(ctx context.Context, r io.Reader, uId model.Id) (model.ImportReport, error)
+}
+var _ ICalendarUsecase = &CalendarUsecase{}
+
+// CalendarUsecase creates and removes notes with notes, the notes it replaces write their events to outbox
+type CalendarUsecase struct {
+	noteRepo repo.IRepoNote
+	feedRepo repo.IRepoCalendarFeed
+	notes INoteUsecase
+	outbox repo.IRepoOutbox
+	tx repo.ITransactor
+	now func() time.Time
+}
+
+func NewCalendarUsecase(n repo.IRepoNote, f repo.IRepoCalendarFeed, notes INoteUsecase, outbox repo.IRepoOutbox, tx repo.ITransactor) *CalendarUsecase {
+	return &CalendarUsecase{
+		noteRepo: n,
+		feedRepo: f,
+		notes: notes,
+		outbox: outbox,
+		tx: tx,
+		now: time.Now,
+	}
+}
+
+func(u *CalendarUsecase) Export(ctx context.Context, w io.Writer, uId model.Id, component IcalComponent) error {
+	limit := uint64(exportNotesLimit)
+	notes, err := u.noteRepo.GetAllOffset(ctx, repo.NoteFilter{UserId: uId, Page: repo.PageFilter{Limit: &limit}})
+	if err != nil {
+		return fmt.Errorf("export calendar: %w", err)
+	}
+
+	if err := EncodeCalendar(w, notes, u.now(), component); err != nil {
+		return fmt.Errorf("export calendar: %w", err)
+	}
+
+	return nil
+}
+
+func(u *CalendarUsecase) CreateFeed(ctx context.Context, uId model.Id) (string, error) {
+	b := make([]byte, feedTokenLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("create feed: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	f := model.CalendarFeed{UserId: uId, TokenHash: hashFeedToken(token), CreatedAt: u.now().UTC()}
+	if err := u.feedRepo.Upsert(ctx, &f); err != nil {
+		return "", fmt.Errorf("create feed: %w", err)
+	}
+
+	return token, nil
+}
+
+func(u *CalendarUsecase) RevokeFeed(ctx context.Context, uId model.Id) error {
+	if err := u.feedRepo.Delete(ctx, uId); err != nil {
+		return fmt.Errorf("revoke feed: %w", err)
+	}
+
+	return nil
+}
+
+func(u *CalendarUsecase) FindFeedUser(ctx context.Context, token string) (model.Id, error) {
+	f, err := u.feedRepo.GetByTokenHash(ctx, hashFeedToken(token))
+	if errors.Is(err, repo.ErrNotFound) {
+		return 0, ErrFeedNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("find feed: %w", err)
+	}
+
+	return f.UserId, nil
+}
+
+func(u *CalendarUsecase) Import(ctx context.Context, r io.Reader, uId model.Id) (model.ImportReport, error) {
+	var report model.ImportReport
+
+	items, err := DecodeCalendar(r)
+	if err != nil {
+		return report, fmt.Errorf("import calendar: %w", err)
+	}
+
+	// a failed note rolls back the whole import so it can be retried
+	err = u.tx.InTransaction(ctx, func(ctx context.Context) error {
+		for _, item := range items {
+			res, err := u.importItem(ctx, item, uId)
+			if err != nil {
+				return err
+			}
+			report.Add(res)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return model.ImportReport{}, fmt.Errorf("import calendar: %w", err)
+	}
+
+	return report, nil
+}
+
+func(u *CalendarUsecase) importItem(ctx context.Context, item CalendarItem, uId model.Id) (model.ImportItem, error) {
+	res := model.ImportItem{Uid: item.Uid, Status: model.ImportSkipped, Reason: item.Skip}
+	if item.Skip != "" {
+		return res, nil
+	}
+
+	n := item.Note
+	n.UserId = uId
+	n.Uid = item.Uid
+
+	old, found, err := u.importedNote(ctx, item.Uid, uId, true)
+	if err != nil {
+		return res, err
+	}
+
+	if !found {
+		id, err := u.notes.CreateNote(ctx, &n)
+		if err != nil {
+			return res, err
+		}
+
+		res.NoteId = id
+		res.Status = model.ImportCreated
+		return res, nil
+	}
+
+	res.NoteId = old.Id
+	n = mergeCalendarNote(old, n, u.now())
+	if sameNote(old, n) {
+		res.Reason = "unchanged"
+		return res, nil
+	}
+
+	if err := u.replaceNote(ctx, old, &n); err != nil {
+		return res, err
+	}
+
+	res.Status = model.ImportUpdated
+	return res, nil
+}
+
+// replaceNote stores n over old, it must run in a transaction
+func(u *CalendarUsecase) replaceNote(ctx context.Context, old model.Note, n *model.Note) error {
+	if err := u.noteRepo.Update(ctx, n); err != nil {
+		return err
+	}
+
+	return emitNoteUpdated(ctx, u.outbox, u.now(), old.IsFinished, *n)
+}
+
+// importedNote finds a note of the user by the UID it was imported with or exported under,
+// forUpdate locks it until the transaction of ctx ends
+func(u *CalendarUsecase) importedNote(ctx context.Context, uid string, uId model.Id, forUpdate bool) (model.Note, bool, error) {
+	if uid == "" {
+		return model.Note{}, false, nil
+	}
+
+	getByUid, getById := u.noteRepo.GetByUid, u.noteRepo.GetById
+	if forUpdate {
+		getByUid, getById = u.noteRepo.GetByUidForUpdate, u.noteRepo.GetByIdForUpdate
+	}
+
+	n, err := getByUid(ctx, uId, uid)
+	if err == nil {
+		return n, true, nil
+	}
+	if !errors.Is(err, repo.ErrNotFound) {
+		return model.Note{}, false, err
+	}
+
+	var id model.Id
+	if _, err := fmt.Sscanf(uid, "note-%d@todonote", &id); err != nil || NoteUid(model.Note{Id: id}) != uid {
+		return model.Note{}, false, nil
+	}
+
+	n, err = getById(ctx, id)
+	if errors.Is(err, repo.ErrNotFound) {
+		return model.Note{}, false, nil
+	}
+	if err != nil {
+		return model.Note{}, false, err
+	}
+
+	return n, n.UserId == uId && n.Uid == "", nil
+}
+
+// mergeCalendarNote keeps what iCalendar items do not carry from the stored note,
+// a note finished by the item without COMPLETED time is finished at now
+func mergeCalendarNote(old, n model.Note, now time.Time) model.Note {
+	n.Id = old.Id
+	n.UserId = old.UserId
+	// notes exported from here keep their id based UID
+	n.Uid = old.Uid
+	if n.Date.IsZero() {
+		n.Date, n.TimeZone, n.IsFloating = old.Date, old.TimeZone, old.IsFloating
+	}
+	n.Priority, n.Projects, n.Contexts = old.Priority, old.Projects, old.Contexts
+	switch {
+	case !n.IsFinished:
+		n.FinishedAt = nil
+	case n.FinishedAt != nil:
+	case old.IsFinished:
+		n.FinishedAt = old.FinishedAt
+	default:
+		finishedAt := now.UTC()
+		n.FinishedAt = &finishedAt
+	}
+
+	return n
+}
+
+func sameNote(a, b model.Note) bool {
+	return a.Title == b.Title &&
+		a.Text == b.Text &&
+		a.Date.Equal(b.Date) &&
+		a.IsFinished == b.IsFinished &&
+		a.TimeZone == b.TimeZone &&
+		a.IsFloating == b.IsFloating &&
+		a.ReminderOffset == b.ReminderOffset
+}
+
+func hashFeedToken(token string) []byte {
+	h := sha256.Sum256([]byte(token))
+	return h[:]
+}
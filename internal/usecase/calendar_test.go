@@ -0,0 +1,163 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+	"todoNote/internal/model"
+	in_memory "todoNote/internal/repo/in-memory"
+)
+
+func newCalendarUsecase() *CalendarUsecase {
+	notes := in_memory.NewRepoNote()
+	outbox := in_memory.NewRepoOutbox()
+	return NewCalendarUsecase(notes, in_memory.NewRepoCalendarFeed(), NewNoteUsecase(notes, outbox, in_memory.NewTransactor()), outbox, in_memory.NewTransactor())
+}
+
+func TestCalendarUsecase_Feed(t *testing.T) {
+	ctx := context.Background()
+	uc := newCalendarUsecase()
+
+	_, err := uc.FindFeedUser(ctx, "unknown")
+	assert.True(t, errors.Is(err, ErrFeedNotFound))
+
+	token, err := uc.CreateFeed(ctx, 1)
+	assert.Nil(t, err)
+
+	uId, err := uc.FindFeedUser(ctx, token)
+	assert.Nil(t, err)
+	assert.Equal(t, model.Id(1), uId)
+
+	t.Run("new feed replaces the old one", func(t *testing.T) {
+		rotated, err := uc.CreateFeed(ctx, 1)
+		assert.Nil(t, err)
+		assert.NotEqual(t, token, rotated)
+
+		_, err = uc.FindFeedUser(ctx, token)
+		assert.True(t, errors.Is(err, ErrFeedNotFound))
+		token = rotated
+	})
+
+	t.Run("revoked feed is not found", func(t *testing.T) {
+		assert.Nil(t, uc.RevokeFeed(ctx, 1))
+		_, err := uc.FindFeedUser(ctx, token)
+		assert.True(t, errors.Is(err, ErrFeedNotFound))
+	})
+}
+
+func TestCalendarUsecase_Export(t *testing.T) {
+	ctx := context.Background()
+	uc := newCalendarUsecase()
+
+	var buf bytes.Buffer
+	assert.Nil(t, uc.Export(ctx, &buf, 1, IcalTodo))
+	assert.Equal(t, 3, strings.Count(buf.String(), "BEGIN:VTODO"))
+	assert.Equal(t, 3, strings.Count(buf.String(), "STATUS:NEEDS-ACTION"))
+
+	buf.Reset()
+	assert.Nil(t, uc.Export(ctx, &buf, 2, IcalEvent))
+	assert.NotContains(t, buf.String(), "BEGIN:VEVENT")
+}
+
+func TestCalendarUsecase_Import(t *testing.T) {
+	ctx := context.Background()
+	uc := newCalendarUsecase()
+
+	cal := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"BEGIN:VEVENT",
+		"UID:abc@example.com",
+		"DTSTART;TZID=Europe/Kyiv:20211002T100000",
+		"SUMMARY:dentist",
+		"END:VEVENT",
+		"BEGIN:VTODO",
+		"UID:note-1@todonote",
+		"DUE:20211003T090000Z",
+		"SUMMARY:first note, renamed",
+		"END:VTODO",
+		"BEGIN:VEVENT",
+		"UID:broken@example.com",
+		"DTSTART;TZID=Mars/Olympus:20211002T100000",
+		"SUMMARY:nowhere",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	report, err := uc.Import(ctx, strings.NewReader(cal), 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, report.Created)
+	assert.Equal(t, 1, report.Updated)
+	assert.Equal(t, 1, report.Skipped)
+	assert.Equal(t, model.ImportSkipped, report.Items[2].Status)
+	assert.Contains(t, report.Items[2].Reason, "Mars/Olympus")
+
+	created, err := uc.noteRepo.GetById(ctx, report.Items[0].NoteId)
+	assert.Nil(t, err)
+	assert.Equal(t, "abc@example.com", created.Uid)
+	assert.Equal(t, "Europe/Kyiv", created.TimeZone)
+	assert.True(t, time.Date(2021, 10, 2, 7, 0, 0, 0, time.UTC).Equal(created.Date))
+
+	updated, err := uc.noteRepo.GetById(ctx, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, "first note, renamed", updated.Title)
+	assert.Equal(t, "", updated.Uid)
+	assert.Equal(t, []model.EventType{model.EventNoteCreated, model.EventNoteUpdated}, claimedTypes(t, uc.outbox))
+
+	t.Run("re-import does not duplicate notes", func(t *testing.T) {
+		report, err := uc.Import(ctx, strings.NewReader(cal), 1)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, report.Created)
+		assert.Equal(t, 0, report.Updated)
+		assert.Equal(t, 3, report.Skipped)
+		assert.Equal(t, created.Id, report.Items[0].NoteId)
+		assert.Equal(t, "unchanged", report.Items[0].Reason)
+	})
+
+	t.Run("uid of another user's note creates a new note", func(t *testing.T) {
+		report, err := uc.Import(ctx, strings.NewReader(cal), 2)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, report.Created)
+		assert.NotEqual(t, model.Id(1), report.Items[1].NoteId)
+	})
+
+	t.Run("completed todo keeps its completion time", func(t *testing.T) {
+		todo := func(status string) string {
+			return strings.Join([]string{
+				"BEGIN:VCALENDAR",
+				"VERSION:2.0",
+				"BEGIN:VTODO",
+				"UID:todo@example.com",
+				"DUE:20211003T090000Z",
+				"SUMMARY:taxes",
+				status,
+				"END:VTODO",
+				"END:VCALENDAR",
+			}, "\r\n")
+		}
+
+		report, err := uc.Import(ctx, strings.NewReader(todo("STATUS:NEEDS-ACTION")), 1)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, report.Created)
+
+		report, err = uc.Import(ctx, strings.NewReader(todo("STATUS:COMPLETED\r\nCOMPLETED:20211003T081500Z")), 1)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, report.Updated)
+
+		n, err := uc.noteRepo.GetById(ctx, report.Items[0].NoteId)
+		assert.Nil(t, err)
+		assert.True(t, n.IsFinished)
+		if assert.NotNil(t, n.FinishedAt) {
+			assert.True(t, time.Date(2021, 10, 3, 8, 15, 0, 0, time.UTC).Equal(*n.FinishedAt))
+		}
+	})
+
+	t.Run("invalid calendar", func(t *testing.T) {
+		_, err := uc.Import(ctx, strings.NewReader("BEGIN:VEVENT\r\nEND:VEVENT"), 1)
+		assert.True(t, errors.Is(err, ErrInvalidCalendar))
+	})
+}
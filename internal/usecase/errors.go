@@ -21,3 +21,35 @@ func(e ElemNotFound) Error() string {
 	return fmt.Sprintf("no such %v found (id: %v) for user.go (id: %v)", e.TypeName, e.ElemId, e.UserId)
 }
 
+
+
+var (
+	ErrMfaNotEnrolled    = fmt.Errorf("mfa is not enrolled")
+	ErrMfaAlreadyEnabled = fmt.Errorf("mfa is already enabled")
+	ErrMfaInvalidCode    = fmt.Errorf("invalid mfa code")
+)
+
+var ErrIdentityLinked = fmt.Errorf("identity is linked to another user")
+
+var ErrSessionRevoked = fmt.Errorf("session is revoked or expired")
+
+const sessionType = "session"
+func NewSessionNotFoundError(eId, uId int64) *ElemNotFound {
+	return NewElemNotFoundError(sessionType, eId, uId)
+}
+
+var ErrInvalidPreferences = fmt.Errorf("invalid preferences")
+
+var ErrInvalidTimeZone = fmt.Errorf("unknown time zone")
+
+var (
+	ErrExportNotFound = fmt.Errorf("no data export requested")
+	ErrDeletionNotScheduled = fmt.Errorf("account deletion is not scheduled")
+)
+
+var ErrFeedNotFound = fmt.Errorf("no such calendar feed")
+
+var (
+	ErrInvalidOrganization = fmt.Errorf("organization name must have 1 to 64 characters and no /")
+	ErrOrganizationNotFound = fmt.Errorf("no such organization")
+)
@@ -0,0 +1,81 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+	"todoNote/internal/model"
+	"todoNote/internal/repo"
+)
+
+// NotePayload is the note as events show it to other services
+type NotePayload struct {
+	Id model.Id `json:"id"`
+	UserId model.Id `json:"user_id"`
+	OrganizationId model.Id `json:"organization_id"`
+	Title string `json:"title"`
+	Text string `json:"text"`
+	Date time.Time `json:"date"`
+	IsFinished bool `json:"is_finished"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Version int64 `json:"version"`
+}
+
+// UserPayload is the user as events show it, secrets are never part of it
+type UserPayload struct {
+	Id model.Id `json:"id"`
+	Name string `json:"name"`
+	TimeZone string `json:"time_zone"`
+	// OrganizationId is missing from deletions of the service itself, like purges of deleted accounts
+	OrganizationId model.Id `json:"organization_id,omitempty"`
+}
+
+func notePayload(n model.Note) NotePayload {
+	return NotePayload{
+		Id: n.Id,
+		UserId: n.UserId,
+		OrganizationId: n.OrganizationId,
+		Title: n.Title,
+		Text: n.Text,
+		Date: n.Date,
+		IsFinished: n.IsFinished,
+		FinishedAt: n.FinishedAt,
+		Version: n.Version,
+	}
+}
+
+func userPayload(u model.User) UserPayload {
+	return UserPayload{
+		Id: u.Id,
+		Name: u.Name,
+		TimeZone: u.TimeZone,
+		OrganizationId: u.OrganizationId,
+	}
+}
+
+// emit writes the event to the outbox, it is published only if the transaction of ctx commits
+func emit(ctx context.Context, outbox repo.IRepoOutbox, at time.Time, eventType model.EventType, userId, subjectId model.Id, payload interface{}) error {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("emit %v: %w", eventType, err)
+	}
+
+	if _, err := outbox.Insert(ctx, model.NewEvent(eventType, userId, subjectId, b, at.UTC())); err != nil {
+		return fmt.Errorf("emit %v: %w", eventType, err)
+	}
+
+	return nil
+}
+
+// emitNoteUpdated writes note.updated of n and note.completed when the update finished the note
+func emitNoteUpdated(ctx context.Context, outbox repo.IRepoOutbox, at time.Time, wasFinished bool, n model.Note) error {
+	if err := emit(ctx, outbox, at, model.EventNoteUpdated, n.UserId, n.Id, notePayload(n)); err != nil {
+		return err
+	}
+	if n.IsFinished && !wasFinished {
+		return emit(ctx, outbox, at, model.EventNoteCompleted, n.UserId, n.Id, notePayload(n))
+	}
+
+	return nil
+}
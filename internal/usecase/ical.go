@@ -0,0 +1,163 @@
+package usecase
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"todoNote/internal/model"
+)
+
+const (
+	icalProdId = "-//todoNote//todoNote//EN"
+	icalUtcFormat = "20060102T150405Z"
+	// floating times have no zone designator
+	icalLocalFormat = "20060102T150405"
+	icalLineLength = 75
+)
+
+type IcalComponent = string
+const (
+	IcalEvent IcalComponent = "VEVENT"
+	IcalTodo IcalComponent = "VTODO"
+)
+
+// NoteUid is a stable iCalendar UID of the note, imported notes keep their original UID
+func NoteUid(n model.Note) string {
+	if n.Uid != "" {
+		return n.Uid
+	}
+
+	return fmt.Sprintf("note-%v@todonote", n.Id)
+}
+
+// EncodeCalendar writes notes as iCalendar (RFC 5545) events or todos, floating notes keep local time
+func EncodeCalendar(w io.Writer, notes []model.Note, stamp time.Time, component IcalComponent) error {
+	e := NewCalendarEncoder(w, stamp, component)
+	for _, n := range notes {
+		if err := e.Encode(n); err != nil {
+			return err
+		}
+	}
+
+	return e.Close()
+}
+
+// CalendarEncoder writes the notes of EncodeCalendar one by one, Close finishes the calendar
+type CalendarEncoder struct {
+	e icalEncoder
+	stamp time.Time
+	component IcalComponent
+}
+
+func NewCalendarEncoder(w io.Writer, stamp time.Time, component IcalComponent) *CalendarEncoder {
+	c := &CalendarEncoder{e: icalEncoder{w: bufio.NewWriter(w)}, stamp: stamp, component: component}
+	c.e.line("BEGIN:VCALENDAR")
+	c.e.line("VERSION:2.0")
+	c.e.line("PRODID:" + icalProdId)
+	c.e.line("CALSCALE:GREGORIAN")
+
+	return c
+}
+
+func(c *CalendarEncoder) Encode(n model.Note) error {
+	c.e.component(c.component, n, c.stamp)
+	if c.e.err != nil {
+		return fmt.Errorf("encode calendar: %w", c.e.err)
+	}
+
+	return nil
+}
+
+func(c *CalendarEncoder) Close() error {
+	c.e.line("END:VCALENDAR")
+	if c.e.err != nil {
+		return fmt.Errorf("encode calendar: %w", c.e.err)
+	}
+
+	if err := c.e.w.Flush(); err != nil {
+		return fmt.Errorf("encode calendar: %w", err)
+	}
+
+	return nil
+}
+
+type icalEncoder struct {
+	w *bufio.Writer
+	err error
+}
+
+func(e *icalEncoder) component(component IcalComponent, n model.Note, stamp time.Time) {
+	e.line("BEGIN:" + component)
+	e.line("UID:" + NoteUid(n))
+	e.line("DTSTAMP:" + stamp.UTC().Format(icalUtcFormat))
+	e.line("DTSTART" + icalDate(n))
+	if component == IcalTodo {
+		e.line("DUE" + icalDate(n))
+	}
+	e.line("SUMMARY:" + icalEscape(n.Title))
+	if n.Text != "" {
+		e.line("DESCRIPTION:" + icalEscape(n.Text))
+	}
+	if component == IcalTodo {
+		status := "NEEDS-ACTION"
+		if n.IsFinished {
+			status = "COMPLETED"
+		}
+		e.line("STATUS:" + status)
+	} else if n.IsFinished {
+		// events have no completion status
+		e.line("X-TODONOTE-FINISHED:TRUE")
+	}
+	if n.ReminderOffset > 0 {
+		e.line("BEGIN:VALARM")
+		e.line("ACTION:DISPLAY")
+		e.line(fmt.Sprintf("TRIGGER:-PT%vM", int(n.ReminderOffset / time.Minute)))
+		e.line("DESCRIPTION:" + icalEscape(n.Title))
+		e.line("END:VALARM")
+	}
+	e.line("END:" + component)
+}
+
+func icalDate(n model.Note) string {
+	if n.IsFloating {
+		return ":" + n.Date.Format(icalLocalFormat)
+	}
+
+	return ":" + n.Date.UTC().Format(icalUtcFormat)
+}
+
+// line writes a content line folded to 75 octets without splitting utf-8 sequences
+func(e *icalEncoder) line(l string) {
+	if e.err != nil {
+		return
+	}
+
+	limit := icalLineLength
+	for len(l) > limit {
+		cut := limit
+		for cut > 0 && !isRuneStart(l[cut]) {
+			cut--
+		}
+
+		if _, e.err = e.w.WriteString(l[:cut] + "\r\n "); e.err != nil {
+			return
+		}
+		l = l[cut:]
+		// continuation lines start with a space
+		limit = icalLineLength - 1
+	}
+
+	_, e.err = e.w.WriteString(l + "\r\n")
+}
+
+func isRuneStart(b byte) bool {
+	return b & 0xC0 != 0x80
+}
+
+var icalEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+func icalEscape(s string) string {
+	return icalEscaper.Replace(s)
+}
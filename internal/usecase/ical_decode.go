@@ -0,0 +1,334 @@
+package usecase
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"todoNote/internal/model"
+)
+
+const (
+	icalDateFormat = "20060102"
+	// icalMaxLine bounds an unfolded content line
+	icalMaxLine = 1 << 20
+)
+
+var ErrInvalidCalendar = fmt.Errorf("invalid iCalendar data")
+
+// CalendarItem is an event or todo read from an iCalendar file
+type CalendarItem struct {
+	Uid string
+	// Component is VEVENT or VTODO
+	Component IcalComponent
+	// Note has Date in UTC, floating notes keep their wall clock in UTC
+	Note model.Note
+	// Skip tells why the item can not become a note
+	Skip string
+}
+
+// DecodeCalendar reads VEVENT and VTODO components of an iCalendar (RFC 5545) stream
+func DecodeCalendar(r io.Reader) ([]CalendarItem, error) {
+	lines, err := unfoldLines(r)
+	if err != nil {
+		return nil, fmt.Errorf("decode calendar: %w", err)
+	}
+
+	var (
+		items []CalendarItem
+		inCalendar bool
+		// stack of open components
+		open []string
+		props []icalProperty
+		alarm []icalProperty
+		alarms int
+	)
+	for _, l := range lines {
+		if l == "" {
+			continue
+		}
+
+		p, ok := parseProperty(l)
+		if !ok {
+			return nil, fmt.Errorf("decode calendar: %w: malformed line %q", ErrInvalidCalendar, l)
+		}
+
+		switch p.name {
+		case "BEGIN":
+			c := strings.ToUpper(p.value)
+			if !inCalendar {
+				if c != "VCALENDAR" {
+					return nil, fmt.Errorf("decode calendar: %w: expected VCALENDAR", ErrInvalidCalendar)
+				}
+				inCalendar = true
+				continue
+			}
+
+			open = append(open, c)
+			if len(open) == 1 && (c == IcalEvent || c == IcalTodo) {
+				props = nil
+				alarm = nil
+				alarms = 0
+			}
+			if len(open) == 2 && c == "VALARM" {
+				alarms++
+			}
+		case "END":
+			c := strings.ToUpper(p.value)
+			if len(open) == 0 {
+				if c == "VCALENDAR" && inCalendar {
+					return items, nil
+				}
+				return nil, fmt.Errorf("decode calendar: %w: unexpected END:%v", ErrInvalidCalendar, c)
+			}
+			if open[len(open)-1] != c {
+				return nil, fmt.Errorf("decode calendar: %w: unexpected END:%v", ErrInvalidCalendar, c)
+			}
+
+			open = open[:len(open)-1]
+			if len(open) == 0 && (c == IcalEvent || c == IcalTodo) {
+				items = append(items, calendarItem(c, props, alarm))
+			}
+		default:
+			if !inCalendar {
+				return nil, fmt.Errorf("decode calendar: %w: expected VCALENDAR", ErrInvalidCalendar)
+			}
+
+			switch {
+			case len(open) == 1:
+				props = append(props, p)
+			// only the first alarm of an item becomes its reminder
+			case len(open) == 2 && open[1] == "VALARM" && alarms == 1:
+				alarm = append(alarm, p)
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("decode calendar: %w: missing END:VCALENDAR", ErrInvalidCalendar)
+}
+
+func calendarItem(component string, props, alarm []icalProperty) CalendarItem {
+	item := CalendarItem{Component: component}
+	var start, due *icalProperty
+
+	for i := range props {
+		p := props[i]
+		switch p.name {
+		case "UID":
+			item.Uid = p.value
+		case "SUMMARY":
+			item.Note.Title = icalUnescape(p.value)
+		case "DESCRIPTION":
+			item.Note.Text = icalUnescape(p.value)
+		case "DTSTART":
+			start = &props[i]
+		case "DUE":
+			due = &props[i]
+		case "STATUS":
+			if strings.EqualFold(p.value, "COMPLETED") {
+				item.Note.IsFinished = true
+			}
+		case "COMPLETED":
+			item.Note.IsFinished = true
+			// COMPLETED is in UTC, other values do not tell when
+			if d, err := time.ParseInLocation(icalUtcFormat, p.value, time.UTC); err == nil {
+				item.Note.FinishedAt = &d
+			}
+		case "X-TODONOTE-FINISHED":
+			item.Note.IsFinished = strings.EqualFold(p.value, "TRUE")
+		}
+	}
+
+	// todos are due at DUE, an event or a todo without due date happens at DTSTART
+	date := start
+	if component == IcalTodo && due != nil {
+		date = due
+	}
+
+	switch {
+	case date == nil && component == IcalEvent:
+		item.Skip = "event has no DTSTART"
+		return item
+	case date != nil:
+		if err := icalParseDate(*date, &item.Note); err != nil {
+			item.Skip = err.Error()
+			return item
+		}
+	}
+
+	if item.Note.Title == "" {
+		item.Note.Title = "(no title)"
+	}
+
+	item.Note.ReminderOffset = icalReminder(alarm)
+	return item
+}
+
+// icalParseDate sets the note date from a UTC, TZID, floating or all-day DATE value
+func icalParseDate(p icalProperty, n *model.Note) error {
+	if strings.EqualFold(p.params["VALUE"], "DATE") || len(p.value) == len(icalDateFormat) {
+		d, err := time.ParseInLocation(icalDateFormat, p.value, time.UTC)
+		if err != nil {
+			return fmt.Errorf("invalid %v %q", p.name, p.value)
+		}
+		n.Date = d
+		n.IsFloating = true
+		return nil
+	}
+
+	if strings.HasSuffix(p.value, "Z") {
+		d, err := time.ParseInLocation(icalUtcFormat, p.value, time.UTC)
+		if err != nil {
+			return fmt.Errorf("invalid %v %q", p.name, p.value)
+		}
+		n.Date = d
+		return nil
+	}
+
+	if tzid, ok := p.params["TZID"]; ok {
+		loc, ok := Location(tzid)
+		if !ok {
+			return fmt.Errorf("unknown TZID %q", tzid)
+		}
+
+		d, err := time.ParseInLocation(icalLocalFormat, p.value, loc)
+		if err != nil {
+			return fmt.Errorf("invalid %v %q", p.name, p.value)
+		}
+		n.Date = d.UTC()
+		n.TimeZone = tzid
+		return nil
+	}
+
+	d, err := time.ParseInLocation(icalLocalFormat, p.value, time.UTC)
+	if err != nil {
+		return fmt.Errorf("invalid %v %q", p.name, p.value)
+	}
+	n.Date = d
+	n.IsFloating = true
+	return nil
+}
+
+// icalReminder reads a relative TRIGGER before the start, other alarms are ignored
+func icalReminder(alarm []icalProperty) time.Duration {
+	for _, p := range alarm {
+		if p.name != "TRIGGER" || strings.EqualFold(p.params["VALUE"], "DATE-TIME") ||
+			strings.EqualFold(p.params["RELATED"], "END") {
+			continue
+		}
+
+		d, ok := icalDuration(p.value)
+		if !ok || d > 0 {
+			return 0
+		}
+		if !ValidateReminderOffset(-d) {
+			return 0
+		}
+
+		return -d
+	}
+
+	return 0
+}
+
+var icalDurationRe = regexp.MustCompile(`^([+-])?P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+func icalDuration(v string) (time.Duration, bool) {
+	m := icalDurationRe.FindStringSubmatch(v)
+	if m == nil {
+		return 0, false
+	}
+
+	var d time.Duration
+	for i, unit := range []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second} {
+		if m[i+2] == "" {
+			continue
+		}
+
+		n, err := strconv.Atoi(m[i+2])
+		if err != nil {
+			return 0, false
+		}
+		d += time.Duration(n) * unit
+	}
+
+	if m[1] == "-" {
+		d = -d
+	}
+
+	return d, true
+}
+
+type icalProperty struct {
+	name string
+	params map[string]string
+	value string
+}
+
+// parseProperty splits a content line into name, parameters and value, a colon in a quoted parameter is not a separator
+func parseProperty(l string) (icalProperty, bool) {
+	quoted := false
+	sep := -1
+	for i := 0; i < len(l) && sep < 0; i++ {
+		switch l[i] {
+		case '"':
+			quoted = !quoted
+		case ':':
+			if !quoted {
+				sep = i
+			}
+		}
+	}
+	if sep <= 0 {
+		return icalProperty{}, false
+	}
+
+	p := icalProperty{value: l[sep+1:]}
+	parts := strings.Split(l[:sep], ";")
+	p.name = strings.ToUpper(parts[0])
+	for _, param := range parts[1:] {
+		kv := strings.SplitN(param, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+
+		if p.params == nil {
+			p.params = make(map[string]string)
+		}
+		p.params[strings.ToUpper(kv[0])] = strings.Trim(kv[1], `"`)
+	}
+
+	return p, true
+}
+
+// unfoldLines joins continuation lines which start with a space or a tab
+func unfoldLines(r io.Reader) ([]string, error) {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 0, 64*1024), icalMaxLine)
+
+	var lines []string
+	for s.Scan() {
+		l := strings.TrimSuffix(s.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(l, " ") || strings.HasPrefix(l, "\t")) {
+			lines[len(lines)-1] += l[1:]
+			continue
+		}
+
+		lines = append(lines, l)
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	return lines, nil
+}
+
+var icalUnescaper = strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n")
+
+func icalUnescape(s string) string {
+	return icalUnescaper.Replace(s)
+}
@@ -0,0 +1,120 @@
+package usecase
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+	"todoNote/internal/model"
+)
+
+func TestDecodeCalendar(t *testing.T) {
+	cal := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"PRODID:-//test//EN",
+		"BEGIN:VTIMEZONE",
+		"TZID:America/New_York",
+		"BEGIN:STANDARD",
+		"DTSTART:19701101T020000",
+		"END:STANDARD",
+		"END:VTIMEZONE",
+		"BEGIN:VEVENT",
+		"UID:1@example.com",
+		`DTSTART;TZID="America/New_York":20210702T090000`,
+		`SUMMARY:call\; mom\, la`,
+		" ter",
+		`DESCRIPTION:line\nnext`,
+		"BEGIN:VALARM",
+		"ACTION:DISPLAY",
+		"TRIGGER:-PT1H30M",
+		"END:VALARM",
+		"BEGIN:VALARM",
+		"TRIGGER:-PT5M",
+		"END:VALARM",
+		"END:VEVENT",
+		"BEGIN:VTODO",
+		"UID:2@example.com",
+		"DTSTART:20210701T080000Z",
+		"DUE:20210702T080000Z",
+		"STATUS:COMPLETED",
+		"END:VTODO",
+		"BEGIN:VEVENT",
+		"UID:3@example.com",
+		"DTSTART;VALUE=DATE:20210704",
+		"SUMMARY:holiday",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:4@example.com",
+		"DTSTART:20210705T100000",
+		"SUMMARY:floating",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:5@example.com",
+		"SUMMARY:no start",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	items, err := DecodeCalendar(strings.NewReader(cal))
+	assert.Nil(t, err)
+	assert.Len(t, items, 5)
+
+	assert.Equal(t, "1@example.com", items[0].Uid)
+	assert.Equal(t, "call; mom, later", items[0].Note.Title)
+	assert.Equal(t, "line\nnext", items[0].Note.Text)
+	assert.Equal(t, "America/New_York", items[0].Note.TimeZone)
+	assert.Equal(t, time.Date(2021, 7, 2, 13, 0, 0, 0, time.UTC), items[0].Note.Date)
+	assert.Equal(t, 90 * time.Minute, items[0].Note.ReminderOffset)
+
+	assert.Equal(t, time.Date(2021, 7, 2, 8, 0, 0, 0, time.UTC), items[1].Note.Date)
+	assert.True(t, items[1].Note.IsFinished)
+	assert.Equal(t, "(no title)", items[1].Note.Title)
+
+	assert.True(t, items[2].Note.IsFloating)
+	assert.Equal(t, time.Date(2021, 7, 4, 0, 0, 0, 0, time.UTC), items[2].Note.Date)
+
+	assert.True(t, items[3].Note.IsFloating)
+	assert.Equal(t, "", items[3].Note.TimeZone)
+	assert.Equal(t, time.Date(2021, 7, 5, 10, 0, 0, 0, time.UTC), items[3].Note.Date)
+
+	assert.Equal(t, "event has no DTSTART", items[4].Skip)
+}
+
+func TestDecodeCalendar_Invalid(t *testing.T) {
+	for name, cal := range map[string]string{
+		"no calendar": "BEGIN:VEVENT\r\nEND:VEVENT\r\n",
+		"unclosed": "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nEND:VEVENT\r\n",
+		"mismatched end": "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nEND:VTODO\r\nEND:VCALENDAR\r\n",
+		"no colon": "BEGIN:VCALENDAR\r\nSUMMARY\r\nEND:VCALENDAR\r\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := DecodeCalendar(strings.NewReader(cal))
+			assert.ErrorIs(t, err, ErrInvalidCalendar)
+		})
+	}
+}
+
+func TestDecodeCalendar_RoundTrip(t *testing.T) {
+	notes := []model.Note{
+		{Id: 1, Title: "call; mom", Date: time.Date(2021, 10, 2, 7, 30, 0, 0, time.UTC), ReminderOffset: 15 * time.Minute, IsFinished: true},
+		{Id: 2, Title: strings.Repeat("й", 60), Date: time.Date(2021, 10, 4, 9, 0, 0, 0, time.UTC), IsFloating: true},
+	}
+
+	for _, component := range []IcalComponent{IcalEvent, IcalTodo} {
+		var buf bytes.Buffer
+		assert.Nil(t, EncodeCalendar(&buf, notes, time.Now(), component))
+
+		items, err := DecodeCalendar(&buf)
+		assert.Nil(t, err)
+		assert.Len(t, items, len(notes))
+		for i, item := range items {
+			assert.Equal(t, NoteUid(notes[i]), item.Uid)
+			assert.Equal(t, notes[i].Title, item.Note.Title)
+			assert.Equal(t, notes[i].Date, item.Note.Date)
+			assert.Equal(t, notes[i].IsFloating, item.Note.IsFloating)
+			assert.Equal(t, notes[i].IsFinished, item.Note.IsFinished)
+			assert.Equal(t, notes[i].ReminderOffset, item.Note.ReminderOffset)
+		}
+	}
+}
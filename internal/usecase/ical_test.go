@@ -0,0 +1,59 @@
+package usecase
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+	"todoNote/internal/model"
+)
+
+func TestEncodeCalendar(t *testing.T) {
+	stamp := time.Date(2021, 10, 1, 10, 0, 0, 0, time.UTC)
+	notes := []model.Note{
+		{Id: 1, Title: "call; mom, later", Text: "line\nnext", Date: time.Date(2021, 10, 2, 7, 30, 0, 0, time.UTC), ReminderOffset: 15 * time.Minute},
+		{Id: 2, Title: "stand-up", Date: time.Date(2021, 10, 4, 9, 0, 0, 0, time.UTC), IsFloating: true, IsFinished: true},
+		{Id: 3, Title: strings.Repeat("й", 60), Date: stamp},
+	}
+
+	var buf bytes.Buffer
+	assert.Nil(t, EncodeCalendar(&buf, notes, stamp, IcalEvent))
+	out := buf.String()
+
+	assert.True(t, strings.HasPrefix(out, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n"))
+	assert.True(t, strings.HasSuffix(out, "END:VCALENDAR\r\n"))
+	assert.Contains(t, out, "UID:note-1@todonote\r\n")
+	assert.Contains(t, out, "DTSTAMP:20211001T100000Z\r\n")
+	assert.Contains(t, out, "DTSTART:20211002T073000Z\r\n")
+	assert.Contains(t, out, `SUMMARY:call\; mom\, later`+"\r\n")
+	assert.Contains(t, out, `DESCRIPTION:line\nnext`+"\r\n")
+	assert.Contains(t, out, "TRIGGER:-PT15M\r\n")
+	assert.Contains(t, out, "DTSTART:20211004T090000\r\n")
+	assert.Contains(t, out, "X-TODONOTE-FINISHED:TRUE\r\n")
+
+	for _, l := range strings.Split(out, "\r\n") {
+		assert.LessOrEqual(t, len(l), icalLineLength)
+	}
+
+	unfolded := strings.ReplaceAll(out, "\r\n ", "")
+	assert.Contains(t, unfolded, "SUMMARY:" + strings.Repeat("й", 60) + "\r\n")
+}
+
+func TestEncodeCalendarTodo(t *testing.T) {
+	stamp := time.Date(2021, 10, 1, 10, 0, 0, 0, time.UTC)
+	notes := []model.Note{
+		{Id: 1, Title: "done", Date: stamp, IsFinished: true},
+		{Id: 2, Title: "open", Date: stamp},
+	}
+
+	var buf bytes.Buffer
+	assert.Nil(t, EncodeCalendar(&buf, notes, stamp, IcalTodo))
+	out := buf.String()
+
+	assert.Equal(t, 2, strings.Count(out, "BEGIN:VTODO"))
+	assert.Contains(t, out, "DUE:20211001T100000Z\r\n")
+	assert.Contains(t, out, "STATUS:COMPLETED\r\n")
+	assert.Contains(t, out, "STATUS:NEEDS-ACTION\r\n")
+	assert.NotContains(t, out, "X-TODONOTE-FINISHED")
+}
@@ -0,0 +1,135 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"todoNote/internal/model"
+	"todoNote/internal/repo"
+)
+
+const (
+	userAttemptPrefix = "user:"
+	ipAttemptPrefix   = "ip:"
+	mfaAttemptPrefix  = "mfa:"
+
+	// failures older than the window are forgotten, times of attempts are kept in UTC
+	attemptWindow = time.Hour
+)
+
+type AttemptPolicy struct {
+	FreeAttempts int
+	BaseLock     time.Duration
+	MaxLock      time.Duration
+}
+
+var (
+	userAttemptPolicy = AttemptPolicy{FreeAttempts: 5, BaseLock: time.Second, MaxLock: 15 * time.Minute}
+	ipAttemptPolicy   = AttemptPolicy{FreeAttempts: 20, BaseLock: time.Second, MaxLock: 15 * time.Minute}
+)
+
+func UserAttemptKey(name string) string {
+	return userAttemptPrefix + strings.ToLower(name)
+}
+
+func IpAttemptKey(ip string) string {
+	return ipAttemptPrefix + ip
+}
+
+func MfaAttemptKey(uId model.Id) string {
+	return fmt.Sprintf("%v%v", mfaAttemptPrefix, uId)
+}
+
+type ILoginGuardUsecase interface {
+	Check(ctx context.Context, keys ...string) (time.Duration, error)
+	Failure(ctx context.Context, keys ...string) error
+	Success(ctx context.Context, keys ...string) error
+}
+var _ ILoginGuardUsecase = &LoginGuardUsecase{}
+
+type LoginGuardUsecase struct {
+	attemptsRepo repo.IRepoLoginAttempts
+	now          func() time.Time
+}
+
+func NewLoginGuardUsecase(r repo.IRepoLoginAttempts) *LoginGuardUsecase {
+	return &LoginGuardUsecase{
+		attemptsRepo: r,
+		now:          time.Now,
+	}
+}
+
+// Check returns how long the caller has to wait before the next attempt, zero means it is allowed
+func(u *LoginGuardUsecase) Check(ctx context.Context, keys ...string) (time.Duration, error) {
+	now := u.now().UTC()
+
+	var wait time.Duration
+	for _, k := range keys {
+		a, err := u.attemptsRepo.Get(ctx, k)
+		if err != nil {
+			return 0, fmt.Errorf("check login attempts: %w", err)
+		}
+
+		if d := a.LockedUntil.Sub(now); d > wait {
+			wait = d
+		}
+	}
+
+	return wait, nil
+}
+
+func(u *LoginGuardUsecase) Failure(ctx context.Context, keys ...string) error {
+	now := u.now().UTC()
+
+	for _, k := range keys {
+		failures, err := u.attemptsRepo.AddFailure(ctx, k, now, attemptWindow)
+		if err != nil {
+			return fmt.Errorf("login failure: %w", err)
+		}
+
+		if lock := policyFor(k).lock(failures); lock > 0 {
+			if err := u.attemptsRepo.LockUntil(ctx, k, now.Add(lock)); err != nil {
+				return fmt.Errorf("login failure: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func(u *LoginGuardUsecase) Success(ctx context.Context, keys ...string) error {
+	for _, k := range keys {
+		if err := u.attemptsRepo.Delete(ctx, k); err != nil {
+			return fmt.Errorf("login success: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func policyFor(key string) AttemptPolicy {
+	if strings.HasPrefix(key, ipAttemptPrefix) {
+		return ipAttemptPolicy
+	}
+
+	return userAttemptPolicy
+}
+
+// lock doubles with every failure after the free ones
+func(p AttemptPolicy) lock(failures int) time.Duration {
+	over := failures - p.FreeAttempts
+	if over <= 0 {
+		return 0
+	}
+
+	lock := p.BaseLock
+	for i := 1; i < over; i++ {
+		lock *= 2
+		if lock >= p.MaxLock {
+			return p.MaxLock
+		}
+	}
+
+	return lock
+}
@@ -0,0 +1,97 @@
+package usecase
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+	in_memory "todoNote/internal/repo/in-memory"
+)
+
+func TestAttemptPolicy_Lock(t *testing.T) {
+	p := AttemptPolicy{FreeAttempts: 2, BaseLock: time.Second, MaxLock: 5 * time.Second}
+
+	tts := []struct{
+		failures int
+		out time.Duration
+	}{
+		{1, 0},
+		{2, 0},
+		{3, time.Second},
+		{4, 2 * time.Second},
+		{5, 4 * time.Second},
+		{6, 5 * time.Second},
+		{60, 5 * time.Second},
+	}
+
+	for _, tt := range tts {
+		assert.Equal(t, tt.out, p.lock(tt.failures))
+	}
+}
+
+func TestLoginGuardUsecase(t *testing.T) {
+	now := time.Date(2021, 10, 1, 10, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+	key := UserAttemptKey("User")
+
+	uc := NewLoginGuardUsecase(in_memory.NewRepoLoginAttempts())
+	uc.now = fixedClock(now)
+
+	for i := 0; i < userAttemptPolicy.FreeAttempts; i++ {
+		assert.Nil(t, uc.Failure(ctx, key))
+	}
+
+	wait, err := uc.Check(ctx, key, IpAttemptKey("127.0.0.1"))
+	assert.Nil(t, err)
+	assert.Equal(t, time.Duration(0), wait)
+
+	assert.Nil(t, uc.Failure(ctx, key))
+	assert.Nil(t, uc.Failure(ctx, key))
+	wait, err = uc.Check(ctx, UserAttemptKey("user"))
+	assert.Nil(t, err)
+	assert.Equal(t, 2 * userAttemptPolicy.BaseLock, wait)
+
+	uc.now = fixedClock(now.Add(2 * userAttemptPolicy.BaseLock))
+	wait, _ = uc.Check(ctx, key)
+	assert.Equal(t, time.Duration(0), wait)
+
+	t.Run("failures are forgotten after window", func(t *testing.T) {
+		uc.now = fixedClock(now.Add(attemptWindow + time.Minute))
+		assert.Nil(t, uc.Failure(ctx, key))
+		wait, _ := uc.Check(ctx, key)
+		assert.Equal(t, time.Duration(0), wait)
+	})
+
+	t.Run("success resets counter", func(t *testing.T) {
+		for i := 0; i < userAttemptPolicy.FreeAttempts + 1; i++ {
+			assert.Nil(t, uc.Failure(ctx, key))
+		}
+		assert.Nil(t, uc.Success(ctx, key))
+
+		wait, _ := uc.Check(ctx, key)
+		assert.Equal(t, time.Duration(0), wait)
+	})
+}
+
+func TestLoginGuardUsecase_LocalClock(t *testing.T) {
+	ctx := context.Background()
+	key := UserAttemptKey("user")
+	attempts := in_memory.NewRepoLoginAttempts()
+
+	uc := NewLoginGuardUsecase(attempts)
+	uc.now = fixedClock(time.Date(2021, 10, 1, 10, 0, 0, 0, time.FixedZone("UTC+3", 3 * 60 * 60)))
+
+	for i := 0; i < userAttemptPolicy.FreeAttempts + 1; i++ {
+		assert.Nil(t, uc.Failure(ctx, key))
+	}
+
+	// attempts are kept in UTC whatever the zone of the clock
+	a, err := attempts.Get(ctx, key)
+	assert.Nil(t, err)
+	assert.Equal(t, time.UTC, a.LastFailure.Location())
+	assert.Equal(t, time.UTC, a.LockedUntil.Location())
+
+	wait, err := uc.Check(ctx, key)
+	assert.Nil(t, err)
+	assert.Equal(t, userAttemptPolicy.BaseLock, wait)
+}
@@ -0,0 +1,35 @@
+package usecase
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"regexp"
+)
+
+var (
+	markdown = goldmark.New(goldmark.WithExtensions(extension.GFM))
+	// htmlPolicy allows what users write in Markdown and drops scripts, styles and unsafe links
+	htmlPolicy = newHtmlPolicy()
+)
+
+func newHtmlPolicy() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	// task list items
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").OnElements("input")
+
+	return p
+}
+
+// RenderMarkdown renders note text as sanitized HTML, raw HTML in the text is dropped as well
+func RenderMarkdown(text string) (string, error) {
+	var buf bytes.Buffer
+	if err := markdown.Convert([]byte(text), &buf); err != nil {
+		return "", fmt.Errorf("render markdown: %w", err)
+	}
+
+	return htmlPolicy.Sanitize(buf.String()), nil
+}
@@ -0,0 +1,27 @@
+package usecase
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	tts := []struct{
+		name string
+		in string
+		want string
+	}{
+		{"markdown", "# Plan\n\n- [x] **milk**\n- ~~bread~~", "<h1>Plan</h1>\n<ul>\n<li><input checked=\"\" disabled=\"\" type=\"checkbox\"> <strong>milk</strong></li>\n<li><del>bread</del></li>\n</ul>\n"},
+		{"raw html is dropped", "hi <script>alert(1)</script>", "<p>hi alert(1)</p>\n"},
+		{"unsafe link", "[click](javascript:alert(1))", "<p>click</p>\n"},
+		{"link", "[docs](https://example.com)", "<p><a href=\"https://example.com\" rel=\"nofollow\">docs</a></p>\n"},
+	}
+
+	for _, tt := range tts {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RenderMarkdown(tt.in)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
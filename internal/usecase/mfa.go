@@ -0,0 +1,193 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"todoNote/internal/model"
+	"todoNote/internal/repo"
+)
+
+const (
+	MfaIssuer          = "todo notes service"
+	recoveryCodesCount = 10
+)
+
+type IMfaUsecase interface {
+	Enroll(ctx context.Context, uId model.Id) (model.MfaEnrollment, error)
+	Confirm(ctx context.Context, uId model.Id, code string) ([]string, error)
+	IsEnabled(ctx context.Context, uId model.Id) (bool, error)
+	Verify(ctx context.Context, uId model.Id, code string) error
+	Disable(ctx context.Context, uId model.Id, code string) error
+}
+var _ IMfaUsecase = &MfaUsecase{}
+
+type MfaUsecase struct {
+	mfaRepo  repo.IRepoMfa
+	userRepo repo.IRepoUser
+	now      func() time.Time
+}
+
+func NewMfaUsecase(m repo.IRepoMfa, u repo.IRepoUser) *MfaUsecase {
+	return &MfaUsecase{
+		mfaRepo:  m,
+		userRepo: u,
+		now:      time.Now,
+	}
+}
+
+func(u *MfaUsecase) Enroll(ctx context.Context, uId model.Id) (model.MfaEnrollment, error) {
+	m, err := u.get(ctx, uId)
+	if err != nil && !errors.Is(err, ErrMfaNotEnrolled) {
+		return model.MfaEnrollment{}, fmt.Errorf("enroll mfa: %w", err)
+	}
+	if m != nil && m.IsEnabled {
+		return model.MfaEnrollment{}, ErrMfaAlreadyEnabled
+	}
+
+	usr, err := u.userRepo.GetById(ctx, uId)
+	if err != nil {
+		return model.MfaEnrollment{}, fmt.Errorf("enroll mfa: %w", err)
+	}
+
+	secret, err := GenerateTotpSecret()
+	if err != nil {
+		return model.MfaEnrollment{}, fmt.Errorf("enroll mfa: %w", err)
+	}
+
+	if err := u.mfaRepo.Upsert(ctx, model.NewMfa(uId, secret)); err != nil {
+		return model.MfaEnrollment{}, fmt.Errorf("enroll mfa: %w", err)
+	}
+
+	return model.MfaEnrollment{
+		Secret: secret,
+		Uri:    TotpUri(MfaIssuer, usr.Name, secret),
+	}, nil
+}
+
+func(u *MfaUsecase) Confirm(ctx context.Context, uId model.Id, code string) ([]string, error) {
+	m, err := u.get(ctx, uId)
+	if err != nil {
+		return nil, fmt.Errorf("confirm mfa: %w", err)
+	}
+
+	if m.IsEnabled {
+		return nil, ErrMfaAlreadyEnabled
+	}
+
+	step, ok := TotpStep(m.Secret, code, u.now())
+	if !ok {
+		return nil, ErrMfaInvalidCode
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, fmt.Errorf("confirm mfa: %w", err)
+	}
+
+	m.IsEnabled = true
+	m.RecoveryCodes = hashes
+	m.LastStep = step
+	if err := u.mfaRepo.Upsert(ctx, m); err != nil {
+		return nil, fmt.Errorf("confirm mfa: %w", err)
+	}
+
+	return codes, nil
+}
+
+func(u *MfaUsecase) IsEnabled(ctx context.Context, uId model.Id) (bool, error) {
+	m, err := u.get(ctx, uId)
+	if errors.Is(err, ErrMfaNotEnrolled) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("is mfa enabled: %w", err)
+	}
+
+	return m.IsEnabled, nil
+}
+
+// Verify accepts either a totp code of a later time step than the last accepted one or one of the unused recovery codes,
+// the latter is consumed
+func(u *MfaUsecase) Verify(ctx context.Context, uId model.Id, code string) error {
+	m, err := u.get(ctx, uId)
+	if err != nil {
+		return fmt.Errorf("verify mfa: %w", err)
+	}
+
+	if !m.IsEnabled {
+		return ErrMfaNotEnrolled
+	}
+
+	if step, ok := TotpStep(m.Secret, code, u.now()); ok {
+		// an intercepted code must not be replayed while it is valid
+		used, err := u.mfaRepo.UseStep(ctx, uId, step)
+		if err != nil {
+			return fmt.Errorf("verify mfa: %w", err)
+		}
+		if !used {
+			return ErrMfaInvalidCode
+		}
+
+		return nil
+	}
+
+	// the repository removes the code in one step so that concurrent logins cannot both spend it
+	used, err := u.mfaRepo.UseRecoveryCode(ctx, uId, hashRecoveryCode(code))
+	if err != nil {
+		return fmt.Errorf("verify mfa: %w", err)
+	}
+	if !used {
+		return ErrMfaInvalidCode
+	}
+
+	return nil
+}
+
+func(u *MfaUsecase) Disable(ctx context.Context, uId model.Id, code string) error {
+	if err := u.Verify(ctx, uId, code); err != nil {
+		return err
+	}
+
+	if err := u.mfaRepo.Delete(ctx, uId); err != nil {
+		return fmt.Errorf("disable mfa: %w", err)
+	}
+
+	return nil
+}
+
+func(u *MfaUsecase) get(ctx context.Context, uId model.Id) (*model.Mfa, error) {
+	m, err := u.mfaRepo.GetByUserId(ctx, uId)
+	if errors.Is(err, repo.ErrNotFound) {
+		return nil, ErrMfaNotEnrolled
+	}
+
+	return m, err
+}
+
+func generateRecoveryCodes() ([]string, [][]byte, error) {
+	codes := make([]string, 0, recoveryCodesCount)
+	hashes := make([][]byte, 0, recoveryCodesCount)
+
+	for i := 0; i < recoveryCodesCount; i++ {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, fmt.Errorf("generate recovery codes: %w", err)
+		}
+
+		code := fmt.Sprintf("%x-%x", b[:2], b[2:])
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+
+	return codes, hashes, nil
+}
+
+func hashRecoveryCode(code string) []byte {
+	h := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(code))))
+	return h[:]
+}
@@ -0,0 +1,160 @@
+package usecase
+
+import (
+	"context"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+	"todoNote/internal/model"
+	"todoNote/internal/repo"
+	"todoNote/internal/usecase/mocks"
+)
+
+//go:generate mockgen -package=mocks -destination=mocks/mfa.go todoNote/internal/repo IRepoMfa
+
+func fixedClock(t time.Time) func() time.Time {
+	return func() time.Time { return t }
+}
+
+func TestMfaUsecase_Enroll(t *testing.T) {
+	ctr := gomock.NewController(t)
+	defer ctr.Finish()
+
+	mockMfa := mocks.NewMockIRepoMfa(ctr)
+	mockMfa.EXPECT().GetByUserId(gomock.Any(), model.Id(1)).
+		Return(nil, repo.NewNotFoundError(1))
+	mockMfa.EXPECT().Upsert(gomock.Any(), gomock.Any()).
+		Return(nil).
+		Do(func(_ context.Context, m *model.Mfa) {
+			assert.Equal(t, model.Id(1), m.UserId)
+			assert.False(t, m.IsEnabled)
+			assert.NotEqual(t, "", m.Secret)
+		})
+
+	mockUser := mocks.NewMockIRepoUser(ctr)
+	mockUser.EXPECT().GetById(gomock.Any(), model.Id(1)).
+		Return(&model.User{Id: 1, Name: "user"}, nil)
+
+	uc := NewMfaUsecase(mockMfa, mockUser)
+	e, err := uc.Enroll(context.Background(), 1)
+	assert.Nil(t, err)
+	assert.Contains(t, e.Uri, "secret="+e.Secret)
+}
+
+func TestMfaUsecase_Confirm(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+
+	tts := []struct{
+		name string
+		stored *model.Mfa
+		code string
+		outErr error
+	}{
+		{"success", &model.Mfa{UserId: 1, Secret: rfc6238Secret}, "050471", nil},
+		{"wrong code", &model.Mfa{UserId: 1, Secret: rfc6238Secret}, "000000", ErrMfaInvalidCode},
+		{"already enabled", &model.Mfa{UserId: 1, Secret: rfc6238Secret, IsEnabled: true}, "050471", ErrMfaAlreadyEnabled},
+	}
+
+	for _, tt := range tts {
+		t.Run(tt.name, func(t *testing.T) {
+			ctr := gomock.NewController(t)
+			defer ctr.Finish()
+
+			mockMfa := mocks.NewMockIRepoMfa(ctr)
+			mockMfa.EXPECT().GetByUserId(gomock.Any(), model.Id(1)).Return(tt.stored, nil)
+			if tt.outErr == nil {
+				mockMfa.EXPECT().Upsert(gomock.Any(), gomock.Any()).
+					Return(nil).
+					Do(func(_ context.Context, m *model.Mfa) {
+						assert.True(t, m.IsEnabled)
+						assert.Equal(t, recoveryCodesCount, len(m.RecoveryCodes))
+						assert.Equal(t, now.Unix() / totpPeriod, m.LastStep)
+					})
+			}
+
+			uc := NewMfaUsecase(mockMfa, nil)
+			uc.now = fixedClock(now)
+
+			codes, err := uc.Confirm(context.Background(), 1, tt.code)
+			assert.Equal(t, tt.outErr, err)
+			if tt.outErr == nil {
+				assert.Equal(t, recoveryCodesCount, len(codes))
+			}
+		})
+	}
+}
+
+func TestMfaUsecase_Verify(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+
+	t.Run("totp code", func(t *testing.T) {
+		ctr := gomock.NewController(t)
+		defer ctr.Finish()
+
+		mockMfa := mocks.NewMockIRepoMfa(ctr)
+		mockMfa.EXPECT().GetByUserId(gomock.Any(), model.Id(1)).
+			Return(&model.Mfa{UserId: 1, Secret: rfc6238Secret, IsEnabled: true}, nil)
+		mockMfa.EXPECT().UseStep(gomock.Any(), model.Id(1), now.Unix() / totpPeriod).Return(true, nil)
+
+		uc := NewMfaUsecase(mockMfa, nil)
+		uc.now = fixedClock(now)
+		assert.Nil(t, uc.Verify(context.Background(), 1, "050471"))
+	})
+
+	t.Run("replayed totp code", func(t *testing.T) {
+		ctr := gomock.NewController(t)
+		defer ctr.Finish()
+
+		mockMfa := mocks.NewMockIRepoMfa(ctr)
+		mockMfa.EXPECT().GetByUserId(gomock.Any(), model.Id(1)).
+			Return(&model.Mfa{UserId: 1, Secret: rfc6238Secret, IsEnabled: true}, nil)
+		mockMfa.EXPECT().UseStep(gomock.Any(), model.Id(1), now.Unix() / totpPeriod).Return(false, nil)
+
+		uc := NewMfaUsecase(mockMfa, nil)
+		uc.now = fixedClock(now)
+		assert.Equal(t, ErrMfaInvalidCode, uc.Verify(context.Background(), 1, "050471"))
+	})
+
+	t.Run("recovery code is consumed", func(t *testing.T) {
+		ctr := gomock.NewController(t)
+		defer ctr.Finish()
+
+		codes := [][]byte{hashRecoveryCode("aaaa-bbbbbb"), hashRecoveryCode("cccc-dddddd")}
+		mockMfa := mocks.NewMockIRepoMfa(ctr)
+		mockMfa.EXPECT().GetByUserId(gomock.Any(), model.Id(1)).
+			Return(&model.Mfa{UserId: 1, Secret: rfc6238Secret, IsEnabled: true, RecoveryCodes: codes}, nil)
+		mockMfa.EXPECT().UseRecoveryCode(gomock.Any(), model.Id(1), hashRecoveryCode("cccc-dddddd")).Return(true, nil)
+
+		uc := NewMfaUsecase(mockMfa, nil)
+		uc.now = fixedClock(now)
+		assert.Nil(t, uc.Verify(context.Background(), 1, " CCCC-DDDDDD"))
+	})
+
+	t.Run("used recovery code", func(t *testing.T) {
+		ctr := gomock.NewController(t)
+		defer ctr.Finish()
+
+		mockMfa := mocks.NewMockIRepoMfa(ctr)
+		mockMfa.EXPECT().GetByUserId(gomock.Any(), model.Id(1)).
+			Return(&model.Mfa{UserId: 1, Secret: rfc6238Secret, IsEnabled: true}, nil)
+		mockMfa.EXPECT().UseRecoveryCode(gomock.Any(), model.Id(1), hashRecoveryCode("cccc-dddddd")).Return(false, nil)
+
+		uc := NewMfaUsecase(mockMfa, nil)
+		uc.now = fixedClock(now)
+		assert.Equal(t, ErrMfaInvalidCode, uc.Verify(context.Background(), 1, "cccc-dddddd"))
+	})
+
+	t.Run("not enabled", func(t *testing.T) {
+		ctr := gomock.NewController(t)
+		defer ctr.Finish()
+
+		mockMfa := mocks.NewMockIRepoMfa(ctr)
+		mockMfa.EXPECT().GetByUserId(gomock.Any(), model.Id(1)).
+			Return(&model.Mfa{UserId: 1, Secret: rfc6238Secret}, nil)
+
+		uc := NewMfaUsecase(mockMfa, nil)
+		uc.now = fixedClock(now)
+		assert.Equal(t, ErrMfaNotEnrolled, uc.Verify(context.Background(), 1, "050471"))
+	})
+}
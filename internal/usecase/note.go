@@ -2,29 +2,39 @@ package usecase
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 	"todoNote/internal/model"
 	"todoNote/internal/repo"
-	in_memory "todoNote/internal/repo/in-memory"
 )
 
 type INoteUsecase interface {
 	CreateNote(ctx context.Context, n *model.Note) (model.Id, error)
 	FindNote(ctx context.Context, noteId model.Id, userId model.Id, zone model.TimeZone) (*model.Note, error)
 	FindAll(ctx context.Context, p FindParams) ([]model.Note, error)
-	UpdateNote(ctx context.Context, n *model.Note) error
+	// Each streams the notes FindAll would return, fn errors stop it and are returned as is
+	Each(ctx context.Context, p FindParams, fn func(n model.Note) error) error
+	// UpdateNote changes the fields of n which are set and the zone of the note which is set in zone
+	UpdateNote(ctx context.Context, n *model.Note, zone NoteZone) error
 	RemoveNote(ctx context.Context, noteId, userId model.Id) error
 }
 var _ INoteUsecase = &NoteUsecase{}
 
+// NoteUsecase writes the events of its changes to the outbox in their transactions
 type NoteUsecase struct {
 	noteRepo repo.IRepoNote
+	outbox repo.IRepoOutbox
+	tx repo.ITransactor
+	now func() time.Time
 }
 
-func NewNoteUsecase(r repo.IRepoNote) *NoteUsecase {
+func NewNoteUsecase(r repo.IRepoNote, outbox repo.IRepoOutbox, tx repo.ITransactor) *NoteUsecase {
 	return &NoteUsecase{
 		noteRepo: r,
+		outbox: outbox,
+		tx: tx,
+		now: time.Now,
 	}
 }
 
@@ -32,18 +42,27 @@ func NewNoteUsecase(r repo.IRepoNote) *NoteUsecase {
 func(u *NoteUsecase) CreateNote(ctx context.Context, n *model.Note) (model.Id, error) {
 	note := u.prepareNoteDate(n)
 
-	id, err := u.noteRepo.Insert(ctx, note)
+	var id model.Id
+	err := u.tx.InTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		id, err = u.noteRepo.Insert(ctx, note)
+		if err != nil {
+			return err
+		}
+		n.Id = id
+
+		return emit(ctx, u.outbox, u.now(), model.EventNoteCreated, n.UserId, id, notePayload(*n))
+	})
 	if err != nil {
 		return 0, fmt.Errorf("create note: %w", err)
 	}
-	n.Id = id
 
 	return id, nil
 }
 
 func(u *NoteUsecase) FindNote(ctx context.Context, noteId model.Id, userId model.Id, zone model.TimeZone) (*model.Note, error) {
 	n, err := u.noteRepo.GetById(ctx, noteId)
-	if _, ok := err.(in_memory.NoSuchElementError); ok {
+	if errors.Is(err, repo.ErrNotFound) {
 		return nil, NewNoteNotFoundError(noteId, userId)
 	}
 	if err != nil {
@@ -54,7 +73,7 @@ func(u *NoteUsecase) FindNote(ctx context.Context, noteId model.Id, userId model
 		return nil, NewNoteNotFoundError(noteId, userId)
 	}
 
-	n.Date = Convert(n.Date, zone)
+	n.Date = localize(n, zone)
 
 	return &n, err
 }
@@ -65,12 +84,7 @@ type FindParams struct {
 }
 
 func(u *NoteUsecase) FindAll(ctx context.Context, p FindParams) ([]model.Note, error) {
-	if p.Filter.TakeFrom != nil {
-		t := Convert(*p.Filter.TakeFrom, model.UTC)
-		p.Filter.TakeFrom = &t
-	}
-
-	notes, err := u.noteRepo.GetAllOffset(ctx, p.Filter)
+	notes, err := u.noteRepo.GetAllOffset(ctx, utcFilter(p.Filter, p.Zone))
 	if err != nil {
 		return notes, fmt.Errorf("find all: %w", err)
 	}
@@ -78,57 +92,138 @@ func(u *NoteUsecase) FindAll(ctx context.Context, p FindParams) ([]model.Note, e
 	return u.mapZone(notes, p.Zone), nil
 }
 
-func(u *NoteUsecase) UpdateNote(ctx context.Context, n *model.Note) error {
-	note, err := u.noteRepo.GetById(ctx, n.Id)
-	if _, ok := err.(in_memory.NoSuchElementError); ok {
-		return NewNoteNotFoundError(n.Id, n.UserId)
-	}
+func(u *NoteUsecase) Each(ctx context.Context, p FindParams, fn func(n model.Note) error) error {
+	return u.noteRepo.Each(ctx, utcFilter(p.Filter, p.Zone), func(n model.Note) error {
+		n.Date = localize(n, p.Zone)
+		return fn(n)
+	})
+}
 
-	if err != nil {
-		return fmt.Errorf("update note: %w", err)
+// utcFilter bounds instants in UTC and the wall clock of floating notes by the wall clock of the bounds in the viewer's zone,
+// like floating notes are stored
+func utcFilter(f repo.NoteFilter, zone model.TimeZone) repo.NoteFilter {
+	if f.TakeFrom != nil {
+		t := Convert(*f.TakeFrom, model.UTC)
+		wall := inLocation(Convert(*f.TakeFrom, zone), time.UTC)
+		f.TakeFrom, f.FloatingFrom = &t, &wall
 	}
-
-	if n.UserId != note.UserId {
-		return NewNoteNotFoundError(n.Id, n.UserId)
+	if f.TakeTo != nil {
+		t := Convert(*f.TakeTo, model.UTC)
+		wall := inLocation(Convert(*f.TakeTo, zone), time.UTC)
+		f.TakeTo, f.FloatingTo = &t, &wall
 	}
 
-	updated := u.provideNoteUpdate(&note, n)
-	updated = u.prepareNoteDate(updated)
+	return f
+}
+
+// NoteZone changes the zone of a note on update, nil fields keep those of the note
+type NoteZone struct {
+	TimeZone *model.TimeZone
+	IsFloating *bool
+}
 
-	if err := u.noteRepo.Update(ctx, updated); err != nil {
-		return fmt.Errorf("update note: %w", err)
+// UpdateNote locks the note it reads, so concurrent updates apply one after another.
+// A note which turns floating or fixed without a new date keeps its wall clock in its zone
+func(u *NoteUsecase) UpdateNote(ctx context.Context, n *model.Note, zone NoteZone) error {
+	if zone.TimeZone != nil {
+		z, ok := ValidateZone(*zone.TimeZone)
+		if !ok {
+			return fmt.Errorf("update note: %w", ErrInvalidTimeZone)
+		}
+		zone.TimeZone = &z
 	}
 
-	return nil
+	return u.tx.InTransaction(ctx, func(ctx context.Context) error {
+		note, err := u.noteRepo.GetByIdForUpdate(ctx, n.Id)
+		if errors.Is(err, repo.ErrNotFound) {
+			return NewNoteNotFoundError(n.Id, n.UserId)
+		}
+
+		if err != nil {
+			return fmt.Errorf("update note: %w", err)
+		}
+
+		if n.UserId != note.UserId {
+			return NewNoteNotFoundError(n.Id, n.UserId)
+		}
+
+		n.TimeZone, n.IsFloating = note.TimeZone, note.IsFloating
+		if zone.TimeZone != nil {
+			n.TimeZone = *zone.TimeZone
+		}
+		if zone.IsFloating != nil {
+			n.IsFloating = *zone.IsFloating
+		}
+
+		if n.Date.IsZero() && n.IsFloating != note.IsFloating {
+			n.Date = localize(note, n.TimeZone)
+		}
+		if !n.Date.IsZero() {
+			n = u.prepareNoteDate(n)
+		}
+
+		wasFinished := note.IsFinished
+		updated := u.provideNoteUpdate(&note, n)
+		if updated.IsFinished && !wasFinished {
+			finishedAt := u.now().UTC()
+			updated.FinishedAt = &finishedAt
+		}
+
+		if err := u.noteRepo.Update(ctx, updated); err != nil {
+			return fmt.Errorf("update note: %w", err)
+		}
+
+		if err := emitNoteUpdated(ctx, u.outbox, u.now(), wasFinished, *updated); err != nil {
+			return fmt.Errorf("update note: %w", err)
+		}
+
+		return nil
+	})
 }
 
 func(u *NoteUsecase) RemoveNote(ctx context.Context, noteId, userId model.Id) error {
-	n, err := u.noteRepo.GetById(ctx, noteId)
-	if err != nil {
-		if _, ok := err.(in_memory.NoSuchElementError); ok {
-			return NewNoteNotFoundError(noteId, userId)
+	return u.tx.InTransaction(ctx, func(ctx context.Context) error {
+		n, err := u.noteRepo.GetByIdForUpdate(ctx, noteId)
+		if err != nil {
+			if errors.Is(err, repo.ErrNotFound) {
+				return NewNoteNotFoundError(noteId, userId)
+			}
+
+			return fmt.Errorf("remove note: %w", err)
 		}
 
-		return fmt.Errorf("remove note: %w", err)
-	}
+		if n.UserId != userId {
+			return NewNoteNotFoundError(noteId, userId)
+		}
 
-	if n.UserId != userId {
-		return NewNoteNotFoundError(noteId, userId)
-	}
+		if err := u.noteRepo.Delete(ctx, noteId); err != nil {
+			return fmt.Errorf("remove note: %w", err)
+		}
 
-	if err := u.noteRepo.Delete(ctx, noteId); err != nil {
-		return fmt.Errorf("remove note: %w", err)
-	}
+		if err := emit(ctx, u.outbox, u.now(), model.EventNoteDeleted, userId, noteId, notePayload(n)); err != nil {
+			return fmt.Errorf("remove note: %w", err)
+		}
 
-	return nil
+		return nil
+	})
 }
 
 func(u *NoteUsecase) prepareNoteDate(n *model.Note) *model.Note {
+	n.TimeZone = NormalizeZone(n.TimeZone)
 	if n.Date.IsZero() {
 		n.Date = time.Now()
 	}
 
-	n.Date = Convert(n.Date, model.UTC)
+	if !n.IsFloating {
+		n.Date = Convert(n.Date, model.UTC)
+		return n
+	}
+
+	wall := n.Date
+	if n.TimeZone != "" {
+		wall = Convert(n.Date, n.TimeZone)
+	}
+	n.Date = inLocation(wall, time.UTC)
 	return n
 }
 
@@ -149,14 +244,35 @@ func(u *NoteUsecase) provideNoteUpdate(old, new *model.Note) *model.Note {
 		old.Date = new.Date
 	}
 
+	old.TimeZone = new.TimeZone
+	old.IsFloating = new.IsFloating
+
 	return old
 }
 
 func(u *NoteUsecase) mapZone(notes []model.Note, zone model.TimeZone) []model.Note {
 	for i, n := range notes {
-		n.Date = Convert(n.Date, zone)
+		n.Date = localize(n, zone)
 		notes[i] = n
 	}
 
 	return notes
 }
+
+// localize shows instants in the viewer's zone and places floating wall-clock time into it
+func localize(n model.Note, zone model.TimeZone) time.Time {
+	if !n.IsFloating {
+		return Convert(n.Date, zone)
+	}
+
+	loc, ok := Location(zone)
+	if !ok {
+		loc = time.UTC
+	}
+	return inLocation(n.Date, loc)
+}
+
+// inLocation keeps the wall clock of t and changes its zone
+func inLocation(t time.Time, loc *time.Location) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc)
+}
@@ -2,6 +2,8 @@ package usecase
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
@@ -44,7 +46,7 @@ func TestNoteUsecase_CreateNote(t *testing.T) {
 					assert.Equal(t, tt.want, *n)
 				})
 
-			uc := NewNoteUsecase(mockRepo)
+			uc := NewNoteUsecase(mockRepo, in_memory.NewRepoOutbox(), in_memory.NewTransactor())
 
 			id, err := uc.CreateNote(context.Background(), &tt.in)
 			assert.Equal(t, tt.want.Id, id)
@@ -84,7 +86,7 @@ func TestNoteUsecase_FindNote(t *testing.T) {
 				Date: time.Now(),
 			}, nil},
 		{3, 3, model.UTCp4, NewNoteNotFoundError(3, 3).Error(), "no such note",
-			model.Note{},  in_memory.NewNoSuchElementError(3)},
+			model.Note{},  repo.NewNotFoundError(3)},
 	}
 
 
@@ -98,7 +100,7 @@ func TestNoteUsecase_FindNote(t *testing.T) {
 			mockNoteRepo.EXPECT().GetById(context.Background(), tt.noteId).
 				Return(tt.out, tt.outError)
 
-			uc := NewNoteUsecase(mockNoteRepo)
+			uc := NewNoteUsecase(mockNoteRepo, in_memory.NewRepoOutbox(), in_memory.NewTransactor())
 
 			got, err := uc.FindNote(context.Background(), tt.noteId, tt.userId, tt.zone)
 			if err != nil {
@@ -168,7 +170,7 @@ func TestNoteUsecase_FindAll(t *testing.T) {
 			mockRepo.EXPECT().GetAllOffset(context.Background(), tt.filter.Filter).
 				Return(tt.repoOut, tt.repoErr)
 
-			uc := NewNoteUsecase(mockRepo)
+			uc := NewNoteUsecase(mockRepo, in_memory.NewRepoOutbox(), in_memory.NewTransactor())
 
 			got, err := uc.FindAll(context.Background(), tt.filter)
 			assert.Equal(t, tt.out, got)
@@ -189,14 +191,48 @@ func TestNoteUsecase_UpdateNote(t *testing.T) {
 	newDate := time.Now().Add(2*time.Hour)
 	wantDate := newDate.UTC()
 
+	kyiv := "Europe/Kyiv"
+	floating, fixed := true, false
+	instant := time.Date(2021, 10, 2, 7, 0, 0, 0, time.UTC)
+	wall := time.Date(2021, 10, 2, 10, 0, 0, 0, time.UTC)
+
 	tts := []struct {
 		in model.Note
+		zone NoteZone
 		getByIdNote model.Note
 		getByIdErr error
 		updateErr error
 		want model.Note
 		desc string
 	} {
+		{
+			in: model.Note{Id: 1, UserId: 1},
+			zone: NoteZone{IsFloating: &floating},
+			getByIdNote: model.Note{Id: 1, UserId: 1, Title: oldTitle, Date: instant, TimeZone: kyiv},
+			want: model.Note{Id: 1, UserId: 1, Title: oldTitle, Date: wall, TimeZone: kyiv, IsFloating: true},
+			desc: "fixed note turns floating at its wall clock",
+		},
+		{
+			in: model.Note{Id: 1, UserId: 1},
+			zone: NoteZone{IsFloating: &fixed},
+			getByIdNote: model.Note{Id: 1, UserId: 1, Title: oldTitle, Date: wall, TimeZone: kyiv, IsFloating: true},
+			want: model.Note{Id: 1, UserId: 1, Title: oldTitle, Date: instant, TimeZone: kyiv},
+			desc: "floating note turns fixed at its wall clock",
+		},
+		{
+			in: model.Note{Id: 1, UserId: 1},
+			zone: NoteZone{TimeZone: &kyiv},
+			getByIdNote: model.Note{Id: 1, UserId: 1, Title: oldTitle, Date: instant, TimeZone: model.UTC},
+			want: model.Note{Id: 1, UserId: 1, Title: oldTitle, Date: instant, TimeZone: kyiv},
+			desc: "new time zone keeps the instant",
+		},
+		{
+			in: model.Note{Id: 1, UserId: 1, Date: instant},
+			zone: NoteZone{TimeZone: &kyiv},
+			getByIdNote: model.Note{Id: 1, UserId: 1, Title: oldTitle, Date: date, TimeZone: model.UTC, IsFloating: true},
+			want: model.Note{Id: 1, UserId: 1, Title: oldTitle, Date: wall, TimeZone: kyiv, IsFloating: true},
+			desc: "new date of floating note in new time zone",
+		},
 		{
 			in: model.Note{Id: 1, UserId: 1, Title: newTitle},
 			getByIdNote: model.Note{Id: 1, UserId: 1, Title: oldTitle, Text: oldText, IsFinished: true, Date: date},
@@ -218,7 +254,7 @@ func TestNoteUsecase_UpdateNote(t *testing.T) {
 			getByIdNote: model.Note{Id: 1, UserId: 1, Title: oldTitle, Text: oldText, IsFinished: false, Date: date},
 			getByIdErr: nil,
 			updateErr: nil,
-			want: model.Note{Id: 1, UserId: 1, Title: oldTitle, Text: oldText, IsFinished: true, Date: date},
+			want: model.Note{Id: 1, UserId: 1, Title: oldTitle, Text: oldText, IsFinished: true, Date: date, FinishedAt: &date},
 			desc: "new isFinished",
 		},
 		{
@@ -234,7 +270,7 @@ func TestNoteUsecase_UpdateNote(t *testing.T) {
 			getByIdNote: model.Note{Id: 1, UserId: 1, Title: oldTitle, Text: oldText, IsFinished: false, Date: date},
 			getByIdErr: nil,
 			updateErr: nil,
-			want: model.Note{Id: 1, UserId: 1,Title: newTitle, Text: newText, IsFinished: true, Date: wantDate},
+			want: model.Note{Id: 1, UserId: 1,Title: newTitle, Text: newText, IsFinished: true, Date: wantDate, FinishedAt: &date},
 			desc: "new all",
 		},
 		{
@@ -256,7 +292,7 @@ func TestNoteUsecase_UpdateNote(t *testing.T) {
 		{
 			in: model.Note{Id: 1, UserId: 1},
 			getByIdNote: model.Note{},
-			getByIdErr: in_memory.NewNoSuchElementError(1),
+			getByIdErr: repo.NewNotFoundError(1),
 			updateErr: NewNoteNotFoundError(1, 1),
 			//want: model.Note{Id: 1, UserId: 1, Title: oldTitle, Text: oldText, IsFinished: true, Date: date},
 			desc: "wrong note id",
@@ -269,7 +305,7 @@ func TestNoteUsecase_UpdateNote(t *testing.T) {
 			defer ctr.Finish()
 			mockRepo := mocks.NewMockIRepoNote(ctr)
 
-			mockRepo.EXPECT().GetById(gomock.Any(), gomock.Any()).
+			mockRepo.EXPECT().GetByIdForUpdate(gomock.Any(), gomock.Any()).
 				Return(tt.getByIdNote, tt.getByIdErr)
 
 			if tt.getByIdErr == nil && tt.updateErr == nil {
@@ -280,14 +316,22 @@ func TestNoteUsecase_UpdateNote(t *testing.T) {
 					})
 			}
 
-			uc := NewNoteUsecase(mockRepo)
+			uc := NewNoteUsecase(mockRepo, in_memory.NewRepoOutbox(), in_memory.NewTransactor())
+			uc.now = fixedClock(date)
 
-			err := uc.UpdateNote(context.Background(), &tt.in)
+			err := uc.UpdateNote(context.Background(), &tt.in, tt.zone)
 			if err != nil {
 				assert.Equal(t, tt.updateErr.Error(), err.Error())
 			}
 		})
 	}
+
+	t.Run("unknown time zone", func(t *testing.T) {
+		zone := "Mars/Olympus"
+		uc := NewNoteUsecase(mocks.NewMockIRepoNote(gomock.NewController(t)), in_memory.NewRepoOutbox(), in_memory.NewTransactor())
+		err := uc.UpdateNote(context.Background(), &model.Note{Id: 1, UserId: 1}, NoteZone{TimeZone: &zone})
+		assert.True(t, errors.Is(err, ErrInvalidTimeZone))
+	})
 }
 
 func TestNoteUsecase_RemoveNote(t *testing.T) {
@@ -345,10 +389,10 @@ func TestNoteUsecase_RemoveNote(t *testing.T) {
 						assert.Equal(t, tt.wantNoteId, noteId)
 					})
 			}
-			mockRepo.EXPECT().GetById(gomock.Any(), gomock.Any()).
+			mockRepo.EXPECT().GetByIdForUpdate(gomock.Any(), gomock.Any()).
 				Return(tt.storedNote, nil)
 
-			u := NewNoteUsecase(mockRepo)
+			u := NewNoteUsecase(mockRepo, in_memory.NewRepoOutbox(), in_memory.NewTransactor())
 
 			err := u.RemoveNote(context.Background(), tt.inNoteId, tt.inUserId)
 			assert.Equal(t, tt.wantErr, err)
@@ -356,6 +400,108 @@ func TestNoteUsecase_RemoveNote(t *testing.T) {
 	}
 }
 
+func TestNoteUsecase_Rollback(t *testing.T) {
+	ctx := context.Background()
+	notes := in_memory.NewRepoNote()
+	tx := in_memory.NewTransactor()
+	uc := NewNoteUsecase(notes, in_memory.NewRepoOutbox(), tx)
+
+	broken := fmt.Errorf("broken")
+	err := tx.InTransaction(ctx, func(ctx context.Context) error {
+		if err := uc.UpdateNote(ctx, &model.Note{Id: 1, UserId: 1, Title: "changed"}, NoteZone{}); err != nil {
+			return err
+		}
+		if err := uc.RemoveNote(ctx, 2, 1); err != nil {
+			return err
+		}
+		if _, err := uc.CreateNote(ctx, model.NewNote(0, 1, "created", "", time.Now(), false)); err != nil {
+			return err
+		}
+
+		return broken
+	})
+	assert.Equal(t, broken, err)
+
+	all, err := uc.FindAll(ctx, FindParams{Filter: repo.NoteFilter{UserId: 1}, Zone: model.UTC})
+	assert.Nil(t, err)
+	assert.Len(t, all, 3)
+	for _, n := range all {
+		assert.Equal(t, "title", n.Title)
+	}
+}
+
+func TestNoteUsecase_Events(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2021, 10, 2, 10, 0, 0, 0, time.UTC)
+	outbox := in_memory.NewRepoOutbox()
+	tx := in_memory.NewTransactor()
+	uc := NewNoteUsecase(in_memory.NewRepoNote(), outbox, tx)
+	uc.now = func() time.Time { return now }
+
+	id, err := uc.CreateNote(ctx, model.NewNote(0, 1, "created", "", now, false))
+	assert.Nil(t, err)
+	assert.Nil(t, uc.UpdateNote(ctx, &model.Note{Id: id, UserId: 1, IsFinished: true}, NoteZone{}))
+	assert.Nil(t, uc.RemoveNote(ctx, id, 1))
+
+	// changes which are rolled back leave no events
+	broken := fmt.Errorf("broken")
+	err = tx.InTransaction(ctx, func(ctx context.Context) error {
+		if _, err := uc.CreateNote(ctx, model.NewNote(0, 1, "rolled back", "", now, false)); err != nil {
+			return err
+		}
+		return broken
+	})
+	assert.Equal(t, broken, err)
+
+	events, err := outbox.Claim(ctx, now, time.Minute, 10)
+	assert.Nil(t, err)
+	types := make([]model.EventType, 0, len(events))
+	for _, e := range events {
+		assert.Equal(t, id, e.SubjectId)
+		assert.Equal(t, model.Id(1), e.UserId)
+		types = append(types, e.Type)
+	}
+	assert.Equal(t, []model.EventType{model.EventNoteCreated, model.EventNoteUpdated, model.EventNoteCompleted, model.EventNoteDeleted}, types)
+
+	if assert.Len(t, events, 4) {
+		var payload NotePayload
+		assert.Nil(t, json.Unmarshal(events[2].Payload, &payload))
+		assert.Equal(t, "created", payload.Title)
+		assert.True(t, payload.IsFinished)
+		assert.Equal(t, &now, payload.FinishedAt)
+	}
+}
+
+func TestNoteUsecase_FindAllFloating(t *testing.T) {
+	ctx := context.Background()
+	uc := NewNoteUsecase(in_memory.NewRepoNote(), in_memory.NewRepoOutbox(), in_memory.NewTransactor())
+
+	// at 9:00 wherever the viewer is, 6:00 UTC in Kyiv
+	floating := model.NewNote(0, 2, "floating", "", time.Date(2021, 10, 2, 9, 0, 0, 0, time.UTC), false)
+	floating.IsFloating = true
+	_, err := uc.CreateNote(ctx, floating)
+	assert.Nil(t, err)
+	fixed := model.NewNote(0, 2, "fixed", "", time.Date(2021, 10, 2, 7, 0, 0, 0, time.UTC), false)
+	_, err = uc.CreateNote(ctx, fixed)
+	assert.Nil(t, err)
+
+	titles := func(from, to time.Time, zone model.TimeZone) []string {
+		notes, err := uc.FindAll(ctx, FindParams{Filter: repo.NoteFilter{UserId: 2, TakeFrom: &from, TakeTo: &to}, Zone: zone})
+		assert.Nil(t, err)
+		res := make([]string, 0, len(notes))
+		for _, n := range notes {
+			res = append(res, n.Title)
+		}
+		return res
+	}
+
+	kyiv, _ := Location("Europe/Kyiv")
+	// 8:30 to 9:30 in Kyiv is 5:30 to 6:30 UTC, the floating note is there by its wall clock
+	assert.Equal(t, []string{"floating"}, titles(time.Date(2021, 10, 2, 8, 30, 0, 0, kyiv), time.Date(2021, 10, 2, 9, 30, 0, 0, kyiv), "Europe/Kyiv"))
+	// 9:30 to 10:30 in Kyiv holds the instant of the fixed note and 9:00 UTC, which is not the floating one there
+	assert.Equal(t, []string{"fixed"}, titles(time.Date(2021, 10, 2, 9, 30, 0, 0, kyiv), time.Date(2021, 10, 2, 10, 30, 0, 0, kyiv), "Europe/Kyiv"))
+}
+
 func TestNoteUsecase_PrepareNoteDate(t *testing.T) {
 	uc := NoteUsecase{}
 
@@ -375,6 +521,36 @@ func TestNoteUsecase_PrepareNoteDate(t *testing.T) {
 	}
 }
 
+func TestNoteUsecase_FloatingNote(t *testing.T) {
+	uc := NoteUsecase{}
+	kyiv, _ := Location("Europe/Kyiv")
+
+	n := model.Note{Date: time.Date(2021, 1, 10, 7, 0, 0, 0, time.UTC), TimeZone: "Europe/Kyiv", IsFloating: true}
+	got := uc.prepareNoteDate(&n)
+	assert.Equal(t, time.Date(2021, 1, 10, 9, 0, 0, 0, time.UTC), got.Date)
+
+	tts := []struct{
+		zone model.TimeZone
+		out time.Time
+	}{
+		{"Europe/Kyiv", time.Date(2021, 1, 10, 9, 0, 0, 0, kyiv)},
+		{model.UTC, time.Date(2021, 1, 10, 9, 0, 0, 0, time.UTC)},
+		{"America/New_York", time.Date(2021, 1, 10, 14, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tts {
+		local := localize(*got, tt.zone)
+		assert.Equal(t, 9, local.Hour(), tt.zone)
+		assert.True(t, tt.out.Equal(local), tt.zone)
+	}
+
+	t.Run("instant is shown in viewer zone", func(t *testing.T) {
+		n := model.Note{Date: time.Date(2021, 1, 10, 7, 0, 0, 0, time.UTC), TimeZone: "Europe/Kyiv"}
+		got := uc.prepareNoteDate(&n)
+		assert.Equal(t, 2, localize(*got, "America/New_York").Hour())
+	})
+}
+
 func TestNoteUsecase_ProvideNoteUpdate(t *testing.T) {
 	uc := NoteUsecase{}
 	dt := time.Now()
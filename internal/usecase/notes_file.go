@@ -0,0 +1,376 @@
+package usecase
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"todoNote/internal/model"
+)
+
+type NotesFormat = string
+const (
+	NotesCsv NotesFormat = "csv"
+	NotesJson NotesFormat = "json"
+	NotesNdjson NotesFormat = "ndjson"
+	NotesTodoTxt NotesFormat = "todotxt"
+)
+
+var ErrInvalidNotesFile = fmt.Errorf("invalid notes file")
+
+// NoteRecord is a note in csv, json and ndjson files, Id is written on export and ignored on import
+type NoteRecord struct {
+	Id model.Id `json:"id,omitempty"`
+	Title string `json:"title"`
+	Text string `json:"text,omitempty"`
+	Date time.Time `json:"date"`
+	IsFinished bool `json:"is_finished"`
+	TimeZone string `json:"time_zone,omitempty"`
+	IsFloating bool `json:"is_floating,omitempty"`
+	ReminderOffsetMinutes *int `json:"reminder_offset_minutes,omitempty"`
+	Priority string `json:"priority,omitempty"`
+	Projects []string `json:"projects,omitempty"`
+	Contexts []string `json:"contexts,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+var csvHeader = []string{"id", "title", "text", "date", "is_finished", "time_zone", "is_floating", "reminder_offset_minutes",
+	"priority", "projects", "contexts", "finished_at"}
+
+func NewNoteRecord(n model.Note) NoteRecord {
+	minutes := int(n.ReminderOffset / time.Minute)
+	return NoteRecord{
+		Id: n.Id,
+		Title: n.Title,
+		Text: n.Text,
+		Date: n.Date,
+		IsFinished: n.IsFinished,
+		TimeZone: n.TimeZone,
+		IsFloating: n.IsFloating,
+		ReminderOffsetMinutes: &minutes,
+		Priority: n.Priority,
+		Projects: n.Projects,
+		Contexts: n.Contexts,
+		FinishedAt: n.FinishedAt,
+	}
+}
+
+// NoteEncoder writes notes one by one, Close finishes the file
+type NoteEncoder interface {
+	Encode(n model.Note) error
+	Close() error
+}
+
+func ValidNotesFormat(f string) bool {
+	return f == NotesCsv || f == NotesJson || f == NotesNdjson || f == NotesTodoTxt
+}
+
+func NewNoteEncoder(w io.Writer, format NotesFormat) NoteEncoder {
+	switch format {
+	case NotesCsv:
+		return &csvNoteEncoder{w: csv.NewWriter(w)}
+	case NotesNdjson:
+		return &jsonNoteEncoder{w: w, enc: json.NewEncoder(w)}
+	case NotesTodoTxt:
+		return &todoTxtNoteEncoder{w: w}
+	}
+
+	return &jsonNoteEncoder{w: w, enc: json.NewEncoder(w), array: true}
+}
+
+type csvNoteEncoder struct {
+	w *csv.Writer
+	started bool
+}
+
+func(e *csvNoteEncoder) Encode(n model.Note) error {
+	if !e.started {
+		e.started = true
+		if err := e.w.Write(csvHeader); err != nil {
+			return err
+		}
+	}
+
+	return e.w.Write([]string{
+		strconv.FormatInt(n.Id, 10),
+		csvText(n.Title),
+		csvText(n.Text),
+		n.Date.Format(time.RFC3339),
+		strconv.FormatBool(n.IsFinished),
+		n.TimeZone,
+		strconv.FormatBool(n.IsFloating),
+		strconv.Itoa(int(n.ReminderOffset / time.Minute)),
+		n.Priority,
+		csvText(strings.Join(n.Projects, " ")),
+		csvText(strings.Join(n.Contexts, " ")),
+		formatOptionalTime(n.FinishedAt),
+	})
+}
+
+// csvFormulaStart are the first characters which make spreadsheets read a cell as a formula
+const csvFormulaStart = "=+-@\t\r"
+
+// csvText quotes text which spreadsheets would run as a formula with a leading apostrophe, they show it as text.
+// Text which starts with an apostrophe already is quoted too so that csvUnquoteText gives it back unchanged
+func csvText(v string) string {
+	if v != "" && strings.ContainsRune(csvFormulaStart + "'", rune(v[0])) {
+		return "'" + v
+	}
+
+	return v
+}
+
+// csvUnquoteText drops the apostrophe of csvText
+func csvUnquoteText(v string) string {
+	return strings.TrimPrefix(v, "'")
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+
+	return t.Format(time.RFC3339)
+}
+
+func(e *csvNoteEncoder) Close() error {
+	if !e.started {
+		e.started = true
+		if err := e.w.Write(csvHeader); err != nil {
+			return err
+		}
+	}
+
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// jsonNoteEncoder writes a json array or one json object per line
+type jsonNoteEncoder struct {
+	w io.Writer
+	enc *json.Encoder
+	array bool
+	count int
+}
+
+func(e *jsonNoteEncoder) Encode(n model.Note) error {
+	if e.array {
+		sep := ","
+		if e.count == 0 {
+			sep = "["
+		}
+		if _, err := io.WriteString(e.w, sep); err != nil {
+			return err
+		}
+	}
+	e.count++
+
+	return e.enc.Encode(NewNoteRecord(n))
+}
+
+func(e *jsonNoteEncoder) Close() error {
+	if !e.array {
+		return nil
+	}
+
+	end := "]\n"
+	if e.count == 0 {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(e.w, end)
+	return err
+}
+
+// DecodeNotes calls fn for every record, line is the line of csv and ndjson files and the element number of a json array.
+// Records which can not be read are passed with their error, a broken file stops decoding with ErrInvalidNotesFile.
+// An error of r is returned as it is rather than as a broken file
+func DecodeNotes(r io.Reader, format NotesFormat, fn func(line int, rec NoteRecord, err error) error) error {
+	er := &errReader{r: r}
+	r = er
+
+	var err error
+	switch format {
+	case NotesCsv:
+		err = decodeCsvNotes(r, fn)
+	case NotesNdjson:
+		err = decodeNdjsonNotes(r, fn)
+	case NotesTodoTxt:
+		err = decodeTodoTxtNotes(r, fn)
+	default:
+		err = decodeJsonNotes(r, fn)
+	}
+
+	if err != nil && er.err != nil {
+		return fmt.Errorf("decode notes: %w", er.err)
+	}
+	if err != nil {
+		return fmt.Errorf("decode notes: %w", err)
+	}
+
+	return nil
+}
+
+// errReader keeps the first error of r, decoders report it only as text
+type errReader struct {
+	r io.Reader
+	err error
+}
+
+func(r *errReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if err != nil && err != io.EOF && r.err == nil {
+		r.err = err
+	}
+
+	return n, err
+}
+
+func decodeCsvNotes(r io.Reader, fn func(line int, rec NoteRecord, err error) error) error {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidNotesFile, err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, h := range header {
+		columns[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	if _, ok := columns["title"]; !ok {
+		return fmt.Errorf("%w: no title column", ErrInvalidNotesFile)
+	}
+
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidNotesFile, err)
+		}
+
+		line, _ := cr.FieldPos(0)
+		rec, err := csvRecord(row, columns)
+		if err := fn(line, rec, err); err != nil {
+			return err
+		}
+	}
+}
+
+func csvRecord(row []string, columns map[string]int) (NoteRecord, error) {
+	get := func(name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(row) {
+			return ""
+		}
+		return row[i]
+	}
+
+	rec := NoteRecord{Title: csvUnquoteText(get("title")), Text: csvUnquoteText(get("text")), TimeZone: get("time_zone")}
+
+	var err error
+	if v := get("date"); v != "" {
+		if rec.Date, err = time.Parse(time.RFC3339, v); err != nil {
+			return rec, fmt.Errorf("date is not RFC 3339")
+		}
+	}
+	if v := get("is_finished"); v != "" {
+		if rec.IsFinished, err = strconv.ParseBool(v); err != nil {
+			return rec, fmt.Errorf("is_finished is not a boolean")
+		}
+	}
+	if v := get("is_floating"); v != "" {
+		if rec.IsFloating, err = strconv.ParseBool(v); err != nil {
+			return rec, fmt.Errorf("is_floating is not a boolean")
+		}
+	}
+	if v := get("reminder_offset_minutes"); v != "" {
+		m, err := strconv.Atoi(v)
+		if err != nil {
+			return rec, fmt.Errorf("reminder_offset_minutes is not a number")
+		}
+		rec.ReminderOffsetMinutes = &m
+	}
+
+	rec.Priority = get("priority")
+	rec.Projects = strings.Fields(csvUnquoteText(get("projects")))
+	rec.Contexts = strings.Fields(csvUnquoteText(get("contexts")))
+	if v := get("finished_at"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return rec, fmt.Errorf("finished_at is not RFC 3339")
+		}
+		rec.FinishedAt = &t
+	}
+
+	return rec, nil
+}
+
+func decodeNdjsonNotes(r io.Reader, fn func(line int, rec NoteRecord, err error) error) error {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 0, 64*1024), icalMaxLine)
+
+	line := 0
+	for s.Scan() {
+		line++
+		l := strings.TrimSpace(s.Text())
+		if l == "" {
+			continue
+		}
+
+		var rec NoteRecord
+		err := json.Unmarshal([]byte(l), &rec)
+		if err != nil {
+			err = fmt.Errorf("not a note object: %v", err)
+		}
+		if err := fn(line, rec, err); err != nil {
+			return err
+		}
+	}
+
+	if err := s.Err(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidNotesFile, err)
+	}
+
+	return nil
+}
+
+func decodeJsonNotes(r io.Reader, fn func(line int, rec NoteRecord, err error) error) error {
+	d := json.NewDecoder(r)
+	if t, err := d.Token(); err != nil || t != json.Delim('[') {
+		return fmt.Errorf("%w: expected an array of notes", ErrInvalidNotesFile)
+	}
+
+	for i := 1; d.More(); i++ {
+		// broken json stops decoding, a value which does not fit a note is reported
+		var raw json.RawMessage
+		if err := d.Decode(&raw); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidNotesFile, err)
+		}
+
+		var rec NoteRecord
+		err := json.Unmarshal(raw, &rec)
+		if err != nil {
+			err = fmt.Errorf("not a note object: %v", err)
+		}
+
+		if err := fn(i, rec, err); err != nil {
+			return err
+		}
+	}
+
+	if _, err := d.Token(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidNotesFile, err)
+	}
+
+	return nil
+}
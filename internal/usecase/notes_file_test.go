@@ -0,0 +1,169 @@
+package usecase
+
+import (
+	"bytes"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+	"todoNote/internal/model"
+)
+
+func TestNoteEncoder(t *testing.T) {
+	notes := []model.Note{
+		{Id: 1, Title: "milk, bread", Text: "two\nlines", Date: time.Date(2021, 10, 2, 7, 30, 0, 0, time.UTC), IsFinished: true, ReminderOffset: 15 * time.Minute,
+			Priority: "A", Projects: []string{"home", "shop"}},
+		{Id: 2, Title: "stand-up", Date: time.Date(2021, 10, 4, 9, 0, 0, 0, time.UTC), IsFloating: true},
+	}
+
+	tts := []struct{
+		format NotesFormat
+		want string
+		empty string
+	}{
+		{NotesCsv,
+			"id,title,text,date,is_finished,time_zone,is_floating,reminder_offset_minutes,priority,projects,contexts,finished_at\n" +
+				"1,\"milk, bread\",\"two\nlines\",2021-10-02T07:30:00Z,true,,false,15,A,home shop,,\n" +
+				"2,stand-up,,2021-10-04T09:00:00Z,false,,true,0,,,,\n",
+			"id,title,text,date,is_finished,time_zone,is_floating,reminder_offset_minutes,priority,projects,contexts,finished_at\n"},
+		{NotesNdjson,
+			`{"id":1,"title":"milk, bread","text":"two\nlines","date":"2021-10-02T07:30:00Z","is_finished":true,"reminder_offset_minutes":15,"priority":"A","projects":["home","shop"]}` + "\n" +
+				`{"id":2,"title":"stand-up","date":"2021-10-04T09:00:00Z","is_finished":false,"is_floating":true,"reminder_offset_minutes":0}` + "\n",
+			""},
+		{NotesJson,
+			`[{"id":1,"title":"milk, bread","text":"two\nlines","date":"2021-10-02T07:30:00Z","is_finished":true,"reminder_offset_minutes":15,"priority":"A","projects":["home","shop"]}` + "\n" +
+				`,{"id":2,"title":"stand-up","date":"2021-10-04T09:00:00Z","is_finished":false,"is_floating":true,"reminder_offset_minutes":0}` + "\n]\n",
+			"[]\n"},
+		{NotesTodoTxt,
+			"x milk, bread +home +shop due:2021-10-02 pri:A\nstand-up due:2021-10-04\n",
+			""},
+	}
+
+	for _, tt := range tts {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			enc := NewNoteEncoder(&buf, tt.format)
+			for _, n := range notes {
+				assert.Nil(t, enc.Encode(n))
+			}
+			assert.Nil(t, enc.Close())
+			assert.Equal(t, tt.want, buf.String())
+
+			buf.Reset()
+			assert.Nil(t, NewNoteEncoder(&buf, tt.format).Close())
+			assert.Equal(t, tt.empty, buf.String())
+		})
+	}
+}
+
+func TestNoteEncoder_CsvFormulas(t *testing.T) {
+	notes := []model.Note{
+		{Id: 1, Title: "=HYPERLINK(\"http://example.com\")", Text: "+1", Date: time.Date(2021, 10, 2, 7, 30, 0, 0, time.UTC)},
+		{Id: 2, Title: "@SUM(A1)", Text: "-2", Date: time.Date(2021, 10, 2, 7, 30, 0, 0, time.UTC), Projects: []string{"-home"}},
+		{Id: 3, Title: "\tcmd", Text: "\rcmd", Date: time.Date(2021, 10, 2, 7, 30, 0, 0, time.UTC)},
+		{Id: 4, Title: "'quoted", Text: "a=b", Date: time.Date(2021, 10, 2, 7, 30, 0, 0, time.UTC)},
+		{Id: 5, Title: "'=not a formula", Text: "''", Date: time.Date(2021, 10, 2, 7, 30, 0, 0, time.UTC)},
+	}
+
+	var buf bytes.Buffer
+	enc := NewNoteEncoder(&buf, NotesCsv)
+	for _, n := range notes {
+		assert.Nil(t, enc.Encode(n))
+	}
+	assert.Nil(t, enc.Close())
+
+	lines := strings.Split(buf.String(), "\n")
+	assert.Equal(t, "1,\"'=HYPERLINK(\"\"http://example.com\"\")\",'+1,2021-10-02T07:30:00Z,false,,false,0,,,,", lines[1])
+	assert.Equal(t, "2,'@SUM(A1),'-2,2021-10-02T07:30:00Z,false,,false,0,,'-home,,", lines[2])
+	assert.Equal(t, "3,'\tcmd,\"'\rcmd\",2021-10-02T07:30:00Z,false,,false,0,,,,", lines[3])
+	assert.Equal(t, "4,''quoted,a=b,2021-10-02T07:30:00Z,false,,false,0,,,,", lines[4])
+	assert.Equal(t, "5,''=not a formula,''',2021-10-02T07:30:00Z,false,,false,0,,,,", lines[5])
+
+	// the apostrophes are dropped again on import
+	var got []NoteRecord
+	assert.Nil(t, DecodeNotes(&buf, NotesCsv, func(_ int, rec NoteRecord, err error) error {
+		assert.Nil(t, err)
+		got = append(got, rec)
+		return nil
+	}))
+	assert.Len(t, got, len(notes))
+	for i, n := range notes {
+		assert.Equal(t, n.Title, got[i].Title)
+		assert.Equal(t, n.Text, got[i].Text)
+	}
+	assert.Equal(t, []string{"-home"}, got[1].Projects)
+}
+
+func TestDecodeNotes(t *testing.T) {
+	type got struct {
+		line int
+		title string
+		err bool
+	}
+
+	tts := []struct{
+		name string
+		format NotesFormat
+		in string
+		want []got
+	}{
+		{"csv", NotesCsv,
+			"title,date,is_finished\nmilk,2021-10-02T07:30:00Z,true\n\"two\nlines\",,\nbad,yesterday,\n",
+			[]got{{2, "milk", false}, {3, "two\nlines", false}, {5, "bad", true}}},
+		{"ndjson", NotesNdjson,
+			"{\"title\":\"milk\"}\n\n{\"title\":1}\n{\"title\":\"bread\",\"date\":\"2021-10-02T07:30:00Z\"}\n",
+			[]got{{1, "milk", false}, {3, "", true}, {4, "bread", false}}},
+		{"json", NotesJson,
+			`[{"title":"milk"}, {"title":"bread","date":"yesterday"}, {"title":"eggs"}]`,
+			[]got{{1, "milk", false}, {2, "bread", true}, {3, "eggs", false}}},
+		{"todotxt", NotesTodoTxt,
+			"(A) milk @shop\n\nbread due:someday\nx eggs\n",
+			[]got{{1, "milk", false}, {3, "", true}, {4, "eggs", false}}},
+	}
+
+	for _, tt := range tts {
+		t.Run(tt.name, func(t *testing.T) {
+			var res []got
+			err := DecodeNotes(strings.NewReader(tt.in), tt.format, func(line int, rec NoteRecord, err error) error {
+				res = append(res, got{line, rec.Title, err != nil})
+				return nil
+			})
+
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, res)
+		})
+	}
+}
+
+func TestDecodeNotes_Invalid(t *testing.T) {
+	tts := []struct{
+		name string
+		format NotesFormat
+		in string
+	}{
+		{"csv without title", NotesCsv, "text,date\nmilk,\n"},
+		{"csv bare quote", NotesCsv, "title\nmi\"lk\n"},
+		{"json object", NotesJson, `{"title":"milk"}`},
+		{"json truncated", NotesJson, `[{"title":"milk"}, {"title":`},
+	}
+
+	for _, tt := range tts {
+		t.Run(tt.name, func(t *testing.T) {
+			err := DecodeNotes(strings.NewReader(tt.in), tt.format, func(int, NoteRecord, error) error { return nil })
+			assert.ErrorIs(t, err, ErrInvalidNotesFile)
+		})
+	}
+}
+
+func TestDecodeNotes_ReadError(t *testing.T) {
+	readErr := errors.New("body too large")
+	for _, format := range []NotesFormat{NotesCsv, NotesJson, NotesNdjson, NotesTodoTxt} {
+		t.Run(format, func(t *testing.T) {
+			err := DecodeNotes(iotest.ErrReader(readErr), format, func(int, NoteRecord, error) error { return nil })
+			assert.ErrorIs(t, err, readErr)
+			assert.NotErrorIs(t, err, ErrInvalidNotesFile)
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"todoNote/internal/model"
+	"todoNote/internal/repo"
+)
+
+const (
+	maxUserNameLength  = 64
+	provisionNameTries  = 10
+)
+
+type IOidcUsecase interface {
+	Authenticate(ctx context.Context, identity model.UserIdentity, preferredName string) (model.UserInReq, error)
+	Link(ctx context.Context, identity model.UserIdentity) error
+}
+var _ IOidcUsecase = &OidcUsecase{}
+
+// OidcUsecase writes user.created of provisioned users to the outbox in their transaction
+type OidcUsecase struct {
+	identityRepo repo.IRepoIdentity
+	userRepo     repo.IRepoUser
+	outbox       repo.IRepoOutbox
+	tx           repo.ITransactor
+	now          func() time.Time
+}
+
+func NewOidcUsecase(i repo.IRepoIdentity, u repo.IRepoUser, outbox repo.IRepoOutbox, tx repo.ITransactor) *OidcUsecase {
+	return &OidcUsecase{
+		identityRepo: i,
+		userRepo:     u,
+		outbox:       outbox,
+		tx:           tx,
+		now:          time.Now,
+	}
+}
+
+// Authenticate returns the user linked to the identity, a new user is provisioned on the first login.
+// Identities are unique across organizations, provisioned users are of the tenant of ctx
+func(u *OidcUsecase) Authenticate(ctx context.Context, identity model.UserIdentity, preferredName string) (model.UserInReq, error) {
+	linked, err := u.identityRepo.GetByProviderSubject(ctx, identity.Provider, identity.Subject)
+	if err == nil {
+		usr, err := u.userRepo.GetById(repo.AllTenants(ctx), linked.UserId)
+		if err != nil {
+			return model.UserInReq{}, fmt.Errorf("oidc authenticate: linked user: %w", err)
+		}
+
+		return model.UserInReq{Id: usr.Id, OrganizationId: usr.OrganizationId}, nil
+	}
+	if !errors.Is(err, repo.ErrNotFound) {
+		return model.UserInReq{}, fmt.Errorf("oidc authenticate: %w", err)
+	}
+
+	name, err := u.freeUserName(ctx, preferredName, identity)
+	if err != nil {
+		return model.UserInReq{}, fmt.Errorf("oidc authenticate: %w", err)
+	}
+
+	// provisioned users have no password so only the provider can log them in
+	usr := model.NewUser(0, name, []byte{}, model.UTC)
+	err = u.tx.InTransaction(ctx, func(ctx context.Context) error {
+		id, err := u.userRepo.Insert(ctx, usr)
+		if err != nil {
+			return fmt.Errorf("provision user: %w", err)
+		}
+		usr.Id = id
+
+		identity.UserId = id
+		if err := u.identityRepo.Insert(ctx, &identity); err != nil {
+			return fmt.Errorf("link identity: %w", err)
+		}
+
+		return emit(ctx, u.outbox, u.now(), model.EventUserCreated, id, id, userPayload(*usr))
+	})
+	if err != nil {
+		return model.UserInReq{}, fmt.Errorf("oidc authenticate: %w", err)
+	}
+
+	return model.UserInReq{Id: usr.Id, OrganizationId: usr.OrganizationId}, nil
+}
+
+func(u *OidcUsecase) Link(ctx context.Context, identity model.UserIdentity) error {
+	linked, err := u.identityRepo.GetByProviderSubject(ctx, identity.Provider, identity.Subject)
+	if err == nil {
+		if linked.UserId != identity.UserId {
+			return ErrIdentityLinked
+		}
+
+		return nil
+	}
+	if !errors.Is(err, repo.ErrNotFound) {
+		return fmt.Errorf("oidc link: %w", err)
+	}
+
+	if err := u.identityRepo.Insert(ctx, &identity); err != nil {
+		return fmt.Errorf("oidc link: %w", err)
+	}
+
+	return nil
+}
+
+func(u *OidcUsecase) freeUserName(ctx context.Context, preferred string, identity model.UserIdentity) (string, error) {
+	base := strings.TrimSpace(preferred)
+	if base == "" {
+		base = identity.Provider + "-" + identity.Subject
+	}
+	if len(base) > maxUserNameLength - 3 {
+		base = base[:maxUserNameLength - 3]
+	}
+
+	name := base
+	for i := 2; i <= provisionNameTries + 1; i++ {
+		_, err := u.userRepo.GetByUserName(ctx, name)
+		if errors.Is(err, repo.ErrNotFound) {
+			return name, nil
+		}
+		if err != nil {
+			return "", err
+		}
+
+		name = fmt.Sprintf("%v-%v", base, i)
+	}
+
+	return "", fmt.Errorf("no free user name for %v", base)
+}
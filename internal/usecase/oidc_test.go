@@ -0,0 +1,100 @@
+package usecase
+
+import (
+	"context"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"todoNote/internal/model"
+	"todoNote/internal/repo"
+	in_memory "todoNote/internal/repo/in-memory"
+	"todoNote/internal/usecase/mocks"
+)
+
+//go:generate mockgen -package=mocks -destination=mocks/identities.go todoNote/internal/repo IRepoIdentity
+
+func TestOidcUsecase_Authenticate(t *testing.T) {
+	identity := model.UserIdentity{Provider: "corp", Subject: "sub"}
+
+	t.Run("linked user", func(t *testing.T) {
+		ctr := gomock.NewController(t)
+		defer ctr.Finish()
+
+		mockIdentity := mocks.NewMockIRepoIdentity(ctr)
+		mockIdentity.EXPECT().GetByProviderSubject(gomock.Any(), "corp", "sub").
+			Return(&model.UserIdentity{UserId: 5, Provider: "corp", Subject: "sub"}, nil)
+
+		mockUser := mocks.NewMockIRepoUser(ctr)
+		mockUser.EXPECT().GetById(gomock.Any(), model.Id(5)).
+			Return(&model.User{Id: 5, Name: "user", OrganizationId: 3}, nil)
+
+		uc := NewOidcUsecase(mockIdentity, mockUser, in_memory.NewRepoOutbox(), in_memory.NewTransactor())
+		usr, err := uc.Authenticate(context.Background(), identity, "user")
+		assert.Nil(t, err)
+		assert.Equal(t, model.UserInReq{Id: 5, OrganizationId: 3}, usr)
+	})
+
+	t.Run("provision user with free name", func(t *testing.T) {
+		ctr := gomock.NewController(t)
+		defer ctr.Finish()
+
+		mockIdentity := mocks.NewMockIRepoIdentity(ctr)
+		mockIdentity.EXPECT().GetByProviderSubject(gomock.Any(), "corp", "sub").
+			Return(nil, repo.NewNotFoundError("corp:sub"))
+		mockIdentity.EXPECT().Insert(gomock.Any(), gomock.Any()).
+			Return(nil).
+			Do(func(_ context.Context, i *model.UserIdentity) {
+				assert.Equal(t, model.Id(7), i.UserId)
+				assert.Equal(t, "sub", i.Subject)
+			})
+
+		mockUser := mocks.NewMockIRepoUser(ctr)
+		mockUser.EXPECT().GetByUserName(gomock.Any(), "user").
+			Return(&model.User{Id: 1, Name: "user"}, nil)
+		mockUser.EXPECT().GetByUserName(gomock.Any(), "user-2").
+			Return(nil, repo.NewNotFoundError("user-2"))
+		mockUser.EXPECT().Insert(gomock.Any(), gomock.Any()).
+			Return(model.Id(7), nil).
+			Do(func(_ context.Context, u *model.User) {
+				assert.Equal(t, "user-2", u.Name)
+				assert.Empty(t, u.PasswordHash)
+				u.OrganizationId = model.DefaultOrganizationId
+			})
+
+		outbox := in_memory.NewRepoOutbox()
+		uc := NewOidcUsecase(mockIdentity, mockUser, outbox, in_memory.NewTransactor())
+		usr, err := uc.Authenticate(context.Background(), identity, "user")
+		assert.Nil(t, err)
+		assert.Equal(t, model.UserInReq{Id: 7, OrganizationId: model.DefaultOrganizationId}, usr)
+		assert.Equal(t, []model.EventType{model.EventUserCreated}, claimedTypes(t, outbox))
+	})
+}
+
+func TestOidcUsecase_Link(t *testing.T) {
+	t.Run("linked to another user", func(t *testing.T) {
+		ctr := gomock.NewController(t)
+		defer ctr.Finish()
+
+		mockIdentity := mocks.NewMockIRepoIdentity(ctr)
+		mockIdentity.EXPECT().GetByProviderSubject(gomock.Any(), "corp", "sub").
+			Return(&model.UserIdentity{UserId: 2, Provider: "corp", Subject: "sub"}, nil)
+
+		uc := NewOidcUsecase(mockIdentity, nil, nil, nil)
+		err := uc.Link(context.Background(), model.UserIdentity{UserId: 1, Provider: "corp", Subject: "sub"})
+		assert.Equal(t, ErrIdentityLinked, err)
+	})
+
+	t.Run("new link", func(t *testing.T) {
+		ctr := gomock.NewController(t)
+		defer ctr.Finish()
+
+		mockIdentity := mocks.NewMockIRepoIdentity(ctr)
+		mockIdentity.EXPECT().GetByProviderSubject(gomock.Any(), "corp", "sub").
+			Return(nil, repo.NewNotFoundError("corp:sub"))
+		mockIdentity.EXPECT().Insert(gomock.Any(), &model.UserIdentity{UserId: 1, Provider: "corp", Subject: "sub"}).
+			Return(nil)
+
+		uc := NewOidcUsecase(mockIdentity, nil, nil, nil)
+		assert.Nil(t, uc.Link(context.Background(), model.UserIdentity{UserId: 1, Provider: "corp", Subject: "sub"}))
+	})
+}
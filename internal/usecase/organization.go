@@ -0,0 +1,85 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"todoNote/internal/model"
+	"todoNote/internal/repo"
+)
+
+const (
+	maxOrganizationNameLength = 64
+	// organizationSeparator splits organization/name in places which take only a user name, like HTTP Basic
+	organizationSeparator = "/"
+)
+
+type IOrganizationUsecase interface {
+	Create(ctx context.Context, name string) (model.Id, error)
+	// Tenant scopes ctx to the organization of the name, to the default one when the name is empty
+	Tenant(ctx context.Context, name string) (context.Context, error)
+}
+var _ IOrganizationUsecase = &OrganizationUsecase{}
+
+type OrganizationUsecase struct {
+	organizationRepo repo.IRepoOrganization
+	now func() time.Time
+}
+
+func NewOrganizationUsecase(r repo.IRepoOrganization) *OrganizationUsecase {
+	return &OrganizationUsecase{
+		organizationRepo: r,
+		now: time.Now,
+	}
+}
+
+func(u *OrganizationUsecase) Create(ctx context.Context, name string) (model.Id, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > maxOrganizationNameLength || strings.Contains(name, organizationSeparator) {
+		return 0, ErrInvalidOrganization
+	}
+
+	id, err := u.organizationRepo.Insert(ctx, model.NewOrganization(name, u.now().UTC()))
+	if err != nil {
+		return 0, fmt.Errorf("create organization: %w", err)
+	}
+
+	return id, nil
+}
+
+func(u *OrganizationUsecase) Tenant(ctx context.Context, name string) (context.Context, error) {
+	if name == "" {
+		return repo.WithTenant(ctx, model.DefaultOrganizationId), nil
+	}
+
+	o, err := u.organizationRepo.GetByName(ctx, name)
+	if errors.Is(err, repo.ErrNotFound) {
+		return ctx, ErrOrganizationNotFound
+	}
+	if err != nil {
+		return ctx, fmt.Errorf("organization tenant: %w", err)
+	}
+
+	return repo.WithTenant(ctx, o.Id), nil
+}
+
+// SplitUserName splits organization/name, a name without organization is of the default one
+func SplitUserName(s string) (organization, name string) {
+	i := strings.Index(s, organizationSeparator)
+	if i < 0 {
+		return "", s
+	}
+
+	return s[:i], s[i + len(organizationSeparator):]
+}
+
+// QualifiedUserName is the name SplitUserName splits
+func QualifiedUserName(organization, name string) string {
+	if organization == "" {
+		return name
+	}
+
+	return organization + organizationSeparator + name
+}
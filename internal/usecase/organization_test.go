@@ -0,0 +1,54 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"todoNote/internal/model"
+	"todoNote/internal/repo"
+	in_memory "todoNote/internal/repo/in-memory"
+)
+
+func TestOrganizationUsecase(t *testing.T) {
+	ctx := context.Background()
+	uc := NewOrganizationUsecase(in_memory.NewRepoOrganization())
+
+	id, err := uc.Create(ctx, " sales ")
+	assert.Nil(t, err)
+
+	_, err = uc.Create(ctx, "sales")
+	assert.True(t, errors.Is(err, repo.ErrConflict))
+
+	for _, name := range []string{"", "a/b", strings.Repeat("a", maxOrganizationNameLength + 1)} {
+		_, err = uc.Create(ctx, name)
+		assert.Equal(t, ErrInvalidOrganization, err)
+	}
+
+	tenantCtx, err := uc.Tenant(ctx, "sales")
+	assert.Nil(t, err)
+	tenant, _ := repo.Tenant(tenantCtx)
+	assert.Equal(t, id, tenant)
+
+	tenantCtx, err = uc.Tenant(ctx, "")
+	assert.Nil(t, err)
+	tenant, _ = repo.Tenant(tenantCtx)
+	assert.Equal(t, model.DefaultOrganizationId, tenant)
+
+	_, err = uc.Tenant(ctx, "support")
+	assert.Equal(t, ErrOrganizationNotFound, err)
+}
+
+func TestSplitUserName(t *testing.T) {
+	organization, name := SplitUserName("sales/user")
+	assert.Equal(t, "sales", organization)
+	assert.Equal(t, "user", name)
+
+	organization, name = SplitUserName("user")
+	assert.Equal(t, "", organization)
+	assert.Equal(t, "user", name)
+
+	assert.Equal(t, "sales/user", QualifiedUserName("sales", "user"))
+	assert.Equal(t, "user", QualifiedUserName("", "user"))
+}
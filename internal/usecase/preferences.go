@@ -0,0 +1,114 @@
+package usecase
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"todoNote/internal/model"
+)
+
+const (
+	maxDisplayNameLength = 64
+	maxReminderOffset = 7 * 24 * time.Hour
+)
+
+// locale is a simplified BCP 47 tag: language with optional region or script subtags
+var localeRule = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{2,8})*$`)
+
+var dateFormats = map[model.DateFormat]bool{
+	model.DateFormatIso: true,
+	model.DateFormatEu: true,
+	model.DateFormatUs: true,
+}
+
+// ParseWeekday accepts english day names in any case
+func ParseWeekday(day string) (time.Weekday, bool) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(d.String(), day) {
+			return d, true
+		}
+	}
+
+	return 0, false
+}
+
+func ValidatePreferences(p model.PreferencesUpdate) error {
+	if p.DisplayName != nil && len(*p.DisplayName) > maxDisplayNameLength {
+		return fmt.Errorf("%w: display name is longer than %v", ErrInvalidPreferences, maxDisplayNameLength)
+	}
+
+	if p.Locale != nil && !localeRule.MatchString(*p.Locale) {
+		return fmt.Errorf("%w: unknown locale %q", ErrInvalidPreferences, *p.Locale)
+	}
+
+	if p.WeekStart != nil && (*p.WeekStart < time.Sunday || *p.WeekStart > time.Saturday) {
+		return fmt.Errorf("%w: unknown week start day", ErrInvalidPreferences)
+	}
+
+	if p.ReminderOffset != nil && !ValidateReminderOffset(*p.ReminderOffset) {
+		return fmt.Errorf("%w: reminder offset is out of range", ErrInvalidPreferences)
+	}
+
+	if p.DateFormat != nil && !dateFormats[*p.DateFormat] {
+		return fmt.Errorf("%w: unknown date format %q", ErrInvalidPreferences, *p.DateFormat)
+	}
+
+	return nil
+}
+
+func ValidateReminderOffset(d time.Duration) bool {
+	return d >= 0 && d <= maxReminderOffset
+}
+
+func applyPreferences(p *model.Preferences, upd model.PreferencesUpdate) {
+	if upd.DisplayName != nil {
+		p.DisplayName = *upd.DisplayName
+	}
+
+	if upd.Locale != nil {
+		p.Locale = *upd.Locale
+	}
+
+	if upd.WeekStart != nil {
+		p.WeekStart = *upd.WeekStart
+	}
+
+	if upd.ReminderOffset != nil {
+		p.ReminderOffset = *upd.ReminderOffset
+	}
+
+	if upd.DateFormat != nil {
+		p.DateFormat = *upd.DateFormat
+	}
+}
+
+type Period = string
+const (
+	PeriodDay Period = "day"
+	PeriodWeek Period = "week"
+	PeriodMonth Period = "month"
+	PeriodYear Period = "year"
+)
+
+// PeriodRange returns [from, to) of the day, week, month or year containing t in the zone of t,
+// weeks begin on weekStart
+func PeriodRange(t time.Time, period Period, weekStart time.Weekday) (time.Time, time.Time, bool) {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+
+	switch period {
+	case PeriodDay:
+		return day, day.AddDate(0, 0, 1), true
+	case PeriodWeek:
+		from := day.AddDate(0, 0, -((int(day.Weekday()) - int(weekStart) + 7) % 7))
+		return from, from.AddDate(0, 0, 7), true
+	case PeriodMonth:
+		from := day.AddDate(0, 0, 1 - day.Day())
+		return from, from.AddDate(0, 1, 0), true
+	case PeriodYear:
+		from := time.Date(t.Year(), time.January, 1, 0, 0, 0, 0, t.Location())
+		return from, from.AddDate(1, 0, 0), true
+	}
+
+	return time.Time{}, time.Time{}, false
+}
@@ -0,0 +1,78 @@
+package usecase
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+	"todoNote/internal/model"
+)
+
+func TestValidatePreferences(t *testing.T) {
+	str := func(s string) *string { return &s }
+	dur := func(d time.Duration) *time.Duration { return &d }
+
+	tts := []struct{
+		in model.PreferencesUpdate
+		valid bool
+	}{
+		{model.PreferencesUpdate{}, true},
+		{model.PreferencesUpdate{Locale: str("en"), DateFormat: str(model.DateFormatUs), ReminderOffset: dur(time.Hour)}, true},
+		{model.PreferencesUpdate{Locale: str("zh-Hant-TW")}, true},
+		{model.PreferencesUpdate{Locale: str("english please")}, false},
+		{model.PreferencesUpdate{DateFormat: str("dd/mm")}, false},
+		{model.PreferencesUpdate{ReminderOffset: dur(-time.Minute)}, false},
+		{model.PreferencesUpdate{ReminderOffset: dur(30 * 24 * time.Hour)}, false},
+		{model.PreferencesUpdate{DisplayName: str(string(make([]byte, 65)))}, false},
+	}
+
+	for _, tt := range tts {
+		err := ValidatePreferences(tt.in)
+		assert.Equal(t, tt.valid, err == nil, err)
+		if err != nil {
+			assert.True(t, errors.Is(err, ErrInvalidPreferences))
+		}
+	}
+}
+
+func TestParseWeekday(t *testing.T) {
+	d, ok := ParseWeekday("Sunday")
+	assert.True(t, ok)
+	assert.Equal(t, time.Sunday, d)
+
+	d, ok = ParseWeekday("monday")
+	assert.True(t, ok)
+	assert.Equal(t, time.Monday, d)
+
+	_, ok = ParseWeekday("mon")
+	assert.False(t, ok)
+}
+
+func TestPeriodRange(t *testing.T) {
+	// wednesday
+	at := time.Date(2021, 10, 6, 15, 30, 0, 0, time.UTC)
+
+	tts := []struct{
+		period Period
+		weekStart time.Weekday
+		from time.Time
+		to time.Time
+	}{
+		{PeriodDay, time.Monday, time.Date(2021, 10, 6, 0, 0, 0, 0, time.UTC), time.Date(2021, 10, 7, 0, 0, 0, 0, time.UTC)},
+		{PeriodWeek, time.Monday, time.Date(2021, 10, 4, 0, 0, 0, 0, time.UTC), time.Date(2021, 10, 11, 0, 0, 0, 0, time.UTC)},
+		{PeriodWeek, time.Sunday, time.Date(2021, 10, 3, 0, 0, 0, 0, time.UTC), time.Date(2021, 10, 10, 0, 0, 0, 0, time.UTC)},
+		{PeriodWeek, time.Wednesday, time.Date(2021, 10, 6, 0, 0, 0, 0, time.UTC), time.Date(2021, 10, 13, 0, 0, 0, 0, time.UTC)},
+		{PeriodMonth, time.Monday, time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC), time.Date(2021, 11, 1, 0, 0, 0, 0, time.UTC)},
+		{PeriodYear, time.Monday, time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tts {
+		from, to, ok := PeriodRange(at, tt.period, tt.weekStart)
+		assert.True(t, ok)
+		assert.Equal(t, tt.from, from, tt.period)
+		assert.Equal(t, tt.to, to, tt.period)
+	}
+
+	_, _, ok := PeriodRange(at, "decade", time.Monday)
+	assert.False(t, ok)
+}
@@ -0,0 +1,129 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+	"todoNote/internal/model"
+	"todoNote/internal/repo"
+)
+
+const (
+	maxUserAgentLength = 255
+	// last seen is not written on every request to keep reads cheap
+	sessionTouchInterval = time.Minute
+)
+
+type ISessionUsecase interface {
+	Start(ctx context.Context, uId model.Id, userAgent, ip string) (model.Id, error)
+	Validate(ctx context.Context, sessionId, uId model.Id) error
+	FindAll(ctx context.Context, uId model.Id) ([]model.Session, error)
+	Revoke(ctx context.Context, sessionId, uId model.Id) error
+}
+var _ ISessionUsecase = &SessionUsecase{}
+
+type SessionUsecase struct {
+	sessionRepo repo.IRepoSession
+	lifetime    time.Duration
+	now         func() time.Time
+}
+
+// NewSessionUsecase takes token lifetime, a session can not outlive the token it was created for
+func NewSessionUsecase(r repo.IRepoSession, lifetime time.Duration) *SessionUsecase {
+	return &SessionUsecase{
+		sessionRepo: r,
+		lifetime:    lifetime,
+		now:         time.Now,
+	}
+}
+
+func(u *SessionUsecase) Start(ctx context.Context, uId model.Id, userAgent, ip string) (model.Id, error) {
+	now := u.now().UTC()
+	if err := u.sessionRepo.DeleteExpired(ctx, now); err != nil {
+		return 0, fmt.Errorf("start session: %w", err)
+	}
+
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+
+	s := model.NewSession(uId, userAgent, ip, now, now.Add(u.lifetime))
+	id, err := u.sessionRepo.Insert(ctx, s)
+	if err != nil {
+		return 0, fmt.Errorf("start session: %w", err)
+	}
+
+	return id, nil
+}
+
+func(u *SessionUsecase) Validate(ctx context.Context, sessionId, uId model.Id) error {
+	s, err := u.sessionRepo.GetById(ctx, sessionId)
+	if errors.Is(err, repo.ErrNotFound) {
+		return ErrSessionRevoked
+	}
+	if err != nil {
+		return fmt.Errorf("validate session: %w", err)
+	}
+
+	now := u.now().UTC()
+	if s.UserId != uId || now.After(s.ExpiresAt) {
+		return ErrSessionRevoked
+	}
+
+	if now.Sub(s.LastSeenAt) < sessionTouchInterval {
+		return nil
+	}
+
+	if err := u.sessionRepo.Touch(ctx, sessionId, now); err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			return ErrSessionRevoked
+		}
+
+		return fmt.Errorf("validate session: %w", err)
+	}
+
+	return nil
+}
+
+func(u *SessionUsecase) FindAll(ctx context.Context, uId model.Id) ([]model.Session, error) {
+	sessions, err := u.sessionRepo.GetAllByUser(ctx, uId)
+	if err != nil {
+		return nil, fmt.Errorf("find sessions: %w", err)
+	}
+
+	now := u.now().UTC()
+	active := make([]model.Session, 0, len(sessions))
+	for _, s := range sessions {
+		if now.After(s.ExpiresAt) {
+			continue
+		}
+		active = append(active, s)
+	}
+
+	return active, nil
+}
+
+func(u *SessionUsecase) Revoke(ctx context.Context, sessionId, uId model.Id) error {
+	s, err := u.sessionRepo.GetById(ctx, sessionId)
+	if errors.Is(err, repo.ErrNotFound) {
+		return NewSessionNotFoundError(sessionId, uId)
+	}
+	if err != nil {
+		return fmt.Errorf("revoke session: %w", err)
+	}
+
+	if s.UserId != uId {
+		return NewSessionNotFoundError(sessionId, uId)
+	}
+
+	if err := u.sessionRepo.Delete(ctx, sessionId); err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			return NewSessionNotFoundError(sessionId, uId)
+		}
+
+		return fmt.Errorf("revoke session: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+	in_memory "todoNote/internal/repo/in-memory"
+)
+
+func TestSessionUsecase(t *testing.T) {
+	now := time.Date(2021, 10, 1, 10, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+
+	uc := NewSessionUsecase(in_memory.NewRepoSession(), time.Hour)
+	uc.now = fixedClock(now)
+
+	first, err := uc.Start(ctx, 1, "curl", "127.0.0.1")
+	assert.Nil(t, err)
+	second, err := uc.Start(ctx, 1, "firefox", "127.0.0.2")
+	assert.Nil(t, err)
+
+	assert.Nil(t, uc.Validate(ctx, first, 1))
+	assert.True(t, errors.Is(uc.Validate(ctx, first, 2), ErrSessionRevoked))
+
+	sessions, err := uc.FindAll(ctx, 1)
+	assert.Nil(t, err)
+	assert.Len(t, sessions, 2)
+
+	t.Run("revoke someone else's session", func(t *testing.T) {
+		err := uc.Revoke(ctx, first, 2)
+		assert.IsType(t, &ElemNotFound{}, err)
+		assert.Nil(t, uc.Validate(ctx, first, 1))
+	})
+
+	t.Run("revoked session is rejected", func(t *testing.T) {
+		assert.Nil(t, uc.Revoke(ctx, first, 1))
+		assert.True(t, errors.Is(uc.Validate(ctx, first, 1), ErrSessionRevoked))
+		assert.IsType(t, &ElemNotFound{}, uc.Revoke(ctx, first, 1))
+		assert.Nil(t, uc.Validate(ctx, second, 1))
+	})
+
+	t.Run("expired session is rejected", func(t *testing.T) {
+		uc.now = fixedClock(now.Add(time.Hour + time.Minute))
+		assert.True(t, errors.Is(uc.Validate(ctx, second, 1), ErrSessionRevoked))
+
+		sessions, err := uc.FindAll(ctx, 1)
+		assert.Nil(t, err)
+		assert.Len(t, sessions, 0)
+	})
+}
@@ -0,0 +1,265 @@
+package usecase
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"todoNote/internal/model"
+	"todoNote/internal/repo"
+)
+
+const (
+	syncChangesLimit = 500
+	syncTokenPrefix = "v1:"
+)
+
+var ErrInvalidSyncToken = fmt.Errorf("invalid sync token")
+
+type SyncStatus = string
+const (
+	SyncApplied SyncStatus = "applied"
+	SyncConflict SyncStatus = "conflict"
+	SyncRejected SyncStatus = "rejected"
+)
+
+// SyncChanges are the changes after a token, clients ask again with Token while More is set
+type SyncChanges struct {
+	Changes []model.NoteChange
+	Token string
+	More bool
+}
+
+// ClientChange is a note created, changed or deleted on a client while it was offline
+type ClientChange struct {
+	// ClientId identifies a new note in the results, NoteId is 0 for new notes
+	ClientId string
+	NoteId model.Id
+	// BaseVersion is the version the client changed, a newer one on the server is a conflict
+	BaseVersion int64
+	Deleted bool
+	// Note replaces the whole note, its date is as in changes so floating notes carry their wall clock in UTC
+	Note NoteRecord
+}
+
+type SyncResult struct {
+	ClientId string
+	NoteId model.Id
+	Status SyncStatus
+	// Version of the applied change, 0 for deletions
+	Version int64
+	Reason string
+	// Current is the server side of a conflict
+	Current *model.NoteChange
+}
+
+type ISyncUsecase interface {
+	// Changes returns notes and tombstones changed after the token, an empty token starts from the beginning
+	Changes(ctx context.Context, uId model.Id, token string) (SyncChanges, error)
+	// Push applies client changes in order, conflicts and invalid changes are reported and skipped
+	Push(ctx context.Context, uId model.Id, changes []ClientChange) ([]SyncResult, error)
+}
+var _ ISyncUsecase = &SyncUsecase{}
+
+// SyncUsecase writes the events of pushed changes to the outbox in their transaction
+type SyncUsecase struct {
+	noteRepo repo.IRepoNote
+	userRepo repo.IRepoUser
+	outbox repo.IRepoOutbox
+	tx repo.ITransactor
+	now func() time.Time
+}
+
+func NewSyncUsecase(n repo.IRepoNote, u repo.IRepoUser, outbox repo.IRepoOutbox, tx repo.ITransactor) *SyncUsecase {
+	return &SyncUsecase{
+		noteRepo: n,
+		userRepo: u,
+		outbox: outbox,
+		tx: tx,
+		now: time.Now,
+	}
+}
+
+func(u *SyncUsecase) Changes(ctx context.Context, uId model.Id, token string) (SyncChanges, error) {
+	since, err := DecodeSyncToken(token)
+	if err != nil {
+		return SyncChanges{}, err
+	}
+
+	changes, err := u.noteRepo.Changes(ctx, uId, since, syncChangesLimit + 1)
+	if err != nil {
+		return SyncChanges{}, fmt.Errorf("sync changes: %w", err)
+	}
+
+	res := SyncChanges{Changes: changes}
+	if len(changes) > syncChangesLimit {
+		res.Changes = changes[:syncChangesLimit]
+		res.More = true
+	}
+
+	last := since
+	if len(res.Changes) > 0 {
+		last = res.Changes[len(res.Changes)-1].Version
+	}
+	res.Token = EncodeSyncToken(last)
+
+	return res, nil
+}
+
+func(u *SyncUsecase) Push(ctx context.Context, uId model.Id, changes []ClientChange) ([]SyncResult, error) {
+	usr, err := u.userRepo.GetById(ctx, uId)
+	if err != nil {
+		return nil, fmt.Errorf("sync push: %w", err)
+	}
+
+	results := make([]SyncResult, 0, len(changes))
+	err = u.tx.InTransaction(ctx, func(ctx context.Context) error {
+		for _, c := range changes {
+			res, err := u.apply(ctx, usr, c)
+			if err != nil {
+				return err
+			}
+			results = append(results, res)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("sync push: %w", err)
+	}
+
+	return results, nil
+}
+
+func(u *SyncUsecase) apply(ctx context.Context, usr *model.User, c ClientChange) (SyncResult, error) {
+	res := SyncResult{ClientId: c.ClientId, NoteId: c.NoteId, Status: SyncRejected}
+	if !c.Deleted {
+		if err := validateRecord(c.Note); err != nil {
+			res.Reason = err.Error()
+			return res, nil
+		}
+	}
+
+	if c.NoteId == 0 {
+		if c.Deleted {
+			res.Reason = "a new note can not be deleted"
+			return res, nil
+		}
+
+		n := u.syncNote(c.Note, usr, model.Note{})
+		id, err := u.noteRepo.Insert(ctx, n)
+		if err != nil {
+			return res, err
+		}
+		if err := emit(ctx, u.outbox, u.now(), model.EventNoteCreated, n.UserId, id, notePayload(*n)); err != nil {
+			return res, err
+		}
+
+		res.NoteId, res.Status, res.Version = id, SyncApplied, n.Version
+		return res, nil
+	}
+
+	old, err := u.noteRepo.GetByIdForUpdate(ctx, c.NoteId)
+	if err != nil && !errors.Is(err, repo.ErrNotFound) {
+		return res, err
+	}
+
+	// notes of other users look deleted
+	if err != nil || old.UserId != usr.Id {
+		if c.Deleted {
+			res.Status = SyncApplied
+			return res, nil
+		}
+
+		res.Status = SyncConflict
+		res.Reason = "note was deleted"
+		res.Current = &model.NoteChange{NoteId: c.NoteId, Deleted: true}
+		return res, nil
+	}
+
+	if old.Version != c.BaseVersion {
+		res.Status = SyncConflict
+		res.Reason = "note was changed"
+		res.Current = &model.NoteChange{Version: old.Version, NoteId: old.Id, Note: &old}
+		return res, nil
+	}
+
+	if c.Deleted {
+		if err := u.noteRepo.Delete(ctx, old.Id); err != nil {
+			return res, err
+		}
+		if err := emit(ctx, u.outbox, u.now(), model.EventNoteDeleted, old.UserId, old.Id, notePayload(old)); err != nil {
+			return res, err
+		}
+
+		res.Status = SyncApplied
+		return res, nil
+	}
+
+	n := u.syncNote(c.Note, usr, old)
+	if err := u.noteRepo.Update(ctx, n); err != nil {
+		return res, err
+	}
+	if err := emitNoteUpdated(ctx, u.outbox, u.now(), old.IsFinished, *n); err != nil {
+		return res, err
+	}
+
+	res.Status, res.Version = SyncApplied, n.Version
+	return res, nil
+}
+
+// syncNote is the record as stored, what the record does not carry is kept from old
+func(u *SyncUsecase) syncNote(rec NoteRecord, usr *model.User, old model.Note) *model.Note {
+	n := recordNote(rec, usr)
+	n.Id, n.Uid = old.Id, old.Uid
+
+	switch {
+	case rec.Date.IsZero() && old.Id != 0:
+		n.Date, n.TimeZone, n.IsFloating = old.Date, old.TimeZone, old.IsFloating
+	case rec.Date.IsZero():
+		n.Date = u.now().UTC()
+	case n.IsFloating:
+		n.Date = inLocation(rec.Date, time.UTC)
+	default:
+		n.Date = rec.Date.UTC()
+	}
+
+	switch {
+	case !n.IsFinished:
+		n.FinishedAt = nil
+	case n.FinishedAt != nil:
+	case old.IsFinished:
+		n.FinishedAt = old.FinishedAt
+	default:
+		finishedAt := u.now().UTC()
+		n.FinishedAt = &finishedAt
+	}
+
+	return n
+}
+
+// EncodeSyncToken hides the version so clients do not depend on it
+func EncodeSyncToken(version int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(syncTokenPrefix + strconv.FormatInt(version, 10)))
+}
+
+func DecodeSyncToken(token string) (int64, error) {
+	if token == "" {
+		return 0, nil
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || !strings.HasPrefix(string(b), syncTokenPrefix) {
+		return 0, ErrInvalidSyncToken
+	}
+
+	v, err := strconv.ParseInt(strings.TrimPrefix(string(b), syncTokenPrefix), 10, 64)
+	if err != nil || v < 0 {
+		return 0, ErrInvalidSyncToken
+	}
+
+	return v, nil
+}
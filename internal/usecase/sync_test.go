@@ -0,0 +1,168 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+	"todoNote/internal/model"
+	"todoNote/internal/repo"
+	in_memory "todoNote/internal/repo/in-memory"
+)
+
+func newSyncUsecase() *SyncUsecase {
+	uc := NewSyncUsecase(in_memory.NewRepoNote(), in_memory.NewRepoUser(), in_memory.NewRepoOutbox(), in_memory.NewTransactor())
+	uc.now = fixedClock(time.Date(2021, 10, 2, 10, 0, 0, 0, time.UTC))
+	return uc
+}
+
+// claimedTypes are the types of the events waiting in the outbox, oldest first
+func claimedTypes(t *testing.T, outbox repo.IRepoOutbox) []model.EventType {
+	events, err := outbox.Claim(context.Background(), time.Now(), time.Minute, 100)
+	assert.Nil(t, err)
+
+	types := make([]model.EventType, 0, len(events))
+	for _, e := range events {
+		types = append(types, e.Type)
+	}
+
+	return types
+}
+
+func TestSyncToken(t *testing.T) {
+	v, err := DecodeSyncToken(EncodeSyncToken(42))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(42), v)
+
+	v, err = DecodeSyncToken("")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), v)
+
+	for _, token := range []string{"42", "!!", EncodeSyncToken(-1)} {
+		_, err := DecodeSyncToken(token)
+		assert.True(t, errors.Is(err, ErrInvalidSyncToken), token)
+	}
+}
+
+func TestSyncUsecase_Changes(t *testing.T) {
+	ctx := context.Background()
+	uc := newSyncUsecase()
+
+	all, err := uc.Changes(ctx, 1, "")
+	assert.Nil(t, err)
+	assert.Len(t, all.Changes, 3)
+	assert.False(t, all.More)
+
+	none, err := uc.Changes(ctx, 1, all.Token)
+	assert.Nil(t, err)
+	assert.Len(t, none.Changes, 0)
+	assert.Equal(t, all.Token, none.Token)
+
+	n, err := uc.noteRepo.GetById(ctx, 2)
+	assert.Nil(t, err)
+	n.Title = "changed"
+	assert.Nil(t, uc.noteRepo.Update(ctx, &n))
+	assert.Nil(t, uc.noteRepo.Delete(ctx, 1))
+
+	changed, err := uc.Changes(ctx, 1, all.Token)
+	assert.Nil(t, err)
+	assert.Len(t, changed.Changes, 2)
+	assert.Equal(t, "changed", changed.Changes[0].Note.Title)
+	assert.Equal(t, model.NoteChange{Version: 5, NoteId: 1, Deleted: true}, changed.Changes[1])
+
+	t.Run("other users see nothing", func(t *testing.T) {
+		other, err := uc.Changes(ctx, 2, "")
+		assert.Nil(t, err)
+		assert.Len(t, other.Changes, 0)
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		_, err := uc.Changes(ctx, 1, "bogus")
+		assert.True(t, errors.Is(err, ErrInvalidSyncToken))
+	})
+}
+
+func TestSyncUsecase_Push(t *testing.T) {
+	ctx := context.Background()
+	uc := newSyncUsecase()
+
+	floating := time.Date(2021, 10, 3, 9, 0, 0, 0, time.UTC)
+	results, err := uc.Push(ctx, 1, []ClientChange{
+		{ClientId: "new", Note: NoteRecord{Title: "offline", Date: floating, IsFloating: true, TimeZone: "Europe/Kyiv"}},
+		{NoteId: 1, BaseVersion: 1, Note: NoteRecord{Title: "renamed", IsFinished: true}},
+		{NoteId: 2, BaseVersion: 1, Note: NoteRecord{Title: "stale"}},
+		{NoteId: 3, BaseVersion: 3, Deleted: true},
+		{NoteId: 3, BaseVersion: 3, Deleted: true},
+		{ClientId: "bad", Note: NoteRecord{}},
+	})
+	assert.Nil(t, err)
+	assert.Len(t, results, 6)
+
+	assert.Equal(t, SyncApplied, results[0].Status)
+	assert.Equal(t, "new", results[0].ClientId)
+	created, err := uc.noteRepo.GetById(ctx, results[0].NoteId)
+	assert.Nil(t, err)
+	assert.True(t, floating.Equal(created.Date))
+	assert.Equal(t, results[0].Version, created.Version)
+
+	assert.Equal(t, SyncApplied, results[1].Status)
+	renamed, err := uc.noteRepo.GetById(ctx, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, "renamed", renamed.Title)
+	assert.NotNil(t, renamed.FinishedAt)
+
+	assert.Equal(t, SyncConflict, results[2].Status)
+	assert.Equal(t, int64(2), results[2].Current.Version)
+
+	assert.Equal(t, SyncApplied, results[3].Status)
+	// deleting a deleted note again is not a conflict
+	assert.Equal(t, SyncApplied, results[4].Status)
+
+	assert.Equal(t, SyncRejected, results[5].Status)
+	assert.Equal(t, "title is empty", results[5].Reason)
+
+	t.Run("change of a deleted note conflicts", func(t *testing.T) {
+		results, err := uc.Push(ctx, 1, []ClientChange{{NoteId: 3, BaseVersion: 3, Note: NoteRecord{Title: "late"}}})
+		assert.Nil(t, err)
+		assert.Equal(t, SyncConflict, results[0].Status)
+		assert.True(t, results[0].Current.Deleted)
+	})
+
+	t.Run("notes of other users look deleted", func(t *testing.T) {
+		other, err := uc.userRepo.Insert(ctx, model.NewUser(0, "other", nil, model.UTC))
+		assert.Nil(t, err)
+
+		results, err := uc.Push(ctx, other, []ClientChange{{NoteId: 1, BaseVersion: renamed.Version, Note: NoteRecord{Title: "theirs"}}})
+		assert.Nil(t, err)
+		assert.Equal(t, SyncConflict, results[0].Status)
+		assert.True(t, results[0].Current.Deleted)
+	})
+}
+
+func TestSyncUsecase_PushEvents(t *testing.T) {
+	ctx := context.Background()
+	uc := newSyncUsecase()
+
+	results, err := uc.Push(ctx, 1, []ClientChange{
+		{ClientId: "new", Note: NoteRecord{Title: "offline"}},
+		{NoteId: 1, BaseVersion: 1, Note: NoteRecord{Title: "renamed", IsFinished: true}},
+		{NoteId: 2, BaseVersion: 7, Note: NoteRecord{Title: "stale"}},
+		{NoteId: 3, BaseVersion: 3, Deleted: true},
+	})
+	assert.Nil(t, err)
+	assert.Len(t, results, 4)
+
+	// conflicts change nothing and leave no events
+	events, err := uc.outbox.Claim(ctx, time.Now(), time.Minute, 10)
+	assert.Nil(t, err)
+	types := make([]model.EventType, 0, len(events))
+	for _, e := range events {
+		types = append(types, e.Type)
+	}
+	assert.Equal(t, []model.EventType{model.EventNoteCreated, model.EventNoteUpdated, model.EventNoteCompleted, model.EventNoteDeleted}, types)
+	if assert.Len(t, events, 4) {
+		assert.Equal(t, results[0].NoteId, events[0].SubjectId)
+		assert.Equal(t, model.Id(3), events[3].SubjectId)
+	}
+}
@@ -1,77 +1,89 @@
 package usecase
 
 import (
+	"fmt"
+	"sync"
 	"time"
+	_ "time/tzdata"
 	"todoNote/internal/model"
 )
 
+// maxZoneLength matches users.time_zone column
+const maxZoneLength = 64
+
 var (
-	hour = 60*60
-	UTCp1  = time.FixedZone(model.UTCp1, 1*hour)
-	UTCp2  = time.FixedZone(model.UTCp2, 2*hour)
-	UTCp3  = time.FixedZone(model.UTCp3, 3*hour)
-	UTCp4  = time.FixedZone(model.UTCp4, 4*hour)
-	UTCp5  = time.FixedZone(model.UTCp5, 5*hour)
-	UTCp6  = time.FixedZone(model.UTCp6, 6*hour)
-	UTCp7  = time.FixedZone(model.UTCp7, 7*hour)
-	UTCp8  = time.FixedZone(model.UTCp8, 8*hour)
-	UTCp9  = time.FixedZone(model.UTCp9, 9*hour)
-	UTCp10  = time.FixedZone(model.UTCp10, 10*hour)
-	UTCp11  = time.FixedZone(model.UTCp11, 11*hour)
-	UTCp12  = time.FixedZone(model.UTCp12, 12*hour)
-
-	UTCm1  = time.FixedZone(model.UTCm1, -1*hour)
-	UTCm2  = time.FixedZone(model.UTCm2, -2*hour)
-	UTCm3  = time.FixedZone(model.UTCm3, -3*hour)
-	UTCm4  = time.FixedZone(model.UTCm4, -4*hour)
-	UTCm5  = time.FixedZone(model.UTCm5, -5*hour)
-	UTCm6  = time.FixedZone(model.UTCm6, -6*hour)
-	UTCm7  = time.FixedZone(model.UTCm7, -7*hour)
-	UTCm8  = time.FixedZone(model.UTCm8, -8*hour)
-	UTCm9  = time.FixedZone(model.UTCm9, -9*hour)
-	UTCm10  = time.FixedZone(model.UTCm10, -10*hour)
-	UTCm11  = time.FixedZone(model.UTCm11, -11*hour)
-	UTCm12  = time.FixedZone(model.UTCm12, -12*hour)
-
-	converter = make(map[model.TimeZone] *time.Location)
-)
+	// legacyZones maps fixed UTC±N offsets accepted before IANA names to their Etc/GMT zones,
+	// note the inverted sign of Etc/GMT names
+	legacyZones = make(map[model.TimeZone]string)
 
+	locations sync.Map
+)
 
 func init()  {
-	converter[model.UTC] = time.UTC
-	converter[model.UTCp1] = UTCp1
-	converter[model.UTCp2] = UTCp2
-	converter[model.UTCp3] = UTCp3
-	converter[model.UTCp4] = UTCp4
-	converter[model.UTCp5] = UTCp5
-	converter[model.UTCp6] = UTCp6
-	converter[model.UTCp7] = UTCp7
-	converter[model.UTCp8] = UTCp8
-	converter[model.UTCp9] = UTCp9
-	converter[model.UTCp10] = UTCp10
-	converter[model.UTCp11] = UTCp11
-	converter[model.UTCp12] = UTCp12
-
-	converter[model.UTCm1] = UTCm1
-	converter[model.UTCm2] = UTCm2
-	converter[model.UTCm3] = UTCm3
-	converter[model.UTCm4] = UTCm4
-	converter[model.UTCm5] = UTCm5
-	converter[model.UTCm6] = UTCm6
-	converter[model.UTCm7] = UTCm7
-	converter[model.UTCm8] = UTCm8
-	converter[model.UTCm9] = UTCm9
-	converter[model.UTCm10] = UTCm10
-	converter[model.UTCm11] = UTCm11
-	converter[model.UTCm12] = UTCm12
+	for _, z := range []model.TimeZone{
+		model.UTCp1, model.UTCp2, model.UTCp3, model.UTCp4, model.UTCp5, model.UTCp6,
+		model.UTCp7, model.UTCp8, model.UTCp9, model.UTCp10, model.UTCp11, model.UTCp12,
+	} {
+		legacyZones[z] = fmt.Sprintf("Etc/GMT-%v", z[len("UTC+"):])
+	}
+
+	for _, z := range []model.TimeZone{
+		model.UTCm1, model.UTCm2, model.UTCm3, model.UTCm4, model.UTCm5, model.UTCm6,
+		model.UTCm7, model.UTCm8, model.UTCm9, model.UTCm10, model.UTCm11, model.UTCm12,
+	} {
+		legacyZones[z] = fmt.Sprintf("Etc/GMT+%v", z[len("UTC-"):])
+	}
 }
 
+// Location resolves an IANA zone name like Europe/Kyiv or a legacy UTC±N offset
+func Location(zone model.TimeZone) (*time.Location, bool) {
+	if zone == "" || zone == "Local" || len(zone) > maxZoneLength {
+		return nil, false
+	}
+
+	if l, ok := locations.Load(zone); ok {
+		return l.(*time.Location), true
+	}
+
+	name := zone
+	if iana, ok := legacyZones[zone]; ok {
+		name = iana
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, false
+	}
+
+	locations.Store(zone, loc)
+	return loc, true
+}
+
+// Convert falls back to UTC for unknown zones
 func Convert(dateTime time.Time, zone model.TimeZone) time.Time {
-	loc := converter[zone]
+	loc, ok := Location(zone)
+	if !ok {
+		loc = time.UTC
+	}
+
 	return dateTime.In(loc)
 }
 
+// ValidateZone returns the zone as it is stored, see NormalizeZone
 func ValidateZone(z string) (model.TimeZone, bool) {
-	_, ok := converter[z]
-	return z, ok
+	if _, ok := Location(z); !ok {
+		return z, false
+	}
+
+	return NormalizeZone(z), true
+}
+
+// NormalizeZone turns a legacy UTC±N offset into its Etc/GMT zone like migrations did with stored ones,
+// other zones are returned as they are
+func NormalizeZone(z model.TimeZone) model.TimeZone {
+	if iana, ok := legacyZones[z]; ok {
+		return iana
+	}
+
+	return z
 }
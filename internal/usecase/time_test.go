@@ -26,6 +26,32 @@ func TestTimeConvert(t *testing.T) {
 	}
 }
 
+func TestTimeConvertIana(t *testing.T) {
+	winter := time.Date(2021, 1, 10, 12, 0, 0, 0, time.UTC)
+	summer := time.Date(2021, 7, 10, 12, 0, 0, 0, time.UTC)
+
+	tts := []struct{
+		in time.Time
+		zone model.TimeZone
+		offset int
+	}{
+		{winter, "Europe/Kyiv", 2 * 60 * 60},
+		{summer, "Europe/Kyiv", 3 * 60 * 60},
+		{summer, "Asia/Kolkata", 5 * 60 * 60 + 30 * 60},
+		{winter, "Asia/Kathmandu", 5 * 60 * 60 + 45 * 60},
+		{summer, "America/New_York", -4 * 60 * 60},
+		{summer, model.UTCp3, 3 * 60 * 60},
+		{summer, "Mars/Olympus", 0},
+	}
+
+	for _, tt := range tts {
+		c := Convert(tt.in, tt.zone)
+		_, offset := c.Zone()
+		assert.Equal(t, tt.offset, offset, tt.zone)
+		assert.True(t, tt.in.Equal(c))
+	}
+}
+
 func TestValidateZone(t *testing.T) {
 	tts := []struct{
 		in string
@@ -38,10 +64,35 @@ func TestValidateZone(t *testing.T) {
 		{"UtC+1", false},
 		{"utc+3", false},
 		{"UTC-", false},
+		{"Europe/Kyiv", true},
+		{"Asia/Kolkata", true},
+		{"America/Argentina/Buenos_Aires", true},
+		{"Mars/Olympus", false},
+		{"Local", false},
+		{"", false},
+		{"../../etc/passwd", false},
 	}
 
 	for _, tt := range tts {
 		_, out := ValidateZone(tt.in)
 		assert.Equal(t, tt.out, out)
 	}
-}
\ No newline at end of file
+}
+func TestNormalizeZone(t *testing.T) {
+	tts := []struct{
+		in string
+		out string
+	}{
+		{"UTC", "UTC"},
+		{"UTC+3", "Etc/GMT-3"},
+		{"UTC-4", "Etc/GMT+4"},
+		{"Europe/Kyiv", "Europe/Kyiv"},
+	}
+
+	for _, tt := range tts {
+		assert.Equal(t, tt.out, NormalizeZone(tt.in))
+		z, ok := ValidateZone(tt.in)
+		assert.True(t, ok)
+		assert.Equal(t, tt.out, z)
+	}
+}
@@ -0,0 +1,167 @@
+package usecase
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+	"todoNote/internal/model"
+)
+
+// todo.txt format, see https://github.com/todotxt/todo.txt
+const todoTxtDateFormat = "2006-01-02"
+
+var todoTxtPriorityRe = regexp.MustCompile(`^\(([A-Z])\)$`)
+
+func ValidPriority(p string) bool {
+	return p == "" || len(p) == 1 && p[0] >= 'A' && p[0] <= 'Z'
+}
+
+func ValidTag(t string) bool {
+	return t != "" && !strings.ContainsAny(t, " \t\r\n")
+}
+
+// todoTxtNoteEncoder writes a task per note, the due date is the note date,
+// the text is written only when it is the untruncated single line title
+type todoTxtNoteEncoder struct {
+	w io.Writer
+}
+
+func(e *todoTxtNoteEncoder) Encode(n model.Note) error {
+	_, err := io.WriteString(e.w, FormatTodoTxt(n) + "\n")
+	return err
+}
+
+func(e *todoTxtNoteEncoder) Close() error {
+	return nil
+}
+
+func FormatTodoTxt(n model.Note) string {
+	var parts []string
+	if n.IsFinished {
+		parts = append(parts, "x")
+		if n.FinishedAt != nil {
+			parts = append(parts, n.FinishedAt.In(n.Date.Location()).Format(todoTxtDateFormat))
+		}
+	} else if n.Priority != "" {
+		parts = append(parts, "(" + n.Priority + ")")
+	}
+
+	description := n.Title
+	if n.Text != "" && !strings.ContainsAny(n.Text, "\r\n") && strings.HasPrefix(n.Text, n.Title) {
+		description = n.Text
+	}
+	parts = append(parts, description)
+
+	for _, p := range n.Projects {
+		parts = append(parts, "+" + p)
+	}
+	for _, c := range n.Contexts {
+		parts = append(parts, "@" + c)
+	}
+	parts = append(parts, "due:" + n.Date.Format(todoTxtDateFormat))
+	// completed tasks lose the leading priority, the pri tag keeps it
+	if n.IsFinished && n.Priority != "" {
+		parts = append(parts, "pri:" + n.Priority)
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// ParseTodoTxt reads a task line, the due or else the creation date becomes a floating all-day date
+// and a description longer than a title is kept whole in the text
+func ParseTodoTxt(line string) (NoteRecord, error) {
+	var rec NoteRecord
+	words := strings.Fields(line)
+
+	if len(words) > 0 && words[0] == "x" {
+		rec.IsFinished = true
+		words = words[1:]
+		if d, ok := todoTxtDate(words); ok {
+			rec.FinishedAt = &d
+			words = words[1:]
+		}
+	} else if len(words) > 0 && todoTxtPriorityRe.MatchString(words[0]) {
+		rec.Priority = words[0][1:2]
+		words = words[1:]
+	}
+
+	var created time.Time
+	if d, ok := todoTxtDate(words); ok {
+		created = d
+		words = words[1:]
+	}
+
+	var description []string
+	for _, w := range words {
+		switch {
+		case len(w) > 1 && w[0] == '+':
+			rec.Projects = append(rec.Projects, w[1:])
+		case len(w) > 1 && w[0] == '@':
+			rec.Contexts = append(rec.Contexts, w[1:])
+		case strings.HasPrefix(w, "due:"):
+			d, err := time.Parse(todoTxtDateFormat, w[len("due:"):])
+			if err != nil {
+				return rec, fmt.Errorf("due is not a YYYY-MM-DD date")
+			}
+			rec.Date = d
+		case strings.HasPrefix(w, "pri:") && rec.Priority == "":
+			rec.Priority = w[len("pri:"):]
+		default:
+			description = append(description, w)
+		}
+	}
+
+	if rec.Date.IsZero() {
+		rec.Date = created
+	}
+	if !rec.Date.IsZero() {
+		// dates have no zone, in UTC the wall clock stays midnight
+		rec.IsFloating = true
+		rec.TimeZone = model.UTC
+	}
+
+	rec.Title = strings.Join(description, " ")
+	if utf8.RuneCountInString(rec.Title) > maxTitleLength {
+		rec.Text = rec.Title
+		rec.Title = strings.TrimSpace(string([]rune(rec.Title)[:maxTitleLength]))
+	}
+
+	return rec, nil
+}
+
+func todoTxtDate(words []string) (time.Time, bool) {
+	if len(words) == 0 {
+		return time.Time{}, false
+	}
+
+	d, err := time.Parse(todoTxtDateFormat, words[0])
+	return d, err == nil
+}
+
+func decodeTodoTxtNotes(r io.Reader, fn func(line int, rec NoteRecord, err error) error) error {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 0, 64*1024), icalMaxLine)
+
+	line := 0
+	for s.Scan() {
+		line++
+		if strings.TrimSpace(s.Text()) == "" {
+			continue
+		}
+
+		rec, err := ParseTodoTxt(s.Text())
+		if err := fn(line, rec, err); err != nil {
+			return err
+		}
+	}
+
+	if err := s.Err(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidNotesFile, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package usecase
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+	"todoNote/internal/model"
+)
+
+func TestParseTodoTxt(t *testing.T) {
+	date := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	}
+	finished := date(2021, 10, 3)
+
+	tts := []struct{
+		line string
+		want NoteRecord
+	}{
+		{"(A) Call Mom +Family @phone due:2021-10-05",
+			NoteRecord{Title: "Call Mom", Priority: "A", Projects: []string{"Family"}, Contexts: []string{"phone"},
+				Date: date(2021, 10, 5), IsFloating: true, TimeZone: model.UTC}},
+		{"x 2021-10-03 2021-10-01 Pay bills +Home pri:B",
+			NoteRecord{Title: "Pay bills", IsFinished: true, FinishedAt: &finished, Priority: "B", Projects: []string{"Home"},
+				Date: date(2021, 10, 1), IsFloating: true, TimeZone: model.UTC}},
+		{"2021-10-01 (A) not a priority, email me@example.com",
+			NoteRecord{Title: "(A) not a priority, email me@example.com", Date: date(2021, 10, 1), IsFloating: true, TimeZone: model.UTC}},
+		{"xylophone lesson",
+			NoteRecord{Title: "xylophone lesson"}},
+	}
+
+	for _, tt := range tts {
+		t.Run(tt.line, func(t *testing.T) {
+			got, err := ParseTodoTxt(tt.line)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	t.Run("long description is kept in text", func(t *testing.T) {
+		long := strings.Repeat("word ", 20)
+		got, err := ParseTodoTxt(long + "@home")
+		assert.Nil(t, err)
+		assert.Equal(t, strings.TrimSpace(long), got.Text)
+		assert.True(t, strings.HasPrefix(got.Text, got.Title))
+		assert.LessOrEqual(t, len(got.Title), maxTitleLength)
+	})
+
+	t.Run("invalid due date", func(t *testing.T) {
+		_, err := ParseTodoTxt("Call Mom due:tomorrow")
+		assert.NotNil(t, err)
+	})
+}
+
+func TestFormatTodoTxt(t *testing.T) {
+	finished := time.Date(2021, 10, 3, 18, 0, 0, 0, time.UTC)
+	tts := []struct{
+		note model.Note
+		want string
+	}{
+		{model.Note{Title: "Call Mom", Priority: "A", Projects: []string{"Family"}, Contexts: []string{"phone"},
+			Date: time.Date(2021, 10, 5, 0, 0, 0, 0, time.UTC)},
+			"(A) Call Mom +Family @phone due:2021-10-05"},
+		{model.Note{Title: "Pay bills", Priority: "B", IsFinished: true, FinishedAt: &finished,
+			Date: time.Date(2021, 10, 1, 9, 0, 0, 0, time.UTC)},
+			"x 2021-10-03 Pay bills due:2021-10-01 pri:B"},
+		{model.Note{Title: "Groceries", Text: "milk\nbread", Date: time.Date(2021, 10, 1, 9, 0, 0, 0, time.UTC)},
+			"Groceries due:2021-10-01"},
+	}
+
+	for _, tt := range tts {
+		assert.Equal(t, tt.want, FormatTodoTxt(tt.note))
+	}
+
+	t.Run("round trip", func(t *testing.T) {
+		line := "x 2021-10-03 " + strings.Repeat("word ", 20) + "+Home @phone due:2021-10-01 pri:C"
+		rec, err := ParseTodoTxt(line)
+		assert.Nil(t, err)
+
+		n := model.Note{Title: rec.Title, Text: rec.Text, Date: rec.Date, IsFinished: rec.IsFinished, FinishedAt: rec.FinishedAt,
+			Priority: rec.Priority, Projects: rec.Projects, Contexts: rec.Contexts}
+		assert.Equal(t, strings.Join(strings.Fields(line), " "), FormatTodoTxt(n))
+	})
+}
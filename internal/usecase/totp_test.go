@@ -0,0 +1,69 @@
+package usecase
+
+import (
+	"encoding/base32"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA1 seed from RFC 6238 appendix B
+var rfc6238Secret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestTotpCode(t *testing.T) {
+	tts := []struct{
+		at int64
+		out string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, tt := range tts {
+		code, err := TotpCode(rfc6238Secret, time.Unix(tt.at, 0))
+		assert.Nil(t, err)
+		assert.Equal(t, tt.out, code)
+	}
+}
+
+func TestValidateTotp(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+
+	tts := []struct{
+		code string
+		at time.Time
+		out bool
+	}{
+		{"050471", now, true},
+		{"050471", now.Add(totpPeriod * time.Second), true},
+		{"050471", now.Add(-totpPeriod * time.Second), true},
+		{"050471", now.Add(3 * totpPeriod * time.Second), false},
+		{"050472", now, false},
+		{"50471", now, false},
+	}
+
+	for _, tt := range tts {
+		assert.Equal(t, tt.out, ValidateTotp(rfc6238Secret, tt.code, tt.at))
+	}
+}
+
+func TestGenerateTotpSecret(t *testing.T) {
+	s, err := GenerateTotpSecret()
+	assert.Nil(t, err)
+
+	key, err := totpEncoding.DecodeString(s)
+	assert.Nil(t, err)
+	assert.Equal(t, totpSecretSize, len(key))
+}
+
+func TestTotpUri(t *testing.T) {
+	uri := TotpUri("todo", "user name", "SECRET")
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/todo:user%20name?"))
+	assert.Contains(t, uri, "secret=SECRET")
+	assert.Contains(t, uri, "issuer=todo")
+	assert.Contains(t, uri, "digits=6")
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"golang.org/x/crypto/bcrypt"
+	"time"
 	"todoNote/internal/model"
 	"todoNote/internal/repo"
 )
@@ -16,13 +17,20 @@ type IUserUsecase interface {
 }
 var _ IUserUsecase = &UserUsecase{}
 
+// UserUsecase writes the events of its changes to the outbox in their transactions
 type UserUsecase struct {
 	userRepo repo.IRepoUser
+	outbox repo.IRepoOutbox
+	tx repo.ITransactor
+	now func() time.Time
 }
 
-func NewUserUsecase(r repo.IRepoUser) *UserUsecase {
+func NewUserUsecase(r repo.IRepoUser, outbox repo.IRepoOutbox, tx repo.ITransactor) *UserUsecase {
 	return &UserUsecase{
 		userRepo: r,
+		outbox: outbox,
+		tx: tx,
+		now: time.Now,
 	}
 }
 
@@ -35,12 +43,21 @@ func (u *UserUsecase) Create(ctx context.Context, usr *model.UserNew) (model.Id,
 	if user.TimeZone == "" {
 		user.TimeZone = model.UTC
 	}
-
-	id, err := u.userRepo.Insert(ctx, user);
+	user.TimeZone = NormalizeZone(user.TimeZone)
+
+	var id model.Id
+	err = u.tx.InTransaction(ctx, func(ctx context.Context) error {
+		id, err = u.userRepo.Insert(ctx, user);
+		if err != nil {
+			return err
+		}
+		user.Id = id
+
+		return emit(ctx, u.outbox, u.now(), model.EventUserCreated, id, id, userPayload(*user))
+	})
 	if err != nil {
 		return 0, fmt.Errorf("create user.go: %w", err)
 	}
-	user.Id = id
 
 	return id, nil
 }
@@ -64,20 +81,39 @@ func (u *UserUsecase) FindByName(ctx context.Context, name string) (*model.User,
 }
 
 func (u *UserUsecase) Update(ctx context.Context, usr model.UserUpdate) error {
-	user, err := u.userRepo.GetById(ctx, usr.Id)
-	if err != nil {
+	if err := ValidatePreferences(usr.Preferences); err != nil {
 		return fmt.Errorf("update user: %w", err)
 	}
 
-	if usr.TimeZone != "" {
-		user.TimeZone = usr.TimeZone
-	}
+	return u.tx.InTransaction(ctx, func(ctx context.Context) error {
+		user, err := u.userRepo.GetByIdForUpdate(ctx, usr.Id)
+		if err != nil {
+			return fmt.Errorf("update user: %w", err)
+		}
 
-	return u.userRepo.Update(ctx, user)
+		if usr.TimeZone != "" {
+			user.TimeZone = NormalizeZone(usr.TimeZone)
+		}
+		applyPreferences(&user.Preferences, usr.Preferences)
+
+		if err := u.userRepo.Update(ctx, user); err != nil {
+			return err
+		}
+
+		return emit(ctx, u.outbox, u.now(), model.EventUserUpdated, user.Id, user.Id, userPayload(*user))
+	})
 }
 
 func (u *UserUsecase) Remove(ctx context.Context, uId model.Id) error {
-	if err := u.userRepo.Delete(ctx, uId); err != nil {
+	err := u.tx.InTransaction(ctx, func(ctx context.Context) error {
+		if err := u.userRepo.Delete(ctx, uId); err != nil {
+			return err
+		}
+
+		organizationId, _ := repo.Tenant(ctx)
+		return emit(ctx, u.outbox, u.now(), model.EventUserDeleted, uId, uId, UserPayload{Id: uId, OrganizationId: organizationId})
+	})
+	if err != nil {
 		return fmt.Errorf("user.go remove: %w", err)
 	}
 
@@ -7,7 +7,9 @@ import (
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/crypto/bcrypt"
 	"testing"
+	"time"
 	"todoNote/internal/model"
+	"todoNote/internal/repo"
 	in_memory "todoNote/internal/repo/in-memory"
 	"todoNote/internal/usecase/mocks"
 )
@@ -22,7 +24,7 @@ func TestUserUsecase_Create(t *testing.T) {
 	} {
 		{
 			in: model.UserNew{Name: "new", Password: "123", TimeZone: model.UTCp3},
-			want: model.User{Name: "new", PasswordHash: []byte("123"), TimeZone: model.UTCp3},
+			want: model.User{Name: "new", PasswordHash: []byte("123"), TimeZone: "Etc/GMT-3"},
 			outErr: nil,
 		},
 		{
@@ -45,7 +47,7 @@ func TestUserUsecase_Create(t *testing.T) {
 					assert.NotEqual(t, "", string(user.PasswordHash))
 			})
 
-			uc := NewUserUsecase(mockRepo)
+			uc := NewUserUsecase(mockRepo, in_memory.NewRepoOutbox(), in_memory.NewTransactor())
 			_, err := uc.Create(context.Background(), &tt.in)
 			assert.Equal(t, tt.outErr, err)
 		})
@@ -60,7 +62,7 @@ func TestUserUsecase_FindById(t *testing.T) {
 		mockRepo.EXPECT().GetById(gomock.Any(), model.Id(1)).
 			Return(&model.User{Id: 1}, nil)
 
-		uc := NewUserUsecase(mockRepo)
+		uc := NewUserUsecase(mockRepo, in_memory.NewRepoOutbox(), in_memory.NewTransactor())
 		usr, err := uc.FindById(context.Background(), 1)
 		assert.Equal(t, model.Id(1), usr.Id)
 		assert.Nil(t, err)
@@ -71,9 +73,9 @@ func TestUserUsecase_FindById(t *testing.T) {
 		defer ctr.Finish()
 		mockRepo := mocks.NewMockIRepoUser(ctr)
 		mockRepo.EXPECT().GetById(gomock.Any(), model.Id(1)).
-			Return(nil, in_memory.NewNoSuchElementError(1))
+			Return(nil, repo.NewNotFoundError(1))
 
-		uc := NewUserUsecase(mockRepo)
+		uc := NewUserUsecase(mockRepo, in_memory.NewRepoOutbox(), in_memory.NewTransactor())
 		_, err := uc.FindById(context.Background(), 1)
 		assert.NotNil(t, err)
 	})
@@ -87,7 +89,7 @@ func TestUserUsecase_FindByName(t *testing.T) {
 		mockRepo.EXPECT().GetByUserName(gomock.Any(), "user name").
 			Return(&model.User{Id: 1}, nil)
 
-		uc := NewUserUsecase(mockRepo)
+		uc := NewUserUsecase(mockRepo, in_memory.NewRepoOutbox(), in_memory.NewTransactor())
 		usr, err := uc.FindByName(context.Background(), "user name")
 		assert.Equal(t, model.Id(1), usr.Id)
 		assert.Nil(t, err)
@@ -98,9 +100,9 @@ func TestUserUsecase_FindByName(t *testing.T) {
 		defer ctr.Finish()
 		mockRepo := mocks.NewMockIRepoUser(ctr)
 		mockRepo.EXPECT().GetByUserName(gomock.Any(), "user name").
-			Return(nil, in_memory.NewNoSuchNameError("user name"))
+			Return(nil, repo.NewNotFoundError("user name"))
 
-		uc := NewUserUsecase(mockRepo)
+		uc := NewUserUsecase(mockRepo, in_memory.NewRepoOutbox(), in_memory.NewTransactor())
 		_, err := uc.FindByName(context.Background(), "user name")
 		assert.NotNil(t, err)
 	})
@@ -125,7 +127,7 @@ func TestUserUsecase_Update(t *testing.T) {
 		{
 			in: model.UserUpdate{Id: 2},
 			getByIdUser: model.User{},
-			getByIdErr: in_memory.NewNoSuchElementError(2),
+			getByIdErr: repo.NewNotFoundError(2),
 		},
 	}
 
@@ -134,7 +136,7 @@ func TestUserUsecase_Update(t *testing.T) {
 			ctr := gomock.NewController(t)
 			defer ctr.Finish()
 			mockRepo := mocks.NewMockIRepoUser(ctr)
-			mockRepo.EXPECT().GetById(gomock.Any(), gomock.Any()).
+			mockRepo.EXPECT().GetByIdForUpdate(gomock.Any(), gomock.Any()).
 				Return(&tt.getByIdUser, tt.getByIdErr)
 			if tt.getByIdErr == nil {
 				mockRepo.EXPECT().Update(gomock.Any(), gomock.Any()).
@@ -144,7 +146,7 @@ func TestUserUsecase_Update(t *testing.T) {
 					})
 			}
 
-			uc := NewUserUsecase(mockRepo)
+			uc := NewUserUsecase(mockRepo, in_memory.NewRepoOutbox(), in_memory.NewTransactor())
 			err := uc.Update(context.Background(), tt.in)
 			if err != nil {
 				assert.Equal(t, fmt.Sprintf("update user: %v", tt.getByIdErr.Error()), err.Error())
@@ -164,12 +166,29 @@ func TestUserUsecase_Remove(t *testing.T) {
 				assert.Equal(t, model.Id(1), id)
 		})
 
-		uc := NewUserUsecase(mockRepo)
+		uc := NewUserUsecase(mockRepo, in_memory.NewRepoOutbox(), in_memory.NewTransactor())
 		err := uc.Remove(context.Background(), 1)
 		assert.Nil(t, err)
 	})
 }
 
+func TestUserUsecase_Events(t *testing.T) {
+	ctx := context.Background()
+	outbox := in_memory.NewRepoOutbox()
+	uc := NewUserUsecase(in_memory.NewRepoUser(), outbox, in_memory.NewTransactor())
+
+	assert.Nil(t, uc.Update(ctx, model.UserUpdate{Id: 1, TimeZone: model.UTC}))
+	assert.Nil(t, uc.Remove(ctx, 1))
+
+	events, err := outbox.Claim(ctx, time.Now(), time.Minute, 10)
+	assert.Nil(t, err)
+	if assert.Len(t, events, 2) {
+		assert.Equal(t, model.EventUserUpdated, events[0].Type)
+		assert.JSONEq(t, `{"id":1,"name":"user","time_zone":"UTC","organization_id":1}`, string(events[0].Payload))
+		assert.Equal(t, model.EventUserDeleted, events[1].Type)
+	}
+}
+
 func TestUserUsecase_HashUser(t *testing.T) {
 	uc := UserUsecase{}
 	word := "u"
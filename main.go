@@ -2,19 +2,24 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
-	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
 	"time"
+	in_memory "todoNote/internal/repo/in-memory"
 	"todoNote/internal/repo/postgres"
+	"todoNote/internal/repo/sqlite"
 	http2 "todoNote/internal/server/http"
 )
 
 const(
 	httpPortEnv = "HTTP_PORT"
+	migrateOnStartupEnv = "MIGRATE_ON_STARTUP"
 )
 
 func testHandler(w http.ResponseWriter, r *http.Request) {
@@ -23,6 +28,9 @@ func testHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func main() {
+	migrateOnStartup := flag.Bool("migrate", os.Getenv(migrateOnStartupEnv) == "true", "apply pending migrations before serving")
+	flag.Parse()
+
 	//mux := http.NewServeMux()
 	//mux.HandleFunc("/", testHandler)
 	//log.Fatal(http.ListenAndServe(":8080", apmhttp.Wrap(mux)))
@@ -36,32 +44,85 @@ func main() {
 		cancel()
 	} ()
 
-	dbConnection := make(chan *pgx.Conn, 1)
-	go func(ctx context.Context,c chan *pgx.Conn) {
-		defer close(c)
-		conn, err := postgres.Connect(ctx)
-		if err != nil {
-			log.Fatal(err)
+	kind := os.Getenv(storageEnv)
+	if kind == "" || flag.Arg(0) == "migrate" {
+		kind = storagePostgres
+	}
+
+	var repos http2.Repositories
+	switch kind {
+	case storagePostgres:
+		conn := connectPostgres(ctx)
+		defer conn.Close()
+
+		if flag.Arg(0) == "migrate" {
+			if err := migrate(ctx, conn, flag.Args()[1:], os.Stdout); err != nil {
+				log.Fatal(err)
+			}
+			return
 		}
-		c <- conn
-	} (ctx, dbConnection)
 
-	conn := <- dbConnection
-	defer conn.Close(context.Background())
+		if *migrateOnStartup {
+			if err := migrate(ctx, conn, []string{"up"}, os.Stdout); err != nil {
+				log.Fatal(err)
+			}
+		}
 
-	go func(ctx context.Context, conn *pgx.Conn) {
-		if err := postgres.Ping(ctx, conn); err != nil {
+		prometheus.MustRegister(postgres.NewPoolCollector(conn))
+		repos = postgresRepositories(conn)
+	case storageSqlite:
+		// migrations of SQLite are applied when it is opened
+		conn, err := sqlite.Connect(ctx)
+		if err != nil {
 			log.Fatal(err)
 		}
-	}(ctx, conn)
+		defer conn.Close()
+
+		log.Println("successfully opened sqlite db")
+		repos = sqliteRepositories(conn)
+	case storageMemory:
+		repos = memoryRepositories()
+		if dir := os.Getenv(memoryDirEnv); dir != "" {
+			interval, err := memorySnapshotInterval()
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			store, err := in_memory.Open(dir)
+			if err != nil {
+				log.Fatal(err)
+			}
+			defer func() {
+				if err := store.Close(); err != nil {
+					log.Printf("could not close in-memory store: %v", err)
+				}
+			}()
+
+			log.Printf("successfully opened in-memory store in %v", dir)
+			go store.Run(ctx, interval)
+			repos.Note = store.Notes()
+			repos.User = store.Users()
+			repos.Organization = store.Organizations()
+			repos.Outbox = store.Outbox()
+			repos.Mfa = store.Mfa()
+			repos.Session = store.Sessions()
+			repos.Identity = store.Identities()
+			repos.Export = store.Exports()
+			repos.AccountDeletion = store.AccountDeletions()
+			repos.CalendarFeed = store.CalendarFeeds()
+		}
+	default:
+		log.Fatalf("unknown %v %q, use %v, %v or %v", storageEnv, kind, storagePostgres, storageSqlite, storageMemory)
+	}
 
-	log.Println("successfully connected to db")
-	repos := http2.Repositories{
-		Note: postgres.NewRepoNote(conn),
-		User: postgres.NewRepoUser(conn),
+	if flag.Arg(0) == "organization" {
+		if err := organization(ctx, repos.Organization, flag.Args()[1:], os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
 	}
 
-	r, err := http2.NewRouter(repos)
+	r, wait, err := http2.NewRouter(ctx, repos)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -84,6 +145,31 @@ func main() {
 	if err := srv.Shutdown(ctxShutdown); err != nil {
 		log.Fatalf("could not shutdown properly: %v", err)
 	}
+	// storage is closed after the background work which uses it
+	wait()
 
 	log.Printf("server shut down")
 }
+
+func connectPostgres(ctx context.Context) *pgxpool.Pool {
+	dbConnection := make(chan *pgxpool.Pool, 1)
+	go func(ctx context.Context,c chan *pgxpool.Pool) {
+		defer close(c)
+		pool, err := postgres.Connect(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		c <- pool
+	} (ctx, dbConnection)
+
+	conn := <- dbConnection
+
+	go func(ctx context.Context, conn *pgxpool.Pool) {
+		if err := postgres.Ping(ctx, conn); err != nil {
+			log.Fatal(err)
+		}
+	}(ctx, conn)
+
+	log.Println("successfully connected to db")
+	return conn
+}
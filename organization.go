@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"todoNote/internal/repo"
+	"todoNote/internal/usecase"
+)
+
+const organizationUsage = "usage: todoNote organization create NAME"
+
+// organization runs the organization subcommand against the selected storage
+func organization(ctx context.Context, r repo.IRepoOrganization, args []string, out io.Writer) error {
+	if len(args) != 2 || args[0] != "create" {
+		return errors.New(organizationUsage)
+	}
+
+	id, err := usecase.NewOrganizationUsecase(r).Create(ctx, args[1])
+	if errors.Is(err, repo.ErrConflict) {
+		return fmt.Errorf("organization %q already exists", args[1])
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "created organization %v with id %v\n", args[1], id)
+	return nil
+}
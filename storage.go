@@ -0,0 +1,91 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"time"
+	in_memory "todoNote/internal/repo/in-memory"
+	"todoNote/internal/repo/postgres"
+	"todoNote/internal/repo/sqlite"
+	http2 "todoNote/internal/server/http"
+)
+
+const (
+	storageEnv = "STORAGE"
+
+	storagePostgres = "postgres"
+	storageSqlite = "sqlite"
+	storageMemory = "memory"
+
+	// memoryDirEnv keeps the memory storage in the directory apart from login attempts, without it everything is lost on restart
+	memoryDirEnv = "MEMORY_DIR"
+	memorySnapshotIntervalEnv = "MEMORY_SNAPSHOT_INTERVAL"
+	defaultMemorySnapshotInterval = 5 * time.Minute
+)
+
+func postgresRepositories(conn postgres.DB) http2.Repositories {
+	return http2.Repositories{
+		Note: postgres.NewRepoNote(conn),
+		User: postgres.NewRepoUser(conn),
+		Mfa: postgres.NewRepoMfa(conn),
+		Identity: postgres.NewRepoIdentity(conn),
+		LoginAttempts: postgres.NewRepoLoginAttempts(conn),
+		Session: postgres.NewRepoSession(conn),
+		Export: postgres.NewRepoExport(conn),
+		AccountDeletion: postgres.NewRepoAccountDeletion(conn),
+		CalendarFeed: postgres.NewRepoCalendarFeed(conn),
+		Organization: postgres.NewRepoOrganization(conn),
+		Outbox: postgres.NewRepoOutbox(conn),
+		Transactor: postgres.NewTransactor(conn),
+	}
+}
+
+func sqliteRepositories(conn *sql.DB) http2.Repositories {
+	return http2.Repositories{
+		Note: sqlite.NewRepoNote(conn),
+		User: sqlite.NewRepoUser(conn),
+		Mfa: sqlite.NewRepoMfa(conn),
+		Identity: sqlite.NewRepoIdentity(conn),
+		LoginAttempts: sqlite.NewRepoLoginAttempts(conn),
+		Session: sqlite.NewRepoSession(conn),
+		Export: sqlite.NewRepoExport(conn),
+		AccountDeletion: sqlite.NewRepoAccountDeletion(conn),
+		CalendarFeed: sqlite.NewRepoCalendarFeed(conn),
+		Organization: sqlite.NewRepoOrganization(conn),
+		Outbox: sqlite.NewRepoOutbox(conn),
+		Transactor: sqlite.NewTransactor(conn),
+	}
+}
+
+func memoryRepositories() http2.Repositories {
+	return http2.Repositories{
+		Note: in_memory.NewRepoNote(),
+		User: in_memory.NewRepoUser(),
+		Mfa: in_memory.NewRepoMfa(),
+		Identity: in_memory.NewRepoIdentity(),
+		LoginAttempts: in_memory.NewRepoLoginAttempts(),
+		Session: in_memory.NewRepoSession(),
+		Export: in_memory.NewRepoExport(),
+		AccountDeletion: in_memory.NewRepoAccountDeletion(),
+		CalendarFeed: in_memory.NewRepoCalendarFeed(),
+		Organization: in_memory.NewRepoOrganization(),
+		Outbox: in_memory.NewRepoOutbox(),
+		Transactor: in_memory.NewTransactor(),
+	}
+}
+
+// memorySnapshotInterval is MEMORY_SNAPSHOT_INTERVAL, a duration like 5m
+func memorySnapshotInterval() (time.Duration, error) {
+	v := os.Getenv(memorySnapshotIntervalEnv)
+	if v == "" {
+		return defaultMemorySnapshotInterval, nil
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("%v must be a positive duration, got %q", memorySnapshotIntervalEnv, v)
+	}
+
+	return d, nil
+}